@@ -52,6 +52,11 @@ type properties struct {
 // Bazel.
 type BazelModuleBase struct {
 	bazelProperties properties
+
+	// Whether a bp2build converter created a Bazel target for this module, or the reason it
+	// didn't. Only set in Bazel conversion mode.
+	bp2buildConverted         bool
+	bp2buildUnconvertedReason *UnconvertedReason
 }
 
 // Bazelable is specifies the interface for modules that can be converted to Bazel.
@@ -63,6 +68,107 @@ type Bazelable interface {
 	ConvertWithBp2build(ctx BazelConversionPathContext) bool
 	GetBazelBuildFileContents(c Config, path, name string) (string, error)
 	ConvertedToBazel(ctx BazelConversionPathContext) bool
+	Bp2buildConverted() bool
+	Bp2buildUnconvertedReason() *UnconvertedReason
+	markBp2buildConverted()
+	setBp2buildUnconvertedReason(ctx BazelConversionPathContext)
+}
+
+// UnconvertedReasonType is the kind of reason that a module was not converted by bp2build.
+type UnconvertedReasonType int
+
+const (
+	// The module type has no bp2build converter.
+	UnconvertedReasonUnsupportedModuleType UnconvertedReasonType = iota
+
+	// The module sets a property that its bp2build converter can't convert.
+	UnconvertedReasonUnsupportedProperty
+
+	// The module depends on a module that was not converted.
+	UnconvertedReasonUnconvertedDep
+
+	// The module sets bazel_module: { bp2build_available: false }, or is in a package that isn't
+	// enabled for bp2build and doesn't opt in.
+	UnconvertedReasonBp2buildAvailableFalse
+
+	// The module is in the bp2build module denylist.
+	UnconvertedReasonDenylisted
+)
+
+func (t UnconvertedReasonType) String() string {
+	switch t {
+	case UnconvertedReasonUnsupportedModuleType:
+		return "UNSUPPORTED_MODULE_TYPE"
+	case UnconvertedReasonUnsupportedProperty:
+		return "UNSUPPORTED_PROPERTY"
+	case UnconvertedReasonUnconvertedDep:
+		return "UNCONVERTED_DEP"
+	case UnconvertedReasonBp2buildAvailableFalse:
+		return "BP2BUILD_AVAILABLE_FALSE"
+	case UnconvertedReasonDenylisted:
+		return "DENYLISTED"
+	default:
+		return fmt.Sprintf("%d", int(t))
+	}
+}
+
+// UnconvertedReason describes why a module was not converted by bp2build.
+type UnconvertedReason struct {
+	ReasonType UnconvertedReasonType
+
+	// A human readable explanation, e.g. the name of the unsupported property.
+	Detail string
+}
+
+// MarkBp2buildUnconvertible records why the bp2build converter of a module didn't convert it, for
+// bp2build to report. It should be called by converters that return without creating a target
+// for a module that ConvertWithBp2build returned true for.
+func (b *BazelModuleBase) MarkBp2buildUnconvertible(reasonType UnconvertedReasonType, detail string) {
+	b.bp2buildUnconvertedReason = &UnconvertedReason{
+		ReasonType: reasonType,
+		Detail:     detail,
+	}
+}
+
+// Bp2buildConverted returns whether a bp2build converter created a Bazel target for this module.
+func (b *BazelModuleBase) Bp2buildConverted() bool {
+	return b.bp2buildConverted
+}
+
+// Bp2buildUnconvertedReason returns the reason that bp2build didn't convert this module, or nil if
+// it was converted or has a handcrafted label.
+func (b *BazelModuleBase) Bp2buildUnconvertedReason() *UnconvertedReason {
+	return b.bp2buildUnconvertedReason
+}
+
+func (b *BazelModuleBase) markBp2buildConverted() {
+	b.bp2buildConverted = true
+	b.bp2buildUnconvertedReason = nil
+}
+
+// setBp2buildUnconvertedReason sets the reason that an unconverted module was not converted, if
+// its converter didn't already record one, following the checks of ConvertWithBp2build.
+func (b *BazelModuleBase) setBp2buildUnconvertedReason(ctx BazelConversionPathContext) {
+	if b.bp2buildConverted || b.HasHandcraftedLabel() || b.bp2buildUnconvertedReason != nil {
+		return
+	}
+
+	propValue := b.bazelProperties.Bazel_module.Bp2build_available
+	switch {
+	case bp2buildModuleDoNotConvert[ctx.Module().Name()]:
+		b.MarkBp2buildUnconvertible(UnconvertedReasonDenylisted, "in the bp2build module denylist")
+	case !ctx.Config().bp2buildModuleTypeConfig[ctx.ModuleType()]:
+		b.MarkBp2buildUnconvertible(UnconvertedReasonUnsupportedModuleType, ctx.ModuleType())
+	case propValue != nil && !*propValue:
+		b.MarkBp2buildUnconvertible(UnconvertedReasonBp2buildAvailableFalse,
+			"bazel_module.bp2build_available is false")
+	case !b.ConvertWithBp2build(ctx):
+		b.MarkBp2buildUnconvertible(UnconvertedReasonBp2buildAvailableFalse,
+			fmt.Sprintf("package %q is not enabled for bp2build", ctx.ModuleDir()))
+	default:
+		b.MarkBp2buildUnconvertible(UnconvertedReasonUnsupportedProperty,
+			"the bp2build converter did not create a target")
+	}
 }
 
 // BazelModule is a lightweight wrapper interface around Module for Bazel-convertible modules.
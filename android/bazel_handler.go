@@ -69,6 +69,16 @@ type BazelContext interface {
 	// Returns the results of GetOutputFiles and GetCcObjectFiles in a single query (in that order).
 	GetCcInfo(label string, archType ArchType) (cquery.CcInfo, bool, error)
 
+	// Returns the jars of the JavaInfo provider of the given bazel target label.
+	GetJavaInfo(label string, archType ArchType) (cquery.JavaInfo, bool, error)
+
+	// Returns the runfiles of the DefaultInfo provider of the given bazel target label.
+	GetRunfiles(label string, archType ArchType) ([]string, bool)
+
+	// Returns the value of a field of a provider of the given bazel target label, see
+	// cquery.GetProviderField.
+	GetProviderField(label string, archType ArchType, provider, field string) ([]string, bool)
+
 	// ** End cquery methods
 
 	// Issues commands to Bazel to receive results for all cquery requests
@@ -125,6 +135,11 @@ type MockBazelContext struct {
 
 	LabelToOutputFiles map[string][]string
 	LabelToCcInfo      map[string]cquery.CcInfo
+	LabelToJavaInfo    map[string]cquery.JavaInfo
+	LabelToRunfiles    map[string][]string
+
+	// The provider field values of each label, keyed by "<provider>.<field>".
+	LabelToProviderFields map[string]map[string][]string
 }
 
 func (m MockBazelContext) GetOutputFiles(label string, archType ArchType) ([]string, bool) {
@@ -137,6 +152,21 @@ func (m MockBazelContext) GetCcInfo(label string, archType ArchType) (cquery.CcI
 	return result, ok, nil
 }
 
+func (m MockBazelContext) GetJavaInfo(label string, archType ArchType) (cquery.JavaInfo, bool, error) {
+	result, ok := m.LabelToJavaInfo[label]
+	return result, ok, nil
+}
+
+func (m MockBazelContext) GetRunfiles(label string, archType ArchType) ([]string, bool) {
+	result, ok := m.LabelToRunfiles[label]
+	return result, ok
+}
+
+func (m MockBazelContext) GetProviderField(label string, archType ArchType, provider, field string) ([]string, bool) {
+	result, ok := m.LabelToProviderFields[label][provider+"."+field]
+	return result, ok
+}
+
 func (m MockBazelContext) InvokeBazel() error {
 	panic("unimplemented")
 }
@@ -174,6 +204,38 @@ func (bazelCtx *bazelContext) GetCcInfo(label string, archType ArchType) (cquery
 	return ret, ok, err
 }
 
+func (bazelCtx *bazelContext) GetJavaInfo(label string, archType ArchType) (cquery.JavaInfo, bool, error) {
+	result, ok := bazelCtx.cquery(label, cquery.GetJavaInfo, archType)
+	if !ok {
+		return cquery.JavaInfo{}, ok, nil
+	}
+
+	bazelOutput := strings.TrimSpace(result)
+	ret, err := cquery.GetJavaInfo.ParseResult(bazelOutput)
+	return ret, ok, err
+}
+
+func (bazelCtx *bazelContext) GetRunfiles(label string, archType ArchType) ([]string, bool) {
+	rawString, ok := bazelCtx.cquery(label, cquery.GetRunfiles, archType)
+	var ret []string
+	if ok {
+		bazelOutput := strings.TrimSpace(rawString)
+		ret = cquery.GetRunfiles.ParseResult(bazelOutput)
+	}
+	return ret, ok
+}
+
+func (bazelCtx *bazelContext) GetProviderField(label string, archType ArchType, provider, field string) ([]string, bool) {
+	requestType := cquery.GetProviderField(provider, field)
+	rawString, ok := bazelCtx.cquery(label, requestType, archType)
+	var ret []string
+	if ok {
+		bazelOutput := strings.TrimSpace(rawString)
+		ret = requestType.ParseResult(bazelOutput)
+	}
+	return ret, ok
+}
+
 func (n noopBazelContext) GetOutputFiles(label string, archType ArchType) ([]string, bool) {
 	panic("unimplemented")
 }
@@ -182,6 +244,18 @@ func (n noopBazelContext) GetCcInfo(label string, archType ArchType) (cquery.CcI
 	panic("unimplemented")
 }
 
+func (n noopBazelContext) GetJavaInfo(label string, archType ArchType) (cquery.JavaInfo, bool, error) {
+	panic("unimplemented")
+}
+
+func (n noopBazelContext) GetRunfiles(label string, archType ArchType) ([]string, bool) {
+	panic("unimplemented")
+}
+
+func (n noopBazelContext) GetProviderField(label string, archType ArchType, provider, field string) ([]string, bool) {
+	panic("unimplemented")
+}
+
 func (n noopBazelContext) GetPrebuiltCcStaticLibraryFiles(label string, archType ArchType) ([]string, bool) {
 	panic("unimplemented")
 }
@@ -209,6 +283,16 @@ func NewBazelContext(c *config) (BazelContext, error) {
 		return noopBazelContext{}, nil
 	}
 
+	// Replay the outputs of Bazel from fixture files instead of running it, to test mixed builds
+	// offline.
+	if fixtureDir := c.Getenv("BAZEL_FIXTURE_DIR"); fixtureDir != "" {
+		return &bazelContext{
+			bazelRunner: &fixtureBazelRunner{dir: fixtureDir},
+			paths:       fixtureBazelPaths(c, fixtureDir),
+			requests:    make(map[cqueryKey]bool),
+		}, nil
+	}
+
 	p, err := bazelPathsFromConfig(c)
 	if err != nil {
 		return nil, err
@@ -257,6 +341,22 @@ func bazelPathsFromConfig(c *config) (*bazelPaths, error) {
 	}
 }
 
+// fixtureBazelPaths returns the paths to use with a fixtureBazelRunner, which doesn't need Bazel
+// itself. The output base defaults to the fixture directory, as the paths in the fixtures are
+// relative to it.
+func fixtureBazelPaths(c *config, fixtureDir string) *bazelPaths {
+	p := bazelPaths{
+		buildDir:     c.buildDir,
+		outputBase:   c.Getenv("BAZEL_OUTPUT_BASE"),
+		workspaceDir: c.Getenv("BAZEL_WORKSPACE"),
+		metricsDir:   c.Getenv("BAZEL_METRICS_DIR"),
+	}
+	if p.outputBase == "" {
+		p.outputBase = fixtureDir
+	}
+	return &p
+}
+
 func (p *bazelPaths) BazelMetricsDir() string {
 	return p.metricsDir
 }
@@ -313,6 +413,29 @@ func (r *mockBazelRunner) issueBazelCommand(paths *bazelPaths,
 	return "", "", nil
 }
 
+// fixtureBazelRunner replays canned outputs of Bazel commands from a directory of fixture files
+// instead of running Bazel. The output of a command is read from "<run name>.out" in the
+// directory, e.g. cquery-buildroot.out and aquery-buildroot.out. Commands without a fixture fail,
+// except for builds, whose output isn't used.
+type fixtureBazelRunner struct {
+	dir string
+}
+
+func (r *fixtureBazelRunner) issueBazelCommand(paths *bazelPaths,
+	runName bazel.RunName,
+	command bazelCommand,
+	extraFlags ...string) (string, string, error) {
+	fixture := filepath.Join(absolutePath(r.dir), runName.String()+".out")
+	data, err := ioutil.ReadFile(fixture)
+	if os.IsNotExist(err) && command.command == "build" {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", fmt.Errorf("no Bazel fixture for %s command [%s]: %s",
+			command.command, command.expression, err)
+	}
+	return string(data), "", nil
+}
+
 type builtinBazelRunner struct{}
 
 // Issues the given bazel command with given build label and additional flags.
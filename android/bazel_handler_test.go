@@ -1,6 +1,7 @@
 package android
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -116,3 +117,71 @@ func testBazelContext(t *testing.T, bazelCommandResults map[bazelCommand]string)
 		requests:    map[cqueryKey]bool{},
 	}, p.buildDir
 }
+
+func TestFixtureBazelRunner(t *testing.T) {
+	fixtureDir := t.TempDir()
+	fixtures := map[string]string{
+		"cquery-buildroot.out": `//foo:bar|arm64>>out/foo/bar.txt`,
+		"aquery-buildroot.out": `
+{
+  "artifacts": [{
+    "id": 1,
+    "pathFragmentId": 1
+  }],
+  "actions": [{
+    "targetId": 1,
+    "actionKey": "x",
+    "mnemonic": "x",
+    "arguments": ["touch", "one"],
+    "outputIds": [1],
+    "primaryOutputId": 1
+  }],
+  "pathFragments": [{
+    "id": 1,
+    "label": "one"
+  }]
+}`,
+	}
+	for name, contents := range fixtures {
+		if err := ioutil.WriteFile(filepath.Join(fixtureDir, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := TestConfig(t.TempDir(), map[string]string{
+		"USE_BAZEL_ANALYSIS": "1",
+		"BAZEL_FIXTURE_DIR":  fixtureDir,
+	}, "", nil)
+	bazelContext, err := NewBazelContext(config.config)
+	if err != nil {
+		t.Fatalf("Did not expect error creating a Bazel context, but got %s", err)
+	}
+
+	if _, ok := bazelContext.GetOutputFiles("//foo:bar", Arm64); ok {
+		t.Errorf("Did not expect cquery results prior to running InvokeBazel()")
+	}
+	if err := bazelContext.InvokeBazel(); err != nil {
+		t.Fatalf("Did not expect error invoking Bazel, but got %s", err)
+	}
+
+	g, ok := bazelContext.GetOutputFiles("//foo:bar", Arm64)
+	if !ok {
+		t.Errorf("Expected cquery results after running InvokeBazel(), but got none")
+	} else if w := []string{"out/foo/bar.txt"}; !reflect.DeepEqual(w, g) {
+		t.Errorf("Expected output %s, got %s", w, g)
+	}
+	if g, w := bazelContext.OutputBase(), fixtureDir; g != w {
+		t.Errorf("Expected output base %q, got %q", w, g)
+	}
+	if got, want := len(bazelContext.BuildStatementsToRegister()), 1; got != want {
+		t.Errorf("Expected %d registered build statements, got %d", want, got)
+	}
+
+	// Without an aquery fixture, invoking Bazel fails.
+	if err := os.Remove(filepath.Join(fixtureDir, "aquery-buildroot.out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bazelContext.InvokeBazel(); err == nil {
+		t.Errorf("Expected an error invoking Bazel without an aquery fixture")
+	}
+}
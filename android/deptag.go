@@ -43,3 +43,21 @@ func IsInstallDepNeeded(tag blueprint.DependencyTag) bool {
 	}
 	return false
 }
+
+// Dependency tags can implement this interface and return true from StaticLinkDep to annotate
+// that the contents of the child are linked into the outputs of the parent, e.g. for static
+// libraries. The SBOM records a STATIC_LINK relationship between the parent and the child.
+type StaticLinkDependencyTag interface {
+	// If StaticLinkDep returns true then the outputs of the parent contain the child.
+	StaticLinkDep() bool
+}
+
+// IsStaticLinkDep returns true if the dependency tag implements the StaticLinkDependencyTag
+// interface and the StaticLinkDep returns true, meaning that the outputs of the parent contain the
+// child.
+func IsStaticLinkDep(tag blueprint.DependencyTag) bool {
+	if s, ok := tag.(StaticLinkDependencyTag); ok {
+		return s.StaticLinkDep()
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+)
+
+// The license_policy module type declares which license conditions may not be included in a set of
+// modules, e.g.
+//
+//    license_policy {
+//        name: "no_restricted_in_vendor_or_apex",
+//        conditions: ["restricted", "by_exception_only"],
+//        partitions: ["vendor"],
+//        module_types: ["apex"],
+//        allowlist: ["libfoo", "com.android.bar:libbaz"],
+//        because: "restricted code may not be shipped in the vendor partition or an apex",
+//    }
+//
+// The license conditions of a module propagate to the modules that statically link it or package
+// it, i.e. along dependencies with a StaticLinkDependencyTag or PackagingItem tag. The
+// license_policy singleton reports an error for every module that a policy applies to that
+// includes, directly or transitively, a module with one of the conditions of the policy. The
+// violations, including the chain of modules that includes the offending module, are also written
+// to out/soong/license_policy/violations.json by the license-policy-report phony target.
+
+func init() {
+	RegisterLicensePolicyBuildComponents(InitRegistrationContext)
+}
+
+// Register the license_policy module type and the singleton that enforces the policies.
+func RegisterLicensePolicyBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("license_policy", LicensePolicyFactory)
+	ctx.RegisterSingletonType("license_policy", licensePolicySingletonFactory)
+}
+
+var PrepareForTestWithLicensePolicy = FixtureRegisterWithContext(RegisterLicensePolicyBuildComponents)
+
+type licensePolicyProperties struct {
+	// The license conditions that may not be included in the modules the policy applies to.
+	Conditions []string
+
+	// The policy applies to the modules that install files into any of these partitions, e.g.
+	// vendor.
+	Partitions []string
+
+	// The policy applies to the modules of any of these module types, e.g. apex.
+	Module_types []string
+
+	// Violations that are allowed, either the name of a module that may be included in any module
+	// the policy applies to, or <module>:<included module> to allow it in a single module.
+	Allowlist []string
+
+	// The reason for the policy, which is included in the errors.
+	Because *string
+
+	// If true, violations of the policy are only written to the report and do not fail the build.
+	Warn_only *bool
+}
+
+type licensePolicyModule struct {
+	ModuleBase
+
+	properties licensePolicyProperties
+}
+
+func (m *licensePolicyModule) GenerateAndroidBuildActions(ModuleContext) {
+	// Nothing to do, the policies are enforced by the license_policy singleton.
+}
+
+func LicensePolicyFactory() Module {
+	module := &licensePolicyModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// appliesTo returns true if the policy applies to the given variant of a module.
+func (m *licensePolicyModule) appliesTo(ctx SingletonContext, module Module) bool {
+	if InList(ctx.ModuleType(module), m.properties.Module_types) {
+		return true
+	}
+	for _, installed := range module.FilesToInstall() {
+		if InList(sbomPartition(ctx, installed), m.properties.Partitions) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the module may include the given module.
+func (m *licensePolicyModule) allows(module, included string) bool {
+	return InList(included, m.properties.Allowlist) || InList(module+":"+included, m.properties.Allowlist)
+}
+
+// A single violation of a license policy.
+type licensePolicyViolation struct {
+	// The name of the policy that was violated.
+	Policy string `json:"policy"`
+
+	// The name of the module the policy applies to.
+	Module string `json:"module"`
+
+	// The name of the module that has the conditions, which is included in the module.
+	Included string `json:"included"`
+
+	// The conditions of the included module that are not allowed by the policy.
+	Conditions []string `json:"conditions"`
+
+	// The chain of modules from the module to the included module.
+	Path []string `json:"path"`
+
+	// Whether the policy is warn-only, if false the violation fails the build.
+	WarnOnly bool `json:"warn_only"`
+}
+
+func (v licensePolicyViolation) String() string {
+	return fmt.Sprintf("includes %q with license conditions %q through %s",
+		v.Included, v.Conditions, strings.Join(v.Path, " -> "))
+}
+
+func licensePolicySingletonFactory() Singleton {
+	return &licensePolicySingleton{}
+}
+
+type licensePolicySingleton struct {
+	report OutputPath
+}
+
+func (s *licensePolicySingleton) GenerateBuildActions(ctx SingletonContext) {
+	var policies []*licensePolicyModule
+	ctx.VisitAllModules(func(module Module) {
+		if policy, ok := module.(*licensePolicyModule); ok {
+			policies = append(policies, policy)
+		}
+	})
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name() < policies[j].Name() })
+
+	var violations []licensePolicyViolation
+	reported := make(map[string]bool)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+		for _, policy := range policies {
+			if !policy.appliesTo(ctx, module) {
+				continue
+			}
+			for _, v := range checkLicensePolicy(ctx, policy, module) {
+				// Report the violation once for all the variants of the module.
+				key := v.Policy + " " + v.Module + " " + v.Included
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+				violations = append(violations, v)
+
+				if !v.WarnOnly {
+					msg := fmt.Sprintf("violates license policy %q: %s", v.Policy, v)
+					if because := proptools.String(policy.properties.Because); because != "" {
+						msg += ": " + because
+					}
+					ctx.ModuleErrorf(module, "%s", msg)
+				}
+			}
+		}
+	})
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Included < b.Included
+	})
+	if violations == nil {
+		violations = []licensePolicyViolation{}
+	}
+	content, err := json.MarshalIndent(violations, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal license policy violations: %s", err)
+		return
+	}
+
+	s.report = PathForOutput(ctx, "license_policy", "violations.json")
+	WriteFileRule(ctx, s.report, string(content))
+
+	ctx.Phony("license-policy-report", s.report)
+}
+
+func (s *licensePolicySingleton) MakeVars(ctx MakeVarsContext) {
+	ctx.DistForGoal("license-policy-report", s.report)
+}
+
+// licenseGraphDeps returns the variants of the modules that are statically linked or packaged into
+// the given variant of a module.
+func licenseGraphDeps(ctx SingletonContext, module Module) []Module {
+	if !ctx.ModuleHasProvider(module, licenseGraphInfoProvider) {
+		return nil
+	}
+	return ctx.ModuleProvider(module, licenseGraphInfoProvider).(licenseGraphInfo).Deps
+}
+
+// checkLicensePolicy returns the violations of the policy by the given variant of a module, one for
+// every module it includes that has any of the conditions of the policy, with the shortest chain of
+// modules that includes it. Only the edges of the variants that are actually included are followed.
+func checkLicensePolicy(ctx SingletonContext, policy *licensePolicyModule, module Module) []licensePolicyViolation {
+	name := ctx.ModuleName(module)
+	parents := map[Module]Module{module: nil}
+	var queue []Module
+	for _, dep := range licenseGraphDeps(ctx, module) {
+		if _, seen := parents[dep]; !seen {
+			parents[dep] = module
+			queue = append(queue, dep)
+		}
+	}
+
+	var violations []licensePolicyViolation
+	reported := make(map[string]bool)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		depName := ctx.ModuleName(dep)
+
+		var conditions []string
+		for _, condition := range dep.base().commonProperties.Effective_license_conditions {
+			if InList(condition, policy.properties.Conditions) {
+				conditions = append(conditions, condition)
+			}
+		}
+		conditions = SortedUniqueStrings(conditions)
+		if len(conditions) > 0 && !reported[depName] && !policy.allows(name, depName) {
+			// Report each included module once, with the shortest chain through any of its variants.
+			reported[depName] = true
+			var path []string
+			for m := dep; m != nil; m = parents[m] {
+				path = append([]string{ctx.ModuleName(m)}, path...)
+			}
+			violations = append(violations, licensePolicyViolation{
+				Policy:     policy.Name(),
+				Module:     name,
+				Included:   depName,
+				Conditions: conditions,
+				Path:       path,
+				WarnOnly:   proptools.Bool(policy.properties.Warn_only),
+			})
+		}
+
+		for _, next := range licenseGraphDeps(ctx, dep) {
+			if _, seen := parents[next]; !seen {
+				parents[next] = dep
+				queue = append(queue, next)
+			}
+		}
+	}
+	return violations
+}
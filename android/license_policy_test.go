@@ -0,0 +1,284 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const licensePolicyTestModules = `
+	license_kind {
+		name: "restricted_kind",
+		conditions: ["restricted"],
+	}
+
+	license {
+		name: "restricted_license",
+		license_kinds: ["restricted_kind"],
+	}
+
+	test_module {
+		name: "vendor_bin",
+		vendor: true,
+		static_libs: ["libmid"],
+	}
+
+	test_module {
+		name: "system_bin",
+		static_libs: ["librestricted"],
+	}
+
+	test_module {
+		name: "libmid",
+		static: true,
+		static_libs: ["librestricted"],
+	}
+
+	test_module {
+		name: "librestricted",
+		licenses: ["restricted_license"],
+		static: true,
+	}
+`
+
+var licensePolicyTests = []struct {
+	name               string
+	policy             string
+	expectedErrors     []string
+	expectedViolations []licensePolicyViolation
+}{
+	{
+		name: "violation",
+		policy: `
+			license_policy {
+				name: "no_restricted_in_vendor",
+				conditions: ["restricted", "by_exception_only"],
+				partitions: ["vendor"],
+				because: "restricted code may not be shipped in the vendor partition",
+			}`,
+		expectedErrors: []string{
+			`module "vendor_bin".*violates license policy "no_restricted_in_vendor": includes "librestricted" ` +
+				`with license conditions \["restricted"\] through vendor_bin -> libmid -> librestricted: ` +
+				`restricted code may not be shipped in the vendor partition`,
+		},
+		expectedViolations: []licensePolicyViolation{
+			{
+				Policy:     "no_restricted_in_vendor",
+				Module:     "vendor_bin",
+				Included:   "librestricted",
+				Conditions: []string{"restricted"},
+				Path:       []string{"vendor_bin", "libmid", "librestricted"},
+			},
+		},
+	},
+	{
+		name: "module type",
+		policy: `
+			license_policy {
+				name: "no_restricted_in_test_module",
+				conditions: ["restricted"],
+				module_types: ["test_module"],
+			}`,
+		expectedErrors: []string{
+			`module "libmid".*violates license policy "no_restricted_in_test_module": includes "librestricted"`,
+			`module "system_bin".*violates license policy "no_restricted_in_test_module": includes "librestricted"`,
+			`module "vendor_bin".*violates license policy "no_restricted_in_test_module": includes "librestricted"`,
+		},
+	},
+	{
+		name: "allowlist",
+		policy: `
+			license_policy {
+				name: "no_restricted_in_vendor",
+				conditions: ["restricted"],
+				partitions: ["vendor"],
+				allowlist: ["vendor_bin:librestricted"],
+			}`,
+		expectedViolations: []licensePolicyViolation{},
+	},
+	{
+		name: "warn only",
+		policy: `
+			license_policy {
+				name: "no_restricted_in_vendor",
+				conditions: ["restricted"],
+				partitions: ["vendor"],
+				warn_only: true,
+			}`,
+		expectedViolations: []licensePolicyViolation{
+			{
+				Policy:     "no_restricted_in_vendor",
+				Module:     "vendor_bin",
+				Included:   "librestricted",
+				Conditions: []string{"restricted"},
+				Path:       []string{"vendor_bin", "libmid", "librestricted"},
+				WarnOnly:   true,
+			},
+		},
+	},
+}
+
+func TestLicensePolicy(t *testing.T) {
+	for _, test := range licensePolicyTests {
+		t.Run(test.name, func(t *testing.T) {
+			result := GroupFixturePreparers(
+				PrepareForTestWithArchMutator,
+				PrepareForTestWithLicenses,
+				PrepareForTestWithLicensePolicy,
+				FixtureWithRootAndroidBp(licensePolicyTestModules+test.policy),
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("test_module", testSbomModuleFactory)
+				}),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+
+			if test.expectedViolations == nil {
+				return
+			}
+
+			report := result.SingletonForTests("license_policy").Output("license_policy/violations.json")
+			var violations []licensePolicyViolation
+			if err := json.Unmarshal([]byte(ContentFromFileRuleForTests(t, report)), &violations); err != nil {
+				t.Fatal(err)
+			}
+			AssertDeepEquals(t, "violations", test.expectedViolations, violations)
+		})
+	}
+}
+
+type testLicensePolicyModule struct {
+	ModuleBase
+	properties struct {
+		Static_libs []string `android:"arch_variant"`
+		Static      bool
+	}
+}
+
+func (m *testLicensePolicyModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, testStaticLinkDependencyTag{}, m.properties.Static_libs...)
+}
+
+func (m *testLicensePolicyModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	if !m.properties.Static {
+		ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), outputFile)
+	}
+}
+
+func testLicensePolicyModuleFactory() Module {
+	module := &testLicensePolicyModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, HostAndDeviceSupported, MultilibCommon)
+	return module
+}
+
+func TestLicensePolicyFollowsVariantEdges(t *testing.T) {
+	bp := `
+		license_kind {
+			name: "restricted_kind",
+			conditions: ["restricted"],
+		}
+
+		license {
+			name: "restricted_license",
+			license_kinds: ["restricted_kind"],
+		}
+
+		test_module {
+			name: "vendor_bin",
+			vendor: true,
+			static_libs: ["libmid"],
+		}
+
+		test_module {
+			name: "host_tool",
+			host_supported: true,
+			device_supported: false,
+			static_libs: ["libmid"],
+		}
+
+		test_module {
+			name: "libmid",
+			host_supported: true,
+			static: true,
+			target: {
+				host: {
+					static_libs: ["librestricted"],
+				},
+			},
+		}
+
+		test_module {
+			name: "librestricted",
+			host_supported: true,
+			licenses: ["restricted_license"],
+			static: true,
+		}
+
+		license_policy {
+			name: "no_restricted_in_vendor",
+			conditions: ["restricted"],
+			partitions: ["vendor"],
+		}
+
+		license_policy {
+			name: "no_restricted_in_test_module",
+			conditions: ["restricted"],
+			module_types: ["test_module"],
+			warn_only: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithLicenses,
+		PrepareForTestWithLicensePolicy,
+		FixtureWithRootAndroidBp(bp),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", testLicensePolicyModuleFactory)
+		}),
+	).RunTest(t)
+
+	// Only the host variant of libmid links librestricted, so the vendor binary doesn't include it.
+	report := result.SingletonForTests("license_policy").Output("license_policy/violations.json")
+	var violations []licensePolicyViolation
+	if err := json.Unmarshal([]byte(ContentFromFileRuleForTests(t, report)), &violations); err != nil {
+		t.Fatal(err)
+	}
+	AssertDeepEquals(t, "violations", []licensePolicyViolation{
+		{
+			Policy:     "no_restricted_in_test_module",
+			Module:     "host_tool",
+			Included:   "librestricted",
+			Conditions: []string{"restricted"},
+			Path:       []string{"host_tool", "libmid", "librestricted"},
+			WarnOnly:   true,
+		},
+		{
+			Policy:     "no_restricted_in_test_module",
+			Module:     "libmid",
+			Included:   "librestricted",
+			Conditions: []string{"restricted"},
+			Path:       []string{"libmid", "librestricted"},
+			WarnOnly:   true,
+		},
+	}, violations)
+}
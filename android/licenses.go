@@ -308,3 +308,45 @@ type LicenseInfo struct {
 }
 
 var LicenseInfoProvider = blueprint.NewProvider(LicenseInfo{})
+
+// licenseGraphInfo contains the dependencies of a module that the licenses of the dependency apply
+// to, which singletons can't find without the dependency tags.
+type licenseGraphInfo struct {
+	// The names of the modules whose contents are linked into the outputs of this module.
+	StaticLinkDeps []string
+
+	// The names of the modules whose outputs are packaged into the outputs of this module, e.g.
+	// the contents of an apex or a filesystem image.
+	PackagedDeps []string
+
+	// The variants of the modules in StaticLinkDeps and PackagedDeps that this variant depends on.
+	Deps []Module
+}
+
+var licenseGraphInfoProvider = blueprint.NewProvider(licenseGraphInfo{})
+
+// Records the dependencies of a module that are tagged as StaticLinkDependencyTag or
+// PackagingItem.
+func licenseGraphDepsGatherer(ctx ModuleContext) {
+	var info licenseGraphInfo
+	ctx.VisitDirectDeps(func(dep Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		staticLink := IsStaticLinkDep(tag)
+		if staticLink {
+			info.StaticLinkDeps = append(info.StaticLinkDeps, ctx.OtherModuleName(dep))
+		}
+		pi, ok := tag.(PackagingItem)
+		packaged := ok && pi.IsPackagingItem()
+		if packaged {
+			info.PackagedDeps = append(info.PackagedDeps, ctx.OtherModuleName(dep))
+		}
+		if staticLink || packaged {
+			info.Deps = append(info.Deps, dep)
+		}
+	})
+	if len(info.StaticLinkDeps) > 0 || len(info.PackagedDeps) > 0 {
+		info.StaticLinkDeps = SortedUniqueStrings(info.StaticLinkDeps)
+		info.PackagedDeps = SortedUniqueStrings(info.PackagedDeps)
+		ctx.SetProvider(licenseGraphInfoProvider, info)
+	}
+}
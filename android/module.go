@@ -1847,6 +1847,8 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			return
 		}
 
+		licenseGraphDepsGatherer(ctx)
+
 		m.module.GenerateAndroidBuildActions(ctx)
 		if ctx.Failed() {
 			return
@@ -67,6 +67,8 @@ func RegisterMutatorsForBazelConversion(ctx *Context, preArchMutators, depsMutat
 		f(mctx)
 	}
 
+	registerBp2buildConversionStatusMutator(mctx)
+
 	mctx.mutators.registerAll(ctx)
 }
 
@@ -516,6 +518,18 @@ func registerDepsMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("deps", depsMutator).Parallel()
 }
 
+// registerBp2buildConversionStatusMutator registers a mutator that runs after the bp2build
+// mutators to record why the modules that weren't converted were not.
+func registerBp2buildConversionStatusMutator(ctx RegisterMutatorsContext) {
+	ctx.TopDown("bp2build_conversion_status", bp2buildConversionStatusMutator).Parallel()
+}
+
+func bp2buildConversionStatusMutator(ctx TopDownMutatorContext) {
+	if b, ok := ctx.Module().(Bazelable); ok {
+		b.setBp2buildUnconvertedReason(ctx)
+	}
+}
+
 func registerDepsMutatorBp2Build(ctx RegisterMutatorsContext) {
 	// TODO(b/179313531): Consider a separate mutator that only runs depsMutator for modules that are
 	// being converted to build targets.
@@ -542,6 +556,9 @@ func (t *topDownMutatorContext) CreateBazelTargetModule(
 
 	b := t.createModuleWithoutInheritance(factory, &nameProp, attrs).(BazelTargetModule)
 	b.SetBazelTargetModuleProperties(bazelProps)
+	if bazelable, ok := t.Module().(Bazelable); ok {
+		bazelable.markBp2buildConverted()
+	}
 	return b
 }
 
@@ -41,6 +41,10 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "Without" properties matched (same rules as above)
+//
+// Violations of rules that are marked with WarnOnly, or of any rule when the
+// SOONG_NEVERALLOW_AUDIT environment variable is true, do not fail the build. They are
+// collected into a report instead, see neverallow_audit.go.
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
@@ -228,7 +232,9 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 
 	osClass := ctx.Module().Target().Os.Class
 
-	for _, r := range neverallowRules(ctx.Config()) {
+	audit := neverallowAuditMode(ctx.Config())
+
+	for _, r := range allNeverallowRules(ctx.Config()) {
 		n := r.(*rule)
 		if !n.appliesToPath(dir) {
 			continue
@@ -254,6 +260,11 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
+		if audit || n.warnOnly {
+			recordNeverallowViolation(ctx, n, properties)
+			continue
+		}
+
 		ctx.ModuleErrorf("violates " + n.String())
 	}
 }
@@ -366,6 +377,9 @@ type Rule interface {
 	WithoutMatcher(properties string, matcher ValueMatcher) Rule
 
 	Because(reason string) Rule
+
+	// Report violations of the rule in the neverallow audit report instead of failing the build.
+	WarnOnly() Rule
 }
 
 type rule struct {
@@ -386,6 +400,12 @@ type rule struct {
 	unlessProps []ruleProperty
 
 	onlyBootclasspathJar bool
+
+	// Whether violations are only reported in the neverallow audit report.
+	warnOnly bool
+
+	// Where the rule was declared if it came from a neverallow module, empty otherwise.
+	source string
 }
 
 // Create a new NeverAllow rule.
@@ -466,6 +486,11 @@ func (r *rule) BootclasspathJar() Rule {
 	return r
 }
 
+func (r *rule) WarnOnly() Rule {
+	r.warnOnly = true
+	return r
+}
+
 func (r *rule) String() string {
 	s := "neverallow"
 	for _, v := range r.paths {
@@ -498,6 +523,9 @@ func (r *rule) String() string {
 	if len(r.reason) != 0 {
 		s += " which is restricted because " + r.reason
 	}
+	if len(r.source) != 0 {
+		s += " (declared by " + r.source + ")"
+	}
 	return s
 }
 
@@ -608,6 +636,13 @@ func hasAllProperties(properties []interface{}, props []ruleProperty) bool {
 }
 
 func hasProperty(properties []interface{}, prop ruleProperty) bool {
+	_, ok := matchingPropertyValue(properties, prop)
+	return ok
+}
+
+// Returns the first value of the property that is matched by the rule property, and whether there
+// was one.
+func matchingPropertyValue(properties []interface{}, prop ruleProperty) (string, bool) {
 	for _, propertyStruct := range properties {
 		propertiesValue := reflect.ValueOf(propertyStruct).Elem()
 		for _, v := range prop.fields {
@@ -620,15 +655,17 @@ func hasProperty(properties []interface{}, prop ruleProperty) bool {
 			continue
 		}
 
+		var matched string
 		check := func(value string) bool {
+			matched = value
 			return prop.matcher.Test(value)
 		}
 
 		if matchValue(propertiesValue, check) {
-			return true
+			return matched, true
 		}
 	}
-	return false
+	return "", false
 }
 
 func matchValue(value reflect.Value, check func(string) bool) bool {
@@ -675,6 +712,19 @@ func neverallowRules(config Config) []Rule {
 	}).([]Rule)
 }
 
+var allNeverallowRulesKey = NewOnceKey("allNeverallowRules")
+
+// Returns the rules added by AddNeverAllowRules (or setTestNeverallowRules) followed by the rules
+// declared by neverallow modules.
+//
+// Must only be called after the neverallow rule gatherer has run.
+func allNeverallowRules(config Config) []Rule {
+	return config.Once(allNeverallowRulesKey, func() interface{} {
+		rules := append([]Rule(nil), neverallowRules(config)...)
+		return append(rules, neverallowModuleRules(config)...)
+	}).([]Rule)
+}
+
 // Overrides the default neverallow rules for the supplied config.
 //
 // For testing only.
@@ -693,6 +743,7 @@ func PrepareForTestWithNeverallowRules(testRules []Rule) FixturePreparer {
 			}
 		}),
 		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			RegisterNeverallowBuildComponents(ctx)
 			ctx.PostDepsMutators(registerNeverallowMutator)
 		}),
 	)
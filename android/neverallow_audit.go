@@ -0,0 +1,206 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint/proptools"
+)
+
+// The neverallow audit report lists the violations of neverallow rules that did not fail the
+// build, either because the rule was marked with WarnOnly or because audit mode was enabled by
+// setting SOONG_NEVERALLOW_AUDIT=true, which makes every rule warn-only.
+//
+// The report is written to out/soong/neverallow/violations.json and violations.csv by the
+// neverallow-report phony target, and is disted with droidcore in audit mode, which allows the
+// work needed to clean up the tree to be measured before a new rule is enforced.
+
+// Returns true if violations of all neverallow rules should be reported instead of failing the
+// build.
+func neverallowAuditMode(config Config) bool {
+	return config.IsEnvTrue("SOONG_NEVERALLOW_AUDIT")
+}
+
+// A single violation of a neverallow rule.
+type neverallowViolation struct {
+	// The name of the module that violates the rule.
+	Module string `json:"module"`
+
+	// The type of the module that violates the rule.
+	ModuleType string `json:"module_type"`
+
+	// The Android.bp file that defines the module.
+	Blueprint string `json:"blueprint"`
+
+	// The properties that matched the rule, in the form <property>=<value>.
+	MatchedProperties []string `json:"matched_properties"`
+
+	// The rule that was violated, as it would be reported in the error.
+	Rule string `json:"rule"`
+
+	// The reason the rule exists.
+	Reason string `json:"reason"`
+
+	// Whether the rule is marked as warn-only, if false the violation would fail the build
+	// outside of audit mode.
+	WarnOnly bool `json:"warn_only"`
+}
+
+// Violations are recorded once per module and rule, regardless of the number of variants of the
+// module that violate the rule.
+type neverallowViolationKey struct {
+	module qualifiedModuleName
+	rule   *rule
+}
+
+var neverallowViolationsKey = NewOnceKey("neverallowViolations")
+
+// The map from neverallowViolationKey to the neverallowViolation recorded by the neverallow
+// mutator.
+func neverallowViolationsMap(config Config) *sync.Map {
+	return config.Once(neverallowViolationsKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+func recordNeverallowViolation(ctx BottomUpMutatorContext, r *rule, properties []interface{}) {
+	key := neverallowViolationKey{
+		module: qualifiedModuleName{pkg: ctx.ModuleDir(), name: ctx.ModuleName()},
+		rule:   r,
+	}
+
+	violations := neverallowViolationsMap(ctx.Config())
+	if _, exists := violations.Load(key); exists {
+		return
+	}
+
+	var matched []string
+	for _, prop := range r.props {
+		if value, ok := matchingPropertyValue(properties, prop); ok {
+			matched = append(matched, propertyNameForFields(prop.fields)+"="+value)
+		}
+	}
+
+	violations.LoadOrStore(key, neverallowViolation{
+		Module:            ctx.ModuleName(),
+		ModuleType:        ctx.ModuleType(),
+		Blueprint:         ctx.BlueprintsFile(),
+		MatchedProperties: matched,
+		Rule:              r.String(),
+		Reason:            r.reason,
+		WarnOnly:          r.warnOnly,
+	})
+}
+
+// Returns the recorded violations sorted by Android.bp file, module and rule.
+func neverallowViolations(config Config) []neverallowViolation {
+	var violations []neverallowViolation
+	neverallowViolationsMap(config).Range(func(_, value interface{}) bool {
+		violations = append(violations, value.(neverallowViolation))
+		return true
+	})
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Blueprint != b.Blueprint {
+			return a.Blueprint < b.Blueprint
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Rule < b.Rule
+	})
+	return violations
+}
+
+func propertyNameForFields(fields []string) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = proptools.PropertyNameForField(f)
+	}
+	return strings.Join(names, ".")
+}
+
+func neverallowAuditSingletonFactory() Singleton {
+	return &neverallowAuditSingleton{}
+}
+
+type neverallowAuditSingleton struct {
+	jsonReport OutputPath
+	csvReport  OutputPath
+}
+
+func (s *neverallowAuditSingleton) GenerateBuildActions(ctx SingletonContext) {
+	violations := neverallowViolations(ctx.Config())
+
+	jsonContent, err := neverallowViolationsJson(violations)
+	if err != nil {
+		ctx.Errorf("failed to marshal neverallow violations: %s", err)
+		return
+	}
+	csvContent, err := neverallowViolationsCsv(violations)
+	if err != nil {
+		ctx.Errorf("failed to write neverallow violations: %s", err)
+		return
+	}
+
+	s.jsonReport = PathForOutput(ctx, "neverallow", "violations.json")
+	s.csvReport = PathForOutput(ctx, "neverallow", "violations.csv")
+	WriteFileRule(ctx, s.jsonReport, jsonContent)
+	WriteFileRule(ctx, s.csvReport, csvContent)
+
+	ctx.Phony("neverallow-report", s.jsonReport, s.csvReport)
+}
+
+func (s *neverallowAuditSingleton) MakeVars(ctx MakeVarsContext) {
+	if neverallowAuditMode(ctx.Config()) {
+		ctx.DistForGoal("droidcore", s.jsonReport, s.csvReport)
+	}
+}
+
+func neverallowViolationsJson(violations []neverallowViolation) (string, error) {
+	if violations == nil {
+		violations = []neverallowViolation{}
+	}
+	data, err := json.MarshalIndent(violations, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func neverallowViolationsCsv(violations []neverallowViolation) (string, error) {
+	sb := &strings.Builder{}
+	w := csv.NewWriter(sb)
+	w.Write([]string{"module", "module_type", "blueprint", "matched_properties", "rule", "reason", "warn_only"})
+	for _, v := range violations {
+		w.Write([]string{
+			v.Module,
+			v.ModuleType,
+			v.Blueprint,
+			strings.Join(v.MatchedProperties, " "),
+			v.Rule,
+			v.Reason,
+			strconv.FormatBool(v.WarnOnly),
+		})
+	}
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n"), w.Error()
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The neverallow module type allows neverallow rules to be declared in Android.bp files, e.g.
+//
+//    neverallow {
+//        name: "no_O0_in_vendor_foo",
+//        in: ["vendor/foo"],
+//        with: ["cflags=-O0"],
+//        because: "vendor/foo must be built with optimizations",
+//    }
+//
+// The rules are enforced by the neverallow mutator alongside the rules that are added by
+// AddNeverAllowRules.
+
+func init() {
+	RegisterNeverallowBuildComponents(InitRegistrationContext)
+}
+
+// Register the neverallow module type, the mutator that gathers the rules from it and the
+// singleton that writes the neverallow audit report.
+func RegisterNeverallowBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("neverallow", NeverallowFactory)
+	ctx.PreArchMutators(RegisterNeverallowRuleGatherer)
+	ctx.RegisterSingletonType("neverallow_audit", neverallowAuditSingletonFactory)
+}
+
+// Registers the mutator that gathers the rules from all the neverallow modules.
+//
+// The rules are not dependent on arch so this is registered before the arch phase, the rules are
+// then enforced after the deps have been resolved.
+func RegisterNeverallowRuleGatherer(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("neverallowRuleGatherer", neverallowRuleGatherer).Parallel()
+}
+
+type neverallowModuleProperties struct {
+	// Directories that the rule applies to, defaults to all directories. Maps to Rule.In.
+	In []string
+
+	// Directories that the rule does not apply to. Maps to Rule.NotIn.
+	Not_in []string
+
+	// Module types that the rule applies to, defaults to all module types. Maps to
+	// Rule.ModuleType.
+	Module_type []string
+
+	// Module types that the rule does not apply to. Maps to Rule.NotModuleType.
+	Not_module_type []string
+
+	// Names of modules, the rule applies to modules that directly depend on any of them. Maps to
+	// Rule.InDirectDeps.
+	In_direct_deps []string
+
+	// Properties that must match for the rule to apply, in the form <property>=<value>. Nested
+	// properties are separated by a '.', and a value of * matches any value. Maps to Rule.With.
+	With []string
+
+	// Properties that must match a regular expression for the rule to apply, in the form
+	// <property>=<regexp>. Maps to Rule.WithMatcher with a Regexp matcher.
+	With_regexp []string
+
+	// Properties that must start with a prefix for the rule to apply, in the form
+	// <property>=<prefix>. Maps to Rule.WithMatcher with a StartsWith matcher.
+	With_starts_with []string
+
+	// Properties that must be set for the rule to apply. Maps to Rule.WithMatcher with an is-set
+	// matcher.
+	With_is_set []string
+
+	// Properties that must not match for the rule to apply, in the form <property>=<value>. Maps
+	// to Rule.Without.
+	Without []string
+
+	// Properties that must not match a regular expression for the rule to apply, in the form
+	// <property>=<regexp>. Maps to Rule.WithoutMatcher with a Regexp matcher.
+	Without_regexp []string
+
+	// Properties that must not start with a prefix for the rule to apply, in the form
+	// <property>=<prefix>. Maps to Rule.WithoutMatcher with a StartsWith matcher.
+	Without_starts_with []string
+
+	// Properties that must not be set for the rule to apply. Maps to Rule.WithoutMatcher with an
+	// is-set matcher.
+	Without_is_set []string
+
+	// The reason why the rule exists, reported to the owners of any module that violates it.
+	// Maps to Rule.Because.
+	Because *string
+
+	// If true, violations of the rule are reported in the neverallow audit report instead of
+	// failing the build. Maps to Rule.WarnOnly.
+	Warn_only *bool
+}
+
+type neverallowModule struct {
+	ModuleBase
+
+	properties neverallowModuleProperties
+}
+
+func (m *neverallowModule) DepsMutator(ctx BottomUpMutatorContext) {
+	// Nothing to do.
+}
+
+func (m *neverallowModule) GenerateAndroidBuildActions(ModuleContext) {
+	// Nothing to do.
+}
+
+func NeverallowFactory() Module {
+	module := &neverallowModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// rule creates the Rule described by the module's properties, reporting any errors in the
+// properties to ctx.
+func (m *neverallowModule) rule(ctx BaseModuleContext) Rule {
+	p := &m.properties
+
+	if String(p.Because) == "" {
+		ctx.PropertyErrorf("because", "must be set")
+	}
+
+	r := NeverAllow()
+	if len(p.In) > 0 {
+		r.In(p.In...)
+	}
+	if len(p.Not_in) > 0 {
+		r.NotIn(p.Not_in...)
+	}
+	if len(p.Module_type) > 0 {
+		r.ModuleType(p.Module_type...)
+	}
+	if len(p.Not_module_type) > 0 {
+		r.NotModuleType(p.Not_module_type...)
+	}
+	if len(p.In_direct_deps) > 0 {
+		r.InDirectDeps(p.In_direct_deps...)
+	}
+
+	propertyMatchers := []struct {
+		name       string
+		values     []string
+		hasValue   bool
+		newMatcher func(value string) (ValueMatcher, error)
+		add        func(property string, matcher ValueMatcher) Rule
+	}{
+		{"with", p.With, true, newValueMatcher, r.WithMatcher},
+		{"with_regexp", p.With_regexp, true, newRegexpMatcher, r.WithMatcher},
+		{"with_starts_with", p.With_starts_with, true, newStartsWithMatcher, r.WithMatcher},
+		{"with_is_set", p.With_is_set, false, newIsSetMatcher, r.WithMatcher},
+		{"without", p.Without, true, newValueMatcher, r.WithoutMatcher},
+		{"without_regexp", p.Without_regexp, true, newRegexpMatcher, r.WithoutMatcher},
+		{"without_starts_with", p.Without_starts_with, true, newStartsWithMatcher, r.WithoutMatcher},
+		{"without_is_set", p.Without_is_set, false, newIsSetMatcher, r.WithoutMatcher},
+	}
+	for _, pm := range propertyMatchers {
+		for _, v := range pm.values {
+			property, value := v, ""
+			if pm.hasValue {
+				i := strings.Index(v, "=")
+				if i <= 0 {
+					ctx.PropertyErrorf(pm.name, "%q must be in the form <property>=<value>", v)
+					continue
+				}
+				property, value = v[:i], v[i+1:]
+			} else if property == "" {
+				ctx.PropertyErrorf(pm.name, "property names must not be empty")
+				continue
+			}
+
+			matcher, err := pm.newMatcher(value)
+			if err != nil {
+				ctx.PropertyErrorf(pm.name, "invalid value for %q: %s", property, err)
+				continue
+			}
+			pm.add(property, matcher)
+		}
+	}
+
+	r.Because(String(p.Because))
+	if Bool(p.Warn_only) {
+		r.WarnOnly()
+	}
+	r.(*rule).source = fmt.Sprintf("module %q in %s", ctx.ModuleName(), ctx.BlueprintsFile())
+
+	return r
+}
+
+func newValueMatcher(value string) (ValueMatcher, error) {
+	return selectMatcher(value), nil
+}
+
+func newRegexpMatcher(value string) (ValueMatcher, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+	return &regexMatcher{re}, nil
+}
+
+func newStartsWithMatcher(value string) (ValueMatcher, error) {
+	return StartsWith(value), nil
+}
+
+func newIsSetMatcher(string) (ValueMatcher, error) {
+	return isSetMatcherInstance, nil
+}
+
+var neverallowModuleRuleMapKey = NewOnceKey("neverallowModuleRuleMap")
+
+// The map from the qualifiedModuleName of a neverallow module to the Rule it declares.
+func neverallowModuleRuleMap(config Config) *sync.Map {
+	return config.Once(neverallowModuleRuleMapKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// Gathers the rules declared by neverallow modules for use by the neverallow mutator.
+func neverallowRuleGatherer(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(*neverallowModule)
+	if !ok {
+		return
+	}
+
+	r := m.rule(ctx)
+	if ctx.Failed() {
+		return
+	}
+
+	neverallowModuleRuleMap(ctx.Config()).Store(m.qualifiedModuleId(ctx), r)
+}
+
+// neverallowModuleRules returns the rules declared by neverallow modules, sorted by the name of
+// the module that declared them.
+func neverallowModuleRules(config Config) []Rule {
+	type namedRule struct {
+		name string
+		rule Rule
+	}
+	var rules []namedRule
+	neverallowModuleRuleMap(config).Range(func(key, value interface{}) bool {
+		rules = append(rules, namedRule{key.(qualifiedModuleName).String(), value.(Rule)})
+		return true
+	})
+	sort.Slice(rules, func(i, j int) bool { return rules[i].name < rules[j].name })
+
+	ret := make([]Rule, len(rules))
+	for i, r := range rules {
+		ret[i] = r.rule
+	}
+	return ret
+}
@@ -15,6 +15,7 @@
 package android
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/blueprint"
@@ -296,6 +297,186 @@ var neverallowTests = []struct {
 			"Only boot images may be imported as a makefile goal.",
 		},
 	},
+	// neverallow module tests
+	{
+		name:  "neverallow module",
+		rules: []Rule{},
+		fs: map[string][]byte{
+			"build/policy/Android.bp": []byte(`
+				neverallow {
+					name: "no_O0",
+					in: ["vendor/foo"],
+					with: ["cflags=-O0"],
+					because: "vendor/foo must be optimized",
+				}`),
+			"vendor/foo/Android.bp": []byte(`
+				cc_library {
+					name: "libfoo",
+					cflags: ["-Wall", "-O0"],
+				}
+				cc_library {
+					name: "libfoo_optimized",
+					cflags: ["-O2"],
+				}`),
+			"vendor/bar/Android.bp": []byte(`
+				cc_library {
+					name: "libbar",
+					cflags: ["-O0"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libfoo": violates neverallow dir:vendor/foo/* Cflags=-O0 ` +
+				`which is restricted because vendor/foo must be optimized ` +
+				`(declared by module "no_O0" in build/policy/Android.bp)`,
+		},
+	},
+	{
+		name:  "neverallow module matchers",
+		rules: []Rule{},
+		fs: map[string][]byte{
+			"build/policy/Android.bp": []byte(`
+				neverallow {
+					name: "no_java_sdk_variant",
+					not_in: ["vendor/allowed"],
+					module_type: ["java_library"],
+					with_regexp: ["sdk_version=^(system|module)_"],
+					without_is_set: ["uncompress_dex"],
+					because: "no system or module sdk",
+				}
+				neverallow {
+					name: "no_include_dirs",
+					not_module_type: ["java_library"],
+					with_starts_with: ["include_dirs=vendor/"],
+					because: "no vendor include_dirs",
+				}
+				neverallow {
+					name: "no_libbad_deps",
+					in_direct_deps: ["libbad"],
+					because: "libbad is bad",
+				}`),
+			"vendor/allowed/Android.bp": []byte(`
+				java_library {
+					name: "allowed",
+					sdk_version: "system_current",
+				}`),
+			"other/Android.bp": []byte(`
+				java_library {
+					name: "java_system",
+					sdk_version: "system_current",
+				}
+				java_library {
+					name: "java_public",
+					sdk_version: "current",
+				}
+				java_library {
+					name: "java_system_uncompressed",
+					sdk_version: "system_current",
+					uncompress_dex: true,
+				}
+				cc_library {
+					name: "libinclude",
+					include_dirs: ["vendor/foo/include"],
+				}
+				cc_library {
+					name: "libbad",
+				}
+				cc_library {
+					name: "libuser",
+					static_libs: ["libbad"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "java_system": violates neverallow -dir:vendor/allowed/* type:java_library`,
+			`module "libinclude": violates neverallow -type:java_library Include_dirs.starts-with(vendor/)`,
+			`module "libuser": violates neverallow deps:libbad`,
+		},
+	},
+	{
+		name:  "invalid neverallow module",
+		rules: []Rule{},
+		fs: map[string][]byte{
+			"build/policy/Android.bp": []byte(`
+				neverallow {
+					name: "invalid",
+					with: ["cflags"],
+					with_regexp: ["cflags=(-O0"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "invalid": because: must be set`,
+			`module "invalid": with: "cflags" must be in the form <property>=<value>`,
+			`module "invalid": with_regexp: invalid value for "cflags": error parsing regexp`,
+		},
+	},
+}
+
+func TestNeverallowAudit(t *testing.T) {
+	rules := []Rule{
+		NeverAllow().In("vendor").With("cflags", "-O0").Because("vendor must be optimized").WarnOnly(),
+		NeverAllow().With("vndk.enabled", "true").Because("no vndk"),
+	}
+
+	fs := MockFS{
+		"vendor/Android.bp": []byte(`
+			cc_library {
+				name: "libvendor",
+				cflags: ["-Wall", "-O0"],
+			}`),
+		"system/Android.bp": []byte(`
+			cc_library {
+				name: "libvndk",
+				vndk: {
+					enabled: true,
+				},
+			}`),
+	}
+
+	warnOnlyViolation := `libvendor,cc_library,vendor/Android.bp,cflags=-O0,` +
+		`neverallow dir:vendor/* Cflags=-O0 which is restricted because vendor must be optimized,` +
+		`vendor must be optimized,true`
+	enforcedViolation := `libvndk,cc_library,system/Android.bp,vndk.enabled=true,` +
+		`neverallow Vndk.Enabled=true which is restricted because no vndk,no vndk,false`
+	header := "module,module_type,blueprint,matched_properties,rule,reason,warn_only"
+
+	t.Run("warn only", func(t *testing.T) {
+		result := GroupFixturePreparers(
+			prepareForNeverAllowTest,
+			PrepareForTestWithNeverallowRules(rules),
+			fs.AddToFixture(),
+		).
+			ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+				`module "libvndk": violates neverallow Vndk.Enabled=true`,
+			})).
+			RunTest(t)
+
+		// The singleton does not run when the mutators fail, so check the recorded violations.
+		violations := neverallowViolations(result.Config)
+		AssertIntEquals(t, "violations", 1, len(violations))
+		AssertStringEquals(t, "module", "libvendor", violations[0].Module)
+		AssertArrayString(t, "matched properties", []string{"cflags=-O0"}, violations[0].MatchedProperties)
+	})
+
+	t.Run("audit mode", func(t *testing.T) {
+		result := GroupFixturePreparers(
+			prepareForNeverAllowTest,
+			PrepareForTestWithNeverallowRules(rules),
+			fs.AddToFixture(),
+			FixtureMergeEnv(map[string]string{
+				"SOONG_NEVERALLOW_AUDIT": "true",
+			}),
+		).RunTest(t)
+
+		singleton := result.SingletonForTests("neverallow_audit")
+		csv := ContentFromFileRuleForTests(t, singleton.Output("neverallow/violations.csv"))
+		AssertStringEquals(t, "violations.csv",
+			strings.Join([]string{header, enforcedViolation, warnOnlyViolation}, "\n")+"\n", csv)
+
+		json := ContentFromFileRuleForTests(t, singleton.Output("neverallow/violations.json"))
+		AssertStringDoesContain(t, "violations.json", json, `"module": "libvendor"`)
+		AssertStringDoesContain(t, "violations.json", json, `"matched_properties": [
+      "vndk.enabled=true"
+    ]`)
+	})
 }
 
 var prepareForNeverAllowTest = GroupFixturePreparers(
@@ -323,6 +504,7 @@ func TestNeverallow(t *testing.T) {
 }
 
 type mockCcLibraryProperties struct {
+	Cflags           []string
 	Include_dirs     []string
 	Vendor_available *bool
 	Static_libs      []string
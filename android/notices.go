@@ -15,9 +15,13 @@
 package android
 
 import (
+	"encoding/json"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 func init() {
@@ -25,8 +29,18 @@ func init() {
 	pctx.SourcePathVariable("generate_notice", "build/soong/scripts/generate-notice-files.py")
 
 	pctx.HostBinToolVariable("minigzip", "minigzip")
+
+	RegisterNoticesBuildComponents(InitRegistrationContext)
+}
+
+// RegisterNoticesBuildComponents registers the singleton that aggregates the notices of each
+// partition.
+func RegisterNoticesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("notices", noticesSingletonFactory)
 }
 
+var PrepareForTestWithNotices = FixtureRegisterWithContext(RegisterNoticesBuildComponents)
+
 type NoticeOutputs struct {
 	Merged       OptionalPath
 	TxtOutput    OptionalPath
@@ -100,3 +114,125 @@ func BuildNoticeOutput(ctx ModuleContext, installPath InstallPath, installFilena
 		HtmlGzOutput: OptionalPathForPath(htmlGzOutput),
 	}
 }
+
+// ModuleNoticeTexts returns the NOTICE files and the license_text files of the licenses of a module.
+func ModuleNoticeTexts(module Module) Paths {
+	base := module.base()
+	texts := append(Paths(nil), base.noticeFiles...)
+	texts = append(texts, base.commonProperties.Effective_license_text...)
+	return SortedUniquePaths(texts)
+}
+
+// NoticeIndexEntry is a file in a partition or package, with the license texts that apply to it.
+type NoticeIndexEntry struct {
+	// The path of the file on the device or in the package, e.g. /system/bin/foo.
+	Installed string
+
+	// The NOTICE and license_text files of the module that installs the file.
+	Texts Paths
+}
+
+// NoticeIndexOutputs are the aggregated notices created by BuildNoticeIndex.
+type NoticeIndexOutputs struct {
+	// The gzipped NOTICE.xml read by the settings app.
+	XmlGz OutputPath
+
+	// An html index of the license texts and the files they apply to.
+	Html OutputPath
+}
+
+// noticeIndexManifest is the input of notice_gen.
+type noticeIndexManifest struct {
+	Files []noticeIndexFile `json:"files"`
+}
+
+type noticeIndexFile struct {
+	Name  string   `json:"name"`
+	Texts []string `json:"texts"`
+}
+
+// BuildNoticeIndex creates the rules that aggregate the license texts of the given files into
+// NOTICE.xml.gz and NOTICE.html in outDir. Identical license texts are only included once, with
+// the list of the files they apply to.
+func BuildNoticeIndex(ctx BuilderContext, outDir OutputPath, title string,
+	entries []NoticeIndexEntry) NoticeIndexOutputs {
+
+	entries = append([]NoticeIndexEntry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Installed < entries[j].Installed })
+
+	manifest := noticeIndexManifest{Files: []noticeIndexFile{}}
+	var texts Paths
+	for _, e := range entries {
+		if len(e.Texts) == 0 {
+			continue
+		}
+		manifest.Files = append(manifest.Files, noticeIndexFile{Name: e.Installed, Texts: e.Texts.Strings()})
+		texts = append(texts, e.Texts...)
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	manifestFile := outDir.Join(ctx, "notice_manifest.json")
+	WriteFileRule(ctx, manifestFile, string(content))
+
+	outputs := NoticeIndexOutputs{
+		XmlGz: outDir.Join(ctx, "NOTICE.xml.gz"),
+		Html:  outDir.Join(ctx, "NOTICE.html"),
+	}
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("notice_gen").
+		FlagWithInput("-i ", manifestFile).
+		FlagWithOutput("-o ", outputs.XmlGz).
+		FlagWithOutput("-html ", outputs.Html).
+		FlagWithArg("-title ", proptools.ShellEscape(title)).
+		Implicits(SortedUniquePaths(texts))
+	rule.Build("notice_index_"+strings.ReplaceAll(outDir.Rel(), "/", "_"), title)
+
+	return outputs
+}
+
+func noticesSingletonFactory() Singleton {
+	return &noticesSingleton{}
+}
+
+// noticesSingleton aggregates the license texts of the files installed into each partition of the
+// device into out/soong/notices/<partition>/NOTICE.xml.gz and NOTICE.html.
+type noticesSingleton struct {
+	outputs Paths
+}
+
+func (s *noticesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	entries := make(map[string][]NoticeIndexEntry)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+		texts := ModuleNoticeTexts(module)
+		if len(texts) == 0 {
+			return
+		}
+		for _, installed := range module.FilesToInstall() {
+			if devicePath := sbomDevicePath(ctx, installed); devicePath != "" {
+				partition := strings.Split(devicePath, "/")[0]
+				entries[partition] = append(entries[partition], NoticeIndexEntry{
+					Installed: "/" + devicePath,
+					Texts:     texts,
+				})
+			}
+		}
+	})
+
+	for _, partition := range SortedStringKeys(entries) {
+		outputs := BuildNoticeIndex(ctx, PathForOutput(ctx, "notices", partition),
+			"Notices for files contained in the "+partition+" partition", entries[partition])
+		s.outputs = append(s.outputs, outputs.XmlGz, outputs.Html)
+	}
+
+	ctx.Phony("notices", s.outputs...)
+}
+
+func (s *noticesSingleton) MakeVars(ctx MakeVarsContext) {
+	ctx.DistForGoal("notices", s.outputs...)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNotices(t *testing.T) {
+	bp := `
+		test_module {
+			name: "foo",
+			notice: "NOTICE_SHARED",
+		}
+
+		test_module {
+			name: "bar",
+			notice: "NOTICE_SHARED",
+			vendor: true,
+		}
+
+		test_module {
+			name: "baz",
+			notice: "NOTICE_BAZ",
+		}
+
+		test_module {
+			name: "libstatic",
+			notice: "NOTICE_STATIC",
+			static: true,
+		}
+
+		test_module {
+			name: "no_notice",
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithNotices,
+		FixtureWithRootAndroidBp(bp),
+		FixtureMergeMockFs(MockFS{
+			"NOTICE_SHARED": nil,
+			"NOTICE_BAZ":    nil,
+			"NOTICE_STATIC": nil,
+		}),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", testSbomModuleFactory)
+		}),
+	).RunTest(t)
+
+	notices := result.SingletonForTests("notices")
+
+	readManifest := func(partition string) noticeIndexManifest {
+		var manifest noticeIndexManifest
+		content := ContentFromFileRuleForTests(t, notices.Output("notices/"+partition+"/notice_manifest.json"))
+		if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+			t.Fatal(err)
+		}
+		return manifest
+	}
+
+	AssertDeepEquals(t, "system notices", noticeIndexManifest{
+		Files: []noticeIndexFile{
+			{Name: "/system/bin/baz", Texts: []string{"NOTICE_BAZ"}},
+			{Name: "/system/bin/foo", Texts: []string{"NOTICE_SHARED"}},
+		},
+	}, readManifest("system"))
+
+	AssertDeepEquals(t, "vendor notices", noticeIndexManifest{
+		Files: []noticeIndexFile{
+			{Name: "/vendor/bin/bar", Texts: []string{"NOTICE_SHARED"}},
+		},
+	}, readManifest("vendor"))
+
+	html := notices.Output("notices/system/NOTICE.html")
+	AssertStringDoesContain(t, "notice_gen command", html.RuleParams.Command, "notice_gen")
+	AssertStringDoesContain(t, "notice_gen command", html.RuleParams.Command,
+		"-title 'Notices for files contained in the system partition'")
+	AssertStringListContains(t, "notice_gen inputs", html.Implicits.Strings(), "NOTICE_BAZ")
+	AssertStringListContains(t, "notice_gen inputs", html.Implicits.Strings(), "NOTICE_SHARED")
+	AssertStringEquals(t, "notice_gen xml output", "out/soong/notices/system/NOTICE.xml.gz",
+		html.ImplicitOutputs.Strings()[0])
+}
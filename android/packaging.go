@@ -214,6 +214,37 @@ func (p *PackagingBase) GatherPackagingSpecs(ctx ModuleContext) map[string]Packa
 	return m
 }
 
+// GatherPackagingNotices returns the files returned by GatherPackagingSpecs with the NOTICE and
+// license_text files of the modules that install them. The paths of the files are relative to the
+// root of the package.
+func (p *PackagingBase) GatherPackagingNotices(ctx ModuleContext) []NoticeIndexEntry {
+	specs := p.GatherPackagingSpecs(ctx)
+	texts := make(map[string]Paths)
+	// Follow the same dependencies as TransitivePackagingSpecs to find the module of each file.
+	ctx.WalkDeps(func(child, parent Module) bool {
+		tag := ctx.OtherModuleDependencyTag(child)
+		if parent == ctx.Module() {
+			if pi, ok := tag.(PackagingItem); !ok || !pi.IsPackagingItem() {
+				return false
+			}
+		} else if !IsInstallDepNeeded(tag) || child.IsHideFromMake() {
+			return false
+		}
+		for _, ps := range child.PackagingSpecs() {
+			if _, ok := specs[ps.relPathInPackage]; ok {
+				texts[ps.relPathInPackage] = append(texts[ps.relPathInPackage], ModuleNoticeTexts(child)...)
+			}
+		}
+		return true
+	})
+
+	var entries []NoticeIndexEntry
+	for _, rel := range SortedStringKeys(specs) {
+		entries = append(entries, NoticeIndexEntry{Installed: rel, Texts: SortedUniquePaths(texts[rel])})
+	}
+	return entries
+}
+
 // See PackageModule.CopyDepsToZip
 func (p *PackagingBase) CopyDepsToZip(ctx ModuleContext, zipOut WritablePath) (entries []string) {
 	m := p.GatherPackagingSpecs(ctx)
@@ -473,6 +473,15 @@ func (r *RuleBuilder) depFileMergerCmd(depFiles WritablePaths) *RuleBuilderComma
 		Inputs(depFiles.Paths())
 }
 
+// sboxModuleName returns the name of the module in the form //path/to:module.
+func sboxModuleName(ctx ModuleContext) string {
+	dir := ctx.ModuleDir()
+	if dir == "." {
+		dir = ""
+	}
+	return "//" + dir + ":" + ctx.ModuleName()
+}
+
 // Build adds the built command line to the build graph, with dependencies on Inputs and Tools, and output files for
 // Outputs.
 func (r *RuleBuilder) Build(name string, desc string) {
@@ -637,6 +646,12 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			Flag("--sandbox-path").Text(shared.TempDirForOutDir(PathForOutput(r.ctx).String())).
 			Flag("--manifest").Input(r.sboxManifestPath)
 
+		// Pass the name of the module to sbox so that errors in strict mode can name it and
+		// the strict mode allowlist can refer to it.
+		if mctx, ok := r.ctx.(ModuleContext); ok {
+			sboxCmd.FlagWithArg("--module ", sboxModuleName(mctx))
+		}
+
 		// Replace the command string, and add the sbox tool and manifest textproto to the
 		// dependencies of the final sbox rule.
 		commandString = sboxCmd.buf.String()
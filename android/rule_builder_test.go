@@ -687,7 +687,7 @@ func TestRuleBuilder_Build(t *testing.T) {
 		sandboxPath := shared.TempDirForOutDir("out/soong")
 
 		cmd := `rm -rf ` + outDir + `/gen && ` +
-			sbox + ` --sandbox-path ` + sandboxPath + ` --manifest ` + manifest
+			sbox + ` --sandbox-path ` + sandboxPath + ` --manifest ` + manifest + ` --module //:foo_sbox`
 		module := result.ModuleForTests("foo_sbox", "")
 		check(t, module.Output("gen/foo_sbox"), module.Output(rspFile2),
 			cmd, outFile, depFile, rspFile, rspFile2, false, []string{manifest}, []string{sbox})
@@ -703,7 +703,7 @@ func TestRuleBuilder_Build(t *testing.T) {
 		sandboxPath := shared.TempDirForOutDir("out/soong")
 
 		cmd := `rm -rf ` + outDir + `/gen && ` +
-			sbox + ` --sandbox-path ` + sandboxPath + ` --manifest ` + manifest
+			sbox + ` --sandbox-path ` + sandboxPath + ` --manifest ` + manifest + ` --module //:foo_sbox_inputs`
 
 		module := result.ModuleForTests("foo_sbox_inputs", "")
 		check(t, module.Output("gen/foo_sbox_inputs"), module.Output(rspFile2),
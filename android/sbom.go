@@ -0,0 +1,327 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Generates an SPDX software bill of materials for each partition of the device.
+//
+// The sbom singleton collects the installed files and the licenses of every module into a manifest
+// per partition, and sbom_gen turns the manifest into an SPDX document in the tag-value and JSON
+// formats, adding the checksums of the installed files once they have been built.
+
+func init() {
+	RegisterSbomBuildComponents(InitRegistrationContext)
+}
+
+// RegisterSbomBuildComponents registers the singleton that generates the SBOMs.
+func RegisterSbomBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("sbom", sbomSingletonFactory)
+}
+
+var PrepareForTestWithSbom = FixtureRegisterWithContext(RegisterSbomBuildComponents)
+
+// sbomManifest is the input of sbom_gen for a single partition.
+type sbomManifest struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+
+	Packages []sbomPackage `json:"packages"`
+
+	// The license kinds without an SPDX license identifier that are referenced by the packages.
+	Licenses []sbomLicense `json:"licenses,omitempty"`
+}
+
+// sbomPackage is a module, with the files it installs into the partition.
+type sbomPackage struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+
+	// The SPDX license expression built from the license kinds of the module.
+	LicenseDeclared   string   `json:"license_declared"`
+	LicenseConditions []string `json:"license_conditions,omitempty"`
+
+	Files []sbomFile `json:"files,omitempty"`
+
+	// The ids of the packages that are linked into the files of this package.
+	StaticLinkDeps []string `json:"static_link_deps,omitempty"`
+}
+
+type sbomFile struct {
+	ID string `json:"id"`
+
+	// The path of the file on the device, relative to the root of the device.
+	Name string `json:"name"`
+
+	// The path of the installed file in the build, to compute its checksums.
+	Path string `json:"path"`
+}
+
+// sbomLicense is a license kind without an SPDX license identifier, which is referenced as a
+// LicenseRef.
+type sbomLicense struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	URL        string   `json:"url,omitempty"`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// The prefix of the names of license kinds that correspond to an SPDX license identifier.
+const spdxLicenseKindPrefix = "SPDX-license-identifier-"
+
+var spdxIDInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxID returns an SPDX identifier with the given prefix for a name, which may only contain
+// letters, numbers, "." and "-".
+func spdxID(prefix, name string) string {
+	return prefix + spdxIDInvalidChars.ReplaceAllString(name, "-")
+}
+
+// spdxLicenseExpression returns the SPDX license expression for a module with the given license
+// kinds, which all apply to the module.
+func spdxLicenseExpression(kinds []string) string {
+	if len(kinds) == 0 {
+		return "NOASSERTION"
+	}
+	var ids []string
+	for _, kind := range kinds {
+		if strings.HasPrefix(kind, spdxLicenseKindPrefix) {
+			ids = append(ids, strings.TrimPrefix(kind, spdxLicenseKindPrefix))
+		} else {
+			ids = append(ids, spdxID("LicenseRef-", kind))
+		}
+	}
+	ids = SortedUniqueStrings(ids)
+	if len(ids) > 1 {
+		return "(" + strings.Join(ids, " AND ") + ")"
+	}
+	return ids[0]
+}
+
+// sbomModule is the union of all the variants of a module.
+type sbomModule struct {
+	name           string
+	licenseKinds   []string
+	conditions     []string
+	staticLinkDeps []string
+
+	// The installed files of the module by partition.
+	files map[string]InstallPaths
+}
+
+func sbomSingletonFactory() Singleton {
+	return &sbomSingleton{}
+}
+
+type sbomSingleton struct {
+	outputs Paths
+}
+
+func (s *sbomSingleton) GenerateBuildActions(ctx SingletonContext) {
+	modules := make(map[string]*sbomModule)
+	licenseKinds := make(map[string]*licenseKindModule)
+
+	ctx.VisitAllModules(func(module Module) {
+		if lk, ok := module.(*licenseKindModule); ok {
+			licenseKinds[ctx.ModuleName(module)] = lk
+			return
+		}
+		if !module.Enabled() {
+			return
+		}
+
+		name := ctx.ModuleName(module)
+		m := modules[name]
+		if m == nil {
+			m = &sbomModule{name: name, files: make(map[string]InstallPaths)}
+			modules[name] = m
+		}
+
+		props := &module.base().commonProperties
+		m.licenseKinds = append(m.licenseKinds, props.Effective_license_kinds...)
+		m.conditions = append(m.conditions, props.Effective_license_conditions...)
+		if ctx.ModuleHasProvider(module, licenseGraphInfoProvider) {
+			info := ctx.ModuleProvider(module, licenseGraphInfoProvider).(licenseGraphInfo)
+			m.staticLinkDeps = append(m.staticLinkDeps, info.StaticLinkDeps...)
+		}
+
+		for _, installed := range module.FilesToInstall() {
+			if partition := sbomPartition(ctx, installed); partition != "" {
+				m.files[partition] = append(m.files[partition], installed)
+			}
+		}
+	})
+
+	partitions := make(map[string]bool)
+	for _, m := range modules {
+		m.licenseKinds = SortedUniqueStrings(m.licenseKinds)
+		m.conditions = SortedUniqueStrings(m.conditions)
+		m.staticLinkDeps = SortedUniqueStrings(m.staticLinkDeps)
+		for partition := range m.files {
+			partitions[partition] = true
+		}
+	}
+
+	for _, partition := range SortedStringKeys(partitions) {
+		manifest, installed := sbomManifestForPartition(ctx, partition, modules, licenseKinds)
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			ctx.Errorf("failed to write the SBOM manifest of %s: %s", partition, err)
+			return
+		}
+
+		manifestFile := PathForOutput(ctx, "sbom", partition, "sbom_manifest.json")
+		WriteFileRule(ctx, manifestFile, string(content))
+
+		tagValue := PathForOutput(ctx, "sbom", partition, "sbom.spdx")
+		jsonFile := PathForOutput(ctx, "sbom", partition, "sbom.spdx.json")
+		rule := NewRuleBuilder(pctx, ctx)
+		rule.Command().BuiltTool("sbom_gen").
+			FlagWithInput("-i ", manifestFile).
+			FlagWithOutput("-o ", tagValue).
+			FlagWithOutput("-json ", jsonFile).
+			Implicits(installed.Paths())
+		rule.Build("sbom_"+partition, partition+" SBOM")
+
+		s.outputs = append(s.outputs, tagValue, jsonFile)
+	}
+
+	ctx.Phony("sbom", s.outputs...)
+}
+
+func (s *sbomSingleton) MakeVars(ctx MakeVarsContext) {
+	ctx.DistForGoal("sbom", s.outputs...)
+}
+
+// sbomDevicePath returns the path of an installed file relative to the root of the device, or ""
+// for files that aren't installed on the device.
+func sbomDevicePath(ctx PathContext, installed InstallPath) string {
+	deviceDir := filepath.Join("target", "product", ctx.Config().DeviceName())
+	rel, err := filepath.Rel(deviceDir, installed.partitionDir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.Join(rel, Rel(ctx, installed.PartitionDir(), installed.String()))
+}
+
+// sbomPartition returns the partition of the device an installed file is in, e.g. system or
+// vendor, or "" for files that aren't installed on the device.
+func sbomPartition(ctx PathContext, installed InstallPath) string {
+	if devicePath := sbomDevicePath(ctx, installed); devicePath != "" {
+		return strings.Split(devicePath, "/")[0]
+	}
+	return ""
+}
+
+// sbomManifestForPartition returns the manifest of a partition and the installed files it
+// references. It contains the modules that install files into the partition and the modules that
+// are linked into them.
+func sbomManifestForPartition(ctx SingletonContext, partition string, modules map[string]*sbomModule,
+	licenseKinds map[string]*licenseKindModule) (*sbomManifest, InstallPaths) {
+
+	manifest := &sbomManifest{
+		Name: fmt.Sprintf("%s-%s", ctx.Config().DeviceName(), partition),
+		Namespace: "https://android.com/sbom/" +
+			path.Join(ctx.Config().DeviceName(), ctx.Config().BuildId(), partition),
+	}
+
+	// Add the modules that are statically linked into the installed modules.
+	included := make(map[string]bool)
+	var include func(name string)
+	include = func(name string) {
+		if m, ok := modules[name]; ok && !included[name] {
+			included[name] = true
+			for _, dep := range m.staticLinkDeps {
+				include(dep)
+			}
+		}
+	}
+	for _, name := range SortedStringKeys(modules) {
+		if len(modules[name].files[partition]) > 0 {
+			include(name)
+		}
+	}
+
+	names := SortedStringKeys(included)
+	ids := make(map[string]string, len(names))
+	usedIDs := make(map[string]bool, len(names))
+	for _, name := range names {
+		id := spdxID("SPDXRef-Package-", name)
+		for i := 2; usedIDs[id]; i++ {
+			id = spdxID("SPDXRef-Package-", fmt.Sprintf("%s-%d", name, i))
+		}
+		ids[name] = id
+		usedIDs[id] = true
+	}
+
+	var installed InstallPaths
+	usedKinds := make(map[string]bool)
+	for _, name := range names {
+		m := modules[name]
+		pkg := sbomPackage{
+			Name:              name,
+			ID:                ids[name],
+			LicenseDeclared:   spdxLicenseExpression(m.licenseKinds),
+			LicenseConditions: m.conditions,
+		}
+
+		files := append(InstallPaths(nil), m.files[partition]...)
+		sort.Slice(files, func(i, j int) bool { return files[i].String() < files[j].String() })
+		for i, file := range files {
+			if i > 0 && files[i-1].String() == file.String() {
+				continue
+			}
+			pkg.Files = append(pkg.Files, sbomFile{
+				ID:   fmt.Sprintf("SPDXRef-File-%s-%d", strings.TrimPrefix(pkg.ID, "SPDXRef-Package-"), len(pkg.Files)+1),
+				Name: "./" + sbomDevicePath(ctx, file),
+				Path: file.String(),
+			})
+			installed = append(installed, file)
+		}
+
+		for _, dep := range m.staticLinkDeps {
+			if id, ok := ids[dep]; ok {
+				pkg.StaticLinkDeps = append(pkg.StaticLinkDeps, id)
+			}
+		}
+
+		for _, kind := range m.licenseKinds {
+			if !strings.HasPrefix(kind, spdxLicenseKindPrefix) {
+				usedKinds[kind] = true
+			}
+		}
+
+		manifest.Packages = append(manifest.Packages, pkg)
+	}
+
+	for _, kind := range SortedStringKeys(usedKinds) {
+		license := sbomLicense{ID: spdxID("LicenseRef-", kind), Name: kind}
+		if lk, ok := licenseKinds[kind]; ok {
+			license.URL = lk.properties.Url
+			license.Conditions = lk.properties.Conditions
+		}
+		manifest.Licenses = append(manifest.Licenses, license)
+	}
+
+	return manifest, installed
+}
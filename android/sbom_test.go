@@ -0,0 +1,203 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type testSbomModule struct {
+	ModuleBase
+	Properties struct {
+		Static_libs []string
+		Static      bool
+	}
+}
+
+type testStaticLinkDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (testStaticLinkDependencyTag) StaticLinkDep() bool {
+	return true
+}
+
+func (m *testSbomModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, testStaticLinkDependencyTag{}, m.Properties.Static_libs...)
+}
+
+func (m *testSbomModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	if !m.Properties.Static {
+		ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), outputFile)
+	}
+}
+
+func testSbomModuleFactory() Module {
+	module := &testSbomModule{}
+	module.AddProperties(&module.Properties)
+	InitAndroidArchModule(module, DeviceSupported, MultilibCommon)
+	return module
+}
+
+func TestSbom(t *testing.T) {
+	bp := `
+		license_kind {
+			name: "SPDX-license-identifier-Apache-2.0",
+			conditions: ["notice"],
+		}
+
+		license_kind {
+			name: "legacy_notice",
+			conditions: ["notice"],
+			url: "https://example.com/license",
+		}
+
+		license {
+			name: "bin_license",
+			license_kinds: ["SPDX-license-identifier-Apache-2.0", "legacy_notice"],
+		}
+
+		license {
+			name: "static_license",
+			license_kinds: ["legacy_notice"],
+		}
+
+		test_module {
+			name: "bin",
+			licenses: ["bin_license"],
+			static_libs: ["libstatic"],
+		}
+
+		test_module {
+			name: "libstatic",
+			licenses: ["static_license"],
+			static: true,
+		}
+
+		test_module {
+			name: "libunused",
+			static: true,
+		}
+
+		test_module {
+			name: "unlicensed",
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithLicenses,
+		PrepareForTestWithSbom,
+		FixtureWithRootAndroidBp(bp),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", testSbomModuleFactory)
+		}),
+	).RunTest(t)
+
+	sbom := result.SingletonForTests("sbom")
+
+	var manifest sbomManifest
+	content := ContentFromFileRuleForTests(t, sbom.Output("sbom/system/sbom_manifest.json"))
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	binFile := "out/soong/target/product/test_device/system/bin/bin"
+	unlicensedFile := "out/soong/target/product/test_device/system/bin/unlicensed"
+
+	AssertDeepEquals(t, "manifest", sbomManifest{
+		Name:      "test_device-system",
+		Namespace: "https://android.com/sbom/test_device/system",
+		Packages: []sbomPackage{
+			{
+				Name:              "bin",
+				ID:                "SPDXRef-Package-bin",
+				LicenseDeclared:   "(Apache-2.0 AND LicenseRef-legacy-notice)",
+				LicenseConditions: []string{"notice"},
+				Files: []sbomFile{
+					{ID: "SPDXRef-File-bin-1", Name: "./system/bin/bin", Path: binFile},
+				},
+				StaticLinkDeps: []string{"SPDXRef-Package-libstatic"},
+			},
+			{
+				Name:              "libstatic",
+				ID:                "SPDXRef-Package-libstatic",
+				LicenseDeclared:   "LicenseRef-legacy-notice",
+				LicenseConditions: []string{"notice"},
+			},
+			{
+				Name:            "unlicensed",
+				ID:              "SPDXRef-Package-unlicensed",
+				LicenseDeclared: "NOASSERTION",
+				Files: []sbomFile{
+					{ID: "SPDXRef-File-unlicensed-1", Name: "./system/bin/unlicensed", Path: unlicensedFile},
+				},
+			},
+		},
+		Licenses: []sbomLicense{
+			{
+				ID:         "LicenseRef-legacy-notice",
+				Name:       "legacy_notice",
+				URL:        "https://example.com/license",
+				Conditions: []string{"notice"},
+			},
+		},
+	}, normalizeSbomManifestForTests(result.Config, manifest))
+
+	spdx := sbom.Output("sbom/system/sbom.spdx")
+	AssertStringDoesContain(t, "sbom_gen command", spdx.RuleParams.Command, "sbom_gen")
+	AssertStringListContains(t, "sbom_gen inputs", spdx.Implicits.Strings(), binFile)
+	AssertStringListContains(t, "sbom_gen inputs", spdx.Implicits.Strings(), unlicensedFile)
+	AssertStringEquals(t, "sbom_gen json output", "out/soong/sbom/system/sbom.spdx.json",
+		spdx.ImplicitOutputs.Strings()[0])
+}
+
+// normalizeSbomManifestForTests replaces the build directory in the paths of the installed files
+// with out/soong.
+func normalizeSbomManifestForTests(config Config, manifest sbomManifest) sbomManifest {
+	for i := range manifest.Packages {
+		for j := range manifest.Packages[i].Files {
+			file := &manifest.Packages[i].Files[j]
+			file.Path = StringPathRelativeToTop(config.BuildDir(), file.Path)
+		}
+	}
+	return manifest
+}
+
+func TestSpdxLicenseExpression(t *testing.T) {
+	testCases := []struct {
+		kinds    []string
+		expected string
+	}{
+		{nil, "NOASSERTION"},
+		{[]string{"SPDX-license-identifier-MIT"}, "MIT"},
+		{[]string{"legacy_by_exception_only"}, "LicenseRef-legacy-by-exception-only"},
+		{
+			[]string{"SPDX-license-identifier-MIT", "SPDX-license-identifier-BSD-3-Clause", "SPDX-license-identifier-MIT"},
+			"(BSD-3-Clause AND MIT)",
+		},
+	}
+	for _, tc := range testCases {
+		AssertStringEquals(t, "license expression", tc.expected, spdxLicenseExpression(tc.kinds))
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/google/blueprint"
+)
+
+// Writes a machine-readable catalog of every test module to out/soong/test_catalog.json.
+//
+// The test module types describe each of their variants with a TestModuleInfo provider, which the
+// test_catalog singleton merges into one entry per module, so that test schedulers can find the
+// tests, their configs and their data without going through module-info.json.
+
+func init() {
+	RegisterTestCatalogBuildComponents(InitRegistrationContext)
+}
+
+// RegisterTestCatalogBuildComponents registers the singleton that writes the test catalog.
+func RegisterTestCatalogBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("test_catalog", testCatalogSingletonFactory)
+}
+
+var PrepareForTestWithTestCatalog = FixtureRegisterWithContext(RegisterTestCatalogBuildComponents)
+
+// TestModuleInfo describes a variant of a test module in the test catalog.
+type TestModuleInfo struct {
+	// The test config of the test, either provided by the module or autogenerated, or nil if it
+	// has none.
+	TestConfig Path
+
+	// The extra test configs installed with the test.
+	ExtraTestConfigs Paths
+
+	// The test suites the test is installed into, e.g. cts or general-tests.
+	TestSuites []string
+
+	// The data files installed alongside the test.
+	Data Paths
+
+	// The runner class of the test config if it was generated from the default template of the
+	// module type, or "" if it is not known.
+	Runner string
+
+	// Whether the test is a host side unit test that doesn't need a device.
+	UnitTest bool
+
+	// Whether the test needs to run with root permission.
+	RequireRoot bool
+}
+
+var TestModuleInfoProvider = blueprint.NewProvider(TestModuleInfo{})
+
+// testCatalogEntry is a test module in the catalog.
+type testCatalogEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+
+	// Whether any variant of the test is built for the host or for the device.
+	Host   bool `json:"host"`
+	Device bool `json:"device"`
+
+	// The union of the test suites of all the variants.
+	TestSuites []string `json:"test_suites,omitempty"`
+
+	// Tags that classify the test, e.g. unit_test or require_root.
+	Tags []string `json:"tags,omitempty"`
+
+	// The modules required on the host to run the test.
+	HostRequired []string `json:"host_required,omitempty"`
+
+	Variants []testCatalogVariant `json:"variants"`
+}
+
+type testCatalogVariant struct {
+	Variant string `json:"variant"`
+	Os      string `json:"os"`
+	Arch    string `json:"arch"`
+
+	TestConfig       string   `json:"test_config,omitempty"`
+	ExtraTestConfigs []string `json:"extra_test_configs,omitempty"`
+	Runner           string   `json:"runner,omitempty"`
+	Data             []string `json:"data,omitempty"`
+
+	// The files installed by the variant.
+	Installed []string `json:"installed,omitempty"`
+}
+
+func testCatalogSingletonFactory() Singleton {
+	return &testCatalogSingleton{}
+}
+
+type testCatalogSingleton struct {
+	catalog OutputPath
+}
+
+func (s *testCatalogSingleton) GenerateBuildActions(ctx SingletonContext) {
+	entries := make(map[string]*testCatalogEntry)
+	tags := make(map[string]map[string]bool)
+
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() || !ctx.ModuleHasProvider(module, TestModuleInfoProvider) {
+			return
+		}
+		info := ctx.ModuleProvider(module, TestModuleInfoProvider).(TestModuleInfo)
+
+		name := ctx.ModuleName(module)
+		entry := entries[name]
+		if entry == nil {
+			entry = &testCatalogEntry{
+				Name: name,
+				Type: ctx.ModuleType(module),
+				Path: ctx.ModuleDir(module),
+			}
+			entries[name] = entry
+			tags[name] = make(map[string]bool)
+		}
+
+		target := module.Target()
+		if target.Os.Class == Device {
+			entry.Device = true
+		} else {
+			entry.Host = true
+		}
+		entry.TestSuites = append(entry.TestSuites, info.TestSuites...)
+		entry.HostRequired = append(entry.HostRequired, module.HostRequiredModuleNames()...)
+		if info.UnitTest {
+			tags[name]["unit_test"] = true
+		}
+		if info.RequireRoot {
+			tags[name]["require_root"] = true
+		}
+
+		variant := testCatalogVariant{
+			Variant:          ctx.ModuleSubDir(module),
+			Os:               target.Os.String(),
+			Arch:             target.Arch.ArchType.String(),
+			ExtraTestConfigs: info.ExtraTestConfigs.Strings(),
+			Runner:           info.Runner,
+			Data:             info.Data.Strings(),
+		}
+		if info.TestConfig != nil {
+			variant.TestConfig = info.TestConfig.String()
+		}
+		for _, installed := range module.FilesToInstall() {
+			variant.Installed = append(variant.Installed, installed.ToMakePath().String())
+		}
+		entry.Variants = append(entry.Variants, variant)
+	})
+
+	catalog := []testCatalogEntry{}
+	for _, name := range SortedStringKeys(entries) {
+		entry := entries[name]
+		entry.TestSuites = SortedUniqueStrings(entry.TestSuites)
+		entry.HostRequired = SortedUniqueStrings(entry.HostRequired)
+		entry.Tags = SortedStringKeys(tags[name])
+		sort.Slice(entry.Variants, func(i, j int) bool {
+			return entry.Variants[i].Variant < entry.Variants[j].Variant
+		})
+		catalog = append(catalog, *entry)
+	}
+
+	content, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the test catalog: %s", err)
+		return
+	}
+
+	s.catalog = PathForOutput(ctx, "test_catalog.json")
+	WriteFileRule(ctx, s.catalog, string(content))
+
+	ctx.Phony("test-catalog", s.catalog)
+}
+
+func (s *testCatalogSingleton) MakeVars(ctx MakeVarsContext) {
+	ctx.DistForGoal("test-catalog", s.catalog)
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testCatalogTestModule struct {
+	ModuleBase
+	properties struct {
+		Test_suites  []string
+		Data         []string `android:"path"`
+		Unit_test    *bool
+		Require_root *bool
+	}
+}
+
+func (m *testCatalogTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	testConfig := PathForModuleOut(ctx, ctx.ModuleName()+".config")
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: testConfig,
+	})
+	ctx.SetProvider(TestModuleInfoProvider, TestModuleInfo{
+		TestConfig:  testConfig,
+		TestSuites:  m.properties.Test_suites,
+		Data:        PathsForModuleSrc(ctx, m.properties.Data),
+		Runner:      "com.android.tradefed.testtype.HostTest",
+		UnitTest:    Bool(m.properties.Unit_test),
+		RequireRoot: Bool(m.properties.Require_root),
+	})
+}
+
+func testCatalogTestModuleFactory() Module {
+	module := &testCatalogTestModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, HostAndDeviceSupported, MultilibCommon)
+	return module
+}
+
+func TestTestCatalog(t *testing.T) {
+	bp := `
+		test_module {
+			name: "host_test",
+			host_supported: true,
+			device_supported: false,
+			test_suites: ["general-tests"],
+			data: ["testdata.txt"],
+			unit_test: true,
+		}
+
+		test_module {
+			name: "device_test",
+			test_suites: ["vts", "general-tests"],
+			require_root: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithTestCatalog,
+		FixtureWithRootAndroidBp(bp),
+		FixtureAddTextFile("testdata.txt", ""),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", testCatalogTestModuleFactory)
+		}),
+	).RunTest(t)
+
+	var catalog []testCatalogEntry
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("test_catalog").Output("test_catalog.json"))
+	if err := json.Unmarshal([]byte(content), &catalog); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertDeepEquals(t, "catalog", []testCatalogEntry{
+		{
+			Name:       "device_test",
+			Type:       "test_module",
+			Path:       ".",
+			Device:     true,
+			TestSuites: []string{"general-tests", "vts"},
+			Tags:       []string{"require_root"},
+			Variants: []testCatalogVariant{
+				{
+					Variant:    "android_common",
+					Os:         "android",
+					Arch:       "common",
+					TestConfig: "out/soong/.intermediates/device_test/android_common/device_test.config",
+					Runner:     "com.android.tradefed.testtype.HostTest",
+				},
+			},
+		},
+		{
+			Name:       "host_test",
+			Type:       "test_module",
+			Path:       ".",
+			Host:       true,
+			TestSuites: []string{"general-tests"},
+			Tags:       []string{"unit_test"},
+			Variants: []testCatalogVariant{
+				{
+					Variant:    result.Config.BuildOSCommonTarget.String(),
+					Os:         result.Config.BuildOSCommonTarget.Os.String(),
+					Arch:       "common",
+					TestConfig: "out/soong/.intermediates/host_test/" + result.Config.BuildOSCommonTarget.String() + "/host_test.config",
+					Runner:     "com.android.tradefed.testtype.HostTest",
+					Data:       []string{"testdata.txt"},
+				},
+			},
+		},
+	}, normalizeTestCatalogForTests(result.Config, catalog))
+}
+
+// normalizeTestCatalogForTests replaces the build directory in the paths of the test configs with
+// out/soong.
+func normalizeTestCatalogForTests(config Config, catalog []testCatalogEntry) []testCatalogEntry {
+	for i := range catalog {
+		for j := range catalog[i].Variants {
+			variant := &catalog[i].Variants[j]
+			variant.TestConfig = StringPathRelativeToTop(config.BuildDir(), variant.TestConfig)
+		}
+	}
+	return catalog
+}
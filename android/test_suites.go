@@ -14,16 +14,55 @@
 
 package android
 
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/response"
+)
+
+// The testsuites singleton packages every test suite listed in the test_suites property of a test
+// module into out/soong/packaging/<suite>.zip, along with <suite>-files.txt listing the files in the
+// zip and <suite>-module_manifest.json listing the files of each test module.
+//
+// The files of each variant of a test are packaged into <host|target>/testcases/<module>/, with
+// native tests in an <arch>/ subdirectory. Files that are already installed into a testcases
+// directory keep their path relative to it. The shared libraries of host tests are packaged into
+// host/lib[64]/ where the runpath of the tests finds them.
+//
+// The test_suite_package module type configures how a suite is packaged, e.g. which variants of the
+// tests are packaged:
+//
+//    test_suite_package {
+//        name: "my-tests",
+//        device: false,
+//        tools: [":my-tests-tradefed"],
+//    }
+//
+// and makes the package the output of a goal with the name of the suite that is also distributed,
+// so that the suite doesn't need the compatibility suite packaging in Make.
+
 func init() {
-	RegisterSingletonType("testsuites", testSuiteFilesFactory)
+	RegisterTestSuiteBuildComponents(InitRegistrationContext)
 }
 
-func testSuiteFilesFactory() Singleton {
-	return &testSuiteFiles{}
+// RegisterTestSuiteBuildComponents registers the test_suite_package module type and the singleton
+// that packages the test suites.
+func RegisterTestSuiteBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("test_suite_package", TestSuitePackageFactory)
+	ctx.RegisterSingletonType("testsuites", testSuiteFilesFactory)
 }
 
-type testSuiteFiles struct {
-	robolectric WritablePath
+var PrepareForTestWithTestSuites = FixtureRegisterWithContext(RegisterTestSuiteBuildComponents)
+
+// The rules of the suites that are packaged by a goal even without a test_suite_package module,
+// because they have never been packaged by Make. They keep the contents and the outputs they had
+// before the other suites were packaged.
+var legacyTestSuiteRules = map[string]testSuitePackageRules{
+	"robolectric-tests": {host: true, device: true, goal: true, zipOnly: true},
 }
 
 type TestSuiteModule interface {
@@ -31,45 +70,337 @@ type TestSuiteModule interface {
 	TestSuites() []string
 }
 
+type testSuitePackageProperties struct {
+	// The name of the test suite to package, as listed in the test_suites property of the tests.
+	// Defaults to the name of the module.
+	Test_suite *string
+
+	// Whether to package the host variants of the tests. Defaults to true.
+	Host *bool
+
+	// Whether to package the device variants of the tests. Defaults to true.
+	Device *bool
+
+	// Whether to package the shared libraries the host tests depend on. Defaults to true.
+	Include_shared_libs *bool
+
+	// Files to package into the tools/ directory of the suite, e.g. the test harness.
+	Tools []string `android:"path"`
+}
+
+type testSuitePackageModule struct {
+	ModuleBase
+
+	properties testSuitePackageProperties
+
+	tools Paths
+}
+
+func (m *testSuitePackageModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	// The suite is packaged by the testsuites singleton.
+	m.tools = PathsForModuleSrc(ctx, m.properties.Tools)
+}
+
+func (m *testSuitePackageModule) testSuite() string {
+	return proptools.StringDefault(m.properties.Test_suite, m.Name())
+}
+
+func (m *testSuitePackageModule) rules() testSuitePackageRules {
+	return testSuitePackageRules{
+		host:       proptools.BoolDefault(m.properties.Host, true),
+		device:     proptools.BoolDefault(m.properties.Device, true),
+		sharedLibs: proptools.BoolDefault(m.properties.Include_shared_libs, true),
+		tools:      m.tools,
+		goal:       true,
+	}
+}
+
+func TestSuitePackageFactory() Module {
+	module := &testSuitePackageModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// testSuitePackageRules describes how a test suite is packaged.
+type testSuitePackageRules struct {
+	host, device, sharedLibs bool
+
+	tools Paths
+
+	// Whether the package is built by a goal with the name of the suite and distributed.
+	goal bool
+
+	// Whether the goal only builds and distributes the zip, without the file list and the module
+	// manifest.
+	zipOnly bool
+}
+
+// testSuiteVariant is a variant of a test module in a test suite.
+type testSuiteVariant struct {
+	name   string
+	target Target
+
+	// The files installed by the variant and by its dependencies.
+	installed InstallPaths
+	deps      InstallPaths
+
+	info TestModuleInfo
+}
+
+// testSuiteManifestModule is a test module in the module manifest of a suite, with the paths of
+// its files in the package.
+type testSuiteManifestModule struct {
+	Name        string   `json:"name"`
+	TestConfigs []string `json:"test_configs,omitempty"`
+	Files       []string `json:"files"`
+	SharedLibs  []string `json:"shared_libs,omitempty"`
+}
+
+func testSuiteFilesFactory() Singleton {
+	return &testSuiteFiles{}
+}
+
+type testSuiteFiles struct {
+	// The outputs of the suites that are built by a goal, by suite.
+	goals map[string]Paths
+}
+
 func (t *testSuiteFiles) GenerateBuildActions(ctx SingletonContext) {
-	files := make(map[string]map[string]InstallPaths)
+	variants := make(map[string][]testSuiteVariant)
+	packages := make(map[string]*testSuitePackageModule)
 
 	ctx.VisitAllModules(func(m Module) {
-		if tsm, ok := m.(TestSuiteModule); ok {
-			for _, testSuite := range tsm.TestSuites() {
-				if files[testSuite] == nil {
-					files[testSuite] = make(map[string]InstallPaths)
-				}
-				name := ctx.ModuleName(m)
-				files[testSuite][name] = append(files[testSuite][name], tsm.FilesToInstall()...)
+		if p, ok := m.(*testSuitePackageModule); ok {
+			suite := p.testSuite()
+			if other := packages[suite]; other != nil {
+				ctx.ModuleErrorf(m, "test suite %q is already packaged by %q", suite, other.Name())
+				return
 			}
+			packages[suite] = p
+			return
+		}
+		if !m.Enabled() {
+			return
+		}
+
+		var suites []string
+		if tsm, ok := m.(TestSuiteModule); ok {
+			suites = append(suites, tsm.TestSuites()...)
+		}
+		var info TestModuleInfo
+		if ctx.ModuleHasProvider(m, TestModuleInfoProvider) {
+			info = ctx.ModuleProvider(m, TestModuleInfoProvider).(TestModuleInfo)
+			suites = append(suites, info.TestSuites...)
+		}
+
+		for _, suite := range FirstUniqueStrings(suites) {
+			variants[suite] = append(variants[suite], testSuiteVariant{
+				name:      ctx.ModuleName(m),
+				target:    m.Target(),
+				installed: m.FilesToInstall(),
+				deps:      m.base().installFilesDepSet.ToList(),
+				info:      info,
+			})
 		}
 	})
 
-	t.robolectric = robolectricTestSuite(ctx, files["robolectric-tests"])
+	t.goals = make(map[string]Paths)
+	for _, suite := range SortedUniqueStrings(append(SortedStringKeys(variants), SortedStringKeys(packages)...)) {
+		rules := testSuitePackageRules{host: true, device: true, sharedLibs: true}
+		if p := packages[suite]; p != nil {
+			rules = p.rules()
+		} else if legacy, ok := legacyTestSuiteRules[suite]; ok {
+			rules = legacy
+		}
 
-	ctx.Phony("robolectric-tests", t.robolectric)
+		outputs := buildTestSuitePackage(ctx, suite, rules, variants[suite])
+		if rules.zipOnly && len(outputs) > 0 {
+			outputs = outputs[:1]
+		}
+		if rules.goal {
+			t.goals[suite] = outputs
+			ctx.Phony(suite, outputs...)
+		}
+	}
 }
 
 func (t *testSuiteFiles) MakeVars(ctx MakeVarsContext) {
-	ctx.DistForGoal("robolectric-tests", t.robolectric)
+	for _, suite := range SortedStringKeys(t.goals) {
+		ctx.DistForGoal(suite, t.goals[suite]...)
+	}
+}
+
+// buildTestSuitePackage writes the rules that package a test suite and returns the zip, the file
+// list and the module manifest of the suite.
+func buildTestSuitePackage(ctx SingletonContext, suite string, rules testSuitePackageRules,
+	variants []testSuiteVariant) Paths {
+
+	// The source of every file in the package, by its path in the package. The files shared by the
+	// tests are only packaged once, different files at the same path are an error.
+	files := make(map[string]Path)
+	add := func(dest string, src Path) string {
+		if other, exists := files[dest]; !exists {
+			files[dest] = src
+		} else if other.String() != src.String() {
+			ctx.Errorf("test suite %q packages both %s and %s at %s", suite, other, src, dest)
+		}
+		return dest
+	}
+	// The module whose test config is packaged at each path. The variants of a module generate
+	// the same test config, only the first one is packaged.
+	configModules := make(map[string]string)
+	addConfig := func(module, dest string, src Path) string {
+		if configModules[dest] == module {
+			return dest
+		}
+		configModules[dest] = module
+		return add(dest, src)
+	}
+
+	modules := make(map[string]*testSuiteManifestModule)
+	for _, v := range variants {
+		prefix := "target"
+		if v.target.Os.Class != Device {
+			prefix = "host"
+		}
+		if (prefix == "host" && !rules.host) || (prefix == "target" && !rules.device) {
+			continue
+		}
+
+		module := modules[v.name]
+		if module == nil {
+			module = &testSuiteManifestModule{Name: v.name}
+			modules[v.name] = module
+		}
+
+		root := pathForInstall(ctx, v.target.Os, v.target.Arch.ArchType, "", false).ToMakePath().String()
+		moduleDir := filepath.Join(prefix, "testcases", v.name)
+		archDir := moduleDir
+		if v.target.Arch.ArchType != Common {
+			archDir = filepath.Join(moduleDir, v.target.Arch.ArchType.String())
+		}
+
+		// The files of the variant that are not installed into a testcases directory are packaged
+		// relative to the directory that contains all of them.
+		installed := make(map[string]bool)
+		var others []string
+		for _, f := range v.installed {
+			path := f.ToMakePath().String()
+			installed[path] = true
+			if !strings.HasPrefix(testSuiteRel(root, path), "testcases/") {
+				others = append(others, path)
+			}
+		}
+		var base string
+		if len(others) > 0 {
+			base = testSuiteCommonDir(others)
+		}
+
+		for _, f := range v.installed {
+			path := f.ToMakePath().String()
+			if rel := testSuiteRel(root, path); strings.HasPrefix(rel, "testcases/") {
+				module.Files = append(module.Files, add(filepath.Join(prefix, rel), f))
+			} else {
+				module.Files = append(module.Files, add(filepath.Join(archDir, testSuiteRel(base, path)), f))
+			}
+		}
+
+		if v.info.TestConfig != nil {
+			module.TestConfigs = append(module.TestConfigs,
+				addConfig(v.name, filepath.Join(moduleDir, v.name+".config"), v.info.TestConfig))
+		}
+		for _, config := range v.info.ExtraTestConfigs {
+			module.TestConfigs = append(module.TestConfigs,
+				addConfig(v.name, filepath.Join(moduleDir, config.Base()), config))
+		}
+		for _, data := range v.info.Data {
+			module.Files = append(module.Files, add(filepath.Join(archDir, data.Rel()), data))
+		}
+
+		// The shared libraries of device tests are on the device, only those of host tests are
+		// packaged.
+		if prefix == "host" && rules.sharedLibs {
+			for _, f := range v.deps {
+				path := f.ToMakePath().String()
+				if installed[path] {
+					continue
+				}
+				if rel := testSuiteRel(root, path); !strings.HasPrefix(rel, "../") {
+					module.SharedLibs = append(module.SharedLibs, add(filepath.Join(prefix, rel), f))
+				}
+			}
+		}
+	}
+
+	for _, tool := range rules.tools {
+		add(filepath.Join("tools", tool.Base()), tool)
+	}
+
+	// The files are passed to soong_zip as -e <path in zip> -f <file> pairs in a response file, the
+	// command line of a real suite would be too long.
+	dests := SortedStringKeys(files)
+	var srcs Paths
+	args := &strings.Builder{}
+	for _, dest := range dests {
+		srcs = append(srcs, files[dest])
+		if err := response.WriteRspFile(args, []string{"-e", dest, "-f", files[dest].String()}); err != nil {
+			// There should never be I/O errors writing to a strings.Builder.
+			panic(err)
+		}
+		args.WriteString("\n")
+	}
+	zipArgs := PathForOutput(ctx, "packaging", suite+"-zip_args.rsp")
+	WriteFileRule(ctx, zipArgs, args.String())
+
+	zip := PathForOutput(ctx, "packaging", suite+".zip")
+	builder := NewRuleBuilder(pctx, ctx)
+	builder.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", zip).
+		FlagWithInput("@", zipArgs).
+		Implicits(srcs)
+	builder.Build("test_suite_package_"+suite, suite+".zip")
+
+	fileList := PathForOutput(ctx, "packaging", suite+"-files.txt")
+	WriteFileRule(ctx, fileList, strings.Join(dests, "\n"))
+
+	manifest := []testSuiteManifestModule{}
+	for _, name := range SortedStringKeys(modules) {
+		module := modules[name]
+		module.TestConfigs = SortedUniqueStrings(module.TestConfigs)
+		module.Files = SortedUniqueStrings(module.Files)
+		module.SharedLibs = SortedUniqueStrings(module.SharedLibs)
+		manifest = append(manifest, *module)
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the module manifest of test suite %q: %s", suite, err)
+		return nil
+	}
+	moduleManifest := PathForOutput(ctx, "packaging", suite+"-module_manifest.json")
+	WriteFileRule(ctx, moduleManifest, string(content))
+
+	return Paths{zip, fileList, moduleManifest}
+}
+
+// testSuiteRel returns the path of a file relative to a directory, which starts with ../ if the file
+// is not in the directory.
+func testSuiteRel(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return "../" + path
+	}
+	return rel
 }
 
-func robolectricTestSuite(ctx SingletonContext, files map[string]InstallPaths) WritablePath {
-	var installedPaths InstallPaths
-	for _, module := range SortedStringKeys(files) {
-		installedPaths = append(installedPaths, files[module]...)
+// testSuiteCommonDir returns the deepest directory that contains all the given files.
+func testSuiteCommonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		for dir != "." && dir != "/" && !strings.HasPrefix(f, dir+"/") {
+			dir = filepath.Dir(dir)
+		}
 	}
-	testCasesDir := pathForInstall(ctx, BuildOs, X86, "testcases", false).ToMakePath()
-
-	outputFile := PathForOutput(ctx, "packaging", "robolectric-tests.zip")
-	rule := NewRuleBuilder(pctx, ctx)
-	rule.Command().BuiltTool("soong_zip").
-		FlagWithOutput("-o ", outputFile).
-		FlagWithArg("-P ", "host/testcases").
-		FlagWithArg("-C ", testCasesDir.String()).
-		FlagWithRspFileInputList("-r ", outputFile.ReplaceExtension(ctx, "rsp"), installedPaths.Paths())
-	rule.Build("robolectric_tests_zip", "robolectric-tests.zip")
-
-	return outputFile
+	return dir
 }
@@ -0,0 +1,221 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type testSuiteTestModule struct {
+	ModuleBase
+	properties struct {
+		Test_suites []string
+		Data        []string `android:"path"`
+		Shared_libs []string
+		Lib         bool
+		Testcases   bool
+	}
+}
+
+func (m *testSuiteTestModule) InstallInTestcases() bool {
+	return m.properties.Testcases
+}
+
+type testSuiteInstallDepTag struct {
+	blueprint.BaseDependencyTag
+	InstallAlwaysNeededDependencyTag
+}
+
+func (m *testSuiteTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, testSuiteInstallDepTag{}, m.properties.Shared_libs...)
+}
+
+func (m *testSuiteTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	if m.properties.Lib {
+		ctx.InstallFile(PathForModuleInstall(ctx, "lib64"), ctx.ModuleName()+".so", outputFile)
+		return
+	}
+
+	testConfig := PathForModuleOut(ctx, ctx.ModuleName()+".config")
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: testConfig,
+	})
+	if m.properties.Testcases {
+		// Like robolectric tests, which install their files and config into testcases/<module>/.
+		installDir := PathForModuleInstall(ctx, ctx.ModuleName())
+		ctx.InstallFile(installDir, ctx.ModuleName()+".config", testConfig)
+		ctx.InstallFile(installDir, ctx.ModuleName()+".jar", outputFile)
+	} else {
+		ctx.InstallFile(PathForModuleInstall(ctx, "nativetest64", ctx.ModuleName()), ctx.ModuleName(), outputFile)
+	}
+	ctx.SetProvider(TestModuleInfoProvider, TestModuleInfo{
+		TestConfig: testConfig,
+		TestSuites: m.properties.Test_suites,
+		Data:       PathsForModuleSrc(ctx, m.properties.Data),
+	})
+}
+
+func testSuiteTestModuleFactory() Module {
+	module := &testSuiteTestModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, HostAndDeviceSupported, MultilibFirst)
+	return module
+}
+
+func TestTestSuitePackages(t *testing.T) {
+	bp := `
+		test_module {
+			name: "my_test",
+			host_supported: true,
+			test_suites: ["my-tests", "other-tests"],
+			data: ["testdata/data.txt"],
+			shared_libs: ["libfoo"],
+		}
+
+		test_module {
+			name: "libfoo",
+			host_supported: true,
+			lib: true,
+		}
+
+		test_module {
+			name: "my_robolectric_test",
+			host_supported: true,
+			device_supported: false,
+			testcases: true,
+			test_suites: ["robolectric-tests"],
+			shared_libs: ["libfoo"],
+		}
+
+		test_suite_package {
+			name: "my-tests",
+			device: false,
+			tools: ["tradefed.jar"],
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithTestSuites,
+		FixtureWithRootAndroidBp(bp),
+		FixtureAddTextFile("testdata/data.txt", ""),
+		FixtureAddTextFile("tradefed.jar", ""),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", testSuiteTestModuleFactory)
+		}),
+	).RunTest(t)
+
+	testSuites := result.SingletonForTests("testsuites")
+
+	fileList := func(suite string) []string {
+		return strings.Split(ContentFromFileRuleForTests(t, testSuites.Output("packaging/"+suite+"-files.txt")), "\n")
+	}
+
+	AssertDeepEquals(t, "my-tests files", []string{
+		"host/lib64/libfoo.so",
+		"host/testcases/my_test/my_test.config",
+		"host/testcases/my_test/x86_64/my_test",
+		"host/testcases/my_test/x86_64/testdata/data.txt",
+		"tools/tradefed.jar",
+	}, fileList("my-tests"))
+
+	AssertDeepEquals(t, "other-tests files", []string{
+		"host/lib64/libfoo.so",
+		"host/testcases/my_test/my_test.config",
+		"host/testcases/my_test/x86_64/my_test",
+		"host/testcases/my_test/x86_64/testdata/data.txt",
+		"target/testcases/my_test/arm64/my_test",
+		"target/testcases/my_test/arm64/testdata/data.txt",
+		"target/testcases/my_test/my_test.config",
+	}, fileList("other-tests"))
+
+	var manifest []testSuiteManifestModule
+	content := ContentFromFileRuleForTests(t, testSuites.Output("packaging/my-tests-module_manifest.json"))
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	AssertDeepEquals(t, "my-tests module manifest", []testSuiteManifestModule{
+		{
+			Name:        "my_test",
+			TestConfigs: []string{"host/testcases/my_test/my_test.config"},
+			Files: []string{
+				"host/testcases/my_test/x86_64/my_test",
+				"host/testcases/my_test/x86_64/testdata/data.txt",
+			},
+			SharedLibs: []string{"host/lib64/libfoo.so"},
+		},
+	}, manifest)
+
+	// robolectric-tests.zip keeps the files installed into out/host/linux-x86/testcases at the same
+	// paths, without the shared libraries of the tests.
+	AssertDeepEquals(t, "robolectric-tests files", []string{
+		"host/testcases/my_robolectric_test/my_robolectric_test.config",
+		"host/testcases/my_robolectric_test/my_robolectric_test.jar",
+	}, fileList("robolectric-tests"))
+
+	zip := testSuites.Output("packaging/my-tests.zip")
+	AssertStringDoesContain(t, "zip command", zip.RuleParams.Command, "soong_zip")
+	AssertStringListContains(t, "zip inputs", zip.Implicits.Strings(), "out/soong/packaging/my-tests-zip_args.rsp")
+	AssertStringListContains(t, "zip inputs", zip.Implicits.Strings(), "out/soong/.intermediates/my_test/linux_glibc_x86_64/my_test.config")
+	zipArgs := ContentFromFileRuleForTests(t, testSuites.Output("packaging/my-tests-zip_args.rsp"))
+	AssertStringDoesContain(t, "zip args", zipArgs, "-e tools/tradefed.jar -f ")
+
+	// Only the suites with a test_suite_package module are built by a goal.
+	goals := testSuites.Singleton().(*testSuiteFiles).goals
+	AssertDeepEquals(t, "goals", []string{"my-tests", "robolectric-tests"}, SortedStringKeys(goals))
+	AssertPathsRelativeToTopEquals(t, "my-tests goal outputs", []string{
+		"out/soong/packaging/my-tests.zip",
+		"out/soong/packaging/my-tests-files.txt",
+		"out/soong/packaging/my-tests-module_manifest.json",
+	}, goals["my-tests"])
+	// robolectric-tests only builds and distributes the zip, like before the other suites were
+	// packaged.
+	AssertPathsRelativeToTopEquals(t, "robolectric-tests goal outputs", []string{
+		"out/soong/packaging/robolectric-tests.zip",
+	}, goals["robolectric-tests"])
+}
+
+func TestTestSuitePackageConflict(t *testing.T) {
+	bp := `
+		test_suite_package {
+			name: "my-tests",
+			tools: [
+				"a/tradefed.jar",
+				"b/tradefed.jar",
+			],
+		}
+	`
+
+	GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithTestSuites,
+		FixtureWithRootAndroidBp(bp),
+		FixtureAddTextFile("a/tradefed.jar", ""),
+		FixtureAddTextFile("b/tradefed.jar", ""),
+	).
+		ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`test suite "my-tests" packages both .*a/tradefed.jar and .*b/tradefed.jar at tools/tradefed.jar`)).
+		RunTest(t)
+}
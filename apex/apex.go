@@ -378,6 +378,9 @@ type apexBundle struct {
 	// Struct holding the merged notice file paths in different formats
 	mergedNotices android.NoticeOutputs
 
+	// NOTICE.xml.gz and NOTICE.html of the files in this APEX, see buildNoticeIndex
+	noticeIndex android.NoticeIndexOutputs
+
 	// The built APEX file. This is the main product.
 	outputFile android.WritablePath
 
@@ -572,6 +575,14 @@ func (d dependencyTag) ReplaceSourceWithPrebuilt() bool {
 
 var _ android.ReplaceSourceWithPrebuilt = &dependencyTag{}
 
+// IsPackagingItem returns true for the dependencies that are part of the APEX payload, so that
+// the license policies apply to the contents of the APEX.
+func (d dependencyTag) IsPackagingItem() bool {
+	return d.payload
+}
+
+var _ android.PackagingItem = dependencyTag{}
+
 var (
 	androidAppTag   = dependencyTag{name: "androidApp", payload: true}
 	bpfTag          = dependencyTag{name: "bpf", payload: true}
@@ -1237,6 +1248,10 @@ func (a *apexBundle) OutputFiles(tag string) (android.Paths, error) {
 	case "", android.DefaultDistTag:
 		// This is the default dist path.
 		return android.Paths{a.outputFile}, nil
+	case ".notice.xml.gz":
+		return android.Paths{a.noticeIndex.XmlGz}, nil
+	case ".notice.html":
+		return android.Paths{a.noticeIndex.Html}, nil
 	default:
 		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 	}
@@ -2074,6 +2089,7 @@ func (a *apexBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	a.buildApexDependencyInfo(ctx)
 	a.buildLintReports(ctx)
+	a.noticeIndex = a.buildNoticeIndex(ctx)
 
 	// Append meta-files to the filesInfo list so that they are reflected in Android.mk as well.
 	if a.installable() {
@@ -333,6 +333,29 @@ func (a *apexBundle) buildNoticeFiles(ctx android.ModuleContext, apexFileName st
 	return android.BuildNoticeOutput(ctx, a.installDir, apexFileName, android.SortedUniquePaths(noticeFiles))
 }
 
+// buildNoticeIndex creates the build rules for NOTICE.xml.gz and NOTICE.html, which aggregate the
+// license texts of the files in this APEX. Each distinct text is listed once, along with the files
+// it applies to.
+func (a *apexBundle) buildNoticeIndex(ctx android.ModuleContext) android.NoticeIndexOutputs {
+	apexName := proptools.StringDefault(a.properties.Apex_name, a.BaseModuleName())
+	var entries []android.NoticeIndexEntry
+	for _, fi := range a.filesInfo {
+		if !fi.ok() {
+			continue
+		}
+		texts := fi.noticeFiles
+		if fi.module != nil {
+			texts = android.ModuleNoticeTexts(fi.module)
+		}
+		entries = append(entries, android.NoticeIndexEntry{
+			Installed: filepath.Join("/apex", apexName, fi.path()),
+			Texts:     texts,
+		})
+	}
+	return android.BuildNoticeIndex(ctx, android.PathForModuleOut(ctx, "notice_index").OutputPath,
+		"Notices for files contained in "+apexName, entries)
+}
+
 // buildInstalledFilesFile creates a build rule for the installed-files.txt file where the list of
 // files included in this APEX is shown. The text file is dist'ed so that people can see what's
 // included in the APEX without actually downloading and extracting it.
@@ -8,6 +8,8 @@ import (
 var (
 	GetOutputFiles = &getOutputFilesRequestType{}
 	GetCcInfo      = &getCcInfoType{}
+	GetJavaInfo    = &getJavaInfoType{}
+	GetRunfiles    = &getRunfilesType{}
 )
 
 type CcInfo struct {
@@ -18,6 +20,18 @@ type CcInfo struct {
 	SystemIncludes       []string
 }
 
+type JavaInfo struct {
+	// Jars to compile against the target with: the header jars of the target and its exports, or
+	// their class jars if they have no header jars.
+	CompileJars []string
+	// Class jars of the target.
+	RuntimeJars []string
+	// Header jars of the target.
+	HeaderJars []string
+	// Source jars of the target.
+	SourceJars []string
+}
+
 type getOutputFilesRequestType struct{}
 
 // Name returns a string name for this request type. Such request type names must be unique,
@@ -118,6 +132,155 @@ func (g getCcInfoType) ParseResult(rawString string) (CcInfo, error) {
 	}, nil
 }
 
+type getJavaInfoType struct{}
+
+// Name returns a string name for this request type. Such request type names must be unique,
+// and must only consist of alphanumeric characters.
+func (g getJavaInfoType) Name() string {
+	return "getJavaInfo"
+}
+
+// StarlarkFunctionBody returns a starlark function body to process this request type.
+// The returned string is the body of a Starlark function which obtains
+// all request-relevant information about a target and returns a string containing
+// this information.
+// The function should have the following properties:
+//   - `target` is the only parameter to this function (a configured target).
+//   - The return value must be a string.
+//   - The function body should not be indented outside of its own scope.
+func (g getJavaInfoType) StarlarkFunctionBody() string {
+	return `
+java_info = providers(target)["JavaInfo"]
+
+compile_jars = [f.path for f in java_info.compile_jars.to_list()]
+runtime_jars = [f.path for f in java_info.runtime_output_jars]
+header_jars = [jar.compile_jar.path for jar in java_info.outputs.jars if jar.compile_jar]
+source_jars = [f.path for f in java_info.source_jars]
+
+returns = [
+  compile_jars,
+  runtime_jars,
+  header_jars,
+  source_jars,
+]
+
+return "|".join([", ".join(r) for r in returns])`
+}
+
+// ParseResult returns a value obtained by parsing the result of the request's Starlark function.
+// The given rawString must correspond to the string output which was created by evaluating the
+// Starlark given in StarlarkFunctionBody.
+func (g getJavaInfoType) ParseResult(rawString string) (JavaInfo, error) {
+	splitString := strings.Split(rawString, "|")
+	if expectedLen := 4; len(splitString) != expectedLen {
+		return JavaInfo{}, fmt.Errorf("Expected %d items, got %q", expectedLen, splitString)
+	}
+	return JavaInfo{
+		CompileJars: splitOrEmpty(splitString[0], ", "),
+		RuntimeJars: splitOrEmpty(splitString[1], ", "),
+		HeaderJars:  splitOrEmpty(splitString[2], ", "),
+		SourceJars:  splitOrEmpty(splitString[3], ", "),
+	}, nil
+}
+
+type getRunfilesType struct{}
+
+// Name returns a string name for this request type. Such request type names must be unique,
+// and must only consist of alphanumeric characters.
+func (g getRunfilesType) Name() string {
+	return "getRunfiles"
+}
+
+// StarlarkFunctionBody returns a starlark function body to process this request type.
+// The returned string is the body of a Starlark function which obtains
+// all request-relevant information about a target and returns a string containing
+// this information.
+// The function should have the following properties:
+//   - `target` is the only parameter to this function (a configured target).
+//   - The return value must be a string.
+//   - The function body should not be indented outside of its own scope.
+func (g getRunfilesType) StarlarkFunctionBody() string {
+	return `
+runfiles = providers(target)["DefaultInfo"].default_runfiles
+return ", ".join([f.path for f in runfiles.files.to_list()])`
+}
+
+// ParseResult returns a value obtained by parsing the result of the request's Starlark function.
+// The given rawString must correspond to the string output which was created by evaluating the
+// Starlark given in StarlarkFunctionBody.
+func (g getRunfilesType) ParseResult(rawString string) []string {
+	return splitOrEmpty(rawString, ", ")
+}
+
+// GetProviderField returns a request type for the value of a field of a provider of a target, e.g.
+// GetProviderField("CcInfo", "compilation_context.defines") or
+// GetProviderField("@rules_foo//foo:defs.bzl%FooInfo", "srcs"). Nested fields are separated by
+// dots. The value is returned as a list: files are returned as their paths, depsets are
+// flattened, and any other value is converted to a string.
+//
+// The request type is a value, so that the requests for the same provider field are queued and
+// issued once.
+func GetProviderField(provider, field string) getProviderFieldType {
+	return getProviderFieldType{provider: provider, field: field}
+}
+
+type getProviderFieldType struct {
+	provider string
+	field    string
+}
+
+// Name returns a string name for this request type. Such request type names must be unique,
+// and must only consist of alphanumeric characters.
+func (g getProviderFieldType) Name() string {
+	return "getProviderField" + escapeName(g.provider) + "__" + escapeName(g.field)
+}
+
+// escapeName replaces every character of s other than letters and digits with an underscore
+// followed by its hex code, so that distinct strings have distinct escaped names, and escaped
+// names never contain two consecutive underscores.
+func escapeName(s string) string {
+	var sb strings.Builder
+	for _, c := range []byte(s) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "_%02x", c)
+		}
+	}
+	return sb.String()
+}
+
+// StarlarkFunctionBody returns a starlark function body to process this request type.
+// The returned string is the body of a Starlark function which obtains
+// all request-relevant information about a target and returns a string containing
+// this information.
+// The function should have the following properties:
+//   - `target` is the only parameter to this function (a configured target).
+//   - The return value must be a string.
+//   - The function body should not be indented outside of its own scope.
+func (g getProviderFieldType) StarlarkFunctionBody() string {
+	return fmt.Sprintf(`
+value = providers(target)[%q]
+for field in %q.split("."):
+  value = getattr(value, field)
+
+if value == None:
+  value = []
+elif type(value) == "depset":
+  value = value.to_list()
+elif type(value) != "list":
+  value = [value]
+
+return ", ".join([v.path if type(v) == "File" else str(v) for v in value])`, g.provider, g.field)
+}
+
+// ParseResult returns a value obtained by parsing the result of the request's Starlark function.
+// The given rawString must correspond to the string output which was created by evaluating the
+// Starlark given in StarlarkFunctionBody.
+func (g getProviderFieldType) ParseResult(rawString string) []string {
+	return splitOrEmpty(rawString, ", ")
+}
+
 // splitOrEmpty is a modification of strings.Split() that returns an empty list
 // if the given string is empty.
 func splitOrEmpty(s string, sep string) []string {
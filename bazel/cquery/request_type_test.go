@@ -100,3 +100,75 @@ func TestGetCcInfoParseResults(t *testing.T) {
 		}
 	}
 }
+
+func TestGetJavaInfoParseResults(t *testing.T) {
+	testCases := []struct {
+		description          string
+		input                string
+		expectedOutput       JavaInfo
+		expectedErrorMessage string
+	}{
+		{
+			description: "no result",
+			input:       "|||",
+			expectedOutput: JavaInfo{
+				CompileJars: []string{},
+				RuntimeJars: []string{},
+				HeaderJars:  []string{},
+				SourceJars:  []string{},
+			},
+		},
+		{
+			description: "all items set",
+			input:       "foo-hjar.jar, dep-hjar.jar|foo.jar|foo-hjar.jar|foo-src.jar",
+			expectedOutput: JavaInfo{
+				CompileJars: []string{"foo-hjar.jar", "dep-hjar.jar"},
+				RuntimeJars: []string{"foo.jar"},
+				HeaderJars:  []string{"foo-hjar.jar"},
+				SourceJars:  []string{"foo-src.jar"},
+			},
+		},
+		{
+			description:          "too few result splits",
+			input:                "|",
+			expectedOutput:       JavaInfo{},
+			expectedErrorMessage: fmt.Sprintf("Expected %d items, got %q", 4, []string{"", ""}),
+		},
+	}
+	for _, tc := range testCases {
+		actualOutput, err := GetJavaInfo.ParseResult(tc.input)
+		if (err == nil && tc.expectedErrorMessage != "") ||
+			(err != nil && err.Error() != tc.expectedErrorMessage) {
+			t.Errorf("%q: expected Error %s, got %s", tc.description, tc.expectedErrorMessage, err)
+		} else if err == nil && !reflect.DeepEqual(tc.expectedOutput, actualOutput) {
+			t.Errorf("%q: expected %#v != actual %#v", tc.description, tc.expectedOutput, actualOutput)
+		}
+	}
+}
+
+func TestGetRunfilesParseResults(t *testing.T) {
+	if g, w := GetRunfiles.ParseResult(""), []string{}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected %#v != actual %#v", w, g)
+	}
+	if g, w := GetRunfiles.ParseResult("bin/foo, data/bar.txt"), []string{"bin/foo", "data/bar.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected %#v != actual %#v", w, g)
+	}
+}
+
+func TestGetProviderField(t *testing.T) {
+	requestType := GetProviderField("@rules_foo//foo:defs.bzl%FooInfo", "compilation_context.defines")
+	if requestType != GetProviderField("@rules_foo//foo:defs.bzl%FooInfo", "compilation_context.defines") {
+		t.Errorf("expected requests for the same provider field to be equal")
+	}
+
+	if g, w := requestType.Name(), "getProviderField_40rules_5ffoo_2f_2ffoo_3adefs_2ebzl_25FooInfo__compilation_5fcontext_2edefines"; g != w {
+		t.Errorf("expected name %q, got %q", w, g)
+	}
+	if a, b := GetProviderField("a_", "b").Name(), GetProviderField("a", "_b").Name(); a == b {
+		t.Errorf("expected distinct names for distinct provider fields, got %q for both", a)
+	}
+
+	if g, w := requestType.ParseResult("-DFOO, -DBAR"), []string{"-DFOO", "-DBAR"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected %#v != actual %#v", w, g)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"android/soong/java"
+	"strings"
+	"testing"
+)
+
+func TestAndroidLibraryBp2Build(t *testing.T) {
+	testCases := []struct {
+		description                        string
+		moduleTypeUnderTest                string
+		moduleTypeUnderTestFactory         android.ModuleFactory
+		moduleTypeUnderTestBp2BuildMutator func(android.TopDownMutatorContext)
+		depsMutators                       []android.RegisterMutatorFunc
+		dir                                string
+		bp                                 string
+		expectedBazelTargets               []string
+		filesystem                         map[string]string
+	}{
+		{
+			description:                        "android_library srcs, manifest and resources",
+			moduleTypeUnderTest:                "android_library",
+			moduleTypeUnderTestFactory:         java.AndroidLibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.AndroidLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"src/Foo.java":              "",
+				"AndroidManifest.xml":       "",
+				"res/values/strings.xml":    "",
+				"res/layout/activity.xml":   "",
+				"other_res/values/dims.xml": "",
+			},
+			bp: `android_library {
+    name: "foo",
+    srcs: ["src/**/*.java"],
+    static_libs: ["static_dep"],
+    libs: ["lib_dep"],
+    sdk_version: "current",
+}
+
+java_library { name: "lib_dep" }
+
+java_library { name: "static_dep" }
+`,
+			expectedBazelTargets: []string{`android_library(
+    name = "foo",
+    deps = [
+        ":lib_dep",
+        ":static_dep",
+    ],
+    exports = [":static_dep"],
+    manifest = "AndroidManifest.xml",
+    resource_files = [
+        "res/layout/activity.xml",
+        "res/values/strings.xml",
+    ],
+    sdk_version = "current",
+    srcs = ["src/Foo.java"],
+)`},
+		},
+		{
+			description:                        "android_library custom manifest and resource_dirs",
+			moduleTypeUnderTest:                "android_library",
+			moduleTypeUnderTestFactory:         java.AndroidLibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.AndroidLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"Foo.java":                     "",
+				"manifest/AndroidManifest.xml": "",
+				"res/values/strings.xml":       "",
+				"other_res/values/dims.xml":    "",
+			},
+			bp: `android_library {
+    name: "foo",
+    srcs: ["Foo.java"],
+    manifest: "manifest/AndroidManifest.xml",
+    resource_dirs: ["other_res"],
+    arch: { arm: { javacflags: ["-DARM"] } },
+}
+`,
+			expectedBazelTargets: []string{`android_library(
+    name = "foo",
+    javacopts = select({
+        "//build/bazel/platforms/arch:arm": ["-DARM"],
+        "//conditions:default": [],
+    }),
+    manifest = "manifest/AndroidManifest.xml",
+    resource_files = ["other_res/values/dims.xml"],
+    srcs = ["Foo.java"],
+)`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		filesystem := make(map[string][]byte)
+		toParse := []string{
+			"Android.bp",
+		}
+		for f, content := range testCase.filesystem {
+			if strings.HasSuffix(f, "Android.bp") {
+				toParse = append(toParse, f)
+			}
+			filesystem[f] = []byte(content)
+		}
+		config := android.TestConfig(buildDir, nil, testCase.bp, filesystem)
+		ctx := android.NewTestContext(config)
+
+		ctx.RegisterModuleType("java_library", java.LibraryFactory)
+		ctx.RegisterModuleType(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestFactory)
+		for _, m := range testCase.depsMutators {
+			ctx.DepsBp2BuildMutators(m)
+		}
+		ctx.RegisterBp2BuildMutator(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestBp2BuildMutator)
+		ctx.RegisterBp2BuildConfig(bp2buildConfig)
+		ctx.RegisterForBazelConversion()
+
+		_, errs := ctx.ParseFileList(".", toParse)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+		_, errs = ctx.ResolveDependencies(config)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+
+		checkDir := "."
+		if testCase.dir != "" {
+			checkDir = testCase.dir
+		}
+		codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+		bazelTargets := generateBazelTargetsForDir(codegenCtx, checkDir)
+		if actualCount, expectedCount := len(bazelTargets), len(testCase.expectedBazelTargets); actualCount != expectedCount {
+			t.Errorf("%s: Expected %d bazel target, got %d", testCase.description, expectedCount, actualCount)
+		} else {
+			for i, target := range bazelTargets {
+				if w, g := testCase.expectedBazelTargets[i], target.content; w != g {
+					t.Errorf(
+						"%s: Expected generated Bazel target to be '%s', got '%s'",
+						testCase.description,
+						w,
+						g,
+					)
+				}
+			}
+		}
+	}
+}
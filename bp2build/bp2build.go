@@ -35,9 +35,29 @@ func Codegen(ctx *CodegenContext) CodegenMetrics {
 	soongInjectionDir := android.PathForOutput(ctx, "soong_injection")
 	writeFiles(ctx, soongInjectionDir, CreateSoongInjectionFiles())
 
+	writeReadinessReport(ctx, metrics.readiness)
+
 	return metrics
 }
 
+// writeReadinessReport writes the conversion readiness report as JSON and the per-directory summary
+// as a table. They are written outside of the bp2build directory to keep them out of the workspace.
+func writeReadinessReport(ctx *CodegenContext, report ReadinessReport) {
+	data, err := report.JSON()
+	if err != nil {
+		panic(fmt.Errorf("Failed to marshal the bp2build readiness report: %s", err))
+	}
+	files := map[string]string{
+		"bp2build_readiness.json": string(data) + "\n",
+		"bp2build_readiness.txt":  report.DirectoryTable(),
+	}
+	for _, name := range android.SortedStringKeys(files) {
+		if err := writeFile(ctx, android.PathForOutput(ctx, name), files[name]); err != nil {
+			panic(fmt.Errorf("Failed to write %q due to %q", name, err))
+		}
+	}
+}
+
 // Get the output directory and create it if it doesn't exist.
 func getOrCreateOutputDir(outputDir android.OutputPath, ctx android.PathContext, dir string) android.OutputPath {
 	dirPath := outputDir.Join(ctx, dir)
@@ -182,11 +182,20 @@ func GenerateBazelTargets(ctx *CodegenContext, generateFilegroups bool) (map[str
 
 	// Simple metrics tracking for bp2build
 	metrics := CodegenMetrics{
-		RuleClassCount: make(map[string]int),
+		RuleClassCount:           make(map[string]int),
+		ModuleTypeCount:          make(map[string]int),
+		ConvertedModuleTypeCount: make(map[string]int),
 	}
 
 	dirs := make(map[string]bool)
 
+	// The module types of the Soong modules, and whether they were converted, keyed by
+	// "<dir>:<name>" as generated targets are named after the module they were converted from.
+	soongModuleTypes := make(map[string]string)
+	convertedModules := make(map[string]bool)
+
+	readiness := newReadinessCollector()
+
 	bpCtx := ctx.Context()
 	bpCtx.VisitAllModules(func(m blueprint.Module) {
 		dir := bpCtx.ModuleDir(m)
@@ -196,9 +205,13 @@ func GenerateBazelTargets(ctx *CodegenContext, generateFilegroups bool) (map[str
 
 		switch ctx.Mode() {
 		case Bp2Build:
+			readiness.addModule(bpCtx, m)
 			if b, ok := m.(android.Bazelable); ok && b.HasHandcraftedLabel() {
 				metrics.handCraftedTargetCount += 1
 				metrics.TotalModuleCount += 1
+				moduleKey := dir + ":" + bpCtx.ModuleName(m)
+				soongModuleTypes[moduleKey] = bpCtx.ModuleType(m)
+				convertedModules[moduleKey] = true
 				pathToBuildFile := getBazelPackagePath(b)
 				// We are using the entire contents of handcrafted build file, so if multiple targets within
 				// a package have handcrafted targets, we only want to include the contents one time.
@@ -216,8 +229,10 @@ func GenerateBazelTargets(ctx *CodegenContext, generateFilegroups bool) (map[str
 			} else if btm, ok := m.(android.BazelTargetModule); ok {
 				t = generateBazelTarget(bpCtx, m, btm)
 				metrics.RuleClassCount[t.ruleClass] += 1
+				convertedModules[dir+":"+t.name] = true
 			} else {
 				metrics.TotalModuleCount += 1
+				soongModuleTypes[dir+":"+bpCtx.ModuleName(m)] = bpCtx.ModuleType(m)
 				return
 			}
 		case QueryView:
@@ -234,6 +249,15 @@ func GenerateBazelTargets(ctx *CodegenContext, generateFilegroups bool) (map[str
 
 		buildFileToTargets[dir] = append(buildFileToTargets[dir], t)
 	})
+
+	for moduleKey, moduleType := range soongModuleTypes {
+		metrics.ModuleTypeCount[moduleType] += 1
+		if convertedModules[moduleKey] {
+			metrics.ConvertedModuleTypeCount[moduleType] += 1
+		}
+	}
+	metrics.readiness = readiness.report()
+
 	if generateFilegroups {
 		// Add a filegroup target that exposes all sources in the subtree of this package
 		// NOTE: This also means we generate a BUILD file for every Android.bp file (as long as it has at least one module)
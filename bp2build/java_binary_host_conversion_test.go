@@ -0,0 +1,159 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"android/soong/java"
+	"strings"
+	"testing"
+)
+
+func TestJavaBinaryHostBp2Build(t *testing.T) {
+	testCases := []struct {
+		description                        string
+		moduleTypeUnderTest                string
+		moduleTypeUnderTestFactory         android.ModuleFactory
+		moduleTypeUnderTestBp2BuildMutator func(android.TopDownMutatorContext)
+		depsMutators                       []android.RegisterMutatorFunc
+		dir                                string
+		bp                                 string
+		expectedBazelTargets               []string
+		filesystem                         map[string]string
+	}{
+		{
+			description:                        "java_binary_host srcs, static_libs and main_class",
+			moduleTypeUnderTest:                "java_binary_host",
+			moduleTypeUnderTestFactory:         java.BinaryHostFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaBinaryHostBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"a.java":     "",
+				"b.java":     "",
+				"linux.java": "",
+			},
+			bp: `java_binary_host {
+    name: "foo",
+    srcs: ["a.java", "b.java"],
+    exclude_srcs: ["b.java"],
+    static_libs: ["static_dep"],
+    main_class: "com.android.foo.Main",
+    javacflags: ["-Xlint:all"],
+    target: { linux_glibc: { srcs: ["linux.java"] } },
+}
+
+java_library_host { name: "static_dep" }
+`,
+			expectedBazelTargets: []string{`java_binary(
+    name = "foo",
+    deps = [":static_dep"],
+    javacopts = ["-Xlint:all"],
+    main_class = "com.android.foo.Main",
+    srcs = ["a.java"] + select({
+        "//build/bazel/platforms/os:linux": ["linux.java"],
+        "//conditions:default": [],
+    }),
+)`},
+		},
+		{
+			description:                        "java_binary_host java_resources",
+			moduleTypeUnderTest:                "java_binary_host",
+			moduleTypeUnderTestFactory:         java.BinaryHostFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaBinaryHostBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			dir:                                "sub",
+			filesystem: map[string]string{
+				"sub/Android.bp": `java_binary_host {
+    name: "foo",
+    srcs: ["Main.java"],
+    java_resources: ["foo.properties"],
+}`,
+			},
+			expectedBazelTargets: []string{`java_binary(
+    name = "foo",
+    resource_strip_prefix = "sub",
+    resources = ["foo.properties"],
+    srcs = ["Main.java"],
+)`},
+		},
+		{
+			description:                        "java_binary is not converted",
+			moduleTypeUnderTest:                "java_binary",
+			moduleTypeUnderTestFactory:         java.BinaryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaBinaryHostBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			bp: `java_binary {
+    name: "foo",
+    srcs: ["a.java"],
+}
+`,
+			expectedBazelTargets: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		filesystem := make(map[string][]byte)
+		toParse := []string{
+			"Android.bp",
+		}
+		for f, content := range testCase.filesystem {
+			if strings.HasSuffix(f, "Android.bp") {
+				toParse = append(toParse, f)
+			}
+			filesystem[f] = []byte(content)
+		}
+		config := android.TestConfig(buildDir, nil, testCase.bp, filesystem)
+		ctx := android.NewTestContext(config)
+
+		ctx.RegisterModuleType("java_library_host", java.LibraryHostFactory)
+		ctx.RegisterModuleType(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestFactory)
+		for _, m := range testCase.depsMutators {
+			ctx.DepsBp2BuildMutators(m)
+		}
+		ctx.RegisterBp2BuildMutator(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestBp2BuildMutator)
+		ctx.RegisterBp2BuildConfig(bp2buildConfig)
+		ctx.RegisterForBazelConversion()
+
+		_, errs := ctx.ParseFileList(".", toParse)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+		_, errs = ctx.ResolveDependencies(config)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+
+		checkDir := "."
+		if testCase.dir != "" {
+			checkDir = testCase.dir
+		}
+		codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+		bazelTargets := generateBazelTargetsForDir(codegenCtx, checkDir)
+		if actualCount, expectedCount := len(bazelTargets), len(testCase.expectedBazelTargets); actualCount != expectedCount {
+			t.Errorf("%s: Expected %d bazel target, got %d", testCase.description, expectedCount, actualCount)
+		} else {
+			for i, target := range bazelTargets {
+				if w, g := testCase.expectedBazelTargets[i], target.content; w != g {
+					t.Errorf(
+						"%s: Expected generated Bazel target to be '%s', got '%s'",
+						testCase.description,
+						w,
+						g,
+					)
+				}
+			}
+		}
+	}
+}
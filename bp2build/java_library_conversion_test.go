@@ -0,0 +1,339 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"android/soong/java"
+	"strings"
+	"testing"
+)
+
+func TestJavaLibraryBp2Build(t *testing.T) {
+	testCases := []struct {
+		description                        string
+		moduleTypeUnderTest                string
+		moduleTypeUnderTestFactory         android.ModuleFactory
+		moduleTypeUnderTestBp2BuildMutator func(android.TopDownMutatorContext)
+		depsMutators                       []android.RegisterMutatorFunc
+		dir                                string
+		bp                                 string
+		expectedBazelTargets               []string
+		filesystem                         map[string]string
+	}{
+		{
+			description:                        "java_library srcs, libs, static_libs, javacflags and sdk_version",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"a.java":        "",
+				"b.java":        "",
+				"excluded.java": "",
+			},
+			bp: `java_library {
+    name: "foo",
+    srcs: ["*.java"],
+    exclude_srcs: ["excluded.java"],
+    libs: ["lib_dep"],
+    static_libs: ["static_dep"],
+    javacflags: ["-Xlint:all"],
+    sdk_version: "current",
+}
+
+java_library { name: "lib_dep" }
+
+java_library { name: "static_dep" }
+`,
+			expectedBazelTargets: []string{`java_library(
+    name = "foo",
+    deps = [
+        ":lib_dep",
+        ":static_dep",
+    ],
+    exports = [":static_dep"],
+    javacopts = ["-Xlint:all"],
+    sdk_version = "current",
+    srcs = [
+        "a.java",
+        "b.java",
+    ],
+)`, `java_library(
+    name = "lib_dep",
+)`, `java_library(
+    name = "static_dep",
+)`},
+		},
+		{
+			description:                        "java_library java_resource_dirs",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"res/a.res":     "",
+				"res/dir/b.res": "",
+			},
+			bp: `java_library {
+    name: "foo",
+    java_resource_dirs: ["res"],
+}
+`,
+			expectedBazelTargets: []string{`java_library(
+    name = "foo",
+    resource_strip_prefix = "res",
+    resources = [
+        "res/a.res",
+        "res/dir/b.res",
+    ],
+)`},
+		},
+		{
+			description:                        "java_library java_resources in a subdirectory",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			dir:                                "sub",
+			filesystem: map[string]string{
+				"sub/Android.bp": `java_library {
+    name: "foo",
+    java_resources: ["a.res", "b.res"],
+}`,
+			},
+			expectedBazelTargets: []string{`java_library(
+    name = "foo",
+    resource_strip_prefix = "sub",
+    resources = [
+        "a.res",
+        "b.res",
+    ],
+)`},
+		},
+		{
+			description:                        "java_library base, arch and os-specific srcs and static_libs",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"common.java": "",
+				"arm64.java":  "",
+			},
+			bp: `java_library {
+    name: "foo",
+    srcs: ["common.java"],
+    static_libs: ["static_dep"],
+    arch: { arm64: { srcs: ["arm64.java"], libs: ["arm64_dep"] } },
+    target: { android: { static_libs: ["android_dep"] } },
+}
+
+java_library { name: "android_dep" }
+
+java_library { name: "arm64_dep" }
+
+java_library { name: "static_dep" }
+`,
+			expectedBazelTargets: []string{`java_library(
+    name = "android_dep",
+)`, `java_library(
+    name = "arm64_dep",
+)`, `java_library(
+    name = "foo",
+    deps = [":static_dep"] + select({
+        "//build/bazel/platforms/arch:arm64": [":arm64_dep"],
+        "//conditions:default": [],
+    }) + select({
+        "//build/bazel/platforms/os:android": [":android_dep"],
+        "//conditions:default": [],
+    }),
+    exports = [":static_dep"] + select({
+        "//build/bazel/platforms/os:android": [":android_dep"],
+        "//conditions:default": [],
+    }),
+    srcs = ["common.java"] + select({
+        "//build/bazel/platforms/arch:arm64": ["arm64.java"],
+        "//conditions:default": [],
+    }),
+)`, `java_library(
+    name = "static_dep",
+)`},
+		},
+		{
+			description:                        "java_library arch-specific exclude_srcs",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"common.java":      "",
+				"for-x86.java":     "",
+				"not-for-x86.java": "",
+			},
+			bp: `java_library {
+    name: "foo",
+    srcs: ["common.java", "not-for-x86.java"],
+    arch: {
+        x86: { srcs: ["for-x86.java"], exclude_srcs: ["not-for-x86.java"] },
+    },
+}
+`,
+			expectedBazelTargets: []string{`java_library(
+    name = "foo",
+    srcs = ["common.java"] + select({
+        "//build/bazel/platforms/arch:x86": ["for-x86.java"],
+        "//conditions:default": ["not-for-x86.java"],
+    }),
+)`},
+		},
+		{
+			description:                        "java_library with srcs that generate java sources is not converted",
+			moduleTypeUnderTest:                "java_library",
+			moduleTypeUnderTestFactory:         java.LibraryFactory,
+			moduleTypeUnderTestBp2BuildMutator: java.JavaLibraryBp2Build,
+			depsMutators:                       []android.RegisterMutatorFunc{java.RegisterDepsBp2Build},
+			filesystem: map[string]string{
+				"a.java":     "",
+				"b.aidl":     "",
+				"c.logtags":  "",
+				"d.proto":    "",
+				"plain.java": "",
+			},
+			bp: `java_library {
+    name: "with_aidl",
+    srcs: ["a.java", "b.aidl"],
+}
+
+java_library {
+    name: "with_logtags",
+    srcs: ["a.java"],
+    target: {
+        android: { srcs: ["c.logtags"] },
+    },
+}
+
+java_library {
+    name: "with_proto",
+    srcs: ["a.java", "d.proto"],
+}
+
+java_library {
+    name: "plain",
+    srcs: ["plain.java"],
+}
+`,
+			expectedBazelTargets: []string{`java_library(
+    name = "plain",
+    srcs = ["plain.java"],
+)`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		filesystem := make(map[string][]byte)
+		toParse := []string{
+			"Android.bp",
+		}
+		for f, content := range testCase.filesystem {
+			if strings.HasSuffix(f, "Android.bp") {
+				toParse = append(toParse, f)
+			}
+			filesystem[f] = []byte(content)
+		}
+		config := android.TestConfig(buildDir, nil, testCase.bp, filesystem)
+		ctx := android.NewTestContext(config)
+
+		ctx.RegisterModuleType(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestFactory)
+		for _, m := range testCase.depsMutators {
+			ctx.DepsBp2BuildMutators(m)
+		}
+		ctx.RegisterBp2BuildMutator(testCase.moduleTypeUnderTest, testCase.moduleTypeUnderTestBp2BuildMutator)
+		ctx.RegisterBp2BuildConfig(bp2buildConfig)
+		ctx.RegisterForBazelConversion()
+
+		_, errs := ctx.ParseFileList(".", toParse)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+		_, errs = ctx.ResolveDependencies(config)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+
+		checkDir := "."
+		if testCase.dir != "" {
+			checkDir = testCase.dir
+		}
+		codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+		bazelTargets := generateBazelTargetsForDir(codegenCtx, checkDir)
+		if actualCount, expectedCount := len(bazelTargets), len(testCase.expectedBazelTargets); actualCount != expectedCount {
+			t.Errorf("%s: Expected %d bazel target, got %d", testCase.description, expectedCount, actualCount)
+		} else {
+			for i, target := range bazelTargets {
+				if w, g := testCase.expectedBazelTargets[i], target.content; w != g {
+					t.Errorf(
+						"%s: Expected generated Bazel target to be '%s', got '%s'",
+						testCase.description,
+						w,
+						g,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestJavaLibraryBp2BuildMetrics(t *testing.T) {
+	bp := `java_library { name: "foo" }
+
+java_library {
+    name: "bar",
+    bazel_module: { bp2build_available: false },
+}
+
+java_library_host { name: "baz" }
+`
+	config := android.TestConfig(buildDir, nil, bp, nil)
+	ctx := android.NewTestContext(config)
+
+	ctx.RegisterModuleType("java_library", java.LibraryFactory)
+	ctx.RegisterModuleType("java_library_host", java.LibraryHostFactory)
+	ctx.DepsBp2BuildMutators(java.RegisterDepsBp2Build)
+	ctx.RegisterBp2BuildMutator("java_library", java.JavaLibraryBp2Build)
+	ctx.RegisterBp2BuildConfig(bp2buildConfig)
+	ctx.RegisterForBazelConversion()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.ResolveDependencies(config)
+	android.FailIfErrored(t, errs)
+
+	codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+	_, metrics := GenerateBazelTargets(codegenCtx, false)
+
+	if g, w := metrics.ModuleTypeCount["java_library"], 2; g != w {
+		t.Errorf("expected %d java_library modules, got %d", w, g)
+	}
+	if g, w := metrics.ConvertedModuleTypeCount["java_library"], 1; g != w {
+		t.Errorf("expected %d converted java_library modules, got %d", w, g)
+	}
+	if g, w := metrics.ModuleTypeCount["java_library_host"], 1; g != w {
+		t.Errorf("expected %d java_library_host modules, got %d", w, g)
+	}
+	if _, ok := metrics.ConvertedModuleTypeCount["java_library_host"]; ok {
+		t.Errorf("expected no converted java_library_host modules")
+	}
+}
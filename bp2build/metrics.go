@@ -14,8 +14,18 @@ type CodegenMetrics struct {
 	// Counts of generated Bazel targets per Bazel rule class
 	RuleClassCount map[string]int
 
+	// Counts of Soong/Blueprint modules per module type
+	ModuleTypeCount map[string]int
+
+	// Counts of Soong/Blueprint modules per module type that were converted to
+	// Bazel, either by a bp2build converter or with a handcrafted target
+	ConvertedModuleTypeCount map[string]int
+
 	// Total number of handcrafted targets
 	handCraftedTargetCount int
+
+	// The modules that block the conversion, and why
+	readiness ReadinessReport
 }
 
 // Print the codegen metrics to stdout.
@@ -26,6 +36,12 @@ func (metrics CodegenMetrics) Print() {
 		fmt.Printf("[bp2build] %s: %d targets\n", ruleClass, count)
 		generatedTargetCount += count
 	}
+	for _, moduleType := range android.SortedStringKeys(metrics.ConvertedModuleTypeCount) {
+		converted := metrics.ConvertedModuleTypeCount[moduleType]
+		total := metrics.ModuleTypeCount[moduleType]
+		fmt.Printf("[bp2build] %s: converted %d of %d modules (%d%%)\n",
+			moduleType, converted, total, converted*100/total)
+	}
 	fmt.Printf(
 		"[bp2build] Generated %d total BUILD targets and included %d handcrafted BUILD targets from %d Android.bp modules.\n",
 		generatedTargetCount,
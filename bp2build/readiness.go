@@ -0,0 +1,346 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/google/blueprint"
+)
+
+// Conversion readiness reporting for bp2build, to plan the migration work.
+
+// ReadinessReport lists the modules that block the conversion of the tree to Bazel, with the reasons
+// they are blocked and how many modules depend on them.
+type ReadinessReport struct {
+	// The modules that were not converted or depend on a module that was not converted, sorted by
+	// the number of modules they unblock.
+	Modules []ModuleReadiness `json:"modules"`
+
+	// A summary per directory, sorted by directory.
+	Directories []DirectoryReadiness `json:"directories"`
+}
+
+// ModuleReadiness is the conversion status of a single module.
+type ModuleReadiness struct {
+	Label     string           `json:"label"`
+	Type      string           `json:"type"`
+	Converted bool             `json:"converted"`
+	Reasons   []BlockingReason `json:"reasons"`
+
+	// The number of modules that transitively depend on this module. A module that isn't converted
+	// blocks all of them from being built with Bazel.
+	Unblocks int `json:"unblocks"`
+}
+
+// BlockingReason is a reason that a module is blocked from being built with Bazel.
+type BlockingReason struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DirectoryReadiness counts the modules in a directory.
+type DirectoryReadiness struct {
+	Dir       string `json:"dir"`
+	Modules   int    `json:"modules"`
+	Converted int    `json:"converted"`
+	Blocked   int    `json:"blocked"`
+}
+
+// readinessModule is the information about a Soong module needed to build the readiness report.
+type readinessModule struct {
+	label      string
+	dir        string
+	moduleType string
+	converted  bool
+	reason     *android.UnconvertedReason
+	deps       []string
+}
+
+// readinessCollector collects the modules and their dependencies while the Bazel targets are
+// generated.
+type readinessCollector struct {
+	modules map[string]*readinessModule
+}
+
+func newReadinessCollector() *readinessCollector {
+	return &readinessCollector{modules: make(map[string]*readinessModule)}
+}
+
+func readinessLabel(dir, name string) string {
+	if dir == "." {
+		dir = ""
+	}
+	return fmt.Sprintf("//%s:%s", dir, name)
+}
+
+// skipForReadiness returns whether a module is left out of the readiness report, because it is
+// never converted on its own.
+func skipForReadiness(m blueprint.Module) bool {
+	if _, ok := m.(android.Defaults); ok {
+		return true
+	}
+	if _, ok := m.(android.BazelTargetModule); ok {
+		return true
+	}
+	return false
+}
+
+// addModule records a Soong module and its direct dependencies.
+func (c *readinessCollector) addModule(ctx bpToBuildContext, m blueprint.Module) {
+	if skipForReadiness(m) {
+		return
+	}
+	dir := ctx.ModuleDir(m)
+	label := readinessLabel(dir, ctx.ModuleName(m))
+	if _, exists := c.modules[label]; exists {
+		return
+	}
+
+	module := &readinessModule{
+		label:      label,
+		dir:        dir,
+		moduleType: ctx.ModuleType(m),
+	}
+	if b, ok := m.(android.Bazelable); ok {
+		module.converted = b.Bp2buildConverted() || b.HasHandcraftedLabel()
+		module.reason = b.Bp2buildUnconvertedReason()
+	}
+	if !module.converted && module.reason == nil {
+		module.reason = &android.UnconvertedReason{
+			ReasonType: android.UnconvertedReasonUnsupportedModuleType,
+			Detail:     module.moduleType,
+		}
+	}
+
+	ctx.VisitDirectDeps(m, func(dep blueprint.Module) {
+		if skipForReadiness(dep) {
+			return
+		}
+		if depLabel := readinessLabel(ctx.ModuleDir(dep), ctx.ModuleName(dep)); depLabel != label {
+			module.deps = append(module.deps, depLabel)
+		}
+	})
+	module.deps = android.SortedUniqueStrings(module.deps)
+
+	c.modules[label] = module
+}
+
+// report builds the readiness report from the collected modules.
+func (c *readinessCollector) report() ReadinessReport {
+	labels := android.SortedStringKeys(c.modules)
+
+	reverseDeps := make(map[string][]string)
+	for _, label := range labels {
+		for _, dep := range c.modules[label].deps {
+			reverseDeps[dep] = append(reverseDeps[dep], label)
+		}
+	}
+
+	unblocks := transitiveDependentCounts(labels, reverseDeps)
+
+	dirs := make(map[string]*DirectoryReadiness)
+	report := ReadinessReport{}
+	for _, label := range labels {
+		module := c.modules[label]
+
+		dir, ok := dirs[module.dir]
+		if !ok {
+			dir = &DirectoryReadiness{Dir: module.dir}
+			dirs[module.dir] = dir
+		}
+		dir.Modules++
+		if module.converted {
+			dir.Converted++
+		}
+
+		var reasons []BlockingReason
+		if !module.converted {
+			reasons = append(reasons, BlockingReason{
+				Type:   module.reason.ReasonType.String(),
+				Detail: module.reason.Detail,
+			})
+		}
+		for _, dep := range module.deps {
+			if d, ok := c.modules[dep]; ok && !d.converted {
+				reasons = append(reasons, BlockingReason{
+					Type:   android.UnconvertedReasonUnconvertedDep.String(),
+					Detail: dep,
+				})
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		dir.Blocked++
+
+		report.Modules = append(report.Modules, ModuleReadiness{
+			Label:     label,
+			Type:      module.moduleType,
+			Converted: module.converted,
+			Reasons:   reasons,
+			Unblocks:  unblocks[label],
+		})
+	}
+
+	sort.SliceStable(report.Modules, func(i, j int) bool {
+		return report.Modules[i].Unblocks > report.Modules[j].Unblocks
+	})
+
+	for _, dir := range android.SortedStringKeys(dirs) {
+		report.Directories = append(report.Directories, *dirs[dir])
+	}
+
+	return report
+}
+
+// transitiveDependentCounts returns the number of modules that directly or transitively depend on
+// each of the given modules.  Variants of a module share a label, so the labels may depend on each
+// other in cycles.  The dependents are computed once per strongly connected component of the
+// reverse dependency graph with Tarjan's algorithm, which finishes a component only after the
+// components of all of its dependents, so the dependents of a component are the union of the
+// already computed dependents of its direct dependents.
+func transitiveDependentCounts(labels []string, reverseDeps map[string][]string) map[string]int {
+	index := make(map[string]int, len(labels))
+	for i, label := range labels {
+		index[label] = i
+	}
+
+	counts := make(map[string]int, len(labels))
+
+	// visitOrder is 1 + the order in which each module was first visited, or 0 if it hasn't been
+	// visited yet.
+	visitOrder := make([]int, len(labels))
+	lowLink := make([]int, len(labels))
+	onStack := make([]bool, len(labels))
+	component := make([]int, len(labels))
+	var stack []int
+	visited := 0
+
+	// closures contains, for each finished component, the sorted indexes of its modules and of
+	// all the modules that transitively depend on them.
+	var closures [][]int
+
+	var visit func(v int)
+	visit = func(v int) {
+		visited++
+		visitOrder[v], lowLink[v] = visited, visited
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, dependent := range reverseDeps[labels[v]] {
+			w := index[dependent]
+			if visitOrder[w] == 0 {
+				visit(w)
+				if lowLink[w] < lowLink[v] {
+					lowLink[v] = lowLink[w]
+				}
+			} else if onStack[w] && visitOrder[w] < lowLink[v] {
+				lowLink[v] = visitOrder[w]
+			}
+		}
+
+		if lowLink[v] != visitOrder[v] {
+			return
+		}
+
+		// v is the root of a component, all the modules above it on the stack are in it.
+		current := len(closures)
+		var members []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component[w] = current
+			members = append(members, w)
+			if w == v {
+				break
+			}
+		}
+
+		var dependents []int
+		for _, m := range members {
+			for _, dependent := range reverseDeps[labels[m]] {
+				if c := component[index[dependent]]; c != current {
+					dependents = mergeSortedInts(dependents, closures[c])
+				}
+			}
+		}
+
+		sort.Ints(members)
+		for _, m := range members {
+			counts[labels[m]] = len(dependents) + len(members) - 1
+		}
+		closures = append(closures, mergeSortedInts(dependents, members))
+	}
+
+	for v := range labels {
+		if visitOrder[v] == 0 {
+			visit(v)
+		}
+	}
+
+	return counts
+}
+
+// mergeSortedInts returns the sorted union of two sorted lists of unique ints.  It never modifies
+// its arguments, but may return one of them.
+func mergeSortedInts(a, b []int) []int {
+	if len(a) == 0 {
+		return b
+	} else if len(b) == 0 {
+		return a
+	}
+	ret := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			ret = append(ret, a[i])
+			i++
+		case a[i] > b[j]:
+			ret = append(ret, b[j])
+			j++
+		default:
+			ret = append(ret, a[i])
+			i++
+			j++
+		}
+	}
+	ret = append(ret, a[i:]...)
+	return append(ret, b[j:]...)
+}
+
+// JSON returns the readiness report as indented JSON.
+func (r ReadinessReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// DirectoryTable returns the per-directory summary of the readiness report as a text table.
+func (r ReadinessReport) DirectoryTable() string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "modules\tconverted\tblocked\t  directory")
+	for _, dir := range r.Directories {
+		fmt.Fprintf(w, "%d\t%d\t%d\t  %s\n", dir.Modules, dir.Converted, dir.Blocked, dir.Dir)
+	}
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"android/soong/java"
+	"reflect"
+	"testing"
+)
+
+func TestReadinessReport(t *testing.T) {
+	bp := `java_library {
+    name: "foo",
+    libs: ["bar"],
+}
+
+java_library {
+    name: "bar",
+    bazel_module: { bp2build_available: false },
+}
+
+java_library {
+    name: "qux",
+    static_libs: ["foo"],
+}
+
+java_library {
+    name: "baz",
+    srcs: ["a.aidl"],
+    libs: ["host"],
+}
+
+java_library_host { name: "host" }
+`
+	report := runReadinessReport(t, bp, map[string][]byte{"a.aidl": nil})
+
+	expectedModules := []ModuleReadiness{
+		{
+			Label:   "//:bar",
+			Type:    "java_library",
+			Reasons: []BlockingReason{{"BP2BUILD_AVAILABLE_FALSE", "bazel_module.bp2build_available is false"}},
+			// foo and qux, through foo
+			Unblocks: 2,
+		},
+		{
+			Label:     "//:foo",
+			Type:      "java_library",
+			Converted: true,
+			Reasons:   []BlockingReason{{"UNCONVERTED_DEP", "//:bar"}},
+			Unblocks:  1,
+		},
+		{
+			Label:    "//:host",
+			Type:     "java_library_host",
+			Reasons:  []BlockingReason{{"UNSUPPORTED_MODULE_TYPE", "java_library_host"}},
+			Unblocks: 1,
+		},
+		{
+			Label: "//:baz",
+			Type:  "java_library",
+			Reasons: []BlockingReason{
+				{"UNSUPPORTED_PROPERTY", "srcs: generated java sources from a.aidl"},
+				{"UNCONVERTED_DEP", "//:host"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(report.Modules, expectedModules) {
+		t.Errorf("expected modules:\n%#v\ngot:\n%#v", expectedModules, report.Modules)
+	}
+
+	expectedDirectories := []DirectoryReadiness{{Dir: ".", Modules: 5, Converted: 2, Blocked: 4}}
+	if !reflect.DeepEqual(report.Directories, expectedDirectories) {
+		t.Errorf("expected directories %#v, got %#v", expectedDirectories, report.Directories)
+	}
+
+	expectedTable := "  modules  converted  blocked  directory\n" +
+		"        5          2        4  .\n"
+	if g, w := report.DirectoryTable(), expectedTable; g != w {
+		t.Errorf("expected table:\n%s\ngot:\n%s", w, g)
+	}
+}
+
+func TestReadinessReportDiamond(t *testing.T) {
+	bp := `java_library {
+    name: "top",
+    libs: ["left", "right"],
+}
+
+java_library {
+    name: "left",
+    libs: ["bottom"],
+}
+
+java_library {
+    name: "right",
+    libs: ["bottom"],
+}
+
+java_library {
+    name: "bottom",
+    bazel_module: { bp2build_available: false },
+}
+`
+	report := runReadinessReport(t, bp, nil)
+
+	expectedModules := []ModuleReadiness{
+		{
+			Label:   "//:bottom",
+			Type:    "java_library",
+			Reasons: []BlockingReason{{"BP2BUILD_AVAILABLE_FALSE", "bazel_module.bp2build_available is false"}},
+			// left, right and top, which depends on bottom through both of them
+			Unblocks: 3,
+		},
+		{
+			Label:     "//:left",
+			Type:      "java_library",
+			Converted: true,
+			Reasons:   []BlockingReason{{"UNCONVERTED_DEP", "//:bottom"}},
+			Unblocks:  1,
+		},
+		{
+			Label:     "//:right",
+			Type:      "java_library",
+			Converted: true,
+			Reasons:   []BlockingReason{{"UNCONVERTED_DEP", "//:bottom"}},
+			Unblocks:  1,
+		},
+	}
+	if !reflect.DeepEqual(report.Modules, expectedModules) {
+		t.Errorf("expected modules:\n%#v\ngot:\n%#v", expectedModules, report.Modules)
+	}
+}
+
+func TestTransitiveDependentCounts(t *testing.T) {
+	labels := []string{"a", "b", "c", "d", "e"}
+	reverseDeps := map[string][]string{
+		// b depends on a, b and c depend on each other and d depends on c.
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"b", "d"},
+	}
+
+	expected := map[string]int{"a": 3, "b": 2, "c": 2, "d": 0, "e": 0}
+	if g, w := transitiveDependentCounts(labels, reverseDeps), expected; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected counts %v, got %v", w, g)
+	}
+}
+
+func runReadinessReport(t *testing.T, bp string, fs map[string][]byte) ReadinessReport {
+	t.Helper()
+	config := android.TestConfig(buildDir, nil, bp, fs)
+	ctx := android.NewTestContext(config)
+
+	ctx.RegisterModuleType("java_library", java.LibraryFactory)
+	ctx.RegisterModuleType("java_library_host", java.LibraryHostFactory)
+	ctx.DepsBp2BuildMutators(java.RegisterDepsBp2Build)
+	ctx.RegisterBp2BuildMutator("java_library", java.JavaLibraryBp2Build)
+	ctx.RegisterBp2BuildConfig(bp2buildConfig)
+	ctx.RegisterForBazelConversion()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.ResolveDependencies(config)
+	android.FailIfErrored(t, errs)
+
+	codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+	_, metrics := GenerateBazelTargets(codegenCtx, false)
+	return metrics.readiness
+
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"android/soong/android"
+	"android/soong/rust"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The expected BUILD file contents of the rust tests are golden files in testdata/rust.
+func TestRustBp2Build(t *testing.T) {
+	testCases := []struct {
+		description string
+		bp          string
+		filesystem  map[string]string
+		golden      string
+	}{
+		{
+			description: "rust_library and rust_proc_macro crate_name, srcs, rustlibs, proc_macros, features and flags",
+			filesystem: map[string]string{
+				"src/lib.rs":     "",
+				"src/foo/mod.rs": "",
+				"bar/lib.rs":     "",
+				"macro/lib.rs":   "",
+			},
+			bp: `rust_library {
+    name: "libfoo",
+    crate_name: "foo",
+    srcs: ["src/lib.rs"],
+    rustlibs: ["libbar"],
+    proc_macros: ["libmacro"],
+    features: ["std"],
+    cfgs: ["android_test"],
+    flags: ["-Copt-level=3"],
+}
+
+rust_library {
+    name: "libbar",
+    crate_name: "bar",
+    srcs: ["bar/lib.rs"],
+}
+
+rust_proc_macro {
+    name: "libmacro",
+    crate_name: "macro",
+    srcs: ["macro/lib.rs"],
+}
+`,
+			golden: "library.BUILD",
+		},
+		{
+			description: "rust_binary edition and arch and os-specific features and rustlibs",
+			filesystem: map[string]string{
+				"bin/main.rs":       "",
+				"bin/util.rs":       "",
+				"libs/arm64/lib.rs": "",
+				"libs/log/lib.rs":   "",
+			},
+			bp: `rust_binary {
+    name: "foo_bin",
+    srcs: ["bin/main.rs"],
+    edition: "2021",
+    arch: { arm64: { features: ["neon"], rustlibs: ["libarm64"] } },
+    target: { android: { rustlibs: ["liblog_rust"] } },
+}
+
+rust_library {
+    name: "libarm64",
+    crate_name: "arm64",
+    srcs: ["libs/arm64/lib.rs"],
+}
+
+rust_library {
+    name: "liblog_rust",
+    crate_name: "log",
+    srcs: ["libs/log/lib.rs"],
+}
+`,
+			golden: "binary_arch.BUILD",
+		},
+		{
+			description: "rust_library_host os-specific flags",
+			filesystem: map[string]string{
+				"host/lib.rs": "",
+			},
+			bp: `rust_library_host {
+    name: "libhost",
+    crate_name: "host",
+    srcs: ["host/lib.rs"],
+    target: { linux_glibc: { flags: ["-Clink-arg=-lrt"] } },
+}
+`,
+			golden: "library_host.BUILD",
+		},
+		{
+			description: "rust crates in one directory tree",
+			filesystem: map[string]string{
+				"src/lib.rs":               "",
+				"src/util.rs":              "",
+				"src/tool.rs":              "",
+				"src/bin/main.rs":          "",
+				"src/bin/cli.rs":           "",
+				"src/sub/Android.bp":       "",
+				"src/sub/lib.rs":           "",
+				"src/tests/integration.rs": "",
+			},
+			bp: `rust_library {
+    name: "libfoo",
+    crate_name: "foo",
+    srcs: ["src/lib.rs"],
+}
+
+rust_binary {
+    name: "foo_bin",
+    srcs: ["src/bin/main.rs"],
+}
+
+rust_binary {
+    name: "foo_tool",
+    srcs: ["src/tool.rs"],
+}
+
+rust_binary {
+    name: "foo_integration",
+    srcs: ["src/tests/integration.rs"],
+    bazel_module: { bp2build_available: false },
+}
+`,
+			golden: "same_dir.BUILD",
+		},
+	}
+
+	for _, testCase := range testCases {
+		filesystem := make(map[string][]byte)
+		for f, content := range testCase.filesystem {
+			filesystem[f] = []byte(content)
+		}
+		config := android.TestConfig(buildDir, nil, testCase.bp, filesystem)
+		ctx := android.NewTestContext(config)
+
+		ctx.RegisterModuleType("rust_library", rust.RustLibraryFactory)
+		ctx.RegisterModuleType("rust_library_host", rust.RustLibraryHostFactory)
+		ctx.RegisterModuleType("rust_binary", rust.RustBinaryFactory)
+		ctx.RegisterModuleType("rust_proc_macro", rust.ProcMacroFactory)
+		ctx.DepsBp2BuildMutators(rust.RegisterDepsBp2Build)
+		ctx.RegisterBp2BuildMutator("rust_library", rust.RustLibraryBp2Build)
+		ctx.RegisterBp2BuildMutator("rust_library_host", rust.RustLibraryHostBp2Build)
+		ctx.RegisterBp2BuildMutator("rust_binary", rust.RustBinaryBp2Build)
+		ctx.RegisterBp2BuildMutator("rust_proc_macro", rust.RustProcMacroBp2Build)
+		ctx.RegisterBp2BuildConfig(bp2buildConfig)
+		ctx.RegisterForBazelConversion()
+
+		_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+		_, errs = ctx.ResolveDependencies(config)
+		if Errored(t, testCase.description, errs) {
+			continue
+		}
+
+		codegenCtx := NewCodegenContext(config, *ctx.Context, Bp2Build)
+		bazelTargets := generateBazelTargetsForDir(codegenCtx, ".")
+		var contents []string
+		for _, target := range bazelTargets {
+			contents = append(contents, target.content)
+		}
+
+		golden, err := ioutil.ReadFile(filepath.Join("testdata", "rust", testCase.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, g := string(golden), strings.Join(contents, "\n\n")+"\n"; w != g {
+			t.Errorf("%s: Expected generated Bazel targets to match %s:\n%s\ngot:\n%s",
+				testCase.description, testCase.golden, w, g)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package bp2build
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
 
+	"android/soong/android"
 	"android/soong/shared"
 )
 
@@ -81,26 +86,216 @@ func readdirToMap(dir string) map[string]os.FileInfo {
 	return result
 }
 
-// Creates a symbolic link at dst pointing to src
-func symlinkIntoForest(topdir, dst, src string) {
-	err := os.Symlink(shared.JoinPath(topdir, src), shared.JoinPath(topdir, dst))
+// The manifest of a symlink forest is written next to the forest, so that it isn't part of the
+// Bazel workspace.
+const symlinkForestManifestSuffix = ".manifest"
+
+// A symlinkForest describes the contents of a symlink forest: the directories in it, and the
+// symlinks with their targets. Paths are relative to the root of the forest.
+type symlinkForest struct {
+	Dirs     map[string]bool
+	Symlinks map[string]string
+
+	// The arguments the forest was planted with. The next run only reuses the contents of the
+	// directories below if it has the same arguments.
+	Args []string
+
+	// The directories of the source and build file trees that were read to plant each directory of
+	// the forest. If they haven't been modified since, the next run reuses the contents of the
+	// forest directory instead of reading them again.
+	Sources map[string]forestDirSources
+}
+
+// forestDirSources are the directories of the source and build file trees that a directory of the
+// symlink forest is merged from, with their modification times.
+type forestDirSources struct {
+	SrcDir          string
+	SrcMtime        int64
+	BuildFilesDir   string
+	BuildFilesMtime int64
+}
+
+// Modification times of directories that don't exist, or that may be modified again without
+// changing their modification time.
+const (
+	mtimeMissing = 0
+	mtimeRacy    = -1
+)
+
+func newSymlinkForest() *symlinkForest {
+	return &symlinkForest{
+		Dirs:     make(map[string]bool),
+		Symlinks: make(map[string]string),
+		Sources:  make(map[string]forestDirSources),
+	}
+}
+
+// previousForest is the symlink forest planted by the previous run, indexed by directory.
+type previousForest struct {
+	*symlinkForest
+	childDirs     map[string][]string
+	childSymlinks map[string][]string
+}
+
+func newPreviousForest(forest *symlinkForest) *previousForest {
+	previous := &previousForest{
+		symlinkForest: forest,
+		childDirs:     make(map[string][]string),
+		childSymlinks: make(map[string][]string),
+	}
+	for dir := range forest.Dirs {
+		if dir != "." {
+			parent := filepath.Dir(dir)
+			previous.childDirs[parent] = append(previous.childDirs[parent], dir)
+		}
+	}
+	for p := range forest.Symlinks {
+		parent := filepath.Dir(p)
+		previous.childSymlinks[parent] = append(previous.childSymlinks[parent], p)
+	}
+	return previous
+}
+
+// dirMtime returns the modification time of a directory in nanoseconds. Directories that were
+// modified since the start of the second in which the forest started to be planted are racy, as
+// they may be modified again after they are read without their modification time changing on
+// file systems with coarse timestamps.
+func dirMtime(dir string, start time.Time) int64 {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return mtimeMissing
+	}
+	if !fi.ModTime().Before(start.Truncate(time.Second)) {
+		return mtimeRacy
+	}
+	return fi.ModTime().UnixNano()
+}
+
+// Records a symbolic link at dst pointing to src
+func (forest *symlinkForest) addSymlink(topdir, dst, src string) {
+	forest.Symlinks[dst] = shared.JoinPath(topdir, src)
+}
+
+func symlinkForestManifestPath(topdir, forest string) string {
+	return shared.JoinPath(topdir, forest) + symlinkForestManifestSuffix
+}
+
+// Reads the manifest of the symlink forest planted by the previous run. Returns nil if there is no
+// usable manifest, in which case the forest is planted from scratch.
+func readSymlinkForestManifest(topdir, forest string) *symlinkForest {
+	data, err := ioutil.ReadFile(symlinkForestManifestPath(topdir, forest))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Cannot create symlink at '%s' pointing to '%s': %s", dst, src, err)
+		return nil
+	}
+	manifest := newSymlinkForest()
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil
+	}
+	if fi, err := os.Stat(shared.JoinPath(topdir, forest)); err != nil || !fi.IsDir() {
+		return nil
+	}
+	return manifest
+}
+
+func writeSymlinkForestManifest(topdir, forest string, manifest *symlinkForest) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(symlinkForestManifestPath(topdir, forest), data, 0666)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write symlink forest manifest for '%s': %s\n", forest, err)
 		os.Exit(1)
 	}
 }
 
-// Recursively plants a symlink forest at forestDir. The symlink tree will
+// Creates a symbolic link at dst pointing to target, replacing anything that was left at dst.
+func symlinkIntoForest(dst, target string) {
+	err := os.Symlink(target, dst)
+	if os.IsExist(err) {
+		os.RemoveAll(dst)
+		err = os.Symlink(target, dst)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot create symlink at '%s' pointing to '%s': %s", dst, target, err)
+		os.Exit(1)
+	}
+}
+
+// Updates the symlink forest on disk from its previous contents to its new contents. Only the
+// symlinks and directories that were added, removed or retargeted are touched.
+func updateSymlinkForest(root string, previous, forest *symlinkForest) {
+	// Remove symlinks that are gone or point elsewhere, then directories that are gone, which also
+	// removes everything below them.
+	for _, p := range android.SortedStringKeys(previous.Symlinks) {
+		if forest.Symlinks[p] != previous.Symlinks[p] {
+			if err := os.Remove(shared.JoinPath(root, p)); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Cannot remove symlink '%s': %s\n", p, err)
+				os.Exit(1)
+			}
+		}
+	}
+	for _, dir := range android.SortedStringKeys(previous.Dirs) {
+		if !forest.Dirs[dir] {
+			os.RemoveAll(shared.JoinPath(root, dir))
+		}
+	}
+
+	// Parent directories sort before their children.
+	for _, dir := range android.SortedStringKeys(forest.Dirs) {
+		if !previous.Dirs[dir] {
+			if err := os.MkdirAll(shared.JoinPath(root, dir), 0777); err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot mkdir '%s': %s\n", dir, err)
+				os.Exit(1)
+			}
+		}
+	}
+	for _, p := range android.SortedStringKeys(forest.Symlinks) {
+		if target := forest.Symlinks[p]; target != previous.Symlinks[p] {
+			symlinkIntoForest(shared.JoinPath(root, p), target)
+		}
+	}
+}
+
+// Recursively computes the symlink forest at forestDir. The symlink tree will
 // contain every file in buildFilesDir and srcDir excluding the files in
 // exclude. Collects every directory encountered during the traversal of srcDir
-// into acc.
-func plantSymlinkForestRecursive(topdir string, forestDir string, buildFilesDir string, srcDir string, exclude *node, acc *[]string, okay *bool) {
+// into acc. If neither buildFilesDir nor srcDir were modified since the previous
+// forest was planted, the contents of forestDir are copied from it instead of
+// reading them.
+func plantSymlinkForestRecursive(topdir string, forest *symlinkForest, previous *previousForest, start time.Time, forestDir string, buildFilesDir string, srcDir string, exclude *node, acc *[]string, okay *bool) {
 	if exclude != nil && exclude.excluded {
 		// This directory is not needed, bail out
 		return
 	}
 
 	*acc = append(*acc, srcDir)
+	sources := forestDirSources{
+		SrcDir:          srcDir,
+		SrcMtime:        dirMtime(shared.JoinPath(topdir, srcDir), start),
+		BuildFilesDir:   buildFilesDir,
+		BuildFilesMtime: dirMtime(shared.JoinPath(topdir, buildFilesDir), start),
+	}
+	forest.Sources[forestDir] = sources
+
+	if previous != nil && sources.SrcMtime != mtimeRacy && sources.BuildFilesMtime != mtimeRacy {
+		if prevSources, ok := previous.Sources[forestDir]; ok && prevSources == sources {
+			forest.Dirs[forestDir] = true
+			for _, p := range previous.childSymlinks[forestDir] {
+				forest.Symlinks[p] = previous.Symlinks[p]
+			}
+			for _, dir := range previous.childDirs[forestDir] {
+				f := filepath.Base(dir)
+				var excludeChild *node
+				if exclude != nil {
+					excludeChild = exclude.children[f]
+				}
+				plantSymlinkForestRecursive(topdir, forest, previous, start, dir,
+					shared.JoinPath(buildFilesDir, f), shared.JoinPath(srcDir, f), excludeChild, acc, okay)
+			}
+			return
+		}
+	}
+
 	srcDirMap := readdirToMap(shared.JoinPath(topdir, srcDir))
 	buildFilesMap := readdirToMap(shared.JoinPath(topdir, buildFilesDir))
 
@@ -113,11 +308,7 @@ func plantSymlinkForestRecursive(topdir string, forestDir string, buildFilesDir
 		allEntries[n] = true
 	}
 
-	err := os.MkdirAll(shared.JoinPath(topdir, forestDir), 0777)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Cannot mkdir '%s': %s\n", forestDir, err)
-		os.Exit(1)
-	}
+	forest.Dirs[forestDir] = true
 
 	for f, _ := range allEntries {
 		if f[0] == '.' {
@@ -149,29 +340,29 @@ func plantSymlinkForestRecursive(topdir string, forestDir string, buildFilesDir
 			if buildFilesChildEntry.IsDir() && excludeChild != nil {
 				// Not in the source tree, but we have to exclude something from under
 				// this subtree, so descend
-				plantSymlinkForestRecursive(topdir, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
+				plantSymlinkForestRecursive(topdir, forest, previous, start, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
 			} else {
 				// Not in the source tree, symlink BUILD file
-				symlinkIntoForest(topdir, forestChild, buildFilesChild)
+				forest.addSymlink(topdir, forestChild, buildFilesChild)
 			}
 		} else if !bExists {
 			if srcChildEntry.IsDir() && excludeChild != nil {
 				// Not in the build file tree, but we have to exclude something from
 				// under this subtree, so descend
-				plantSymlinkForestRecursive(topdir, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
+				plantSymlinkForestRecursive(topdir, forest, previous, start, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
 			} else {
 				// Not in the build file tree, symlink source tree, carry on
-				symlinkIntoForest(topdir, forestChild, srcChild)
+				forest.addSymlink(topdir, forestChild, srcChild)
 			}
 		} else if srcChildEntry.IsDir() && buildFilesChildEntry.IsDir() {
 			// Both are directories. Descend.
-			plantSymlinkForestRecursive(topdir, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
+			plantSymlinkForestRecursive(topdir, forest, previous, start, forestChild, buildFilesChild, srcChild, excludeChild, acc, okay)
 		} else if !srcChildEntry.IsDir() && !buildFilesChildEntry.IsDir() {
 			// Neither is a directory. Prioritize BUILD files generated by bp2build
 			// over any BUILD file imported into external/.
 			fmt.Fprintf(os.Stderr, "Both '%s' and '%s' exist, symlinking the former to '%s'\n",
 				buildFilesChild, srcChild, forestChild)
-			symlinkIntoForest(topdir, forestChild, buildFilesChild)
+			forest.addSymlink(topdir, forestChild, buildFilesChild)
 		} else {
 			// Both exist and one is a file. This is an error.
 			fmt.Fprintf(os.Stderr,
@@ -186,14 +377,104 @@ func plantSymlinkForestRecursive(topdir string, forestDir string, buildFilesDir
 // "srcDir" while excluding paths listed in "exclude". Returns the set of paths
 // under srcDir on which readdir() had to be called to produce the symlink
 // forest.
+//
+// The symlinks and directories of the forest are recorded in a manifest next to
+// it, with the modification times of the directories that were read. If the
+// manifest of the previous run is there, the directories that weren't modified
+// since aren't read again, and only the parts of the forest that changed are
+// updated. Otherwise the forest is planted from scratch.
 func PlantSymlinkForest(topdir string, forest string, buildFiles string, srcDir string, exclude []string) []string {
+	start := time.Now()
+	args := append([]string{buildFiles, srcDir}, exclude...)
+
+	previous := readSymlinkForestManifest(topdir, forest)
+	var reusable *previousForest
+	if previous != nil && reflect.DeepEqual(previous.Args, args) {
+		reusable = newPreviousForest(previous)
+	}
+
 	deps := make([]string, 0)
-	os.RemoveAll(shared.JoinPath(topdir, forest))
 	excludeTree := treeFromExcludePathList(exclude)
 	okay := true
-	plantSymlinkForestRecursive(topdir, forest, buildFiles, srcDir, excludeTree, &deps, &okay)
+	newForest := newSymlinkForest()
+	newForest.Args = args
+	plantSymlinkForestRecursive(topdir, newForest, reusable, start, ".", buildFiles, srcDir, excludeTree, &deps, &okay)
 	if !okay {
 		os.Exit(1)
 	}
+
+	if previous == nil {
+		os.RemoveAll(shared.JoinPath(topdir, forest))
+		previous = newSymlinkForest()
+	}
+
+	// Remove the manifest while the forest is being updated, so that it is planted from scratch
+	// next time if this run is interrupted.
+	os.Remove(symlinkForestManifestPath(topdir, forest))
+	updateSymlinkForest(shared.JoinPath(topdir, forest), previous, newForest)
+	writeSymlinkForestManifest(topdir, forest, newForest)
+
 	return deps
 }
+
+// VerifySymlinkForest checks the symlink forest at "forest" against the manifest
+// that was written when it was planted, and returns a description of every
+// difference between them.
+func VerifySymlinkForest(topdir string, forest string) []string {
+	manifest := readSymlinkForestManifest(topdir, forest)
+	if manifest == nil {
+		return []string{fmt.Sprintf("no manifest for the symlink forest at '%s'", forest)}
+	}
+
+	var problems []string
+	root := shared.JoinPath(topdir, forest)
+	seen := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		seen[rel] = true
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if expected, ok := manifest.Symlinks[rel]; !ok {
+				problems = append(problems, fmt.Sprintf("'%s': unexpected symlink to '%s'", rel, target))
+			} else if target != expected {
+				problems = append(problems, fmt.Sprintf("'%s': points to '%s' instead of '%s'", rel, target, expected))
+			}
+		case info.IsDir():
+			if !manifest.Dirs[rel] {
+				problems = append(problems, fmt.Sprintf("'%s': unexpected directory", rel))
+				return filepath.SkipDir
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("'%s': unexpected file", rel))
+		}
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("cannot walk the symlink forest at '%s': %s", forest, err))
+	}
+
+	for dir := range manifest.Dirs {
+		if !seen[dir] {
+			problems = append(problems, fmt.Sprintf("'%s': missing directory", dir))
+		}
+	}
+	for p := range manifest.Symlinks {
+		if !seen[p] {
+			problems = append(problems, fmt.Sprintf("'%s': missing symlink", p))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bp2build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPlantSymlinkForestIncrementally(t *testing.T) {
+	topdir := t.TempDir()
+	writeFile := func(path string) {
+		t.Helper()
+		path = filepath.Join(topdir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readlink := func(path string) string {
+		t.Helper()
+		target, err := os.Readlink(filepath.Join(topdir, "forest", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return target
+	}
+	lstat := func(path string) os.FileInfo {
+		t.Helper()
+		fi, err := os.Lstat(filepath.Join(topdir, "forest", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi
+	}
+	verify := func() {
+		t.Helper()
+		if problems := VerifySymlinkForest(topdir, "forest"); len(problems) > 0 {
+			t.Errorf("expected a consistent symlink forest, got %q", problems)
+		}
+	}
+
+	writeFile("src/a/a.txt")
+	writeFile("src/b/Android.bp")
+	writeFile("src/b/sub/x.txt")
+	writeFile("src/out/ignored.txt")
+	writeFile("build/b/BUILD")
+	writeFile("build/c/BUILD")
+
+	PlantSymlinkForest(topdir, "forest", "build", "src", []string{"out"})
+	verify()
+
+	if g, w := readlink("a"), filepath.Join(topdir, "src/a"); g != w {
+		t.Errorf("expected a to point to %q, got %q", w, g)
+	}
+	if g, w := readlink("b/BUILD"), filepath.Join(topdir, "build/b/BUILD"); g != w {
+		t.Errorf("expected b/BUILD to point to %q, got %q", w, g)
+	}
+	if _, err := os.Lstat(filepath.Join(topdir, "forest/out")); !os.IsNotExist(err) {
+		t.Errorf("expected excluded out to not be in the forest")
+	}
+	unchanged := lstat("b/sub")
+
+	// A BUILD file is generated in a directory that was a symlink, and one is no longer generated.
+	writeFile("build/a/BUILD")
+	if err := os.RemoveAll(filepath.Join(topdir, "build/c")); err != nil {
+		t.Fatal(err)
+	}
+
+	PlantSymlinkForest(topdir, "forest", "build", "src", []string{"out"})
+	verify()
+
+	if !lstat("a").IsDir() {
+		t.Errorf("expected a to be a directory")
+	}
+	if g, w := readlink("a/a.txt"), filepath.Join(topdir, "src/a/a.txt"); g != w {
+		t.Errorf("expected a/a.txt to point to %q, got %q", w, g)
+	}
+	if _, err := os.Lstat(filepath.Join(topdir, "forest/c")); !os.IsNotExist(err) {
+		t.Errorf("expected c to be removed from the forest")
+	}
+	if !os.SameFile(unchanged, lstat("b/sub")) {
+		t.Errorf("expected the unchanged b/sub symlink to be kept")
+	}
+
+	// The forest is modified behind the back of bp2build.
+	if err := os.Remove(filepath.Join(topdir, "forest/a/BUILD")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile("forest/stray.txt")
+
+	want := []string{
+		"'a/BUILD': missing symlink",
+		"'stray.txt': unexpected file",
+	}
+	if g := VerifySymlinkForest(topdir, "forest"); !reflect.DeepEqual(g, want) {
+		t.Errorf("expected problems %q, got %q", want, g)
+	}
+}
+
+func TestPlantSymlinkForestSkipsUnmodifiedDirs(t *testing.T) {
+	topdir := t.TempDir()
+	writeFile := func(path string) {
+		t.Helper()
+		path = filepath.Join(topdir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Directories modified in the same second as the forest is planted are always read again,
+	// make them look older.
+	past := time.Now().Add(-time.Hour)
+	age := func(dirs ...string) {
+		t.Helper()
+		for _, dir := range dirs {
+			if err := os.Chtimes(filepath.Join(topdir, dir), past, past); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	exists := func(path string) bool {
+		_, err := os.Lstat(filepath.Join(topdir, "forest", path))
+		return err == nil
+	}
+
+	writeFile("src/a/a.txt")
+	writeFile("src/b/b.txt")
+	writeFile("build/a/BUILD")
+	writeFile("build/b/BUILD")
+	age("src", "src/a", "src/b", "build", "build/a", "build/b")
+
+	deps := PlantSymlinkForest(topdir, "forest", "build", "src", nil)
+
+	// A file is added to src/a without changing its modification time, so src/a isn't read
+	// again and the file doesn't show up in the forest. A file added to src/b does.
+	writeFile("src/a/new.txt")
+	age("src/a")
+	writeFile("src/b/new.txt")
+
+	// The order of the deps doesn't matter.
+	sort.Strings(deps)
+	g := PlantSymlinkForest(topdir, "forest", "build", "src", nil)
+	sort.Strings(g)
+	if !reflect.DeepEqual(g, deps) {
+		t.Errorf("expected the same deps %q, got %q", deps, g)
+	}
+	if exists("a/new.txt") {
+		t.Errorf("expected the unmodified src/a to not be read again")
+	}
+	if !exists("a/a.txt") || !exists("a/BUILD") {
+		t.Errorf("expected the contents of a to be kept")
+	}
+	if !exists("b/new.txt") {
+		t.Errorf("expected the modified src/b to be read again")
+	}
+
+	// Planting with different arguments reads every directory again.
+	PlantSymlinkForest(topdir, "forest", "build", "src", []string{"b"})
+	if !exists("a/new.txt") {
+		t.Errorf("expected src/a to be read again when the excludes change")
+	}
+	if exists("b") {
+		t.Errorf("expected the excluded b to be removed")
+	}
+}
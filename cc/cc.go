@@ -693,6 +693,14 @@ func (d libraryDependencyTag) InstallDepNeeded() bool {
 
 var _ android.InstallNeededDependencyTag = libraryDependencyTag{}
 
+// StaticLinkDep returns true for static libraries, whose contents are linked into the binaries or
+// shared libraries that depend on them.
+func (d libraryDependencyTag) StaticLinkDep() bool {
+	return d.static()
+}
+
+var _ android.StaticLinkDependencyTag = libraryDependencyTag{}
+
 // dependencyTag is used for tagging miscellaneous dependency types that don't fit into
 // libraryDependencyTag.  Each tag object is created globally and reused for multiple
 // dependencies (although since the object contains no references, assigning a tag to a
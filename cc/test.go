@@ -439,7 +439,8 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 		configs = append(configs, tradefed.Object{"module_controller", "com.android.tradefed.testtype.suite.module.MinApiLevelModuleController", options})
 	}
 
-	test.testConfig = tradefed.AutoGenNativeTestConfig(ctx, test.Properties.Test_config,
+	var runner string
+	test.testConfig, runner = tradefed.AutoGenNativeTestConfig(ctx, test.Properties.Test_config,
 		test.Properties.Test_config_template, test.Properties.Test_suites, configs, test.Properties.Auto_gen_config, testInstallBase)
 
 	test.extraTestConfigs = android.PathsForModuleSrc(ctx, test.Properties.Test_options.Extra_test_configs)
@@ -457,6 +458,20 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 		test.Properties.Test_options.Unit_test = proptools.BoolPtr(true)
 	}
 	test.binaryDecorator.baseInstaller.install(ctx, file)
+
+	var data android.Paths
+	for _, d := range test.data {
+		data = append(data, d.SrcPath)
+	}
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig:       test.testConfig,
+		ExtraTestConfigs: test.extraTestConfigs,
+		TestSuites:       test.Properties.Test_suites,
+		Data:             data,
+		Runner:           runner,
+		UnitTest:         Bool(test.Properties.Test_options.Unit_test),
+		RequireRoot:      Bool(test.Properties.Require_root),
+	})
 }
 
 func NewTest(hod android.HostOrDeviceSupported) *Module {
@@ -578,12 +593,21 @@ func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Pat
 	if Bool(benchmark.Properties.Require_root) {
 		configs = append(configs, tradefed.Object{"target_preparer", "com.android.tradefed.targetprep.RootTargetPreparer", nil})
 	}
-	benchmark.testConfig = tradefed.AutoGenNativeBenchmarkTestConfig(ctx, benchmark.Properties.Test_config,
+	var runner string
+	benchmark.testConfig, runner = tradefed.AutoGenNativeBenchmarkTestConfig(ctx, benchmark.Properties.Test_config,
 		benchmark.Properties.Test_config_template, benchmark.Properties.Test_suites, configs, benchmark.Properties.Auto_gen_config)
 
 	benchmark.binaryDecorator.baseInstaller.dir = filepath.Join("benchmarktest", ctx.ModuleName())
 	benchmark.binaryDecorator.baseInstaller.dir64 = filepath.Join("benchmarktest64", ctx.ModuleName())
 	benchmark.binaryDecorator.baseInstaller.install(ctx, file)
+
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig:  benchmark.testConfig,
+		TestSuites:  benchmark.Properties.Test_suites,
+		Data:        benchmark.data,
+		Runner:      runner,
+		RequireRoot: Bool(benchmark.Properties.Require_root),
+	})
 }
 
 func NewBenchmark(hod android.HostOrDeviceSupported) *Module {
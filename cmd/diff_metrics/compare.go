@@ -0,0 +1,371 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	smpb "android/soong/ui/metrics/metrics_proto"
+)
+
+// thresholds are the limits above which a change is reported as a regression.
+// Negative values disable a check.
+type thresholds struct {
+	// timePercent is the maximum increase of the real time of any phase, in
+	// percent of the base time.
+	timePercent float64
+	// timeMin is the minimum increase of the real time of a phase for it to
+	// be considered a regression, to filter out noise in short phases.
+	timeMin time.Duration
+
+	// modules and variants are the maximum increase of the number of Soong
+	// modules and variants.
+	modules  int64
+	variants int64
+
+	// heapPercent is the maximum increase of the maximum heap size of
+	// soong_build, in percent.
+	heapPercent float64
+
+	// config makes any difference in the build configuration a regression.
+	// The differences in the informational values are never regressions.
+	config bool
+}
+
+type timeDiff struct {
+	phase     string
+	base, new time.Duration
+	// inBase and inNew are false if the phase did not run in that build.
+	inBase, inNew bool
+}
+
+type valueDiff struct {
+	name      string
+	base, new uint64
+}
+
+type configDiff struct {
+	name, base, new string
+}
+
+// comparison is the difference between two builds.
+type comparison struct {
+	times  []timeDiff
+	counts []valueDiff
+	memory []valueDiff
+	config []configDiff
+	info   []configDiff
+}
+
+func compareMetrics(base, new *smpb.MetricsBase) *comparison {
+	c := &comparison{}
+
+	baseOrder, baseTimes := phaseTimes(base)
+	newOrder, newTimes := phaseTimes(new)
+	for _, phase := range mergeOrder(baseOrder, newOrder) {
+		b, inBase := baseTimes[phase]
+		n, inNew := newTimes[phase]
+		c.times = append(c.times, timeDiff{phase, b, n, inBase, inNew})
+	}
+
+	baseSoong, newSoong := base.GetSoongBuildMetrics(), new.GetSoongBuildMetrics()
+	c.counts = []valueDiff{
+		{"modules", uint64(baseSoong.GetModules()), uint64(newSoong.GetModules())},
+		{"variants", uint64(baseSoong.GetVariants()), uint64(newSoong.GetVariants())},
+		{"total_alloc_count", baseSoong.GetTotalAllocCount(), newSoong.GetTotalAllocCount()},
+	}
+
+	c.memory = []valueDiff{
+		{"soong_build max_heap_size", baseSoong.GetMaxHeapSize(), newSoong.GetMaxHeapSize()},
+		{"soong_build total_alloc_size", baseSoong.GetTotalAllocSize(), newSoong.GetTotalAllocSize()},
+	}
+	baseOrder, baseRss := phaseMaxRss(base)
+	newOrder, newRss := phaseMaxRss(new)
+	for _, phase := range mergeOrder(baseOrder, newOrder) {
+		c.memory = append(c.memory, valueDiff{phase + " max_rss", baseRss[phase] * 1024, newRss[phase] * 1024})
+	}
+
+	c.config = diffValues(configValues(base), configValues(new))
+	c.info = diffValues(infoValues(base), infoValues(new))
+
+	return c
+}
+
+// diffValues returns the values that differ between two lists returned by
+// the same function.
+func diffValues(base, new []configValue) []configDiff {
+	var ret []configDiff
+	for i := range base {
+		if base[i].value != new[i].value {
+			ret = append(ret, configDiff{base[i].name, base[i].value, new[i].value})
+		}
+	}
+	return ret
+}
+
+// phaseTimes returns the total real time of each phase of a build, keyed by
+// the name and description of the phase, and the order the phases appear in.
+func phaseTimes(m *smpb.MetricsBase) ([]string, map[string]time.Duration) {
+	var order []string
+	times := make(map[string]time.Duration)
+	forEachPhase(m, func(phase string, perf *smpb.PerfInfo) {
+		if _, ok := times[phase]; !ok {
+			order = append(order, phase)
+		}
+		times[phase] += time.Duration(perf.GetRealTime())
+	})
+	return order, times
+}
+
+// phaseMaxRss returns the largest maximum resident set size in kB of any
+// process run by each phase.
+func phaseMaxRss(m *smpb.MetricsBase) ([]string, map[string]uint64) {
+	var order []string
+	rss := make(map[string]uint64)
+	forEachPhase(m, func(phase string, perf *smpb.PerfInfo) {
+		for _, p := range perf.GetProcessesResourceInfo() {
+			if _, ok := rss[phase]; !ok {
+				order = append(order, phase)
+			}
+			if p.GetMaxRssKb() > rss[phase] {
+				rss[phase] = p.GetMaxRssKb()
+			}
+		}
+	})
+	return order, rss
+}
+
+func forEachPhase(m *smpb.MetricsBase, f func(phase string, perf *smpb.PerfInfo)) {
+	if total := m.GetTotal(); total != nil {
+		f("total", total)
+	}
+	for _, runs := range [][]*smpb.PerfInfo{
+		m.GetSetupTools(),
+		m.GetSoongRuns(),
+		m.GetBazelRuns(),
+		m.GetKatiRuns(),
+		m.GetNinjaRuns(),
+	} {
+		for _, perf := range runs {
+			phase := perf.GetName()
+			if desc := perf.GetDesc(); desc != "" {
+				phase += "/" + desc
+			}
+			f(phase, perf)
+		}
+	}
+}
+
+// mergeOrder returns the elements of a followed by the elements of b that are
+// not in a.
+func mergeOrder(a, b []string) []string {
+	ret := append([]string(nil), a...)
+	seen := make(map[string]bool)
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+type configValue struct {
+	name, value string
+}
+
+// configValues returns the parts of the metrics that define the
+// configuration of the build, which must match for the performance of two
+// builds to be comparable.
+func configValues(m *smpb.MetricsBase) []configValue {
+	return []configValue{
+		{"platform_version_codename", m.GetPlatformVersionCodename()},
+		{"target_product", m.GetTargetProduct()},
+		{"target_build_variant", m.GetTargetBuildVariant().String()},
+		{"target_arch", m.GetTargetArch().String()},
+		{"target_arch_variant", m.GetTargetArchVariant()},
+		{"target_cpu_variant", m.GetTargetCpuVariant()},
+		{"host_arch", m.GetHostArch().String()},
+		{"host_2nd_arch", m.GetHost_2NdArch().String()},
+		{"host_os", m.GetHostOs()},
+		{"host_os_extra", m.GetHostOsExtra()},
+		{"host_cross_os", m.GetHostCrossOs()},
+		{"host_cross_arch", m.GetHostCrossArch()},
+		{"host_cross_2nd_arch", m.GetHostCross_2NdArch()},
+		{"build_config.use_goma", strconv.FormatBool(m.GetBuildConfig().GetUseGoma())},
+		{"build_config.use_rbe", strconv.FormatBool(m.GetBuildConfig().GetUseRbe())},
+		{"build_config.force_use_goma", strconv.FormatBool(m.GetBuildConfig().GetForceUseGoma())},
+	}
+}
+
+// infoValues returns the parts of the metrics that describe a single build or
+// the machine it ran on. They differ between almost any two builds, so they
+// are only reported.
+func infoValues(m *smpb.MetricsBase) []configValue {
+	return []configValue{
+		{"build_id", m.GetBuildId()},
+		{"build_command", m.GetBuildCommand()},
+		{"system_resource_info.total_physical_memory",
+			strconv.FormatUint(m.GetSystemResourceInfo().GetTotalPhysicalMemory(), 10)},
+		{"system_resource_info.available_cpus",
+			strconv.Itoa(int(m.GetSystemResourceInfo().GetAvailableCpus()))},
+	}
+}
+
+// regressions returns a description of every change in the comparison that
+// exceeds the thresholds.
+func (c *comparison) regressions(t thresholds) []string {
+	var ret []string
+
+	if t.timePercent >= 0 {
+		for _, d := range c.times {
+			if !d.inBase || !d.inNew || d.base == 0 {
+				continue
+			}
+			delta := d.new - d.base
+			percent := float64(delta) * 100 / float64(d.base)
+			if delta >= t.timeMin && percent > t.timePercent {
+				ret = append(ret, fmt.Sprintf("%s: real time increased by %s (%+.1f%%), threshold is %.1f%%",
+					d.phase, formatDuration(delta), percent, t.timePercent))
+			}
+		}
+	}
+
+	for _, x := range []struct {
+		name      string
+		threshold int64
+	}{
+		{"modules", t.modules},
+		{"variants", t.variants},
+	} {
+		if x.threshold < 0 {
+			continue
+		}
+		for _, d := range c.counts {
+			if d.name == x.name && int64(d.new)-int64(d.base) > x.threshold {
+				ret = append(ret, fmt.Sprintf("%s: increased by %d, threshold is %d",
+					d.name, int64(d.new)-int64(d.base), x.threshold))
+			}
+		}
+	}
+
+	if t.heapPercent >= 0 {
+		for _, d := range c.memory {
+			if d.name == "soong_build max_heap_size" && d.base > 0 && d.new > d.base {
+				percent := float64(d.new-d.base) * 100 / float64(d.base)
+				if percent > t.heapPercent {
+					ret = append(ret, fmt.Sprintf("%s: increased by %s (%+.1f%%), threshold is %.1f%%",
+						d.name, formatBytes(d.new-d.base), percent, t.heapPercent))
+				}
+			}
+		}
+	}
+
+	if t.config {
+		for _, d := range c.config {
+			ret = append(ret, fmt.Sprintf("%s: changed from %q to %q", d.name, d.base, d.new))
+		}
+	}
+
+	return ret
+}
+
+func (c *comparison) String() string {
+	sb := &strings.Builder{}
+
+	fmt.Fprintln(sb, "Phase times:")
+	for _, d := range c.times {
+		switch {
+		case !d.inBase:
+			fmt.Fprintf(sb, "  %-40s %12s %12s  (new)\n", d.phase, "-", formatDuration(d.new))
+		case !d.inNew:
+			fmt.Fprintf(sb, "  %-40s %12s %12s  (removed)\n", d.phase, formatDuration(d.base), "-")
+		default:
+			fmt.Fprintf(sb, "  %-40s %12s %12s  %s\n", d.phase, formatDuration(d.base), formatDuration(d.new),
+				formatDelta(formatSignedDuration(d.new-d.base), int64(d.new-d.base), int64(d.base)))
+		}
+	}
+
+	fmt.Fprintln(sb, "Soong build:")
+	for _, d := range c.counts {
+		fmt.Fprintf(sb, "  %-40s %12d %12d  %s\n", d.name, d.base, d.new,
+			formatDelta(fmt.Sprintf("%+d", int64(d.new)-int64(d.base)), int64(d.new)-int64(d.base), int64(d.base)))
+	}
+
+	fmt.Fprintln(sb, "Memory:")
+	for _, d := range c.memory {
+		fmt.Fprintf(sb, "  %-40s %12s %12s  %s\n", d.name, formatBytes(d.base), formatBytes(d.new),
+			formatDelta(formatSignedBytes(int64(d.new)-int64(d.base)), int64(d.new)-int64(d.base), int64(d.base)))
+	}
+
+	if len(c.config) > 0 {
+		fmt.Fprintln(sb, "Config differences:")
+		for _, d := range c.config {
+			fmt.Fprintf(sb, "  %s: %q -> %q\n", d.name, d.base, d.new)
+		}
+	}
+
+	if len(c.info) > 0 {
+		fmt.Fprintln(sb, "Other differences:")
+		for _, d := range c.info {
+			fmt.Fprintf(sb, "  %s: %q -> %q\n", d.name, d.base, d.new)
+		}
+	}
+
+	return sb.String()
+}
+
+func formatDelta(delta string, diff, base int64) string {
+	if base == 0 {
+		return delta
+	}
+	return fmt.Sprintf("%s (%+.1f%%)", delta, float64(diff)*100/float64(base))
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func formatSignedDuration(d time.Duration) string {
+	if d >= 0 {
+		return "+" + formatDuration(d)
+	}
+	return formatDuration(d)
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+func formatSignedBytes(b int64) string {
+	if b < 0 {
+		return "-" + formatBytes(uint64(-b))
+	}
+	return "+" + formatBytes(uint64(b))
+}
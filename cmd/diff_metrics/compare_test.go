@@ -0,0 +1,146 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	smpb "android/soong/ui/metrics/metrics_proto"
+)
+
+func perfInfo(name, desc string, realTime time.Duration, maxRssKb uint64) *smpb.PerfInfo {
+	p := &smpb.PerfInfo{
+		Name:     proto.String(name),
+		Desc:     proto.String(desc),
+		RealTime: proto.Uint64(uint64(realTime)),
+	}
+	if maxRssKb > 0 {
+		p.ProcessesResourceInfo = []*smpb.ProcessResourceInfo{
+			{Name: proto.String(desc), MaxRssKb: proto.Uint64(maxRssKb)},
+		}
+	}
+	return p
+}
+
+func testMetrics(total, soong, ninja time.Duration, modules, variants uint32, heap uint64, product string) *smpb.MetricsBase {
+	return &smpb.MetricsBase{
+		TargetProduct: proto.String(product),
+		Total:         perfInfo("total", "", total, 0),
+		SoongRuns:     []*smpb.PerfInfo{perfInfo("soong", "soong_build", soong, 1024)},
+		NinjaRuns:     []*smpb.PerfInfo{perfInfo("ninja", "ninja", ninja, 0)},
+		SoongBuildMetrics: &smpb.SoongBuildMetrics{
+			Modules:     proto.Uint32(modules),
+			Variants:    proto.Uint32(variants),
+			MaxHeapSize: proto.Uint64(heap),
+		},
+	}
+}
+
+func TestCompareMetrics(t *testing.T) {
+	base := testMetrics(100*time.Second, 40*time.Second, 60*time.Second, 1000, 5000, 1000, "aosp_arm64")
+	new := testMetrics(120*time.Second, 41*time.Second, 79*time.Second, 1010, 5000, 1200, "aosp_x86_64")
+	new.KatiRuns = []*smpb.PerfInfo{perfInfo("kati", "kati build", time.Second, 0)}
+	base.BuildId = proto.String("1234")
+	new.BuildId = proto.String("1235")
+
+	c := compareMetrics(base, new)
+
+	wantTimes := []timeDiff{
+		{"total", 100 * time.Second, 120 * time.Second, true, true},
+		{"soong/soong_build", 40 * time.Second, 41 * time.Second, true, true},
+		{"ninja/ninja", 60 * time.Second, 79 * time.Second, true, true},
+		{"kati/kati build", 0, time.Second, false, true},
+	}
+	if !reflect.DeepEqual(c.times, wantTimes) {
+		t.Errorf("times = %v, want %v", c.times, wantTimes)
+	}
+
+	wantConfig := []configDiff{{"target_product", "aosp_arm64", "aosp_x86_64"}}
+	if !reflect.DeepEqual(c.config, wantConfig) {
+		t.Errorf("config = %v, want %v", c.config, wantConfig)
+	}
+
+	wantInfo := []configDiff{{"build_id", "1234", "1235"}}
+	if !reflect.DeepEqual(c.info, wantInfo) {
+		t.Errorf("info = %v, want %v", c.info, wantInfo)
+	}
+
+	testCases := []struct {
+		name       string
+		thresholds thresholds
+		want       []string
+	}{
+		{
+			name:       "disabled",
+			thresholds: thresholds{timePercent: -1, modules: -1, variants: -1, heapPercent: -1},
+		},
+		{
+			name:       "time",
+			thresholds: thresholds{timePercent: 10, timeMin: 2 * time.Second, modules: -1, variants: -1, heapPercent: -1},
+			want: []string{
+				"total: real time increased by 20s (+20.0%), threshold is 10.0%",
+				"ninja/ninja: real time increased by 19s (+31.7%), threshold is 10.0%",
+			},
+		},
+		{
+			name:       "time minimum",
+			thresholds: thresholds{timePercent: 1, timeMin: 30 * time.Second, modules: -1, variants: -1, heapPercent: -1},
+		},
+		{
+			name:       "modules and variants",
+			thresholds: thresholds{timePercent: -1, modules: 5, variants: 0, heapPercent: -1},
+			want:       []string{"modules: increased by 10, threshold is 5"},
+		},
+		{
+			name:       "heap",
+			thresholds: thresholds{timePercent: -1, modules: -1, variants: -1, heapPercent: 10},
+			want:       []string{"soong_build max_heap_size: increased by 200B (+20.0%), threshold is 10.0%"},
+		},
+		{
+			name:       "config",
+			thresholds: thresholds{timePercent: -1, modules: -1, variants: -1, heapPercent: -1, config: true},
+			want:       []string{`target_product: changed from "aosp_arm64" to "aosp_x86_64"`},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.regressions(tt.thresholds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("regressions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for _, tt := range []struct {
+		in   uint64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0KiB"},
+		{3 * 1024 * 1024 / 2, "1.5MiB"},
+		{5 * 1024 * 1024 * 1024, "5.0GiB"},
+	} {
+		if got := formatBytes(tt.in); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
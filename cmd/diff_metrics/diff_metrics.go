@@ -0,0 +1,104 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// diff_metrics compares the soong_metrics files of two or more builds, and
+// optionally fails if the later builds regressed compared to the first one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var (
+	timeThreshold    = flag.Float64("time_threshold", -1, "fail if the real time of any phase increased by more than this percentage (negative to disable)")
+	timeMinimum      = flag.Duration("time_minimum", 0, "ignore real time increases smaller than this")
+	modulesThreshold = flag.Int64("modules_threshold", -1, "fail if the number of Soong modules increased by more than this (negative to disable)")
+	variantThreshold = flag.Int64("variants_threshold", -1, "fail if the number of Soong variants increased by more than this (negative to disable)")
+	heapThreshold    = flag.Float64("heap_threshold", -1, "fail if the maximum heap size of soong_build increased by more than this percentage (negative to disable)")
+	configMustMatch  = flag.Bool("config_must_match", false, "fail if the build configurations are different (product, variant, architectures and remote build settings)")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: diff_metrics [flags] <base metrics> <metrics> [<metrics> ...]\n\n")
+	fmt.Fprintf(os.Stderr, "Compares each metrics file to the base metrics file. Metrics files may be\n")
+	fmt.Fprintf(os.Stderr, "soong_metrics files or cuj_metrics.pb files, builds in cuj_metrics.pb files\n")
+	fmt.Fprintf(os.Stderr, "are matched by name.\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Fprintf(os.Stderr, "Error, at least two arguments are required\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	t := thresholds{
+		timePercent: *timeThreshold,
+		timeMin:     *timeMinimum,
+		modules:     *modulesThreshold,
+		variants:    *variantThreshold,
+		heapPercent: *heapThreshold,
+		config:      *configMustMatch,
+	}
+
+	base, err := loadMetricsFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading metrics: %v\n", err)
+		os.Exit(1)
+	}
+
+	var regressions []string
+	for _, filename := range flag.Args()[1:] {
+		other, err := loadMetricsFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading metrics: %v\n", err)
+			os.Exit(1)
+		}
+
+		pairs, missing := matchBuilds(base, other)
+		for _, m := range missing {
+			fmt.Fprintf(os.Stderr, "Warning: build %s\n", m)
+		}
+
+		for _, pair := range pairs {
+			title := fmt.Sprintf("%s -> %s", base.filename, other.filename)
+			if pair.name != "" {
+				title += fmt.Sprintf(" (%s)", pair.name)
+			}
+			fmt.Printf("=== %s\n", title)
+
+			c := compareMetrics(pair.base, pair.new)
+			fmt.Print(c.String())
+			fmt.Println()
+
+			for _, r := range c.regressions(t) {
+				regressions = append(regressions, fmt.Sprintf("%s: %s", title, r))
+			}
+		}
+	}
+
+	if len(regressions) > 0 {
+		fmt.Fprintln(os.Stderr, "Regressions found:")
+		for _, r := range regressions {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+
+	smpb "android/soong/ui/metrics/metrics_proto"
+)
+
+// build is the metrics of a single soong_ui invocation.
+type build struct {
+	// name is the name of the critical user journey for builds loaded from a
+	// CriticalUserJourneysMetrics bundle, and empty otherwise.
+	name    string
+	metrics *smpb.MetricsBase
+}
+
+// metricsFile is a soong_metrics or cuj_metrics.pb file.
+type metricsFile struct {
+	filename string
+	builds   []build
+}
+
+// loadMetricsFile reads either a MetricsBase proto as written to
+// out/soong_metrics, or a CriticalUserJourneysMetrics proto as written by the
+// cuj tool.
+func loadMetricsFile(filename string) (*metricsFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	builds, err := parseMetrics(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	return &metricsFile{filename: filename, builds: builds}, nil
+}
+
+func parseMetrics(data []byte) ([]build, error) {
+	// The two protos can't be told apart by their contents, but the only
+	// field of CriticalUserJourneysMetrics is a length delimited field with
+	// the same number as the varint build_date_timestamp field of MetricsBase.
+	// Parsing a MetricsBase as a CriticalUserJourneysMetrics never produces
+	// any CUJs, as all of the fields end up as unknown fields.
+	cujs := &smpb.CriticalUserJourneysMetrics{}
+	if err := proto.Unmarshal(data, cujs); err == nil && len(cujs.GetCujs()) > 0 {
+		var builds []build
+		for _, cuj := range cujs.GetCujs() {
+			metrics := cuj.GetMetrics()
+			if metrics == nil {
+				metrics = &smpb.MetricsBase{}
+			}
+			builds = append(builds, build{name: cuj.GetName(), metrics: metrics})
+		}
+		return builds, nil
+	}
+
+	metrics := &smpb.MetricsBase{}
+	if err := proto.Unmarshal(data, metrics); err != nil {
+		return nil, err
+	}
+	return []build{{metrics: metrics}}, nil
+}
+
+// buildPair is a build from the base file and the matching build from another
+// file.
+type buildPair struct {
+	name      string
+	base, new *smpb.MetricsBase
+}
+
+// matchBuilds pairs up the builds in base and other. Single builds are always
+// paired with each other, builds from CUJ bundles are paired by name. Builds
+// that only exist in one of the files are returned in missing.
+func matchBuilds(base, other *metricsFile) (pairs []buildPair, missing []string) {
+	if len(base.builds) == 1 && len(other.builds) == 1 {
+		name := base.builds[0].name
+		if name == "" {
+			name = other.builds[0].name
+		}
+		return []buildPair{{name, base.builds[0].metrics, other.builds[0].metrics}}, nil
+	}
+
+	otherBuilds := make(map[string]*smpb.MetricsBase)
+	for _, b := range other.builds {
+		otherBuilds[b.name] = b.metrics
+	}
+
+	seen := make(map[string]bool)
+	for _, b := range base.builds {
+		seen[b.name] = true
+		if m, ok := otherBuilds[b.name]; ok {
+			pairs = append(pairs, buildPair{b.name, b.metrics, m})
+		} else {
+			missing = append(missing, fmt.Sprintf("%q is only in %s", b.name, base.filename))
+		}
+	}
+	for _, b := range other.builds {
+		if !seen[b.name] {
+			missing = append(missing, fmt.Sprintf("%q is only in %s", b.name, other.filename))
+		}
+	}
+
+	return pairs, missing
+}
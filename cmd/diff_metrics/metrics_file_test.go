@@ -0,0 +1,97 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	smpb "android/soong/ui/metrics/metrics_proto"
+)
+
+func TestParseMetrics(t *testing.T) {
+	metrics := testMetrics(time.Second, time.Second, time.Second, 1, 2, 3, "aosp_arm64")
+	metrics.BuildDateTimestamp = proto.Int64(1234567890)
+
+	t.Run("metrics", func(t *testing.T) {
+		data, err := proto.Marshal(metrics)
+		if err != nil {
+			t.Fatal(err)
+		}
+		builds, err := parseMetrics(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(builds) != 1 || builds[0].name != "" || !proto.Equal(builds[0].metrics, metrics) {
+			t.Errorf("unexpected builds %v", builds)
+		}
+	})
+
+	t.Run("cujs", func(t *testing.T) {
+		data, err := proto.Marshal(&smpb.CriticalUserJourneysMetrics{
+			Cujs: []*smpb.CriticalUserJourneyMetrics{
+				{Name: proto.String("noop"), Metrics: metrics},
+				{Name: proto.String("clean")},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		builds, err := parseMetrics(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(builds) != 2 {
+			t.Fatalf("expected 2 builds, got %d", len(builds))
+		}
+		if builds[0].name != "noop" || !proto.Equal(builds[0].metrics, metrics) {
+			t.Errorf("unexpected build %v", builds[0])
+		}
+		if builds[1].name != "clean" || builds[1].metrics == nil {
+			t.Errorf("unexpected build %v", builds[1])
+		}
+	})
+}
+
+func TestMatchBuilds(t *testing.T) {
+	a, b, c := &smpb.MetricsBase{}, &smpb.MetricsBase{}, &smpb.MetricsBase{}
+
+	t.Run("single", func(t *testing.T) {
+		pairs, missing := matchBuilds(
+			&metricsFile{"base", []build{{"", a}}},
+			&metricsFile{"new", []build{{"noop", b}}})
+		want := []buildPair{{"noop", a, b}}
+		if !reflect.DeepEqual(pairs, want) || missing != nil {
+			t.Errorf("matchBuilds() = %v, %v, want %v", pairs, missing, want)
+		}
+	})
+
+	t.Run("cujs", func(t *testing.T) {
+		pairs, missing := matchBuilds(
+			&metricsFile{"base", []build{{"noop", a}, {"clean", b}}},
+			&metricsFile{"new", []build{{"clean", c}, {"incremental", a}}})
+		wantPairs := []buildPair{{"clean", b, c}}
+		wantMissing := []string{`"noop" is only in base`, `"incremental" is only in new`}
+		if !reflect.DeepEqual(pairs, wantPairs) {
+			t.Errorf("pairs = %v, want %v", pairs, wantPairs)
+		}
+		if !reflect.DeepEqual(missing, wantMissing) {
+			t.Errorf("missing = %q, want %q", missing, wantMissing)
+		}
+	})
+}
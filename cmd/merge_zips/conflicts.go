@@ -0,0 +1,443 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint/pathtools"
+
+	"android/soong/third_party/zip"
+)
+
+// conflictPolicy is how entries with the same path in multiple input zips are resolved.
+type conflictPolicy int
+
+const (
+	// Fail unless the entries are identical.
+	conflictError conflictPolicy = iota
+	// Take the entry from the first input zip it exists in.
+	conflictFirstWins
+	// Take the entry from the last input zip it exists in.
+	conflictLastWins
+	// Concatenate the contents of the entries, e.g. for META-INF/services files.
+	conflictConcat
+	// Merge the keys of properties files, failing if a key has different values.
+	conflictMerge
+)
+
+var conflictPolicyNames = map[conflictPolicy]string{
+	conflictError:     "error",
+	conflictFirstWins: "first-wins",
+	conflictLastWins:  "last-wins",
+	conflictConcat:    "concat",
+	conflictMerge:     "merge",
+}
+
+func (p conflictPolicy) String() string {
+	return conflictPolicyNames[p]
+}
+
+// combines returns true if the policy combines the contents of the conflicting entries.
+func (p conflictPolicy) combines() bool {
+	return p == conflictConcat || p == conflictMerge
+}
+
+// conflictRule applies a conflict policy to the entries whose path matches a glob.
+type conflictRule struct {
+	pattern string
+	policy  conflictPolicy
+}
+
+// parseConflictRule parses a rule of the form <glob>=<policy>.
+func parseConflictRule(s string) (conflictRule, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return conflictRule{}, fmt.Errorf("conflict rule %q must be of the form <glob>=<policy>", s)
+	}
+	pattern, name := s[:i], s[i+1:]
+	if err := checkConflictGlob(pattern); err != nil {
+		return conflictRule{}, fmt.Errorf("conflict rule %q has invalid glob: %s", s, err)
+	}
+	for policy, policyName := range conflictPolicyNames {
+		if name == policyName {
+			return conflictRule{pattern, policy}, nil
+		}
+	}
+	return conflictRule{}, fmt.Errorf("conflict rule %q has unknown policy %q, expected one of "+
+		"error, first-wins, last-wins, concat or merge", s, name)
+}
+
+// checkConflictGlob returns an error if a pattern would be rejected by pathtools.Match, so that
+// it is reported when the flag is parsed instead of when the first duplicate entry is found.
+func checkConflictGlob(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return err
+	}
+	components := strings.Split(pattern, "/")
+	recursive := 0
+	for i, component := range components {
+		if component == "**" {
+			if i == len(components)-1 {
+				return fmt.Errorf("'**' can't be the last path element")
+			}
+			recursive++
+		} else if strings.Contains(component, "**") {
+			return fmt.Errorf("'**' must be a whole path element")
+		}
+	}
+	if recursive > 1 {
+		return fmt.Errorf("'**' can only be used once")
+	}
+	return nil
+}
+
+type conflictRules []conflictRule
+
+func (r *conflictRules) String() string {
+	return `""`
+}
+
+func (r *conflictRules) Set(s string) error {
+	rule, err := parseConflictRule(s)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, rule)
+	return nil
+}
+
+// policyFor returns the policy of the first rule that matches the given entry, and whether any
+// rule matched.
+func (r conflictRules) policyFor(name string) (conflictPolicy, bool) {
+	for _, rule := range r {
+		match, err := pathtools.Match(rule.pattern, name)
+		if err != nil {
+			// The patterns are checked by parseConflictRule.
+			panic(fmt.Errorf("%s: %s", err.Error(), rule.pattern))
+		}
+		if match {
+			return rule.policy, true
+		}
+	}
+	return conflictError, false
+}
+
+// delaysWrites returns true if entries can't be written as soon as they are seen because a later
+// input zip may replace or add to them.
+func (r conflictRules) delaysWrites() bool {
+	for _, rule := range r {
+		if rule.policy == conflictLastWins || rule.policy.combines() {
+			return true
+		}
+	}
+	return false
+}
+
+// a ZipEntryFromCombination is a ZipEntryContents whose content is combined from the contents of
+// entries with the same path in multiple input zips.
+type ZipEntryFromCombination struct {
+	policy  conflictPolicy
+	sources []ZipEntryContents
+
+	// The combined contents are computed once, as CRC32, Size and WriteToZip all need them.
+	// They are reset when a source is added.
+	combined    []byte
+	combinedErr error
+	hasCombined bool
+}
+
+// add adds the contents of another entry to the combination.
+func (ce *ZipEntryFromCombination) add(entry ZipEntryContents) {
+	ce.sources = append(ce.sources, entry)
+	ce.combined, ce.combinedErr, ce.hasCombined = nil, nil, false
+}
+
+func (ce *ZipEntryFromCombination) String() string {
+	var sources []string
+	for _, source := range ce.sources {
+		sources = append(sources, source.String())
+	}
+	return fmt.Sprintf("%s of %s", ce.policy, strings.Join(sources, ", "))
+}
+
+func (ce *ZipEntryFromCombination) IsDir() bool {
+	return false
+}
+
+func (ce *ZipEntryFromCombination) CRC32() uint32 {
+	contents, _ := ce.Contents()
+	return crc32.ChecksumIEEE(contents)
+}
+
+func (ce *ZipEntryFromCombination) Size() uint64 {
+	contents, _ := ce.Contents()
+	return uint64(len(contents))
+}
+
+// contains returns true if an identical entry is already one of the sources.
+func (ce *ZipEntryFromCombination) contains(entry ZipEntryContents) bool {
+	for _, source := range ce.sources {
+		if source.CRC32() == entry.CRC32() && source.Size() == entry.Size() {
+			return true
+		}
+	}
+	return false
+}
+
+func (ce *ZipEntryFromCombination) Contents() ([]byte, error) {
+	if !ce.hasCombined {
+		ce.combined, ce.combinedErr = ce.combine()
+		ce.hasCombined = true
+	}
+	return ce.combined, ce.combinedErr
+}
+
+// combine reads the contents of the sources and combines them according to the policy.
+func (ce *ZipEntryFromCombination) combine() ([]byte, error) {
+	var contents [][]byte
+	for _, source := range ce.sources {
+		c, err := source.Contents()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, c)
+	}
+
+	if ce.policy == conflictMerge {
+		return mergeProperties(ce.sources, contents)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, c := range contents {
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		buf.Write(c)
+	}
+	return buf.Bytes(), nil
+}
+
+func (ce *ZipEntryFromCombination) WriteToZip(dest string, zw *zip.Writer) error {
+	contents, err := ce.Contents()
+	if err != nil {
+		return fmt.Errorf("%s: %s", dest, err)
+	}
+
+	// Use the header of the first source for the combined entry.
+	fh, err := entryHeader(ce.sources[0])
+	if err != nil {
+		return err
+	}
+	fh.Name = dest
+	fh.Extra = zip.StripExtras(fh.Extra)
+	fh.CRC32 = 0
+	fh.CompressedSize64 = 0
+	fh.UncompressedSize64 = 0
+
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(contents)
+	return err
+}
+
+// entryHeader returns a copy of the header of an entry.
+func entryHeader(entry ZipEntryContents) (*zip.FileHeader, error) {
+	switch e := entry.(type) {
+	case *ZipEntryFromZip:
+		if err := e.inputZip.Open(); err != nil {
+			return nil, err
+		}
+		fh := e.inputZip.Entries()[e.index].FileHeader
+		return &fh, nil
+	case ZipEntryFromBuffer:
+		fh := *e.fh
+		return &fh, nil
+	default:
+		panic(fmt.Errorf("unexpected entry type %T", entry))
+	}
+}
+
+// mergeProperties merges the keys of properties files, in the order they are first seen.  The files
+// are parsed like java.util.Properties.load does, and the merged file has a key=value line per
+// key, so comments, blank lines and the original formatting of the lines are not preserved.  A
+// key with different values in two files is an error.
+func mergeProperties(sources []ZipEntryContents, contents [][]byte) ([]byte, error) {
+	var keys []string
+	values := make(map[string]string)
+	valueSources := make(map[string]ZipEntryContents)
+
+	for i, c := range contents {
+		for _, line := range propertiesLines(string(c)) {
+			key, value := parsePropertiesLine(line)
+			if prev, exists := values[key]; !exists {
+				keys = append(keys, key)
+				values[key] = value
+				valueSources[key] = sources[i]
+			} else if prev != value {
+				return nil, fmt.Errorf("property %q is %q in %v and %q in %v",
+					key, prev, valueSources[key], value, sources[i])
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s=%s\n", escapeProperty(key, true), escapeProperty(values[key], false))
+	}
+	return buf.Bytes(), nil
+}
+
+const propertiesWhitespace = " \t\f"
+
+// propertiesLines returns the logical lines of a properties file, with leading whitespace removed,
+// skipping comments and blank lines.  A line that ends in an odd number of backslashes is
+// continued on the next line, without the next line's leading whitespace.
+func propertiesLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	var lines []string
+	var continued strings.Builder
+	continuing := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimLeft(line, propertiesWhitespace)
+		if !continuing && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+		backslashes := len(line) - len(strings.TrimRight(line, "\\"))
+		if backslashes%2 == 1 {
+			continued.WriteString(line[:len(line)-1])
+			continuing = true
+			continue
+		}
+		continued.WriteString(line)
+		lines = append(lines, continued.String())
+		continued.Reset()
+		continuing = false
+	}
+	if continuing {
+		lines = append(lines, continued.String())
+	}
+	return lines
+}
+
+// parsePropertiesLine splits a logical line of a properties file into its unescaped key and value.
+// The key ends at the first unescaped '=', ':' or whitespace, which may be surrounded by more
+// whitespace.
+func parsePropertiesLine(line string) (key, value string) {
+	keyEnd := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+		} else if line[i] == '=' || line[i] == ':' || strings.IndexByte(propertiesWhitespace, line[i]) >= 0 {
+			keyEnd = i
+			break
+		}
+	}
+
+	rest := strings.TrimLeft(line[keyEnd:], propertiesWhitespace)
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], propertiesWhitespace)
+	}
+	return unescapeProperty(line[:keyEnd]), unescapeProperty(rest)
+}
+
+// unescapeProperty replaces the escape sequences in a key or value of a properties file.
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			buf.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 <= len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					buf.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			buf.WriteByte('u')
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String()
+}
+
+// escapeProperty escapes a key or value so that it is parsed back unchanged by
+// parsePropertiesLine.
+func escapeProperty(s string, key bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '=', ':', '#', '!':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case ' ':
+			if key || i == 0 {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// readZipEntry returns the uncompressed contents of an entry of an input zip.
+func readZipEntry(inputZip InputZip, index int) ([]byte, error) {
+	if err := inputZip.Open(); err != nil {
+		return nil, err
+	}
+	r, err := inputZip.Entries()[index].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
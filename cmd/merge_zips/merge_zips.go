@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -50,6 +51,7 @@ type ZipEntryContents interface {
 	IsDir() bool
 	CRC32() uint32
 	Size() uint64
+	Contents() ([]byte, error)
 	WriteToZip(dest string, zw *zip.Writer) error
 }
 
@@ -92,6 +94,10 @@ func (ze ZipEntryFromZip) Size() uint64 {
 	return ze.size
 }
 
+func (ze ZipEntryFromZip) Contents() ([]byte, error) {
+	return readZipEntry(ze.inputZip, ze.index)
+}
+
 func (ze ZipEntryFromZip) WriteToZip(dest string, zw *zip.Writer) error {
 	if err := ze.inputZip.Open(); err != nil {
 		return err
@@ -121,6 +127,10 @@ func (be ZipEntryFromBuffer) Size() uint64 {
 	return uint64(len(be.content))
 }
 
+func (be ZipEntryFromBuffer) Contents() ([]byte, error) {
+	return be.content, nil
+}
+
 func (be ZipEntryFromBuffer) WriteToZip(dest string, zw *zip.Writer) error {
 	w, err := zw.CreateHeader(be.fh)
 	if err != nil {
@@ -146,7 +156,24 @@ type OutputZip struct {
 	ignoreDuplicates bool
 	excludeDirs      []string
 	excludeFiles     []string
+	conflictRules    conflictRules
 	sourceByDest     map[string]ZipEntryContents
+	orderedDests     []string
+	provenance       map[string]*EntryProvenance
+}
+
+// EntryProvenance records the input zips an entry of the output zip came from.
+type EntryProvenance struct {
+	Entry string `json:"entry"`
+
+	// The input zips the contents of the entry came from, more than one if they were combined.
+	From []string `json:"from"`
+
+	// The input zips with a duplicate entry that was dropped.
+	Ignored []string `json:"ignored,omitempty"`
+
+	// The policy that resolved the duplicate entries, if any.
+	Policy string `json:"policy,omitempty"`
 }
 
 func NewOutputZip(outputWriter *zip.Writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates bool) *OutputZip {
@@ -156,10 +183,21 @@ func NewOutputZip(outputWriter *zip.Writer, sortEntries, emulateJar, stripDirEnt
 		emulateJar:       emulateJar,
 		sortEntries:      sortEntries,
 		sourceByDest:     make(map[string]ZipEntryContents, 0),
+		provenance:       make(map[string]*EntryProvenance),
 		ignoreDuplicates: ignoreDuplicates,
 	}
 }
 
+func (oz *OutputZip) setConflictRules(rules conflictRules) {
+	oz.conflictRules = rules
+}
+
+// delayWrites returns true if the entries are written once all input zips have been processed,
+// instead of as soon as they are seen.
+func (oz *OutputZip) delayWrites() bool {
+	return oz.emulateJar || oz.sortEntries || oz.conflictRules.delaysWrites()
+}
+
 func (oz *OutputZip) setExcludeDirs(excludeDirs []string) {
 	oz.excludeDirs = make([]string, len(excludeDirs))
 	for i, dir := range excludeDirs {
@@ -178,8 +216,10 @@ func (oz *OutputZip) addZipEntry(name string, source ZipEntryContents) (ZipEntry
 		return existingSource, nil
 	}
 	oz.sourceByDest[name] = source
+	oz.orderedDests = append(oz.orderedDests, name)
+	oz.provenance[name] = &EntryProvenance{Entry: name, From: []string{entryOrigin(source)}}
 	// Delay writing an entry if entries need to be rearranged.
-	if oz.emulateJar || oz.sortEntries {
+	if oz.delayWrites() {
 		return nil, nil
 	}
 	return nil, source.WriteToZip(name, oz.outputWriter)
@@ -292,17 +332,71 @@ func (oz *OutputZip) copyEntry(inputZip InputZip, index int) error {
 			entry.name, existingEntry, entry)
 	}
 
-	if oz.ignoreDuplicates ||
-		// Skip manifest and module info files that are not from the first input file
-		(oz.emulateJar && entry.name == jar.ManifestFile || entry.name == jar.ModuleInfoClass) ||
-		// Identical entries
-		(existingEntry.CRC32() == entry.CRC32() && existingEntry.Size() == entry.Size()) ||
-		// Directory entries
-		entry.IsDir() {
+	provenance := oz.provenance[entry.name]
+	if isIdenticalEntry(existingEntry, entry) || entry.IsDir() {
+		provenance.Ignored = append(provenance.Ignored, inputZip.Name())
 		return nil
 	}
 
-	return fmt.Errorf("Duplicate path %v found in %v and %v\n", entry.name, existingEntry, inputZip.Name())
+	policy, explicit := oz.conflictRules.policyFor(entry.name)
+	if !explicit && (oz.ignoreDuplicates ||
+		// Skip manifest and module info files that are not from the first input file
+		(oz.emulateJar && entry.name == jar.ManifestFile || entry.name == jar.ModuleInfoClass)) {
+		policy = conflictFirstWins
+	}
+
+	switch policy {
+	case conflictFirstWins:
+		provenance.Ignored = append(provenance.Ignored, inputZip.Name())
+	case conflictLastWins:
+		oz.sourceByDest[entry.name] = entry
+		provenance.Ignored = append(provenance.Ignored, provenance.From...)
+		provenance.From = []string{inputZip.Name()}
+	case conflictConcat, conflictMerge:
+		combined, ok := existingEntry.(*ZipEntryFromCombination)
+		if !ok {
+			combined = &ZipEntryFromCombination{policy: policy, sources: []ZipEntryContents{existingEntry}}
+			oz.sourceByDest[entry.name] = combined
+		}
+		combined.add(entry)
+		provenance.From = append(provenance.From, inputZip.Name())
+	default:
+		return fmt.Errorf("Duplicate path %v found in %v and %v\n", entry.name, existingEntry, inputZip.Name())
+	}
+	provenance.Policy = policy.String()
+	return nil
+}
+
+// isIdenticalEntry returns true if an entry has the same contents as an existing entry with the
+// same path.
+func isIdenticalEntry(existingEntry, entry ZipEntryContents) bool {
+	if combined, ok := existingEntry.(*ZipEntryFromCombination); ok {
+		return combined.contains(entry)
+	}
+	return existingEntry.CRC32() == entry.CRC32() && existingEntry.Size() == entry.Size()
+}
+
+// entryOrigin returns the name of the input zip an entry came from.
+func entryOrigin(source ZipEntryContents) string {
+	if ze, ok := source.(*ZipEntryFromZip); ok {
+		return ze.inputZip.Name()
+	}
+	return source.String()
+}
+
+// writeProvenance writes the input zips each entry of the output zip came from as JSON, sorted by
+// entry.
+func (oz *OutputZip) writeProvenance(w io.Writer) error {
+	var provenance []*EntryProvenance
+	for _, entry := range oz.alphanumericSorted() {
+		provenance = append(provenance, oz.provenance[entry])
+	}
+	buf, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
 }
 
 func (oz *OutputZip) entriesArray() []string {
@@ -533,9 +627,11 @@ func (miz *ManagedInputZip) Entries() []*zip.File {
 // Actual processing.
 func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string,
 	sortEntries, emulateJar, emulatePar, stripDirEntries, ignoreDuplicates bool,
-	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool) error {
+	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool, conflictRules conflictRules,
+	provenance io.Writer) error {
 
 	out := NewOutputZip(writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates)
+	out.setConflictRules(conflictRules)
 	out.setExcludeFiles(excludeFiles)
 	out.setExcludeDirs(excludeDirs)
 	if manifest != "" {
@@ -575,18 +671,28 @@ func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string
 				}
 			}
 		}
-		// Unless we need to rearrange the entries, the input zip can now be closed.
-		if !(emulateJar || sortEntries) {
+		// Unless we need to rearrange or combine the entries, the input zip can now be closed.
+		if !out.delayWrites() {
 			if err := inputZip.Close(); err != nil {
 				return err
 			}
 		}
 	}
 
+	var err error
 	if emulateJar {
-		return out.writeEntries(out.jarSorted())
+		err = out.writeEntries(out.jarSorted())
 	} else if sortEntries {
-		return out.writeEntries(out.alphanumericSorted())
+		err = out.writeEntries(out.alphanumericSorted())
+	} else if out.delayWrites() {
+		err = out.writeEntries(out.orderedDests)
+	}
+	if err != nil {
+		return err
+	}
+
+	if provenance != nil {
+		return out.writeProvenance(provenance)
 	}
 	return nil
 }
@@ -622,17 +728,21 @@ var (
 	excludeDirs      fileList
 	excludeFiles     fileList
 	zipsToNotStrip   = make(zipsToNotStripSet)
+	conflicts        conflictRules
 	stripDirEntries  = flag.Bool("D", false, "strip directory entries from the output zip file")
 	manifest         = flag.String("m", "", "manifest file to insert in jar")
 	pyMain           = flag.String("pm", "", "__main__.py file to insert in par")
 	prefix           = flag.String("prefix", "", "A file to prefix to the zip file")
 	ignoreDuplicates = flag.Bool("ignore-duplicates", false, "take each entry from the first zip it exists in and don't warn")
+	provenancePath   = flag.String("provenance", "", "file to write the input zips each output entry came from to, as JSON")
 )
 
 func init() {
 	flag.Var(&excludeDirs, "stripDir", "directories to be excluded from the output zip, accepts wildcards")
 	flag.Var(&excludeFiles, "stripFile", "files to be excluded from the output zip, accepts wildcards")
 	flag.Var(&zipsToNotStrip, "zipToNotStrip", "the input zip file which is not applicable for stripping")
+	flag.Var(&conflicts, "conflict", "policy for duplicate entries matching a glob, as <glob>=<policy> where the "+
+		"policy is error, first-wins, last-wins, concat or merge (for properties files), the first matching rule applies")
 }
 
 type FileInputZip struct {
@@ -752,9 +862,19 @@ func main() {
 	for i, input := range inputs {
 		inputZips[i] = inputZipsManager.Manage(&FileInputZip{name: input})
 	}
+	var provenance io.Writer
+	if *provenancePath != "" {
+		provenanceFile, err := os.Create(*provenancePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer provenanceFile.Close()
+		provenance = provenanceFile
+	}
+
 	err = mergeZips(inputZips, writer, *manifest, *pyMain, *sortEntries, *emulateJar, *emulatePar,
 		*stripDirEntries, *ignoreDuplicates, []string(excludeFiles), []string(excludeDirs),
-		map[string]bool(zipsToNotStrip))
+		map[string]bool(zipsToNotStrip), conflicts, provenance)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -17,6 +17,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"strconv"
 	"strings"
@@ -49,6 +50,14 @@ var (
 	manifestFile   = testZipEntry{jar.ManifestFile, 0755, []byte("manifest")}
 	manifestFile2  = testZipEntry{jar.ManifestFile, 0755, []byte("manifest2")}
 	moduleInfoFile = testZipEntry{jar.ModuleInfoClass, 0755, []byte("module-info")}
+
+	service1         = testZipEntry{"META-INF/services/foo", 0755, []byte("a.Impl")}
+	service2         = testZipEntry{"META-INF/services/foo", 0755, []byte("b.Impl\n")}
+	servicesMerged   = testZipEntry{"META-INF/services/foo", 0755, []byte("a.Impl\nb.Impl\n")}
+	properties1      = testZipEntry{"p.properties", 0755, []byte("a=1\nb=2\n")}
+	properties2      = testZipEntry{"p.properties", 0755, []byte("# comment\nb = 2\nc=3 \\\n  4")}
+	properties3      = testZipEntry{"p.properties", 0755, []byte("b: 3\n")}
+	propertiesMerged = testZipEntry{"p.properties", 0755, []byte("a=1\nb=2\nc=3 4\n")}
 )
 
 type testInputZip struct {
@@ -95,6 +104,7 @@ func TestMergeZips(t *testing.T) {
 		ignoreDuplicates bool
 		stripDirEntries  bool
 		zipsToNotStrip   map[string]bool
+		conflicts        []string
 
 		out []testZipEntry
 		err string
@@ -236,10 +246,80 @@ func TestMergeZips(t *testing.T) {
 				"in1": true,
 			},
 		},
+		{
+			name: "conflict first wins",
+			in: [][]testZipEntry{
+				{a, bc},
+				{a2},
+			},
+			out: []testZipEntry{a, bc},
+
+			conflicts: []string{"a=first-wins"},
+		},
+		{
+			name: "conflict last wins",
+			in: [][]testZipEntry{
+				{a, bc},
+				{a2},
+				{a3},
+			},
+			out: []testZipEntry{a3, bc},
+
+			conflicts: []string{"b/*=error", "*=last-wins"},
+		},
+		{
+			name: "conflict error overrides ignore duplicates",
+			in: [][]testZipEntry{
+				{a},
+				{a2},
+			},
+			err: "duplicate",
+
+			ignoreDuplicates: true,
+			conflicts:        []string{"a=error"},
+		},
+		{
+			name: "conflict concat",
+			in: [][]testZipEntry{
+				{service1, a},
+				{service1},
+				{service2},
+			},
+			out: []testZipEntry{servicesMerged, a},
+
+			conflicts: []string{"META-INF/services/*=concat"},
+		},
+		{
+			name: "conflict merge",
+			in: [][]testZipEntry{
+				{properties1},
+				{properties2},
+			},
+			out: []testZipEntry{propertiesMerged},
+
+			conflicts: []string{"**/*.properties=merge"},
+		},
+		{
+			name: "conflict merge different values",
+			in: [][]testZipEntry{
+				{properties1},
+				{properties3},
+			},
+			err: `property "b" is "2" in in0!p.properties and "3" in in1!p.properties`,
+
+			conflicts: []string{"**/*.properties=merge"},
+		},
 	}
 
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
+			var conflicts conflictRules
+			for _, c := range test.conflicts {
+				if err := conflicts.Set(c); err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			inputZips := make([]InputZip, len(test.in))
 			for i, in := range test.in {
 				inputZips[i] = &testInputZip{name: "in" + strconv.Itoa(i), entries: in}
@@ -252,7 +332,7 @@ func TestMergeZips(t *testing.T) {
 
 			err := mergeZips(inputZips, writer, "", "",
 				test.sort, test.jar, false, test.stripDirEntries, test.ignoreDuplicates,
-				test.stripFiles, test.stripDirs, test.zipsToNotStrip)
+				test.stripFiles, test.stripDirs, test.zipsToNotStrip, conflicts, nil)
 
 			closeErr := writer.Close()
 			if closeErr != nil {
@@ -390,3 +470,285 @@ func TestInputZipsManager(t *testing.T) {
 		}
 	})
 }
+
+// zstdTestInputZip is an input zip with a single Zstandard entry.
+type zstdTestInputZip struct {
+	testInputZip
+	compressed []byte
+}
+
+func (ziz *zstdTestInputZip) Open() error {
+	if ziz.reader != nil {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	zw := zip.NewWriter(b)
+	w, err := zw.CreateCompressedHeader(&zip.FileHeader{
+		Name:               ziz.name + ".class",
+		Method:             zip.Zstd,
+		UncompressedSize64: 100,
+	})
+	if err != nil {
+		return err
+	}
+	w.Write(ziz.compressed)
+	w.Close()
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	ziz.reader, err = zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	return err
+}
+
+func TestMergeZipsZstdPassthrough(t *testing.T) {
+	// Not a real Zstandard frame, the contents of the entries are never decompressed.
+	compressed := []byte{0x28, 0xb5, 0x2f, 0xfd, 1, 2, 3}
+	inputZips := []InputZip{
+		&zstdTestInputZip{testInputZip: testInputZip{name: "b"}, compressed: compressed},
+		&zstdTestInputZip{testInputZip: testInputZip{name: "a"}, compressed: compressed},
+	}
+
+	out := &bytes.Buffer{}
+	writer := zip.NewWriter(out)
+	err := mergeZips(inputZips, writer, "", "", true, false, false, false, false, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Method != zip.Zstd || f.CompressedSize64 != uint64(len(compressed)) {
+			t.Errorf("Expected %s to be copied unchanged, got method %d and compressed size %d",
+				f.Name, f.Method, f.CompressedSize64)
+		}
+	}
+	if g, w := strings.Join(names, " "), "a.class b.class"; g != w {
+		t.Errorf("Expected entries %q, got %q", w, g)
+	}
+}
+
+func TestMergeZipsProvenance(t *testing.T) {
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{service1, a, bDir, bc}},
+		&testInputZip{name: "in1", entries: []testZipEntry{service2, a2, bDir}},
+		&testInputZip{name: "in2", entries: []testZipEntry{bc}},
+	}
+
+	var conflicts conflictRules
+	for _, c := range []string{"META-INF/services/*=concat", "a=last-wins"} {
+		if err := conflicts.Set(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provenance := &bytes.Buffer{}
+	writer := zip.NewWriter(&bytes.Buffer{})
+	err := mergeZips(inputZips, writer, "", "", false, false, false, false, false, nil, nil, nil,
+		conflicts, provenance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[
+  {
+    "entry": "META-INF/services/foo",
+    "from": [
+      "in0",
+      "in1"
+    ],
+    "policy": "concat"
+  },
+  {
+    "entry": "a",
+    "from": [
+      "in1"
+    ],
+    "ignored": [
+      "in0"
+    ],
+    "policy": "last-wins"
+  },
+  {
+    "entry": "b/",
+    "from": [
+      "in0"
+    ],
+    "ignored": [
+      "in1"
+    ]
+  },
+  {
+    "entry": "b/c",
+    "from": [
+      "in0"
+    ],
+    "ignored": [
+      "in2"
+    ]
+  }
+]
+`
+	if g := provenance.String(); g != want {
+		t.Errorf("expected provenance:\n%s\ngot:\n%s", want, g)
+	}
+}
+
+func TestParseConflictRule(t *testing.T) {
+	testCases := []struct {
+		rule string
+		want conflictRule
+		err  string
+	}{
+		{
+			rule: "META-INF/services/*=concat",
+			want: conflictRule{"META-INF/services/*", conflictConcat},
+		},
+		{
+			rule: "**/*.properties=merge",
+			want: conflictRule{"**/*.properties", conflictMerge},
+		},
+		{
+			rule: "concat",
+			err:  `conflict rule "concat" must be of the form <glob>=<policy>`,
+		},
+		{
+			rule: "a=newest",
+			err: `conflict rule "a=newest" has unknown policy "newest", expected one of error, ` +
+				`first-wins, last-wins, concat or merge`,
+		},
+		{
+			rule: "[a=concat",
+			err:  `conflict rule "[a=concat" has invalid glob: syntax error in pattern`,
+		},
+		{
+			rule: "a/**=concat",
+			err:  `conflict rule "a/**=concat" has invalid glob: '**' can't be the last path element`,
+		},
+		{
+			rule: "a**/b=concat",
+			err:  `conflict rule "a**/b=concat" has invalid glob: '**' must be a whole path element`,
+		},
+		{
+			rule: "**/a/**/b=concat",
+			err:  `conflict rule "**/a/**/b=concat" has invalid glob: '**' can only be used once`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.rule, func(t *testing.T) {
+			got, err := parseConflictRule(test.rule)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("want error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("want %#v, got %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeProperties(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents []string
+		want     string
+	}{
+		{
+			name:     "key=value",
+			contents: []string{"a=1\nb=2\n", "b=2\nc=3\n"},
+			want:     "a=1\nb=2\nc=3\n",
+		},
+		{
+			name:     "comments and blank lines",
+			contents: []string{"# comment\n\n! comment\na=1\n", "  # indented comment\nb=2"},
+			want:     "a=1\nb=2\n",
+		},
+		{
+			name:     "separators",
+			contents: []string{"a = 1\nb:2\nc 3\nd\t: 4\ne\n", "a=1\nb=2\nc=3\nd=4\ne="},
+			want:     "a=1\nb=2\nc=3\nd=4\ne=\n",
+		},
+		{
+			name:     "continuations",
+			contents: []string{"a=1 \\\n  2\r\nb=3\\\\\nc=4 \\\n\n", "a=1 2\nb=3\\\\\nc=4 "},
+			want:     "a=1 2\nb=3\\\\\nc=4 \n",
+		},
+		{
+			name:     "escapes",
+			contents: []string{"a\\=b=\\ 1\nc\\ d\\u0041=\\t2\n", "a\\=b= \\ 1\ncd A\n"},
+			want:     "a\\=b=\\ 1\nc\\ dA=\\t2\ncd=A\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var sources []ZipEntryContents
+			var contents [][]byte
+			for _, c := range test.contents {
+				sources = append(sources, ZipEntryFromBuffer{content: []byte(c)})
+				contents = append(contents, []byte(c))
+			}
+
+			got, err := mergeProperties(sources, contents)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("want %q, got %q", test.want, string(got))
+			}
+		})
+	}
+}
+
+// countingZipEntry is a ZipEntryFromBuffer that counts how many times its contents are read.
+type countingZipEntry struct {
+	ZipEntryFromBuffer
+	reads *int
+}
+
+func (e countingZipEntry) Contents() ([]byte, error) {
+	*e.reads++
+	return e.ZipEntryFromBuffer.Contents()
+}
+
+func TestZipEntryFromCombinationReadsSourcesOnce(t *testing.T) {
+	reads := 0
+	ce := &ZipEntryFromCombination{
+		policy:  conflictConcat,
+		sources: []ZipEntryContents{countingZipEntry{ZipEntryFromBuffer{content: []byte("a")}, &reads}},
+	}
+	ce.add(countingZipEntry{ZipEntryFromBuffer{content: []byte("b\n")}, &reads})
+
+	if g, w := ce.Size(), uint64(4); g != w {
+		t.Errorf("want size %d, got %d", w, g)
+	}
+	if g, w := ce.CRC32(), crc32.ChecksumIEEE([]byte("a\nb\n")); g != w {
+		t.Errorf("want crc %x, got %x", w, g)
+	}
+	if g, w := reads, 2; g != w {
+		t.Errorf("want %d reads of the sources, got %d", w, g)
+	}
+
+	// Adding a source recombines the contents.
+	ce.add(countingZipEntry{ZipEntryFromBuffer{content: []byte("c\n")}, &reads})
+	if g, w := ce.Size(), uint64(6); g != w {
+		t.Errorf("want size %d, got %d", w, g)
+	}
+	if g, w := reads, 5; g != w {
+		t.Errorf("want %d reads of the sources, got %d", w, g)
+	}
+}
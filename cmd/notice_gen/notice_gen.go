@@ -0,0 +1,276 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// notice_gen writes the aggregated notices of a partition, apex or filesystem from the manifest
+// generated by Soong. Identical license texts are only included once, with the list of the files
+// they apply to.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	input      = flag.String("i", "", "notice manifest generated by Soong")
+	xmlOutput  = flag.String("o", "", "file to write the gzipped NOTICE.xml to")
+	htmlOutput = flag.String("html", "", "file to write the html index of the notices to")
+	title      = flag.String("title", "", "title of the html index")
+)
+
+// manifest is the input generated by Soong, see android/notices.go.
+type manifest struct {
+	Files []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	// The path of the file on the device or in the package.
+	Name string `json:"name"`
+
+	// The paths of the license texts that apply to the file.
+	Texts []string `json:"texts"`
+}
+
+// notice is a license text, with the files it applies to.
+type notice struct {
+	id    string
+	text  string
+	files []string
+}
+
+func main() {
+	flag.Parse()
+
+	if *input == "" || (*xmlOutput == "" && *htmlOutput == "") || flag.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: notice_gen -i manifest.json [-o NOTICE.xml.gz] [-html NOTICE.html] [-title title]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %s", *input, err)
+	}
+
+	notices, err := collectNotices(&m, ioutil.ReadFile)
+	if err != nil {
+		return err
+	}
+
+	if *xmlOutput != "" {
+		buf := &bytes.Buffer{}
+		gz, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		if err != nil {
+			return err
+		}
+		if err := writeXml(gz, notices); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*xmlOutput, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+
+	if *htmlOutput != "" {
+		buf := &bytes.Buffer{}
+		if err := writeHtml(buf, *title, notices); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*htmlOutput, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collectNotices reads the license texts of the files in the manifest and returns the distinct
+// texts, each with the sorted list of files it applies to. The notices are sorted by the first file
+// they apply to, then by text.
+func collectNotices(m *manifest, readFile func(string) ([]byte, error)) ([]*notice, error) {
+	// The id of the text of each path, to read files shared by many modules only once.
+	idsByPath := make(map[string]string)
+	byID := make(map[string]*notice)
+
+	for _, f := range m.Files {
+		seen := make(map[string]bool)
+		for _, path := range f.Texts {
+			id, ok := idsByPath[path]
+			if !ok {
+				data, err := readFile(path)
+				if err != nil {
+					return nil, err
+				}
+				hash := md5.Sum(data)
+				id = hex.EncodeToString(hash[:])
+				idsByPath[path] = id
+				if _, exists := byID[id]; !exists {
+					byID[id] = &notice{id: id, text: string(data)}
+				}
+			}
+			if !seen[id] {
+				seen[id] = true
+				byID[id].files = append(byID[id].files, f.Name)
+			}
+		}
+	}
+
+	var notices []*notice
+	for _, n := range byID {
+		if len(n.files) == 0 {
+			continue
+		}
+		sort.Strings(n.files)
+		n.files = uniqueStrings(n.files)
+		notices = append(notices, n)
+	}
+	sort.Slice(notices, func(i, j int) bool {
+		if notices[i].files[0] != notices[j].files[0] {
+			return notices[i].files[0] < notices[j].files[0]
+		}
+		return notices[i].text < notices[j].text
+	})
+	return notices, nil
+}
+
+// uniqueStrings removes the duplicates from a sorted list.
+func uniqueStrings(list []string) []string {
+	ret := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// writeXml writes the notices in the NOTICE.xml format read by the settings app: a file-name
+// element for every file that refers by contentId to the file-content element of its license text.
+func writeXml(w io.Writer, notices []*notice) error {
+	ew := &errWriter{w: w}
+	ew.printf("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+	ew.printf("<licenses>\n")
+
+	type fileName struct{ name, id string }
+	var fileNames []fileName
+	for _, n := range notices {
+		for _, f := range n.files {
+			fileNames = append(fileNames, fileName{f, n.id})
+		}
+	}
+	sort.SliceStable(fileNames, func(i, j int) bool { return fileNames[i].name < fileNames[j].name })
+	for _, f := range fileNames {
+		ew.printf("<file-name contentId=\"%s\">%s</file-name>\n", f.id, html.EscapeString(f.name))
+	}
+
+	for _, n := range notices {
+		// "]]>" can't appear in a CDATA section, split it across two sections.
+		text := strings.ReplaceAll(n.text, "]]>", "]]]]><![CDATA[>")
+		ew.printf("<file-content contentId=\"%s\"><![CDATA[%s]]></file-content>\n", n.id, text)
+	}
+
+	ew.printf("</licenses>\n")
+	return ew.err
+}
+
+const htmlStyle = `body { padding: 0; font-family: sans-serif; }
+.same-license { background-color: #eeeeee; border-top: 20px solid white; padding: 10px; }
+.label { font-weight: bold; }
+.file-list { margin-left: 1em; color: blue; }`
+
+// writeHtml writes an html index of the notices: a table of contents of all the files linking to
+// their license texts, followed by each license text with the list of files it applies to.
+func writeHtml(w io.Writer, title string, notices []*notice) error {
+	ew := &errWriter{w: w}
+	ew.printf("<html><head>\n")
+	if title != "" {
+		ew.printf("<title>%s</title>\n", html.EscapeString(title))
+	}
+	ew.printf("<style type=\"text/css\">\n%s\n</style>\n", htmlStyle)
+	ew.printf("</head>\n<body topmargin=\"0\" leftmargin=\"0\" rightmargin=\"0\" bottommargin=\"0\">\n")
+	if title != "" {
+		ew.printf("<h1>%s</h1>\n", html.EscapeString(title))
+	}
+
+	type tocEntry struct {
+		name   string
+		anchor int
+	}
+	var toc []tocEntry
+	for i, n := range notices {
+		for _, f := range n.files {
+			toc = append(toc, tocEntry{f, i})
+		}
+	}
+	sort.SliceStable(toc, func(i, j int) bool { return toc[i].name < toc[j].name })
+
+	ew.printf("<div class=\"toc\">\n<ul>\n")
+	for _, e := range toc {
+		ew.printf("<li><a href=\"#id%d\">%s</a></li>\n", e.anchor, html.EscapeString(e.name))
+	}
+	ew.printf("</ul>\n</div><!-- table of contents -->\n")
+
+	ew.printf("<table cellpadding=\"0\" cellspacing=\"0\" border=\"0\">\n")
+	for i, n := range notices {
+		ew.printf("<tr id=\"id%d\"><td class=\"same-license\">\n", i)
+		ew.printf("<div class=\"label\">Notices for file(s):</div>\n<div class=\"file-list\">\n")
+		for _, f := range n.files {
+			ew.printf("%s <br/>\n", html.EscapeString(f))
+		}
+		ew.printf("</div><!-- file-list -->\n")
+		ew.printf("<pre class=\"license-text\">\n%s</pre><!-- license-text -->\n", html.EscapeString(n.text))
+		ew.printf("</td></tr><!-- same-license -->\n")
+	}
+	ew.printf("</table>\n</body></html>\n")
+
+	return ew.err
+}
+
+// errWriter writes formatted output, keeping the first error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
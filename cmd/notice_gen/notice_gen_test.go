@@ -0,0 +1,126 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTexts = map[string]string{
+	"external/foo/LICENSE": "Apache License",
+	"external/bar/NOTICE":  "Apache License",
+	"external/baz/COPYING": "MIT License ]]> <b>",
+}
+
+func testReadFile(path string) ([]byte, error) {
+	if text, ok := testTexts[path]; ok {
+		return []byte(text), nil
+	}
+	return nil, fmt.Errorf("open %s: %w", path, os.ErrNotExist)
+}
+
+func testManifest() *manifest {
+	return &manifest{
+		Files: []manifestFile{
+			{Name: "/system/lib/libfoo.so", Texts: []string{"external/foo/LICENSE"}},
+			{Name: "/system/bin/bar", Texts: []string{"external/bar/NOTICE", "external/baz/COPYING"}},
+			{Name: "/system/lib64/libfoo.so", Texts: []string{"external/foo/LICENSE", "external/bar/NOTICE"}},
+			{Name: "/system/bin/unlicensed"},
+		},
+	}
+}
+
+func TestCollectNotices(t *testing.T) {
+	notices, err := collectNotices(testManifest(), testReadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		text  string
+		files []string
+	}
+	var got []result
+	for _, n := range notices {
+		got = append(got, result{n.text, n.files})
+	}
+	expected := []result{
+		{"Apache License", []string{"/system/bin/bar", "/system/lib/libfoo.so", "/system/lib64/libfoo.so"}},
+		{"MIT License ]]> <b>", []string{"/system/bin/bar"}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected notices %q, got %q", expected, got)
+	}
+
+	m := &manifest{Files: []manifestFile{{Name: "/system/bin/missing", Texts: []string{"missing/LICENSE"}}}}
+	if _, err := collectNotices(m, testReadFile); err == nil {
+		t.Errorf("expected an error for a missing license text")
+	}
+}
+
+func TestWriteXml(t *testing.T) {
+	notices := []*notice{
+		{id: "a1", text: "Apache License", files: []string{"/system/bin/bar", "/system/lib/libfoo.so"}},
+		{id: "b2", text: "MIT License ]]> <b>", files: []string{"/system/bin/&bar"}},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeXml(buf, notices); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="utf-8"?>
+<licenses>
+<file-name contentId="b2">/system/bin/&amp;bar</file-name>
+<file-name contentId="a1">/system/bin/bar</file-name>
+<file-name contentId="a1">/system/lib/libfoo.so</file-name>
+<file-content contentId="a1"><![CDATA[Apache License]]></file-content>
+<file-content contentId="b2"><![CDATA[MIT License ]]]]><![CDATA[> <b>]]></file-content>
+</licenses>
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("expected NOTICE.xml:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestWriteHtml(t *testing.T) {
+	notices := []*notice{
+		{id: "a1", text: "Apache License", files: []string{"/system/bin/bar", "/system/lib/libfoo.so"}},
+		{id: "b2", text: "MIT License <b>", files: []string{"/system/bin/bar"}},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeHtml(buf, "Notices for system", notices); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	for _, s := range []string{
+		"<title>Notices for system</title>",
+		`<li><a href="#id0">/system/bin/bar</a></li>` + "\n" +
+			`<li><a href="#id1">/system/bin/bar</a></li>` + "\n" +
+			`<li><a href="#id0">/system/lib/libfoo.so</a></li>`,
+		`<tr id="id0">`,
+		"/system/bin/bar <br/>\n/system/lib/libfoo.so <br/>",
+		"<pre class=\"license-text\">\nMIT License &lt;b&gt;</pre>",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected the html index to contain %q, got:\n%s", s, got)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sbom_gen writes the SPDX software bill of materials of a partition from the manifest generated by
+// the sbom singleton in Soong, computing the checksums of the installed files.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	input      = flag.String("i", "", "SBOM manifest generated by Soong")
+	output     = flag.String("o", "", "file to write the SPDX document to in the tag-value format")
+	jsonOutput = flag.String("json", "", "file to write the SPDX document to in the JSON format")
+	created    = flag.String("created", "", "creation time of the document in RFC 3339 format, "+
+		"defaults to $SOURCE_DATE_EPOCH or the current time")
+)
+
+// manifest is the input generated by the sbom singleton in Soong, see android/sbom.go.
+type manifest struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Packages  []manifestPackage `json:"packages"`
+	Licenses  []manifestLicense `json:"licenses"`
+}
+
+type manifestPackage struct {
+	Name              string         `json:"name"`
+	ID                string         `json:"id"`
+	LicenseDeclared   string         `json:"license_declared"`
+	LicenseConditions []string       `json:"license_conditions"`
+	Files             []manifestFile `json:"files"`
+	StaticLinkDeps    []string       `json:"static_link_deps"`
+}
+
+type manifestFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type manifestLicense struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Conditions []string `json:"conditions"`
+}
+
+func main() {
+	flag.Parse()
+
+	if *input == "" || (*output == "" && *jsonOutput == "") || flag.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: sbom_gen -i manifest.json [-o sbom.spdx] [-json sbom.spdx.json] [-created time]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %s", *input, err)
+	}
+
+	createdTime, err := creationTime(*created, os.Getenv("SOURCE_DATE_EPOCH"))
+	if err != nil {
+		return err
+	}
+
+	checksums, err := hashFiles(&m)
+	if err != nil {
+		return err
+	}
+
+	doc := buildDocument(&m, checksums, createdTime)
+
+	if *output != "" {
+		buf := &bytes.Buffer{}
+		if err := doc.WriteTagValue(buf); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*output, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+
+	if *jsonOutput != "" {
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*jsonOutput, append(out, '\n'), 0666); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// creationTime returns the creation time of the document from the -created flag or the
+// SOURCE_DATE_EPOCH environment variable, or the current time if neither is set.
+func creationTime(flagValue, sourceDateEpoch string) (time.Time, error) {
+	if flagValue != "" {
+		t, err := time.Parse(time.RFC3339, flagValue)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid -created %q: %s", flagValue, err)
+		}
+		return t, nil
+	}
+	if sourceDateEpoch != "" {
+		secs, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %s", sourceDateEpoch, err)
+		}
+		return time.Unix(secs, 0), nil
+	}
+	return time.Now(), nil
+}
+
+// fileChecksums are the checksums of an installed file.
+type fileChecksums struct {
+	sha1   string
+	sha256 string
+}
+
+// hashFiles computes the checksums of all the files in the manifest in parallel, and returns them
+// by file id.
+func hashFiles(m *manifest) (map[string]fileChecksums, error) {
+	var files []manifestFile
+	for _, p := range m.Packages {
+		files = append(files, p.Files...)
+	}
+
+	checksums := make(map[string]fileChecksums, len(files))
+	var firstErr error
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	ch := make(chan manifestFile)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range ch {
+				c, err := hashFile(f.Path)
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				checksums[f.ID] = c
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, f := range files {
+		ch <- f
+	}
+	close(ch)
+	wg.Wait()
+
+	return checksums, firstErr
+}
+
+// hashFile returns the checksums of a file, or of the target of a symlink as the target may only
+// exist on the device.
+func hashFile(path string) (fileChecksums, error) {
+	sha1Hash, sha256Hash := sha1.New(), sha256.New()
+	w := io.MultiWriter(sha1Hash, sha256Hash)
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return fileChecksums{}, err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(path)
+		if err != nil {
+			return fileChecksums{}, err
+		}
+		io.WriteString(w, dest)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return fileChecksums{}, err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return fileChecksums{}, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	return fileChecksums{
+		sha1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		sha256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// buildDocument returns the SPDX document for the manifest.
+func buildDocument(m *manifest, checksums map[string]fileChecksums, created time.Time) *Document {
+	doc := &Document{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       dataLicense,
+		SPDXID:            documentID,
+		Name:              m.Name,
+		DocumentNamespace: m.Namespace,
+		CreationInfo: CreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{creatorTool},
+		},
+	}
+
+	for _, mp := range m.Packages {
+		p := Package{
+			SPDXID:           mp.ID,
+			Name:             mp.Name,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  mp.LicenseDeclared,
+			CopyrightText:    noAssertion,
+		}
+		if len(mp.LicenseConditions) > 0 {
+			p.Comment = "License conditions: " + strings.Join(mp.LicenseConditions, ", ")
+		}
+
+		// Packages that are only linked into other packages have no files of their own.
+		if len(mp.Files) > 0 {
+			doc.Relationships = append(doc.Relationships, Relationship{documentID, describes, mp.ID})
+
+			p.FilesAnalyzed = true
+			p.LicenseInfoFromFiles = []string{noAssertion}
+			var fileSHA1s []string
+			for _, mf := range mp.Files {
+				c := checksums[mf.ID]
+				doc.Files = append(doc.Files, File{
+					SPDXID:   mf.ID,
+					FileName: mf.Name,
+					Checksums: []Checksum{
+						{checksumSHA1, c.sha1},
+						{checksumSHA256, c.sha256},
+					},
+					LicenseConcluded:   noAssertion,
+					LicenseInfoInFiles: []string{noAssertion},
+					CopyrightText:      noAssertion,
+				})
+				p.HasFiles = append(p.HasFiles, mf.ID)
+				fileSHA1s = append(fileSHA1s, c.sha1)
+				doc.Relationships = append(doc.Relationships, Relationship{mp.ID, contains, mf.ID})
+			}
+			p.PackageVerificationCode = &VerificationCode{verificationCode(fileSHA1s)}
+		}
+
+		for _, dep := range mp.StaticLinkDeps {
+			doc.Relationships = append(doc.Relationships, Relationship{mp.ID, staticLink, dep})
+		}
+
+		doc.Packages = append(doc.Packages, p)
+	}
+
+	for _, ml := range m.Licenses {
+		l := ExtractedLicense{
+			LicenseID: ml.ID,
+			Name:      ml.Name,
+		}
+		if ml.URL != "" {
+			l.ExtractedText = "See " + ml.URL
+			l.SeeAlsos = []string{ml.URL}
+		} else {
+			l.ExtractedText = "License kind " + ml.Name
+		}
+		if len(ml.Conditions) > 0 {
+			l.Comment = "License conditions: " + strings.Join(ml.Conditions, ", ")
+		}
+		doc.HasExtractedLicensingInfos = append(doc.HasExtractedLicensingInfos, l)
+	}
+
+	return doc
+}
+
+// verificationCode returns the package verification code, the SHA1 of the sorted SHA1s of the files
+// of the package.
+func verificationCode(fileSHA1s []string) string {
+	sorted := append([]string(nil), fileSHA1s...)
+	sort.Strings(sorted)
+	h := sha1.Sum([]byte(strings.Join(sorted, "")))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	// The checksums of "foo".
+	fooSHA1   = "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"
+	fooSHA256 = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+)
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("foo"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	// A symlink to a target that only exists on the device.
+	symlink := filepath.Join(dir, "symlink")
+	if err := os.Symlink("foo", symlink); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{file, symlink} {
+		c, err := hashFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.sha1 != fooSHA1 || c.sha256 != fooSHA256 {
+			t.Errorf("%s: expected checksums %s and %s, got %s and %s",
+				filepath.Base(path), fooSHA1, fooSHA256, c.sha1, c.sha256)
+		}
+	}
+
+	if _, err := hashFile(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestCreationTime(t *testing.T) {
+	testCases := []struct {
+		flag, sourceDateEpoch string
+		expected              string
+		err                   bool
+	}{
+		{flag: "2021-06-01T12:00:00Z", sourceDateEpoch: "0", expected: "2021-06-01T12:00:00Z"},
+		{sourceDateEpoch: "1622548800", expected: "2021-06-01T12:00:00Z"},
+		{flag: "yesterday", err: true},
+		{sourceDateEpoch: "yesterday", err: true},
+	}
+	for _, tc := range testCases {
+		created, err := creationTime(tc.flag, tc.sourceDateEpoch)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q, %q: expected an error", tc.flag, tc.sourceDateEpoch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q, %q: unexpected error %s", tc.flag, tc.sourceDateEpoch, err)
+		} else if got := created.UTC().Format(time.RFC3339); got != tc.expected {
+			t.Errorf("%q, %q: expected %s, got %s", tc.flag, tc.sourceDateEpoch, tc.expected, got)
+		}
+	}
+}
+
+func TestBuildDocument(t *testing.T) {
+	m := &manifest{
+		Name:      "generic-system",
+		Namespace: "https://android.com/sbom/generic/1/system",
+		Packages: []manifestPackage{
+			{
+				Name:              "bin",
+				ID:                "SPDXRef-Package-bin",
+				LicenseDeclared:   "(Apache-2.0 AND LicenseRef-legacy-notice)",
+				LicenseConditions: []string{"notice"},
+				Files: []manifestFile{
+					{ID: "SPDXRef-File-bin-1", Name: "./system/bin/bin", Path: "out/bin"},
+				},
+				StaticLinkDeps: []string{"SPDXRef-Package-libstatic"},
+			},
+			{
+				Name:            "libstatic",
+				ID:              "SPDXRef-Package-libstatic",
+				LicenseDeclared: "NOASSERTION",
+			},
+		},
+		Licenses: []manifestLicense{
+			{
+				ID:         "LicenseRef-legacy-notice",
+				Name:       "legacy_notice",
+				URL:        "https://example.com/license",
+				Conditions: []string{"notice"},
+			},
+		},
+	}
+	checksums := map[string]fileChecksums{
+		"SPDXRef-File-bin-1": {sha1: fooSHA1, sha256: fooSHA256},
+	}
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	doc := buildDocument(m, checksums, created)
+	buf := &bytes.Buffer{}
+	if err := doc.WriteTagValue(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `SPDXVersion: SPDX-2.2
+DataLicense: CC0-1.0
+SPDXID: SPDXRef-DOCUMENT
+DocumentName: generic-system
+DocumentNamespace: https://android.com/sbom/generic/1/system
+Creator: Tool: sbom_gen
+Created: 2021-06-01T12:00:00Z
+
+PackageName: bin
+SPDXID: SPDXRef-Package-bin
+PackageDownloadLocation: NOASSERTION
+FilesAnalyzed: true
+PackageVerificationCode: ` + verificationCode([]string{fooSHA1}) + `
+PackageLicenseConcluded: NOASSERTION
+PackageLicenseInfoFromFiles: NOASSERTION
+PackageLicenseDeclared: (Apache-2.0 AND LicenseRef-legacy-notice)
+PackageCopyrightText: NOASSERTION
+PackageComment: <text>License conditions: notice</text>
+
+FileName: ./system/bin/bin
+SPDXID: SPDXRef-File-bin-1
+FileChecksum: SHA1: ` + fooSHA1 + `
+FileChecksum: SHA256: ` + fooSHA256 + `
+LicenseConcluded: NOASSERTION
+LicenseInfoInFile: NOASSERTION
+FileCopyrightText: NOASSERTION
+
+PackageName: libstatic
+SPDXID: SPDXRef-Package-libstatic
+PackageDownloadLocation: NOASSERTION
+FilesAnalyzed: false
+PackageLicenseConcluded: NOASSERTION
+PackageLicenseDeclared: NOASSERTION
+PackageCopyrightText: NOASSERTION
+
+Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-bin
+Relationship: SPDXRef-Package-bin CONTAINS SPDXRef-File-bin-1
+Relationship: SPDXRef-Package-bin STATIC_LINK SPDXRef-Package-libstatic
+
+LicenseID: LicenseRef-legacy-notice
+ExtractedText: <text>See https://example.com/license</text>
+LicenseName: legacy_notice
+LicenseCrossReference: https://example.com/license
+LicenseComment: <text>License conditions: notice</text>
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("expected tag-value document:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestVerificationCode(t *testing.T) {
+	// The verification code doesn't depend on the order of the files.
+	a := verificationCode([]string{fooSHA1, "da39a3ee5e6b4b0d3255bfef95601890afd80709"})
+	b := verificationCode([]string{"da39a3ee5e6b4b0d3255bfef95601890afd80709", fooSHA1})
+	if a != b {
+		t.Errorf("expected the same verification code, got %s and %s", a, b)
+	}
+}
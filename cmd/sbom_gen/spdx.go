@@ -0,0 +1,203 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	spdxVersion    = "SPDX-2.2"
+	noAssertion    = "NOASSERTION"
+	documentID     = "SPDXRef-DOCUMENT"
+	describes      = "DESCRIBES"
+	contains       = "CONTAINS"
+	staticLink     = "STATIC_LINK"
+	dataLicense    = "CC0-1.0"
+	creatorTool    = "Tool: sbom_gen"
+	checksumSHA1   = "SHA1"
+	checksumSHA256 = "SHA256"
+)
+
+// Document is an SPDX document. The field names follow the SPDX 2.2 JSON schema.
+type Document struct {
+	SPDXVersion       string       `json:"spdxVersion"`
+	DataLicense       string       `json:"dataLicense"`
+	SPDXID            string       `json:"SPDXID"`
+	Name              string       `json:"name"`
+	DocumentNamespace string       `json:"documentNamespace"`
+	CreationInfo      CreationInfo `json:"creationInfo"`
+
+	Packages                   []Package          `json:"packages"`
+	Files                      []File             `json:"files,omitempty"`
+	HasExtractedLicensingInfos []ExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+	Relationships              []Relationship     `json:"relationships"`
+}
+
+type CreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type Package struct {
+	SPDXID                  string            `json:"SPDXID"`
+	Name                    string            `json:"name"`
+	DownloadLocation        string            `json:"downloadLocation"`
+	FilesAnalyzed           bool              `json:"filesAnalyzed"`
+	PackageVerificationCode *VerificationCode `json:"packageVerificationCode,omitempty"`
+	LicenseConcluded        string            `json:"licenseConcluded"`
+	LicenseInfoFromFiles    []string          `json:"licenseInfoFromFiles,omitempty"`
+	LicenseDeclared         string            `json:"licenseDeclared"`
+	CopyrightText           string            `json:"copyrightText"`
+	Comment                 string            `json:"comment,omitempty"`
+	HasFiles                []string          `json:"hasFiles,omitempty"`
+}
+
+type VerificationCode struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+type File struct {
+	SPDXID             string     `json:"SPDXID"`
+	FileName           string     `json:"fileName"`
+	Checksums          []Checksum `json:"checksums"`
+	LicenseConcluded   string     `json:"licenseConcluded"`
+	LicenseInfoInFiles []string   `json:"licenseInfoInFiles"`
+	CopyrightText      string     `json:"copyrightText"`
+}
+
+type Checksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+// ExtractedLicense declares a LicenseRef that is used in the license expressions of the document.
+type ExtractedLicense struct {
+	LicenseID     string   `json:"licenseId"`
+	ExtractedText string   `json:"extractedText"`
+	Name          string   `json:"name"`
+	SeeAlsos      []string `json:"seeAlsos,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+}
+
+type Relationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// WriteTagValue writes the document in the SPDX tag-value format. The files of each package are
+// written after the package.
+func (d *Document) WriteTagValue(w io.Writer) error {
+	tw := &tagValueWriter{w: w}
+
+	tw.tag("SPDXVersion", d.SPDXVersion)
+	tw.tag("DataLicense", d.DataLicense)
+	tw.tag("SPDXID", d.SPDXID)
+	tw.tag("DocumentName", d.Name)
+	tw.tag("DocumentNamespace", d.DocumentNamespace)
+	for _, creator := range d.CreationInfo.Creators {
+		tw.tag("Creator", creator)
+	}
+	tw.tag("Created", d.CreationInfo.Created)
+
+	files := make(map[string]File, len(d.Files))
+	for _, f := range d.Files {
+		files[f.SPDXID] = f
+	}
+
+	for _, p := range d.Packages {
+		tw.section()
+		tw.tag("PackageName", p.Name)
+		tw.tag("SPDXID", p.SPDXID)
+		tw.tag("PackageDownloadLocation", p.DownloadLocation)
+		tw.tag("FilesAnalyzed", fmt.Sprint(p.FilesAnalyzed))
+		if p.PackageVerificationCode != nil {
+			tw.tag("PackageVerificationCode", p.PackageVerificationCode.Value)
+		}
+		tw.tag("PackageLicenseConcluded", p.LicenseConcluded)
+		for _, l := range p.LicenseInfoFromFiles {
+			tw.tag("PackageLicenseInfoFromFiles", l)
+		}
+		tw.tag("PackageLicenseDeclared", p.LicenseDeclared)
+		tw.tag("PackageCopyrightText", p.CopyrightText)
+		if p.Comment != "" {
+			tw.text("PackageComment", p.Comment)
+		}
+
+		for _, id := range p.HasFiles {
+			f := files[id]
+			tw.section()
+			tw.tag("FileName", f.FileName)
+			tw.tag("SPDXID", f.SPDXID)
+			for _, c := range f.Checksums {
+				tw.tag("FileChecksum", c.Algorithm+": "+c.Value)
+			}
+			tw.tag("LicenseConcluded", f.LicenseConcluded)
+			for _, l := range f.LicenseInfoInFiles {
+				tw.tag("LicenseInfoInFile", l)
+			}
+			tw.tag("FileCopyrightText", f.CopyrightText)
+		}
+	}
+
+	if len(d.Relationships) > 0 {
+		tw.section()
+	}
+	for _, r := range d.Relationships {
+		tw.tag("Relationship", r.Element+" "+r.Type+" "+r.Related)
+	}
+
+	for _, l := range d.HasExtractedLicensingInfos {
+		tw.section()
+		tw.tag("LicenseID", l.LicenseID)
+		tw.text("ExtractedText", l.ExtractedText)
+		tw.tag("LicenseName", l.Name)
+		for _, url := range l.SeeAlsos {
+			tw.tag("LicenseCrossReference", url)
+		}
+		if l.Comment != "" {
+			tw.text("LicenseComment", l.Comment)
+		}
+	}
+
+	return tw.err
+}
+
+// tagValueWriter writes the tags of the SPDX tag-value format, keeping the first error.
+type tagValueWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (tw *tagValueWriter) tag(tag, value string) {
+	if tw.err == nil {
+		_, tw.err = fmt.Fprintf(tw.w, "%s: %s\n", tag, value)
+	}
+}
+
+// text writes a tag whose value may span multiple lines.
+func (tw *tagValueWriter) text(tag, value string) {
+	tw.tag(tag, "<text>"+strings.ReplaceAll(value, "</text>", "&lt;/text&gt;")+"</text>")
+}
+
+// section writes the blank line that separates the sections of the document.
+func (tw *tagValueWriter) section() {
+	if tw.err == nil {
+		_, tw.err = fmt.Fprintln(tw.w)
+	}
+}
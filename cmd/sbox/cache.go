@@ -0,0 +1,499 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+	"android/soong/makedeps"
+	"android/soong/response"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The action cache stores the outputs of sbox manifests in a local directory, keyed by a hash of
+// everything that can affect the outputs that sbox knows about: the manifest itself, the
+// environment, the contents of the inputs copied into the sandbox and the contents of any files
+// referenced by absolute paths on the command lines.  Only manifests whose commands all run in
+// the sandbox with their inputs copied in are cached, see cacheable.  The commands may still
+// read inputs that were not copied in through paths outside the sandbox, the files listed in
+// the output depfile are recorded in the cache entry and verified before it is used.
+//
+// The cache directory contains:
+//   entries/<key[:2]>/<key>/entry.json    the description of a cached action
+//   entries/<key[:2]>/<key>/outputs/<n>   the contents of the nth output of the action
+//   tmp/                                  entries that are being written
+//   last_trim                             touched every time the cache is trimmed
+//
+// Entries are written to tmp and then renamed into place so that concurrent sbox processes never
+// see partially written entries.  The modification time of entry.json is updated on every hit,
+// when the total size of the entries exceeds the maximum size the least recently used entries are
+// removed.
+
+const (
+	// cacheVersion is included in every key, it must be incremented whenever the format of the
+	// cache or the way keys are computed changes.
+	cacheVersion = 2
+
+	defaultCacheMaxSize = 10 * 1024 * 1024 * 1024
+
+	// The cache is trimmed at most once per trimInterval, to avoid every action walking the
+	// whole cache.
+	trimInterval = time.Minute
+
+	// When the cache is trimmed entries are removed until it is below this fraction of the
+	// maximum size.
+	trimTarget = 0.9
+)
+
+// Environment variables that configure the cache, they are not included in the key.
+const (
+	cacheDirEnv     = "SBOX_CACHE_DIR"
+	cacheMaxSizeEnv = "SBOX_CACHE_MAX_SIZE"
+	cacheStatsEnv   = "SBOX_CACHE_STATS"
+)
+
+type actionCache struct {
+	dir     string
+	maxSize int64
+
+	// statsFile is a file that a line is appended to for every lookup, see writeStats.
+	statsFile string
+
+	// fileHashes memoizes the hashes of files, as the same file may be referenced many times
+	// by a manifest.
+	fileHashes map[string]string
+}
+
+// cacheEntry is the contents of entry.json.
+type cacheEntry struct {
+	Outputs []cacheOutput
+
+	// Depfile is the contents of the output depfile of the manifest, if it has one.
+	Depfile *string `json:",omitempty"`
+
+	// DepfileInputs are the hashes of the files listed in the depfile that are outside the
+	// sandbox when the entry was written.
+	DepfileInputs []cacheInput `json:",omitempty"`
+
+	// Stdout is the combined stdout and stderr of the commands.
+	Stdout []byte `json:",omitempty"`
+
+	// Size is the total size of the outputs, depfile and stdout.
+	Size int64
+}
+
+type cacheOutput struct {
+	Path string
+	Mode os.FileMode
+}
+
+type cacheInput struct {
+	Path string
+	Hash string
+}
+
+func newActionCache(dir string, maxSize int64, statsFile string) *actionCache {
+	return &actionCache{
+		dir:        dir,
+		maxSize:    maxSize,
+		statsFile:  statsFile,
+		fileHashes: make(map[string]string),
+	}
+}
+
+// cacheable returns true if the outputs of a manifest can be cached.  The key can only cover the
+// inputs of commands that run in the sandbox with their inputs copied in (RuleBuilder's
+// SandboxInputs), other commands may read any file through directories, globs or paths
+// computed by the command.
+func cacheable(manifest *sbox_proto.Manifest) bool {
+	for _, command := range manifest.Commands {
+		if !command.GetChdir() {
+			return false
+		}
+	}
+	return len(manifest.Commands) > 0
+}
+
+// key returns the cache key for a manifest.  environ is the environment the commands will be run
+// with, and sandboxesRoot is the directory the sandboxes are created in.
+func (c *actionCache) key(manifest *sbox_proto.Manifest, environ []string, sandboxesRoot string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version %d\n", cacheVersion)
+	fmt.Fprintf(h, "manifest %q\n", proto.MarshalTextString(manifest))
+
+	env := append([]string(nil), environ...)
+	sort.Strings(env)
+	for _, e := range env {
+		if strings.HasPrefix(e, "SBOX_CACHE_") {
+			continue
+		}
+		fmt.Fprintf(h, "env %q\n", e)
+	}
+
+	for _, command := range manifest.Commands {
+		for _, copyPair := range command.CopyBefore {
+			if err := c.hashFile(h, "input", copyPair.GetFrom()); err != nil {
+				return "", err
+			}
+		}
+
+		for _, rspFile := range command.RspFiles {
+			if err := c.hashFile(h, "rsp", rspFile.GetFile()); err != nil {
+				return "", err
+			}
+			files, err := readRspFile(rspFile.GetFile())
+			if err != nil {
+				return "", err
+			}
+			for _, file := range files {
+				if err := c.hashFile(h, "input", file); err != nil {
+					return "", err
+				}
+			}
+		}
+
+		for _, file := range commandFileReferences(command.GetCommand(), sandboxesRoot) {
+			if err := c.hashFile(h, "file", file); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *actionCache) hashFile(w io.Writer, kind, file string) error {
+	hash, err := c.fileHash(file)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %q %s\n", kind, file, hash)
+	return err
+}
+
+func (c *actionCache) fileHash(file string) (string, error) {
+	if hash, ok := c.fileHashes[file]; ok {
+		return hash, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	c.fileHashes[file] = hash
+	return hash, nil
+}
+
+func readRspFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return response.ReadRspFile(f)
+}
+
+// commandFileReferences returns the absolute paths of the regular files outside the sandbox that
+// are referenced by a command line.  The commands run in the sandbox, relative paths refer to the
+// copied inputs, which are already part of the key.
+func commandFileReferences(command, sandboxesRoot string) []string {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return strings.ContainsRune(" \t\n'\";|&()<>=,", r)
+	})
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, word := range words {
+		if seen[word] || !filepath.IsAbs(word) || strings.Contains(word, sandboxDirPlaceholder) {
+			continue
+		}
+		seen[word] = true
+		if sandboxesRoot != "" && strings.HasPrefix(filepath.Clean(word), filepath.Clean(sandboxesRoot)) {
+			continue
+		}
+		if info, err := os.Stat(word); err == nil && info.Mode().IsRegular() {
+			files = append(files, word)
+		}
+	}
+	return files
+}
+
+func (c *actionCache) entryDir(key string) string {
+	return filepath.Join(c.dir, "entries", key[:2], key)
+}
+
+// restore copies the outputs of a cached action to their locations and writes the output depfile.
+// It returns false if there is no usable entry for the key.
+func (c *actionCache) restore(key string, manifest *sbox_proto.Manifest, stdout io.Writer) (bool, error) {
+	dir := c.entryDir(key)
+	entryFile := filepath.Join(dir, "entry.json")
+
+	data, err := ioutil.ReadFile(entryFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false, fmt.Errorf("failed to parse %q: %w", entryFile, err)
+	}
+
+	// Inputs that were discovered through the depfile are not part of the key, verify that
+	// they have not changed.
+	for _, input := range entry.DepfileInputs {
+		hash, err := c.fileHash(input.Path)
+		if err != nil || hash != input.Hash {
+			return false, nil
+		}
+	}
+
+	now := time.Now()
+	for i, output := range entry.Outputs {
+		from := filepath.Join(dir, "outputs", strconv.Itoa(i))
+		if err := copyOneFile(from, output.Path, false, false); err != nil {
+			return false, err
+		}
+		if err := os.Chmod(output.Path, output.Mode); err != nil {
+			return false, err
+		}
+		if err := os.Chtimes(output.Path, now, now); err != nil {
+			return false, err
+		}
+	}
+
+	if entry.Depfile != nil {
+		outputDepFile := manifest.GetOutputDepfile()
+		if err := os.MkdirAll(filepath.Dir(outputDepFile), 0777); err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(outputDepFile, []byte(*entry.Depfile), 0666); err != nil {
+			return false, err
+		}
+	}
+
+	stdout.Write(entry.Stdout)
+
+	// Mark the entry as recently used.
+	os.Chtimes(entryFile, now, now)
+
+	c.writeStats(fmt.Sprintf("hit %d", entry.Size))
+
+	return true, nil
+}
+
+// store adds the outputs of an action that was just run to the cache.
+func (c *actionCache) store(key string, manifest *sbox_proto.Manifest, stdout []byte, sandboxesRoot string) error {
+	tmpRoot := filepath.Join(c.dir, "tmp")
+	if err := os.MkdirAll(tmpRoot, 0777); err != nil {
+		return err
+	}
+	tmpDir, err := ioutil.TempDir(tmpRoot, key[:8])
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	entry := cacheEntry{
+		Stdout: stdout,
+	}
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "outputs"), 0777); err != nil {
+		return err
+	}
+	for _, command := range manifest.Commands {
+		for _, copyPair := range command.CopyAfter {
+			path := joinPath("", copyPair.GetTo())
+			to := filepath.Join(tmpDir, "outputs", strconv.Itoa(len(entry.Outputs)))
+			if err := copyOneFile(path, to, false, false); err != nil {
+				return err
+			}
+			info, err := os.Stat(to)
+			if err != nil {
+				return err
+			}
+			entry.Outputs = append(entry.Outputs, cacheOutput{Path: path, Mode: info.Mode()})
+			entry.Size += info.Size()
+		}
+	}
+
+	if outputDepFile := manifest.GetOutputDepfile(); outputDepFile != "" {
+		data, err := ioutil.ReadFile(outputDepFile)
+		if err != nil {
+			return err
+		}
+		depfile := string(data)
+		entry.Depfile = &depfile
+		entry.Size += int64(len(data))
+
+		deps, err := makedeps.Parse(outputDepFile, bytes.NewBuffer(data))
+		if err != nil {
+			return err
+		}
+		for _, input := range deps.Inputs {
+			if sandboxesRoot != "" && strings.HasPrefix(filepath.Clean(input), filepath.Clean(sandboxesRoot)) {
+				continue
+			}
+			if info, err := os.Stat(input); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			hash, err := c.fileHash(input)
+			if err != nil {
+				return err
+			}
+			entry.DepfileInputs = append(entry.DepfileInputs, cacheInput{input, hash})
+		}
+	}
+	entry.Size += int64(len(stdout))
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "entry.json"), data, 0666); err != nil {
+		return err
+	}
+
+	dir := c.entryDir(key)
+	if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+		return err
+	}
+	// Replace any existing entry, it may have been rejected because its depfile inputs changed.
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return err
+	}
+
+	return c.maybeTrim()
+}
+
+// maybeTrim trims the cache if it has not been trimmed in the last trimInterval.
+func (c *actionCache) maybeTrim() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	lastTrim := filepath.Join(c.dir, "last_trim")
+	if info, err := os.Stat(lastTrim); err == nil && time.Since(info.ModTime()) < trimInterval {
+		return nil
+	}
+	if err := ioutil.WriteFile(lastTrim, nil, 0666); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(lastTrim, now, now); err != nil {
+		return err
+	}
+
+	return c.trim()
+}
+
+// trim removes the least recently used entries until the total size of the cache is below
+// trimTarget of the maximum size.
+func (c *actionCache) trim() error {
+	type entryInfo struct {
+		dir     string
+		size    int64
+		lastUse time.Time
+	}
+
+	entryFiles, err := filepath.Glob(filepath.Join(c.dir, "entries", "*", "*", "entry.json"))
+	if err != nil {
+		return err
+	}
+
+	var entries []entryInfo
+	var total int64
+	for _, entryFile := range entryFiles {
+		info, err := os.Stat(entryFile)
+		if err != nil {
+			// The entry was removed by another sbox process.
+			continue
+		}
+		data, err := ioutil.ReadFile(entryFile)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			// Remove corrupt entries.
+			os.RemoveAll(filepath.Dir(entryFile))
+			continue
+		}
+		entries = append(entries, entryInfo{filepath.Dir(entryFile), entry.Size, info.ModTime()})
+		total += entry.Size
+	}
+
+	if total <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUse.Before(entries[j].lastUse) })
+
+	target := int64(float64(c.maxSize) * trimTarget)
+	for _, entry := range entries {
+		if total <= target {
+			break
+		}
+		if err := os.RemoveAll(entry.dir); err != nil {
+			return err
+		}
+		total -= entry.size
+	}
+
+	return nil
+}
+
+// writeStats appends a line to the stats file.  The lines are "hit <bytes restored>", "miss" or
+// "error <message>", and are short enough to be written atomically by concurrent sbox processes.
+func (c *actionCache) writeStats(line string) {
+	if c.statsFile == "" {
+		return
+	}
+	f, err := os.OpenFile(c.statsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	line = strings.ReplaceAll(line, "\n", " ")
+	if len(line) > 512 {
+		line = line[:512]
+	}
+	f.WriteString(line + "\n")
+}
@@ -0,0 +1,285 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// chdirTemp changes the working directory to a new temporary directory for the duration of the
+// test, sbox manifests contain paths relative to the working directory.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeFile(t *testing.T, file, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func testManifest() *sbox_proto.Manifest {
+	return &sbox_proto.Manifest{
+		Commands: []*sbox_proto.Command{
+			{
+				Command: proto.String("tools/gen __SBOX_SANDBOX_DIR__/in > __SBOX_SANDBOX_DIR__/out"),
+				CopyBefore: []*sbox_proto.Copy{
+					{From: proto.String("src/in"), To: proto.String("in")},
+					{From: proto.String("tools/gen"), To: proto.String("tools/gen")},
+				},
+				Chdir: proto.Bool(true),
+				CopyAfter: []*sbox_proto.Copy{
+					{From: proto.String("out"), To: proto.String("gen/out")},
+				},
+			},
+		},
+		OutputDepfile: proto.String("gen/out.d"),
+	}
+}
+
+func TestActionCacheKey(t *testing.T) {
+	chdirTemp(t)
+	writeFile(t, "src/in", "input")
+	writeFile(t, "tools/gen", "tool v1")
+
+	key := func(manifest *sbox_proto.Manifest, env ...string) string {
+		t.Helper()
+		k, err := newActionCache("cache", 0, "").key(manifest, env, "sandbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	base := key(testManifest(), "A=1", "B=2")
+
+	if k := key(testManifest(), "B=2", "A=1", "SBOX_CACHE_STATS=stats"); k != base {
+		t.Errorf("key depends on the order of the environment or the cache configuration")
+	}
+
+	if k := key(testManifest(), "A=1", "B=3"); k == base {
+		t.Errorf("key does not depend on the environment")
+	}
+
+	changedCommand := testManifest()
+	changedCommand.Commands[0].Command = proto.String("tools/gen -v __SBOX_SANDBOX_DIR__/in > __SBOX_SANDBOX_DIR__/out")
+	if k := key(changedCommand, "A=1", "B=2"); k == base {
+		t.Errorf("key does not depend on the command")
+	}
+
+	writeFile(t, "src/in", "changed input")
+	if k := key(testManifest(), "A=1", "B=2"); k == base {
+		t.Errorf("key does not depend on the contents of the inputs")
+	}
+	writeFile(t, "src/in", "input")
+
+	writeFile(t, "tools/gen", "tool v2")
+	if k := key(testManifest(), "A=1", "B=2"); k == base {
+		t.Errorf("key does not depend on the contents of the tools")
+	}
+}
+
+func TestActionCacheDirectoryInput(t *testing.T) {
+	chdirTemp(t)
+	writeFile(t, "src/dir/a", "a")
+	writeFile(t, "tools/gen", "tool")
+
+	// A command that is not run in the sandbox can read any file under the directory, it
+	// is never cached.
+	unsandboxed := &sbox_proto.Manifest{
+		Commands: []*sbox_proto.Command{
+			{
+				Command: proto.String("tools/gen src/dir > __SBOX_SANDBOX_DIR__/out"),
+				CopyAfter: []*sbox_proto.Copy{
+					{From: proto.String("out"), To: proto.String("gen/out")},
+				},
+			},
+		},
+	}
+	if cacheable(unsandboxed) {
+		t.Errorf("expected manifest that reads a directory outside the sandbox to not be cacheable")
+	}
+
+	// A command that is run in the sandbox can only read the files under the directory that
+	// were copied in.
+	sandboxed := &sbox_proto.Manifest{
+		Commands: []*sbox_proto.Command{
+			{
+				Command: proto.String("tools/gen src/dir > out"),
+				CopyBefore: []*sbox_proto.Copy{
+					{From: proto.String("src/dir/a"), To: proto.String("src/dir/a")},
+					{From: proto.String("tools/gen"), To: proto.String("tools/gen")},
+				},
+				CopyAfter: []*sbox_proto.Copy{
+					{From: proto.String("out"), To: proto.String("gen/out")},
+				},
+				Chdir: proto.Bool(true),
+			},
+		},
+	}
+	if !cacheable(sandboxed) {
+		t.Fatalf("expected sandboxed manifest to be cacheable")
+	}
+
+	cache := newActionCache("cache", defaultCacheMaxSize, "")
+	key, err := cache.key(sandboxed, nil, "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, "gen/out", "output")
+	if err := cache.store(key, sandboxed, nil, "sandbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, "src/dir/a", "changed a")
+	cache = newActionCache("cache", defaultCacheMaxSize, "")
+	key, err = cache.key(sandboxed, nil, "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit, err := cache.restore(key, sandboxed, &bytes.Buffer{}); err != nil || hit {
+		t.Errorf("expected miss after a file under the directory changed, got hit=%v err=%v", hit, err)
+	}
+}
+
+func TestActionCacheStoreRestore(t *testing.T) {
+	chdirTemp(t)
+	writeFile(t, "src/in", "input")
+	writeFile(t, "src/header", "header")
+	writeFile(t, "tools/gen", "tool")
+
+	manifest := testManifest()
+	cache := newActionCache("cache", defaultCacheMaxSize, "stats")
+
+	key, err := cache.key(manifest, nil, "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hit, err := cache.restore(key, manifest, &bytes.Buffer{}); err != nil || hit {
+		t.Fatalf("expected miss on empty cache, got hit=%v err=%v", hit, err)
+	}
+
+	// Simulate running the command.
+	writeFile(t, "gen/out", "output")
+	writeFile(t, "gen/out.d", "outputfile: src/header\n")
+	if err := cache.store(key, manifest, []byte("warning: foo\n"), "sandbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore into a clean tree.
+	os.RemoveAll("gen")
+	stdout := &bytes.Buffer{}
+	hit, err := cache.restore(key, manifest, stdout)
+	if err != nil || !hit {
+		t.Fatalf("expected hit, got hit=%v err=%v", hit, err)
+	}
+	if g, w := readFile(t, "gen/out"), "output"; g != w {
+		t.Errorf("want output %q, got %q", w, g)
+	}
+	if g, w := readFile(t, "gen/out.d"), "outputfile: src/header\n"; g != w {
+		t.Errorf("want depfile %q, got %q", w, g)
+	}
+	if g, w := stdout.String(), "warning: foo\n"; g != w {
+		t.Errorf("want stdout %q, got %q", w, g)
+	}
+
+	// A change to an input that was only listed in the depfile invalidates the entry.
+	writeFile(t, "src/header", "changed header")
+	cache = newActionCache("cache", defaultCacheMaxSize, "stats")
+	if hit, err := cache.restore(key, manifest, &bytes.Buffer{}); err != nil || hit {
+		t.Errorf("expected miss after depfile input changed, got hit=%v err=%v", hit, err)
+	}
+
+	if g, w := readFile(t, "stats"), "hit 42\n"; g != w {
+		t.Errorf("want stats %q, got %q", w, g)
+	}
+}
+
+func TestActionCacheTrim(t *testing.T) {
+	chdirTemp(t)
+	writeFile(t, "tools/gen", "tool")
+
+	cache := newActionCache("cache", 25, "")
+
+	var keys []string
+	for i, contents := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		manifest := testManifest()
+		manifest.OutputDepfile = nil
+		manifest.Commands[0].CopyBefore = nil
+		manifest.Commands[0].Command = proto.String(strings.Repeat("x", i) + " tools/gen")
+
+		key, err := cache.key(manifest, nil, "sandbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+
+		writeFile(t, "gen/out", contents)
+		if err := cache.store(key, manifest, nil, "sandbox"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Make the entries appear to have been used in order.
+		used := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(cache.entryDir(key), "entry.json"), used, used)
+	}
+
+	if err := cache.trim(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, key := range keys {
+		_, err := os.Stat(cache.entryDir(key))
+		// Only the least recently used entry needs to be removed to get below 90% of the
+		// maximum size.
+		if exists, want := err == nil, i > 0; exists != want {
+			t.Errorf("entry %d: want exists=%v, got %v", i, want, exists)
+		}
+	}
+}
@@ -38,9 +38,15 @@ import (
 )
 
 var (
-	sandboxesRoot string
-	manifestFile  string
-	keepOutDir    bool
+	sandboxesRoot       string
+	manifestFile        string
+	keepOutDir          bool
+	cacheDir            string
+	cacheMaxSize        int64
+	cacheStatsFile      string
+	strictMode          bool
+	strictAllowlistFile string
+	moduleName          string
 )
 
 const (
@@ -55,6 +61,30 @@ func init() {
 		"textproto manifest describing the sandboxed command(s)")
 	flag.BoolVar(&keepOutDir, "keep-out-dir", false,
 		"whether to keep the sandbox directory when done")
+	flag.StringVar(&cacheDir, "cache-dir", os.Getenv(cacheDirEnv),
+		"directory of the local action cache, the cache is disabled if empty (default $"+cacheDirEnv+")")
+	flag.Int64Var(&cacheMaxSize, "cache-max-size", envInt64(cacheMaxSizeEnv, defaultCacheMaxSize),
+		"maximum size of the local action cache in bytes (default $"+cacheMaxSizeEnv+")")
+	flag.StringVar(&cacheStatsFile, "cache-stats", os.Getenv(cacheStatsEnv),
+		"file to append local action cache hits and misses to (default $"+cacheStatsEnv+")")
+	flag.BoolVar(&strictMode, "strict", envBool(strictEnv),
+		"run commands with sandboxed inputs with the source tree hidden to detect undeclared inputs (default $"+strictEnv+")")
+	flag.StringVar(&strictAllowlistFile, "strict-allowlist", os.Getenv(strictAllowlistEnv),
+		"file listing modules that are not run in strict mode and directories that are visible in strict mode (default $"+strictAllowlistEnv+")")
+	flag.StringVar(&moduleName, "module", "",
+		"name of the module the manifest belongs to, in the form //path/to:module, used in error messages")
+}
+
+func envBool(name string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(name))
+	return v
+}
+
+func envInt64(name string, defaultValue int64) int64 {
+	if v, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil {
+		return v
+	}
+	return defaultValue
 }
 
 func usageViolation(violation string) {
@@ -71,6 +101,10 @@ func usageViolation(violation string) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == strictChildArg {
+		strictChildMain(os.Args[2:])
+	}
+
 	flag.Usage = func() {
 		usageViolation("")
 	}
@@ -155,6 +189,45 @@ func run() error {
 		}
 	}()
 
+	// Look up the outputs of the manifest in the local action cache.  Any errors from the cache
+	// are reported in the stats file and otherwise ignored, the commands are run instead.
+	var cache *actionCache
+	var cacheKey string
+	if cacheDir != "" && cacheable(manifest) {
+		cache = newActionCache(cacheDir, cacheMaxSize, cacheStatsFile)
+		cacheKey, err = cache.key(manifest, os.Environ(), sandboxesRoot)
+		if err != nil {
+			cache.writeStats("error " + err.Error())
+			cache = nil
+		} else if hit, err := cache.restore(cacheKey, manifest, os.Stdout); err != nil {
+			cache.writeStats("error " + err.Error())
+		} else if hit {
+			return nil
+		} else {
+			cache.writeStats("miss")
+		}
+	}
+
+	var strict *strictSandbox
+	if strictMode {
+		var allowlist *strictAllowlist
+		if strictAllowlistFile != "" {
+			allowlist, err = readStrictAllowlist(strictAllowlistFile)
+			if err != nil {
+				return fmt.Errorf("failed to read strict mode allowlist: %w", err)
+			}
+		}
+		if !allowlist.exempts(moduleName) {
+			strict, err = newStrictSandbox(sandboxesRoot, moduleName, allowlist)
+			if err != nil {
+				return fmt.Errorf("failed to set up strict mode: %w", err)
+			}
+		}
+	}
+
+	// The combined output of the commands, saved in the action cache.
+	stdout := &bytes.Buffer{}
+
 	// If there is more than one command in the manifest use a separate directory for each one.
 	useSubDir := len(manifest.Commands) > 1
 	var commandDepFiles []string
@@ -164,7 +237,7 @@ func run() error {
 		if useSubDir {
 			localTempDir = filepath.Join(localTempDir, strconv.Itoa(i))
 		}
-		depFile, err := runCommand(command, localTempDir)
+		depFile, err := runCommand(command, localTempDir, stdout, strict)
 		if err != nil {
 			// Running the command failed, keep the temporary output directory around in
 			// case a user wants to inspect it for debugging purposes.  Soong will delete
@@ -191,6 +264,12 @@ func run() error {
 		}
 	}
 
+	if cache != nil {
+		if err := cache.store(cacheKey, manifest, stdout.Bytes(), sandboxesRoot); err != nil {
+			cache.writeStats("error " + err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -212,8 +291,11 @@ func readManifest(file string) (*sbox_proto.Manifest, error) {
 }
 
 // runCommand runs a single command from a manifest.  If the command references the
-// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.
-func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, err error) {
+// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.  The output of
+// the command is written to stdout and also appended to commandOutput.  If strict is not nil and
+// the inputs of the command are copied into the sandbox the command is run in strict mode.
+func runCommand(command *sbox_proto.Command, tempDir string, commandOutput io.Writer,
+	strict *strictSandbox) (depFile string, err error) {
 	rawCommand := command.GetCommand()
 	if rawCommand == "" {
 		return "", fmt.Errorf("command is required")
@@ -255,14 +337,9 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 		return "", err
 	}
 
-	cmd := exec.Command("bash", "-c", rawCommand)
-	buf := &bytes.Buffer{}
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = buf
-	cmd.Stderr = buf
-
+	var cmdDir string
 	if command.GetChdir() {
-		cmd.Dir = tempDir
+		cmdDir = tempDir
 		path := os.Getenv("PATH")
 		absPath, err := makeAbsPathEnv(path)
 		if err != nil {
@@ -273,7 +350,29 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 			return "", fmt.Errorf("Failed to update PATH: %w", err)
 		}
 	}
-	err = cmd.Run()
+
+	var buf *bytes.Buffer
+	ran := false
+	if strict != nil && command.GetChdir() {
+		if cmd := strict.command(rawCommand); cmd != nil {
+			var strictBuf *bytes.Buffer
+			strictBuf, err = runBash(cmd, cmdDir)
+			if err == nil {
+				buf, ran = strictBuf, true
+			} else if !strictSetupFailed(err) {
+				// Run the command again with the source tree visible to find out whether it
+				// failed because of an undeclared input.
+				buf, err = runBash(exec.Command("bash", "-c", rawCommand), cmdDir)
+				if err == nil {
+					return "", strict.error(rawCommand, strictBuf.Bytes())
+				}
+				ran = true
+			}
+		}
+	}
+	if !ran {
+		buf, err = runBash(exec.Command("bash", "-c", rawCommand), cmdDir)
+	}
 
 	if err != nil {
 		// The command failed, do a best effort copy of output files out of the sandbox.  This is
@@ -296,6 +395,7 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 
 	// Write the command's combined stdout/stderr.
 	os.Stdout.Write(buf.Bytes())
+	commandOutput.Write(buf.Bytes())
 
 	if err != nil {
 		return "", err
@@ -338,6 +438,27 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 	return depFile, nil
 }
 
+// runBash runs a command in dir, or in the working directory if dir is empty, and returns its
+// combined stdout and stderr.
+func runBash(cmd *exec.Cmd, dir string) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	cmd.Dir = dir
+	err := cmd.Run()
+	return buf, err
+}
+
+// strictSetupFailed returns true if running a command in strict mode failed because strict mode
+// could not be set up, for example because user namespaces are disabled.
+func strictSetupFailed(err error) bool {
+	if exit, ok := err.(*exec.ExitError); ok {
+		return exit.ExitCode() == strictSetupFailedExitCode
+	}
+	return true
+}
+
 // makeOutputDirs creates directories in the sandbox dir for every file that has a rule to be copied
 // out of the sandbox.  This emulate's Ninja's behavior of creating directories for output files
 // so that the tools don't have to.
@@ -0,0 +1,308 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Strict mode runs commands whose inputs are copied into the sandbox (RuleBuilder.SandboxInputs)
+// with the source tree hidden, so that reads of inputs that were not declared, for example through
+// absolute paths into the source tree, fail.  Everything in the source tree is hidden except the
+// out directory and the exposed directories, see hiddenPaths.
+//
+// When a command fails in strict mode it is run again with the source tree visible.  If it then
+// succeeds the failure was caused by an undeclared input, and the files in the hidden part of the
+// source tree that are mentioned in the output of the strict run are reported as the missing
+// inputs.
+//
+// Strict mode is only supported on Linux, where the source tree is hidden in a private mount
+// namespace.  If the namespace can't be created the command is run normally.
+
+const (
+	// strictChildArg is passed as the first argument when sbox runs itself to set up the mount
+	// namespace for a command in strict mode.
+	strictChildArg = "--internal-strict-child"
+
+	// strictSetupFailedExitCode is returned by the strict child if it failed to set up the mount
+	// namespace, in which case the command is run normally.
+	strictSetupFailedExitCode = 113
+
+	strictEnv          = "SBOX_STRICT"
+	strictAllowlistEnv = "SBOX_STRICT_ALLOWLIST"
+)
+
+// Directories in the source tree that are visible to all commands in strict mode, as tools on the
+// PATH are run from them.
+var defaultExposedDirs = []string{"prebuilts"}
+
+// strictAllowlist is the contents of the strict mode allowlist file, which has one entry per line:
+//
+//	//path/to:module   the module is not run in strict mode
+//	//path/to/...      no modules in path/to or its subdirectories are run in strict mode
+//	expose path/to     the directory is visible to all commands in strict mode
+//
+// Empty lines and lines starting with # are ignored.
+type strictAllowlist struct {
+	file           string
+	modules        map[string]bool
+	modulePrefixes []string
+	exposed        []string
+}
+
+func readStrictAllowlist(file string) (*strictAllowlist, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseStrictAllowlist(f, file)
+}
+
+func parseStrictAllowlist(r io.Reader, file string) (*strictAllowlist, error) {
+	a := &strictAllowlist{
+		file:    file,
+		modules: make(map[string]bool),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "expose "):
+			dir := filepath.Clean(strings.TrimSpace(strings.TrimPrefix(line, "expose ")))
+			if filepath.IsAbs(dir) || dir == "." || strings.HasPrefix(dir, "..") {
+				return nil, fmt.Errorf("%s:%d: exposed directory %q must be relative to the top of the source tree",
+					file, lineNum, dir)
+			}
+			a.exposed = append(a.exposed, dir)
+		case strings.HasPrefix(line, "//") && strings.HasSuffix(line, "/..."):
+			a.modulePrefixes = append(a.modulePrefixes, strings.TrimSuffix(line, "..."))
+		case strings.HasPrefix(line, "//") && strings.Contains(line, ":"):
+			a.modules[line] = true
+		default:
+			return nil, fmt.Errorf("%s:%d: expected //path:module, //path/... or expose <dir>, got %q",
+				file, lineNum, line)
+		}
+	}
+	return a, scanner.Err()
+}
+
+// exempts returns true if the module, in the form //path/to:module, must not be run in strict mode.
+func (a *strictAllowlist) exempts(module string) bool {
+	if a == nil || module == "" {
+		return false
+	}
+	if a.modules[module] {
+		return true
+	}
+	dir := module
+	if i := strings.Index(dir, ":"); i >= 0 {
+		dir = dir[:i]
+	}
+	for _, prefix := range a.modulePrefixes {
+		if strings.HasPrefix(dir+"/", prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// strictSandbox is the set of paths that are hidden from the commands of a module in strict mode.
+type strictSandbox struct {
+	// module is the name of the module the commands belong to, in the form //path/to:module, or
+	// empty if it is not known.
+	module    string
+	allowlist *strictAllowlist
+
+	// root is the absolute path of the top of the source tree.
+	root string
+
+	// hidden are the absolute paths of the files and directories that are hidden.
+	hidden []string
+}
+
+// newStrictSandbox returns the strictSandbox for the source tree in the working directory.  The
+// directory that contains sandboxesRoot is always exposed.
+func newStrictSandbox(sandboxesRoot, module string, allowlist *strictAllowlist) (*strictSandbox, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	exposed := append([]string(nil), defaultExposedDirs...)
+	if allowlist != nil {
+		exposed = append(exposed, allowlist.exposed...)
+	}
+
+	absSandboxesRoot, err := filepath.Abs(sandboxesRoot)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(root, absSandboxesRoot); err == nil && !strings.HasPrefix(rel, "..") {
+		// Expose the whole out directory, it contains the tools on the PATH.
+		exposed = append(exposed, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+	}
+
+	hidden, err := hiddenPaths(root, exposed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &strictSandbox{
+		module:    module,
+		allowlist: allowlist,
+		root:      root,
+		hidden:    hidden,
+	}, nil
+}
+
+// hiddenPaths returns the absolute paths of the minimal set of files and directories under root
+// that need to be hidden to hide everything except the exposed paths, which are relative to root.
+func hiddenPaths(root string, exposed []string) ([]string, error) {
+	exposedSet := make(map[string]bool)
+	for _, e := range exposed {
+		exposedSet[filepath.Clean(e)] = true
+	}
+
+	// containsExposed returns true if an exposed path is below rel.
+	containsExposed := func(rel string) bool {
+		for e := range exposedSet {
+			if strings.HasPrefix(e, rel+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var hidden []string
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		entries, err := ioutil.ReadDir(filepath.Join(root, rel))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(rel, entry.Name())
+			switch {
+			case exposedSet[path]:
+				continue
+			case entry.IsDir() && containsExposed(path):
+				if err := walk(path); err != nil {
+					return err
+				}
+			case entry.Mode()&os.ModeSymlink != 0:
+				// Symlinks are resolved by the kernel, their targets are hidden if they
+				// are in the source tree.
+				continue
+			default:
+				hidden = append(hidden, filepath.Join(root, path))
+			}
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(hidden)
+	return hidden, nil
+}
+
+// isHidden returns true if the absolute path is hidden by the strictSandbox.
+func (s *strictSandbox) isHidden(path string) bool {
+	for _, h := range s.hidden {
+		if path == h || strings.HasPrefix(path, h+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// undeclaredInputs returns the files in the hidden part of the source tree that are mentioned in
+// the output of a command that failed in strict mode, or on its command line.
+func (s *strictSandbox) undeclaredInputs(output []byte, rawCommand string) []string {
+	words := strings.FieldsFunc(string(output)+"\n"+rawCommand, func(r rune) bool {
+		return strings.ContainsRune(" \t\n'\"`;:|&()<>=,[]{}", r)
+	})
+
+	seen := make(map[string]bool)
+	var inputs []string
+	for _, word := range words {
+		if !filepath.IsAbs(word) {
+			// Relative paths are relative to the sandbox directory.
+			continue
+		}
+		path := filepath.Clean(word)
+		if seen[path] || !s.isHidden(path) {
+			continue
+		}
+		seen[path] = true
+		if _, err := os.Stat(path); err == nil {
+			inputs = append(inputs, path)
+		}
+	}
+
+	sort.Strings(inputs)
+	return inputs
+}
+
+// error returns an actionable error for a command that failed in strict mode but succeeded with
+// the source tree visible.
+func (s *strictSandbox) error(rawCommand string, strictOutput []byte) error {
+	inputs := s.undeclaredInputs(strictOutput, rawCommand)
+
+	sb := &strings.Builder{}
+	what := "the rule with manifest " + manifestFile
+	if s.module != "" {
+		what = "module " + s.module
+	}
+	fmt.Fprintf(sb, "sbox strict mode: %s read files that were not declared as inputs.\n", what)
+	fmt.Fprintf(sb, "The command failed with the source tree hidden, but succeeded with it visible.\n")
+
+	if len(inputs) > 0 {
+		fmt.Fprintf(sb, "Missing inputs:\n")
+		for _, input := range inputs {
+			if rel, err := filepath.Rel(s.root, input); err == nil {
+				input = rel
+			}
+			fmt.Fprintf(sb, "  %s\n", input)
+		}
+	} else {
+		fmt.Fprintf(sb, "The missing inputs could not be determined, the output of the command in strict mode was:\n")
+		fmt.Fprintf(sb, "%s\n", strings.TrimRight(string(strictOutput), "\n"))
+	}
+
+	fmt.Fprintf(sb, "Add the missing files to the inputs of the module (for example srcs, tool_files or data),")
+	if s.module != "" {
+		allowlistFile := "the file in $" + strictAllowlistEnv
+		if s.allowlist != nil {
+			allowlistFile = s.allowlist.file
+		}
+		fmt.Fprintf(sb, " or add %s to %s to disable strict mode for it.", s.module, allowlistFile)
+	}
+
+	return fmt.Errorf("%s", sb.String())
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// command returns a command that runs rawCommand in a new user and mount namespace with the
+// hidden paths replaced by empty read-only directories or files.
+func (s *strictSandbox) command(rawCommand string) *exec.Cmd {
+	args := append([]string{strictChildArg}, s.hidden...)
+	args = append(args, "--", rawCommand)
+
+	cmd := exec.Command("/proc/self/exe", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd
+}
+
+// strictChildMain runs in the new namespace created by strictSandbox.command.  It hides the paths
+// passed as arguments and then replaces itself with bash running the command.
+func strictChildMain(args []string) {
+	var hidden []string
+	var rawCommand string
+	for i, arg := range args {
+		if arg == "--" && i == len(args)-2 {
+			rawCommand = args[i+1]
+			break
+		}
+		hidden = append(hidden, arg)
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "sbox: failed to set up strict mode: %s\n", err)
+		os.Exit(strictSetupFailedExitCode)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		fail(err)
+	}
+
+	// Don't propagate the mounts back to the parent namespace.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		fail(fmt.Errorf("making / private: %w", err))
+	}
+
+	for _, path := range hidden {
+		info, err := os.Lstat(path)
+		if err != nil {
+			// The path may have been removed since the list was created.
+			continue
+		}
+		if info.IsDir() {
+			err = syscall.Mount("tmpfs", path, "tmpfs", syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, "")
+		} else {
+			err = syscall.Mount("/dev/null", path, "", syscall.MS_BIND, "")
+		}
+		if err != nil {
+			fail(fmt.Errorf("hiding %q: %w", path, err))
+		}
+	}
+
+	err = syscall.Exec(bash, []string{"bash", "-c", rawCommand}, os.Environ())
+	fail(err)
+}
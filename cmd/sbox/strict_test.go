@@ -0,0 +1,98 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrictAllowlist(t *testing.T) {
+	allowlist, err := parseStrictAllowlist(strings.NewReader(`
+		# Modules that read undeclared inputs.
+		//external/foo:libfoo_gen
+		//vendor/...
+
+		expose build/make/tools
+	`), "allowlist.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for module, want := range map[string]bool{
+		"//external/foo:libfoo_gen":   true,
+		"//external/foo:libfoo_other": false,
+		"//vendor:gen":                true,
+		"//vendor/bar:gen":            true,
+		"//vendorx:gen":               false,
+		"":                            false,
+	} {
+		if g := allowlist.exempts(module); g != want {
+			t.Errorf("exempts(%q): want %v, got %v", module, want, g)
+		}
+	}
+
+	if g, w := allowlist.exposed, []string{"build/make/tools"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want exposed %q, got %q", w, g)
+	}
+
+	_, err = parseStrictAllowlist(strings.NewReader("libfoo\n"), "allowlist.txt")
+	if err == nil || !strings.Contains(err.Error(), "allowlist.txt:1:") {
+		t.Errorf("expected error for invalid line, got %v", err)
+	}
+}
+
+func TestStrictHiddenPaths(t *testing.T) {
+	root := chdirTemp(t)
+	writeFile(t, "Makefile", "")
+	writeFile(t, "art/Android.bp", "")
+	writeFile(t, "build/make/core/main.mk", "")
+	writeFile(t, "build/make/tools/tool.py", "")
+	writeFile(t, "build/soong/Android.bp", "")
+	writeFile(t, "out/soong/.temp/sbox/x", "")
+	writeFile(t, "prebuilts/tool", "")
+
+	sandbox, err := newStrictSandbox("out/soong/.temp", "//art:gen", &strictAllowlist{
+		exposed: []string{"build/make/tools"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hidden []string
+	for _, h := range sandbox.hidden {
+		rel, _ := filepath.Rel(root, h)
+		hidden = append(hidden, rel)
+	}
+	want := []string{"Makefile", "art", "build/make/core", "build/soong"}
+	if !reflect.DeepEqual(hidden, want) {
+		t.Errorf("want hidden %q, got %q", want, hidden)
+	}
+
+	output := []byte(root + "/art/Android.bp:1:1: error\n" +
+		"fatal error: '" + root + "/build/soong/missing.h' file not found\n" +
+		"relative art/Android.bp\n")
+	inputs := sandbox.undeclaredInputs(output, "cat "+root+"/build/make/tools/tool.py")
+	if g, w := inputs, []string{root + "/art/Android.bp"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want undeclared inputs %q, got %q", w, g)
+	}
+
+	err = sandbox.error("", output)
+	if err == nil || !strings.Contains(err.Error(), "Missing inputs:\n  art/Android.bp\n") {
+		t.Errorf("expected error naming art/Android.bp, got %v", err)
+	}
+}
@@ -418,6 +418,16 @@ func runBp2Build(configuration android.Config, extraNinjaDeps []string) {
 	symlinkForestDeps := bp2build.PlantSymlinkForest(
 		topDir, workspaceRoot, generatedRoot, configuration.SrcDir(), excludes)
 
+	// Check that the incrementally updated symlink forest matches its manifest.
+	if configuration.IsEnvTrue("BP2BUILD_VERIFY_SYMLINK_FOREST") {
+		if problems := bp2build.VerifySymlinkForest(topDir, workspaceRoot); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "Inconsistent symlink forest: %s\n", problem)
+			}
+			os.Exit(1)
+		}
+	}
+
 	// Only report metrics when in bp2build mode. The metrics aren't relevant
 	// for queryview, since that's a total repo-wide conversion and there's a
 	// 1:1 mapping for each module.
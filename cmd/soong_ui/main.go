@@ -97,6 +97,14 @@ var commands []command = []command{
 		config:      buildActionConfig,
 		stdio:       stdio,
 		run:         runMake,
+	}, {
+		flag:         "--critical-path-mode",
+		description:  "print the critical path report of the last build",
+		simpleOutput: true,
+		logsPrefix:   "critical-path-",
+		config:       dumpVarConfig,
+		stdio:        customStdio,
+		run:          criticalPathReport,
 	},
 }
 
@@ -234,8 +242,13 @@ func main() {
 	stat.AddOutput(status.NewVerboseLog(log, filepath.Join(logsDir, c.logsPrefix+"verbose.log")))
 	stat.AddOutput(status.NewErrorLog(log, filepath.Join(logsDir, c.logsPrefix+"error.log")))
 	stat.AddOutput(status.NewProtoErrorLog(log, buildErrorFile))
-	stat.AddOutput(status.NewCriticalPath(log))
+	stat.AddOutput(status.NewCriticalPath(log,
+		filepath.Join(logsDir, c.logsPrefix+"critical_path.json"),
+		filepath.Join(logsDir, c.logsPrefix+"critical_path.txt")))
 	stat.AddOutput(status.NewBuildProgressLog(log, filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")))
+	if eventLog := config.StatusEventLog(); eventLog != "" {
+		stat.AddOutput(status.NewJsonEventLog(log, eventLog))
+	}
 
 	buildCtx.Verbosef("Detected %.3v GB total RAM", float32(config.TotalRAM())/(1024*1024*1024))
 	buildCtx.Verbosef("Parallelism (local/remote/highmem): %v/%v/%v",
@@ -420,6 +433,44 @@ func dumpVars(ctx build.Context, config build.Config, args []string, _ string) {
 	}
 }
 
+func criticalPathReport(ctx build.Context, config build.Config, args []string, logsDir string) {
+	flags := flag.NewFlagSet("critical-path", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(ctx.Writer, "usage: %s --critical-path-mode [--json] [<report>]\n\n", os.Args[0])
+		fmt.Fprintln(ctx.Writer, "In critical path mode, print the critical path report written by the last")
+		fmt.Fprintln(ctx.Writer, "build, or by the build that wrote the specified critical_path.json file.")
+		fmt.Fprintln(ctx.Writer, "")
+		flags.PrintDefaults()
+	}
+	printJson := flags.Bool("json", false, "Print the report as JSON instead of text")
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	reportFile := filepath.Join(logsDir, "critical_path.json")
+	if flags.NArg() == 1 {
+		reportFile = flags.Arg(0)
+	}
+
+	report, err := status.ReadCriticalPathReport(reportFile)
+	if err != nil {
+		ctx.Fatalf("Failed to read critical path report: %v", err)
+	}
+
+	if *printJson {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			ctx.Fatal(err)
+		}
+		fmt.Println(string(data))
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		ctx.Fatal(err)
+	}
+}
+
 func stdio() terminal.StdioInterface {
 	return terminal.StdioImpl{}
 }
@@ -48,7 +48,7 @@ var (
 func init() {
 	flag.Var(&excludes, "x", "exclude a filespec from the output")
 	flag.Var(&includes, "X", "include a filespec in the output that was previously excluded")
-	flag.Var(&uncompress, "0", "convert a filespec to uncompressed in the output")
+	flag.Var(&uncompress, "0", "convert a filespec to uncompressed in the output, except for zstd entries")
 }
 
 func main() {
@@ -217,7 +217,10 @@ func zip2zip(reader *zip.Reader, writer *zip.Writer, sortOutput, sortJava, setTi
 		if setTime {
 			match.File.SetModTime(staticTime)
 		}
-		if match.uncompress && match.File.FileHeader.Method != zip.Store {
+		// Zstandard entries are only used in intermediate zips, and are copied through unchanged
+		// as there is no decompressor for them.
+		if match.uncompress && match.File.FileHeader.Method != zip.Store &&
+			match.File.FileHeader.Method != zip.Zstd {
 			fh := match.File.FileHeader
 			fh.Name = match.newName
 			fh.Method = zip.Store
@@ -512,3 +512,50 @@ func TestConstantPartOfPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestZip2ZipZstdPassthrough(t *testing.T) {
+	// Not a real Zstandard frame, the contents of the entry are never decompressed.
+	compressed := []byte{0x28, 0xb5, 0x2f, 0xfd, 1, 2, 3}
+
+	inputBuf := &bytes.Buffer{}
+	inputWriter := zip.NewWriter(inputBuf)
+	w, err := inputWriter.CreateCompressedHeader(&zip.FileHeader{
+		Name:               "a/a",
+		Method:             zip.Zstd,
+		UncompressedSize64: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed)
+	w.Close()
+	inputWriter.Close()
+	inputBytes := inputBuf.Bytes()
+	inputReader, err := zip.NewReader(bytes.NewReader(inputBytes), int64(len(inputBytes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputBuf := &bytes.Buffer{}
+	outputWriter := zip.NewWriter(outputBuf)
+	err = zip2zip(inputReader, outputWriter, false, false, false,
+		[]string{"a/a:b/a"}, nil, nil, []string{"**/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputWriter.Close()
+
+	outputBytes := outputBuf.Bytes()
+	outputReader, err := zip.NewReader(bytes.NewReader(outputBytes), int64(len(outputBytes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputReader.File) != 1 {
+		t.Fatalf("Expected 1 output file, got %d", len(outputReader.File))
+	}
+	f := outputReader.File[0]
+	if f.Name != "b/a" || f.Method != zip.Zstd || f.CompressedSize64 != uint64(len(compressed)) {
+		t.Errorf("Expected b/a to be copied unchanged, got %q with method %d and compressed size %d",
+			f.Name, f.Method, f.CompressedSize64)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+// canonicalize writes the entries of a zip file into a new zip file in a canonical form, so that
+// two zip files with the same contents are byte for byte identical:
+//   - entries are sorted by name, or in jar order if jarOrder is set
+//   - timestamps are set to jar.DefaultTime
+//   - the zip64 and extended timestamp extras are stripped
+//   - permissions are 0755 for directories and executable files, 0777 for symlinks and 0644
+//     otherwise
+//   - deflated entries are recompressed at a fixed level, stored entries stay stored as they may
+//     need to be for an APK, and entries with other methods are copied unchanged
+func canonicalize(r *zip.Reader, w *zip.Writer, jarOrder bool) error {
+	files := append([]*zip.File(nil), r.File...)
+	sort.SliceStable(files, func(i, j int) bool {
+		if jarOrder {
+			return jar.EntryNamesLess(files[i].Name, files[j].Name)
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	for i, f := range files {
+		if i > 0 && files[i-1].Name == f.Name {
+			return fmt.Errorf("duplicate entry %q", f.Name)
+		}
+		if err := canonicalizeEntry(f, w); err != nil {
+			return fmt.Errorf("%s: %s", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func canonicalizeEntry(f *zip.File, w *zip.Writer) error {
+	fh := &zip.FileHeader{
+		Name:               f.Name,
+		Method:             f.Method,
+		CRC32:              f.CRC32,
+		UncompressedSize64: f.UncompressedSize64,
+		Extra:              zip.StripExtras(f.Extra),
+	}
+	fh.SetMode(canonicalMode(f.Mode()))
+	fh.SetModTime(jar.DefaultTime)
+
+	var zw io.Writer
+	var err error
+	switch f.Method {
+	case zip.Store:
+		fh.CompressedSize64 = fh.UncompressedSize64
+		zw, err = w.CreateHeaderAndroid(fh)
+	case zip.Deflate:
+		zw, err = w.CreateHeader(fh)
+	default:
+		// The entry can't be recompressed, copy the compressed data with the canonical header.
+		orig := *f
+		fh.Flags = f.Flags
+		fh.CompressedSize64 = f.CompressedSize64
+		orig.FileHeader = *fh
+		return w.CopyFrom(&orig, f.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	zr, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	_, err = io.Copy(zw, zr)
+	return err
+}
+
+// canonicalMode returns the normalized permissions for an entry with the given mode.
+func canonicalMode(mode os.FileMode) os.FileMode {
+	switch {
+	case mode.IsDir():
+		return os.ModeDir | 0755
+	case mode&os.ModeSymlink != 0:
+		return os.ModeSymlink | 0777
+	case mode&0111 != 0:
+		return 0755
+	default:
+		return 0644
+	}
+}
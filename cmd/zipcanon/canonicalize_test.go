@@ -0,0 +1,209 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+type testEntry struct {
+	name     string
+	mode     os.FileMode
+	method   uint16
+	contents string
+	extra    []byte
+}
+
+func testZip(t *testing.T, entries []testEntry, modTime time.Time) *zip.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		fh := &zip.FileHeader{
+			Name:   e.name,
+			Method: e.method,
+			Extra:  e.extra,
+		}
+		fh.SetMode(e.mode)
+		fh.SetModTime(modTime)
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func canonicalizeToBytes(t *testing.T, r *zip.Reader, jarOrder bool) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	if err := canonicalize(r, zw, jarOrder); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCanonicalize(t *testing.T) {
+	// An extended timestamp extra, followed by an unknown extra that is kept.
+	extra := []byte{0x55, 0x54, 5, 0, 1, 1, 2, 3, 4, 0xfe, 0xca, 0, 0}
+
+	entries := []testEntry{
+		{"b/run.sh", 0700, zip.Deflate, "#!/bin/sh", extra},
+		{"a.txt", 0600, zip.Store, "a", nil},
+		{"b/", os.ModeDir | 0700, zip.Store, "", nil},
+		{jar.ManifestFile, 0644, zip.Deflate, "Manifest-Version: 1.0\n", nil},
+	}
+	reversed := []testEntry{entries[3], entries[2], entries[1], entries[0]}
+
+	out := canonicalizeToBytes(t, testZip(t, entries, time.Now()), false)
+	if out2 := canonicalizeToBytes(t, testZip(t, reversed, jar.DefaultTime), false); !bytes.Equal(out, out2) {
+		t.Errorf("expected zips with the same contents to canonicalize to the same bytes")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name   string
+		mode   os.FileMode
+		method uint16
+		extra  []byte
+	}{
+		{jar.ManifestFile, 0644, zip.Deflate, nil},
+		{"a.txt", 0644, zip.Store, nil},
+		{"b/", os.ModeDir | 0755, zip.Store, nil},
+		{"b/run.sh", 0755, zip.Deflate, []byte{0xfe, 0xca, 0, 0}},
+	}
+	if len(zr.File) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(zr.File))
+	}
+	for i, w := range want {
+		f := zr.File[i]
+		if f.Name != w.name {
+			t.Errorf("expected entry %d to be %q, got %q", i, w.name, f.Name)
+			continue
+		}
+		if f.Mode() != w.mode {
+			t.Errorf("%s: expected mode %v, got %v", f.Name, w.mode, f.Mode())
+		}
+		if f.Method != w.method {
+			t.Errorf("%s: expected method %d, got %d", f.Name, w.method, f.Method)
+		}
+		if !bytes.Equal(f.Extra, w.extra) {
+			t.Errorf("%s: expected extra %v, got %v", f.Name, w.extra, f.Extra)
+		}
+		if !f.ModTime().Equal(jar.DefaultTime) {
+			t.Errorf("%s: expected time %v, got %v", f.Name, jar.DefaultTime, f.ModTime())
+		}
+	}
+
+	r, err := zr.File[3].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(contents), "#!/bin/sh"; g != w {
+		t.Errorf("expected contents %q, got %q", w, g)
+	}
+}
+
+func TestCanonicalizeJarOrder(t *testing.T) {
+	entries := []testEntry{
+		{"a.class", 0644, zip.Deflate, "a", nil},
+		{jar.ManifestFile, 0644, zip.Deflate, "Manifest-Version: 1.0\n", nil},
+		{jar.MetaDir, os.ModeDir | 0755, zip.Store, "", nil},
+	}
+	out := canonicalizeToBytes(t, testZip(t, entries, jar.DefaultTime), true)
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{jar.MetaDir, jar.ManifestFile, "a.class"}
+	for i, f := range zr.File {
+		if f.Name != want[i] {
+			t.Errorf("expected entry %d to be %q, got %q", i, want[i], f.Name)
+		}
+	}
+}
+
+func TestCanonicalizeDuplicate(t *testing.T) {
+	entries := []testEntry{
+		{"a", 0644, zip.Deflate, "a", nil},
+		{"a", 0644, zip.Deflate, "b", nil},
+	}
+	zw := zip.NewWriter(&bytes.Buffer{})
+	err := canonicalize(testZip(t, entries, jar.DefaultTime), zw, false)
+	if err == nil || err.Error() != `duplicate entry "a"` {
+		t.Errorf("expected a duplicate entry error, got %v", err)
+	}
+}
+
+func TestRunCanonicalizeRemovesOutputOnError(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.zip")
+	output := filepath.Join(dir, "out.zip")
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, contents := range []string{"a", "b"} {
+		w, err := zw.Create("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCanonicalize(input, output); err == nil {
+		t.Fatal("expected an error for a zip with duplicate entries")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", output, err)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/third_party/zip"
+)
+
+// ZipDiff is the structured difference between two zip files A and B.
+type ZipDiff struct {
+	// Entries that are only in B.
+	Added []Entry `json:"added,omitempty"`
+
+	// Entries that are only in A.
+	Removed []Entry `json:"removed,omitempty"`
+
+	// Entries that are in both zip files, but differ in their metadata or contents.
+	Modified []EntryDiff `json:"modified,omitempty"`
+}
+
+// Entry is an entry that is only in one of the zip files.
+type Entry struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+
+	// Duplicate is 1 for the second entry with the same name in a zip file, 2 for the third
+	// one, etc.
+	Duplicate int `json:"duplicate,omitempty"`
+}
+
+// EntryDiff is the difference between an entry in A and the entry with the same name in B.
+// Entries with the same name in a zip file are compared in order.
+type EntryDiff struct {
+	Name      string       `json:"name"`
+	Duplicate int          `json:"duplicate,omitempty"`
+	Metadata  []FieldDiff  `json:"metadata,omitempty"`
+	Content   *ContentDiff `json:"content,omitempty"`
+
+	// The difference between the contents of a nested zip file, e.g. a jar in an APK, when
+	// diffing recursively.
+	Nested *ZipDiff `json:"nested,omitempty"`
+}
+
+// FieldDiff is a difference in a field of the header of an entry.
+type FieldDiff struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// ContentDiff is a difference in the uncompressed contents of an entry.
+type ContentDiff struct {
+	SizeA   uint64 `json:"size_a"`
+	SizeB   uint64 `json:"size_b"`
+	SHA256A string `json:"sha256_a"`
+	SHA256B string `json:"sha256_b"`
+
+	// The hash is of the compressed contents, as the compression method of the entry, e.g.
+	// zstd, can't be decompressed.
+	CompressedA bool `json:"compressed_a,omitempty"`
+	CompressedB bool `json:"compressed_b,omitempty"`
+}
+
+type diffOptions struct {
+	// Diff the contents of nested zip files that differ.
+	recursive bool
+
+	// Only report differences in the contents of the entries, not in their metadata.
+	contentOnly bool
+}
+
+// nestedZipExtensions are the extensions of entries that are diffed recursively.
+var nestedZipExtensions = map[string]bool{
+	".aar":  true,
+	".apex": true,
+	".apk":  true,
+	".jar":  true,
+	".zip":  true,
+}
+
+// Empty returns true if the zip files have no differences.
+func (d *ZipDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// diffZips returns the differences between zip files a and b.
+func diffZips(a, b *zip.Reader, opts diffOptions) (*ZipDiff, error) {
+	aFiles := entriesByName(a.File)
+	bFiles := entriesByName(b.File)
+
+	diff := &ZipDiff{}
+	for _, name := range sortedNames(aFiles) {
+		for i, af := range aFiles[name] {
+			if i >= len(bFiles[name]) {
+				diff.Removed = append(diff.Removed, Entry{name, af.UncompressedSize64, i})
+				continue
+			}
+			entryDiff, err := diffEntries(af, bFiles[name][i], opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+			if entryDiff != nil {
+				entryDiff.Duplicate = i
+				diff.Modified = append(diff.Modified, *entryDiff)
+			}
+		}
+	}
+	for _, name := range sortedNames(bFiles) {
+		for i, bf := range bFiles[name] {
+			if i >= len(aFiles[name]) {
+				diff.Added = append(diff.Added, Entry{name, bf.UncompressedSize64, i})
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// entriesByName maps the names of the entries to the entries with that name, in the order they
+// are in the zip file.
+func entriesByName(files []*zip.File) map[string][]*zip.File {
+	ret := make(map[string][]*zip.File, len(files))
+	for _, f := range files {
+		ret[f.Name] = append(ret[f.Name], f)
+	}
+	return ret
+}
+
+func sortedNames(files map[string][]*zip.File) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffEntries returns the differences between two entries with the same name, or nil if they are
+// the same.
+func diffEntries(a, b *zip.File, opts diffOptions) (*EntryDiff, error) {
+	diff := &EntryDiff{Name: a.Name}
+
+	if !opts.contentOnly {
+		field := func(name string, a, b interface{}) {
+			as, bs := fmt.Sprint(a), fmt.Sprint(b)
+			if as != bs {
+				diff.Metadata = append(diff.Metadata, FieldDiff{name, as, bs})
+			}
+		}
+		field("method", a.Method, b.Method)
+		field("mode", a.Mode(), b.Mode())
+		field("mtime", a.ModTime(), b.ModTime())
+		field("extra", hex.EncodeToString(a.Extra), hex.EncodeToString(b.Extra))
+		field("comment", a.Comment, b.Comment)
+	}
+
+	if a.CRC32 != b.CRC32 || a.UncompressedSize64 != b.UncompressedSize64 {
+		aContents, aCompressed, err := readEntry(a)
+		if err != nil {
+			return nil, err
+		}
+		bContents, bCompressed, err := readEntry(b)
+		if err != nil {
+			return nil, err
+		}
+		aHash, bHash := sha256.Sum256(aContents), sha256.Sum256(bContents)
+		diff.Content = &ContentDiff{
+			SizeA:       a.UncompressedSize64,
+			SizeB:       b.UncompressedSize64,
+			SHA256A:     hex.EncodeToString(aHash[:]),
+			SHA256B:     hex.EncodeToString(bHash[:]),
+			CompressedA: aCompressed,
+			CompressedB: bCompressed,
+		}
+
+		if opts.recursive && !aCompressed && !bCompressed &&
+			nestedZipExtensions[strings.ToLower(filepath.Ext(a.Name))] {
+			nested, err := diffNestedZips(aContents, bContents, opts)
+			if err != nil {
+				return nil, err
+			}
+			diff.Nested = nested
+		}
+	}
+
+	if len(diff.Metadata) == 0 && diff.Content == nil {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// diffNestedZips returns the differences between the contents of two entries that are zip files,
+// or nil if either of them isn't a valid zip file.
+func diffNestedZips(a, b []byte, opts diffOptions) (*ZipDiff, error) {
+	az, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		return nil, nil
+	}
+	bz, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, nil
+	}
+	return diffZips(az, bz, opts)
+}
+
+// readEntry returns the uncompressed contents of an entry.  If the compression method of the entry
+// is not supported, e.g. zstd, it returns the compressed contents and true instead.  The CRC and
+// size of such entries are still compared by diffEntries.
+func readEntry(f *zip.File) ([]byte, bool, error) {
+	r, err := f.Open()
+	if err == zip.ErrAlgorithm {
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return nil, false, err
+		}
+		contents, err := ioutil.ReadAll(raw)
+		return contents, true, err
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+	contents, err := ioutil.ReadAll(r)
+	return contents, false, err
+}
+
+// String pretty-prints the differences between two zip files.
+func (d *ZipDiff) String() string {
+	buf := &bytes.Buffer{}
+	d.write(buf, "")
+	return buf.String()
+}
+
+func (d *ZipDiff) write(w io.Writer, indent string) {
+	for _, e := range d.Removed {
+		fmt.Fprintf(w, "%s- %s%s (%d bytes)\n", indent, e.Name, duplicateSuffix(e.Duplicate), e.Size)
+	}
+	for _, e := range d.Added {
+		fmt.Fprintf(w, "%s+ %s%s (%d bytes)\n", indent, e.Name, duplicateSuffix(e.Duplicate), e.Size)
+	}
+	for _, e := range d.Modified {
+		fmt.Fprintf(w, "%s~ %s%s\n", indent, e.Name, duplicateSuffix(e.Duplicate))
+		for _, f := range e.Metadata {
+			fmt.Fprintf(w, "%s    %s: %s -> %s\n", indent, f.Field, f.A, f.B)
+		}
+		if c := e.Content; c != nil {
+			fmt.Fprintf(w, "%s    content: %d bytes %s -> %d bytes %s\n", indent,
+				c.SizeA, contentHash(c.SHA256A, c.CompressedA),
+				c.SizeB, contentHash(c.SHA256B, c.CompressedB))
+		}
+		if e.Nested != nil {
+			e.Nested.write(w, indent+"    ")
+		}
+	}
+}
+
+func duplicateSuffix(duplicate int) string {
+	if duplicate == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (duplicate %d)", duplicate)
+}
+
+func contentHash(hash string, compressed bool) string {
+	if compressed {
+		return "compressed sha256 " + hash
+	}
+	return "sha256 " + hash
+}
@@ -0,0 +1,315 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+func sha256String(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func zipBytes(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	out := canonicalizeToBytes(t, testZip(t, entries, jar.DefaultTime), false)
+	return string(out)
+}
+
+func TestDiffZips(t *testing.T) {
+	innerA := zipBytes(t, []testEntry{{"c.class", 0644, zip.Deflate, "c1", nil}})
+	innerB := zipBytes(t, []testEntry{{"c.class", 0644, zip.Deflate, "c2", nil}})
+
+	a := testZip(t, []testEntry{
+		{"same", 0644, zip.Deflate, "same", nil},
+		{"removed", 0644, zip.Deflate, "removed", nil},
+		{"content", 0644, zip.Deflate, "a", nil},
+		{"mode", 0644, zip.Deflate, "mode", nil},
+		{"lib.jar", 0644, zip.Store, innerA, nil},
+	}, jar.DefaultTime)
+	b := testZip(t, []testEntry{
+		{"same", 0644, zip.Store, "same", nil},
+		{"added", 0644, zip.Deflate, "added!", nil},
+		{"content", 0644, zip.Deflate, "b", nil},
+		{"mode", 0755, zip.Deflate, "mode", nil},
+		{"lib.jar", 0644, zip.Store, innerB, nil},
+	}, jar.DefaultTime)
+
+	t.Run("recursive", func(t *testing.T) {
+		got, err := diffZips(a, b, diffOptions{recursive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := &ZipDiff{
+			Added:   []Entry{{Name: "added", Size: 6}},
+			Removed: []Entry{{Name: "removed", Size: 7}},
+			Modified: []EntryDiff{
+				{
+					Name: "content",
+					Content: &ContentDiff{
+						SizeA: 1, SizeB: 1,
+						SHA256A: sha256String("a"), SHA256B: sha256String("b"),
+					},
+				},
+				{
+					Name: "lib.jar",
+					Content: &ContentDiff{
+						SizeA: uint64(len(innerA)), SizeB: uint64(len(innerB)),
+						SHA256A: sha256String(innerA), SHA256B: sha256String(innerB),
+					},
+					Nested: &ZipDiff{
+						Modified: []EntryDiff{
+							{
+								Name: "c.class",
+								Content: &ContentDiff{
+									SizeA: 2, SizeB: 2,
+									SHA256A: sha256String("c1"), SHA256B: sha256String("c2"),
+								},
+							},
+						},
+					},
+				},
+				{
+					Name:     "mode",
+					Metadata: []FieldDiff{{"mode", "-rw-r--r--", "-rwxr-xr-x"}},
+				},
+				{
+					Name:     "same",
+					Metadata: []FieldDiff{{"method", "8", "0"}},
+				},
+			},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected diff:\n%s\ngot:\n%s", want, got)
+		}
+	})
+
+	t.Run("content only", func(t *testing.T) {
+		got, err := diffZips(a, b, diffOptions{contentOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var modified []string
+		for _, m := range got.Modified {
+			modified = append(modified, m.Name)
+			if m.Nested != nil {
+				t.Errorf("%s: expected no nested diff when not recursive", m.Name)
+			}
+		}
+		if want := []string{"content", "lib.jar"}; !reflect.DeepEqual(modified, want) {
+			t.Errorf("expected modified entries %q, got %q", want, modified)
+		}
+	})
+
+	t.Run("identical", func(t *testing.T) {
+		got, err := diffZips(a, a, diffOptions{recursive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Empty() {
+			t.Errorf("expected no differences, got:\n%s", got)
+		}
+	})
+}
+
+func TestDiffZipsDuplicates(t *testing.T) {
+	a := testZip(t, []testEntry{
+		{"a", 0644, zip.Deflate, "a", nil},
+		{"a", 0644, zip.Deflate, "a2", nil},
+		{"b", 0644, zip.Deflate, "b", nil},
+		{"b", 0644, zip.Deflate, "b2", nil},
+	}, jar.DefaultTime)
+	b := testZip(t, []testEntry{
+		{"a", 0644, zip.Deflate, "a", nil},
+		{"b", 0644, zip.Deflate, "b", nil},
+		{"b", 0644, zip.Deflate, "b3", nil},
+		{"b", 0644, zip.Deflate, "b4", nil},
+	}, jar.DefaultTime)
+
+	got, err := diffZips(a, b, diffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ZipDiff{
+		Added:   []Entry{{Name: "b", Size: 2, Duplicate: 2}},
+		Removed: []Entry{{Name: "a", Size: 2, Duplicate: 1}},
+		Modified: []EntryDiff{
+			{
+				Name:      "b",
+				Duplicate: 1,
+				Content: &ContentDiff{
+					SizeA: 2, SizeB: 2,
+					SHA256A: sha256String("b2"), SHA256B: sha256String("b3"),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", want, got)
+	}
+
+	if got, err := diffZips(a, a, diffOptions{}); err != nil || !got.Empty() {
+		t.Errorf("expected no differences between identical zips with duplicates, got %v %v", got, err)
+	}
+}
+
+// zstdZip returns a zip file whose entries use the zstd compression method, which zipcanon can't
+// decompress.  The compressed contents are the uncompressed contents in upper case.
+func zstdZip(t *testing.T, contents ...string) *zip.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	zw.RegisterCompressor(zip.Zstd, func(w io.Writer) (io.WriteCloser, error) {
+		return upperCaseWriter{w}, nil
+	})
+	for i, c := range contents {
+		fh := &zip.FileHeader{Name: fmt.Sprintf("e%d", i), Method: zip.Zstd}
+		fh.SetModTime(jar.DefaultTime)
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+type upperCaseWriter struct {
+	w io.Writer
+}
+
+func (u upperCaseWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
+
+func (upperCaseWriter) Close() error { return nil }
+
+func TestDiffZipsZstd(t *testing.T) {
+	a := zstdZip(t, "same", "a")
+	b := zstdZip(t, "same", "b")
+
+	got, err := diffZips(a, b, diffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ZipDiff{
+		Modified: []EntryDiff{
+			{
+				Name: "e1",
+				Content: &ContentDiff{
+					SizeA: 1, SizeB: 1,
+					SHA256A: sha256String("A"), SHA256B: sha256String("B"),
+					CompressedA: true, CompressedB: true,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestZipDiffString(t *testing.T) {
+	d := &ZipDiff{
+		Added:   []Entry{{Name: "added", Size: 6}},
+		Removed: []Entry{{Name: "removed", Size: 7}},
+		Modified: []EntryDiff{
+			{
+				Name:     "lib.jar",
+				Metadata: []FieldDiff{{"mtime", "2008-01-01 00:00:00 +0000 UTC", "2021-01-01 00:00:00 +0000 UTC"}},
+				Content:  &ContentDiff{SizeA: 1, SizeB: 2, SHA256A: "aa", SHA256B: "bb"},
+				Nested: &ZipDiff{
+					Removed: []Entry{{Name: "c.class", Size: 3}},
+				},
+			},
+			{
+				Name:      "a.zst",
+				Duplicate: 1,
+				Content:   &ContentDiff{SizeA: 1, SizeB: 1, SHA256A: "cc", SHA256B: "dd", CompressedA: true},
+			},
+		},
+	}
+
+	want := "- removed (7 bytes)\n" +
+		"+ added (6 bytes)\n" +
+		"~ lib.jar\n" +
+		"    mtime: 2008-01-01 00:00:00 +0000 UTC -> 2021-01-01 00:00:00 +0000 UTC\n" +
+		"    content: 1 bytes sha256 aa -> 2 bytes sha256 bb\n" +
+		"    - c.class (3 bytes)\n" +
+		"~ a.zst (duplicate 1)\n" +
+		"    content: 1 bytes compressed sha256 cc -> 1 bytes sha256 dd\n"
+	if g := d.String(); g != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, g)
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zipcanon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.zip":      zipBytes(t, []testEntry{{"a", 0644, zip.Deflate, "a", nil}}),
+		"b.zip":      zipBytes(t, []testEntry{{"a", 0644, zip.Deflate, "b", nil}}),
+		"broken.zip": "not a zip file",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"a.zip", "a.zip", 0},
+		{"a.zip", "b.zip", 1},
+		{"a.zip", "missing.zip", 2},
+		{"broken.zip", "a.zip", 2},
+	} {
+		if got := runDiff(filepath.Join(dir, tt.a), filepath.Join(dir, tt.b)); got != tt.want {
+			t.Errorf("runDiff(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// zipcanon rewrites a zip file in a canonical form to track down nondeterminism in jars and APKs,
+// or reports the differences between two zip files.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"android/soong/third_party/zip"
+)
+
+var (
+	output      = flag.String("o", "", "file to write the canonical zip file to")
+	jarOrder    = flag.Bool("jar", false, "sort the entries in jar order instead of by name")
+	diff        = flag.Bool("diff", false, "report the differences between two zip files instead, the exit code is 1 if they differ and 2 on errors")
+	recursive   = flag.Bool("r", false, "when diffing, also diff nested jars, apks and zip files that differ")
+	contentOnly = flag.Bool("content_only", false, "when diffing, ignore differences in the metadata of entries")
+	jsonOutput  = flag.Bool("json", false, "when diffing, print the differences as JSON")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: zipcanon -o output.zip [-jar] input.zip\n")
+	fmt.Fprintf(os.Stderr, "       zipcanon -diff [-r] [-content_only] [-json] a.zip b.zip\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *diff {
+		if flag.NArg() != 2 {
+			usage()
+		}
+		os.Exit(runDiff(flag.Arg(0), flag.Arg(1)))
+	}
+
+	if flag.NArg() != 1 || *output == "" {
+		usage()
+	}
+	if err := runCanonicalize(flag.Arg(0), *output); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func runCanonicalize(input, output string) error {
+	r, err := zip.OpenReader(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	w := zip.NewWriter(f)
+	err = canonicalize(&r.Reader, w, *jarOrder)
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Don't leave a partial zip file behind.  It is closed first, as open files can't be
+		// removed on all platforms.
+		os.Remove(output)
+	}
+	return err
+}
+
+// runDiff prints the differences between two zip files and returns the exit code. Like diff(1), it
+// is 0 if the zip files are the same, 1 if there are differences and 2 if they could not be read.
+func runDiff(a, b string) int {
+	az, err := zip.OpenReader(a)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 2
+	}
+	defer az.Close()
+
+	bz, err := zip.OpenReader(b)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 2
+	}
+	defer bz.Close()
+
+	d, err := diffZips(&az.Reader, &bz.Reader, diffOptions{
+		recursive:   *recursive,
+		contentOnly: *contentOnly,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 2
+		}
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(d.String())
+	}
+
+	if !d.Empty() {
+		return 1
+	}
+	return 0
+}
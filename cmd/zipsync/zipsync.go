@@ -18,6 +18,7 @@ import (
 	"archive/zip"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"log"
@@ -31,6 +32,10 @@ var (
 	outputFile = flag.String("l", "", "output list file")
 	filter     = flag.String("f", "", "optional filter pattern")
 	zipPrefix  = flag.String("zip-prefix", "", "optional prefix within the zip file to extract, stripping the prefix")
+
+	incremental = flag.Bool("incremental", false, "only write the files that changed and remove stale files, "+
+		"instead of recreating the output dir")
+	touchedFile = flag.String("touched", "", "output list of the files that were written")
 )
 
 func must(err error) {
@@ -63,9 +68,223 @@ func writeSymlink(filename string, in io.Reader) error {
 	return err
 }
 
+// zipEntry is an entry of an input zip to extract into the output directory.
+type zipEntry struct {
+	// The path of the entry relative to the output directory.
+	name  string
+	input string
+	file  *zip.File
+}
+
+// selectEntries returns the entries of an input zip that match the prefix and filter, failing if
+// an entry was already seen in another input zip.
+func selectEntries(input string, reader *zip.Reader, prefix, filter string,
+	seen map[string]string) ([]zipEntry, error) {
+
+	var entries []zipEntry
+	for _, f := range reader.File {
+		name := f.Name
+		if prefix != "" {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			name = strings.TrimPrefix(name, prefix)
+		}
+		if filter != "" {
+			if match, err := filepath.Match(filter, filepath.Base(name)); err != nil {
+				return nil, err
+			} else if !match {
+				continue
+			}
+		}
+		if filepath.IsAbs(name) {
+			return nil, fmt.Errorf("%q in %q is an absolute path", name, input)
+		}
+
+		if prev, exists := seen[name]; exists {
+			return nil, fmt.Errorf("%q found in both %q and %q", name, prev, input)
+		}
+		seen[name] = input
+
+		entries = append(entries, zipEntry{name: name, input: input, file: f})
+	}
+	return entries, nil
+}
+
+// syncEntries extracts the entries into the output directory, and returns the extracted files and
+// the files that were written. In incremental mode files that are already up to date are left
+// untouched to keep their mtimes, and files that aren't in the entries are removed. Otherwise the
+// output directory is recreated.
+func syncEntries(outputDir string, entries []zipEntry, incremental bool) (files, touched []string, err error) {
+	if incremental {
+		if err := removeStaleFiles(outputDir, entries); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if err := os.RemoveAll(outputDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0777); err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		f := entry.file
+		filename := filepath.Join(outputDir, entry.name)
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(filename, 0777); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		files = append(files, filename)
+
+		if incremental {
+			if upToDate, err := isUpToDate(filename, f); err != nil {
+				return nil, nil, err
+			} else if upToDate {
+				continue
+			}
+			if err := os.RemoveAll(filename); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if err := extractFile(filename, f); err != nil {
+			return nil, nil, fmt.Errorf("%q in %q: %s", f.Name, entry.input, err)
+		}
+		touched = append(touched, filename)
+	}
+
+	return files, touched, nil
+}
+
+func extractFile(filename string, f *zip.File) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if f.FileInfo().Mode()&os.ModeSymlink != 0 {
+		return writeSymlink(filename, in)
+	}
+	return writeFile(filename, in, f.FileInfo().Mode())
+}
+
+// isUpToDate returns true if the file on disk has the size, CRC and type of the zip entry. The
+// permissions of an up to date file are updated if necessary, which doesn't change its mtime.
+func isUpToDate(filename string, f *zip.File) (bool, error) {
+	fi, err := os.Lstat(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	mode := f.FileInfo().Mode()
+	if mode&os.ModeSymlink != 0 {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return false, nil
+		}
+		dest, err := os.Readlink(filename)
+		if err != nil {
+			return false, err
+		}
+		return uint64(len(dest)) == f.UncompressedSize64 && crc32.ChecksumIEEE([]byte(dest)) == f.CRC32, nil
+	}
+
+	if !fi.Mode().IsRegular() || uint64(fi.Size()) != f.UncompressedSize64 {
+		return false, nil
+	}
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	crc := crc32.NewIEEE()
+	_, err = io.Copy(crc, in)
+	in.Close()
+	if err != nil {
+		return false, err
+	}
+	if crc.Sum32() != f.CRC32 {
+		return false, nil
+	}
+
+	if fi.Mode().Perm() != mode.Perm() {
+		if err := os.Chmod(filename, mode.Perm()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// removeStaleFiles removes the files and directories in the output directory that are not in the
+// entries, or that have a different type than the entry.
+func removeStaleFiles(outputDir string, entries []zipEntry) error {
+	wantFiles := make(map[string]bool)
+	wantDirs := make(map[string]bool)
+	for _, entry := range entries {
+		name := filepath.Clean(entry.name)
+		if entry.file.FileInfo().IsDir() {
+			wantDirs[name] = true
+		} else {
+			wantFiles[name] = true
+		}
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			wantDirs[dir] = true
+		}
+	}
+
+	err := filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			if !info.IsDir() {
+				return os.Remove(path)
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if !wantDirs[rel] {
+				if err := os.RemoveAll(path); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+		} else if !wantFiles[rel] {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func writeFileList(filename string, files []string) error {
+	data := strings.Join(files, "\n")
+	if len(files) > 0 {
+		data += "\n"
+	}
+	return ioutil.WriteFile(filename, []byte(data), 0666)
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: zipsync -d <output dir> [-l <output file>] [-f <pattern>] [zip]...")
+		fmt.Fprintln(os.Stderr, "usage: zipsync -d <output dir> [-l <output file>] [-f <pattern>] [-incremental [-touched <output file>]] [zip]...")
 		flag.PrintDefaults()
 	}
 
@@ -78,20 +297,13 @@ func main() {
 
 	inputs := flag.Args()
 
-	// For now, just wipe the output directory and replace its contents with the zip files
-	// Eventually this could only modify the directory contents as necessary to bring it up
-	// to date with the zip files.
-	must(os.RemoveAll(*outputDir))
-
-	must(os.MkdirAll(*outputDir, 0777))
-
-	var files []string
-	seen := make(map[string]string)
-
 	if *zipPrefix != "" {
 		*zipPrefix = filepath.Clean(*zipPrefix) + "/"
 	}
 
+	var entries []zipEntry
+	seen := make(map[string]string)
+
 	for _, input := range inputs {
 		reader, err := zip.OpenReader(input)
 		if err != nil {
@@ -99,55 +311,18 @@ func main() {
 		}
 		defer reader.Close()
 
-		for _, f := range reader.File {
-			name := f.Name
-			if *zipPrefix != "" {
-				if !strings.HasPrefix(name, *zipPrefix) {
-					continue
-				}
-				name = strings.TrimPrefix(name, *zipPrefix)
-			}
-			if *filter != "" {
-				if match, err := filepath.Match(*filter, filepath.Base(name)); err != nil {
-					log.Fatal(err)
-				} else if !match {
-					continue
-				}
-			}
-			if filepath.IsAbs(name) {
-				log.Fatalf("%q in %q is an absolute path", name, input)
-			}
-
-			if prev, exists := seen[name]; exists {
-				log.Fatalf("%q found in both %q and %q", name, prev, input)
-			}
-			seen[name] = input
-
-			filename := filepath.Join(*outputDir, name)
-			if f.FileInfo().IsDir() {
-				must(os.MkdirAll(filename, 0777))
-			} else {
-				must(os.MkdirAll(filepath.Dir(filename), 0777))
-				in, err := f.Open()
-				if err != nil {
-					log.Fatal(err)
-				}
-				if f.FileInfo().Mode()&os.ModeSymlink != 0 {
-					must(writeSymlink(filename, in))
-				} else {
-					must(writeFile(filename, in, f.FileInfo().Mode()))
-				}
-				in.Close()
-				files = append(files, filename)
-			}
-		}
+		inputEntries, err := selectEntries(input, &reader.Reader, *zipPrefix, *filter, seen)
+		must(err)
+		entries = append(entries, inputEntries...)
 	}
 
+	files, touched, err := syncEntries(*outputDir, entries, *incremental)
+	must(err)
+
 	if *outputFile != "" {
-		data := strings.Join(files, "\n")
-		if len(files) > 0 {
-			data += "\n"
-		}
-		must(ioutil.WriteFile(*outputFile, []byte(data), 0666))
+		must(writeFileList(*outputFile, files))
+	}
+	if *touchedFile != "" {
+		must(writeFileList(*touchedFile, touched))
 	}
 }
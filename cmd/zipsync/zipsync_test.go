@@ -0,0 +1,148 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func testZipReader(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		contents := files[name]
+		fh := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		if name[len(name)-1] == '/' {
+			fh.SetMode(os.ModeDir | 0755)
+		} else {
+			fh.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestIncrementalSync(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "out")
+	sync := func(files map[string]string) []string {
+		t.Helper()
+		entries, err := selectEntries("in.zip", testZipReader(t, files), "", "", make(map[string]string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, touched, err := syncEntries(outputDir, entries, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return touched
+	}
+	path := func(name string) string {
+		return filepath.Join(outputDir, name)
+	}
+
+	touched := sync(map[string]string{"a": "a", "b/c": "c", "b/d": "d", "f": "f"})
+	if want := []string{path("a"), path("b/c"), path("b/d"), path("f")}; !reflect.DeepEqual(touched, want) {
+		t.Errorf("expected all files to be written %q, got %q", want, touched)
+	}
+
+	// Set an old mtime to check that unchanged files are not rewritten.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, name := range []string{"a", "b/c", "b/d", "f"} {
+		if err := os.Chtimes(path(name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(path("stale"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	touched = sync(map[string]string{"a": "a", "b/c": "C", "e/": "", "f": "ff"})
+	if want := []string{path("b/c"), path("f")}; !reflect.DeepEqual(touched, want) {
+		t.Errorf("expected changed files to be written %q, got %q", want, touched)
+	}
+
+	if fi, err := os.Stat(path("a")); err != nil {
+		t.Fatal(err)
+	} else if !fi.ModTime().Equal(old) {
+		t.Errorf("expected the mtime of unchanged a to be kept")
+	}
+	for name, want := range map[string]string{"b/c": "C", "f": "ff"} {
+		if contents, err := ioutil.ReadFile(path(name)); err != nil {
+			t.Fatal(err)
+		} else if string(contents) != want {
+			t.Errorf("expected %s to contain %q, got %q", name, want, contents)
+		}
+	}
+	for _, name := range []string{"b/d", "stale"} {
+		if _, err := os.Lstat(path(name)); !os.IsNotExist(err) {
+			t.Errorf("expected stale %s to be removed", name)
+		}
+	}
+	if fi, err := os.Stat(path("e")); err != nil || !fi.IsDir() {
+		t.Errorf("expected directory e to be created")
+	}
+
+	// A file replaced by a directory of the same name.
+	touched = sync(map[string]string{"a": "a", "b/c": "C", "e/": "", "f/": ""})
+	if len(touched) != 0 {
+		t.Errorf("expected no files to be written, got %q", touched)
+	}
+	if fi, err := os.Stat(path("f")); err != nil || !fi.IsDir() {
+		t.Errorf("expected file f to be replaced by a directory")
+	}
+}
+
+func TestSelectEntries(t *testing.T) {
+	zr := testZipReader(t, map[string]string{"a": "a", "b/c": "c", "b/d": "d"})
+
+	seen := make(map[string]string)
+	entries, err := selectEntries("in.zip", zr, "b/", "c", seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].name != "c" {
+		t.Errorf("expected only c to be selected, got %v", entries)
+	}
+
+	_, err = selectEntries("other.zip", zr, "b/", "", seen)
+	if want := `"c" found in both "in.zip" and "other.zip"`; err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+}
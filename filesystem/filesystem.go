@@ -45,6 +45,9 @@ type filesystem struct {
 
 	output     android.OutputPath
 	installDir android.InstallPath
+
+	// NOTICE.xml.gz and NOTICE.html of the files in the filesystem
+	notices android.NoticeIndexOutputs
 }
 
 type symlinkDefinition struct {
@@ -82,6 +85,10 @@ type filesystemProperties struct {
 
 	// Symbolic links to be created under root with "ln -sf <target> <name>".
 	Symlinks []symlinkDefinition
+
+	// When set to true, the NOTICE.xml.gz of the files in the filesystem is installed into it as
+	// etc/NOTICE.xml.gz under base_dir. Default is false.
+	Include_notices *bool
 }
 
 // android_filesystem packages a set of modules and their transitive dependencies into a filesystem
@@ -141,6 +148,8 @@ func (f *filesystem) installFileName() string {
 var pctx = android.NewPackageContext("android/soong/filesystem")
 
 func (f *filesystem) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	f.notices = f.buildNotices(ctx)
+
 	switch f.fsType(ctx) {
 	case ext4Type:
 		f.output = f.buildImageUsingBuildImage(ctx)
@@ -190,6 +199,13 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 		builder.Command().Text("ln -sf").Text(proptools.ShellEscape(target)).Text(dst.String())
 	}
 
+	if proptools.Bool(f.properties.Include_notices) {
+		base := proptools.StringDefault(f.properties.Base_dir, ".")
+		dst := rootDir.Join(ctx, base, "etc", "NOTICE.xml.gz")
+		builder.Command().Text("mkdir -p").Text(filepath.Dir(dst.String()))
+		builder.Command().Text("cp").Input(f.notices.XmlGz).Text(dst.String())
+	}
+
 	// create extra files if there's any
 	rootForExtraFiles := android.PathForModuleGen(ctx, "root-extra").OutputPath
 	var extraFiles android.OutputPaths
@@ -224,6 +240,18 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 	return zipOut
 }
 
+// buildNotices creates the build rules that aggregate the license texts of the files in the
+// filesystem into NOTICE.xml.gz and NOTICE.html.
+func (f *filesystem) buildNotices(ctx android.ModuleContext) android.NoticeIndexOutputs {
+	base := proptools.StringDefault(f.properties.Base_dir, ".")
+	entries := f.GatherPackagingNotices(ctx)
+	for i := range entries {
+		entries[i].Installed = "/" + filepath.Join(base, entries[i].Installed)
+	}
+	return android.BuildNoticeIndex(ctx, android.PathForModuleOut(ctx, "notice_index").OutputPath,
+		"Notices for files contained in "+f.BaseModuleName(), entries)
+}
+
 func (f *filesystem) buildImageUsingBuildImage(ctx android.ModuleContext) android.OutputPath {
 	depsZipFile := android.PathForModuleOut(ctx, "deps.zip").OutputPath
 	f.CopyDepsToZip(ctx, depsZipFile)
@@ -405,8 +433,13 @@ var _ android.OutputFileProducer = (*filesystem)(nil)
 
 // Implements android.OutputFileProducer
 func (f *filesystem) OutputFiles(tag string) (android.Paths, error) {
-	if tag == "" {
+	switch tag {
+	case "":
 		return []android.Path{f.output}, nil
+	case ".notice.xml.gz":
+		return []android.Path{f.notices.XmlGz}, nil
+	case ".notice.html":
+		return []android.Path{f.notices.Html}, nil
 	}
 	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 }
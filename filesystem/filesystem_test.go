@@ -15,6 +15,7 @@
 package filesystem
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -74,3 +75,58 @@ func TestFileSystemFillsLinkerConfigWithStubLibs(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "linker.config.pb should not have libbar",
 		output.RuleParams.Command, "libbar.so")
 }
+
+func TestFileSystemNotices(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		fixture,
+		android.FixtureMergeMockFs(android.MockFS{
+			"foo/NOTICE_FOO": nil,
+			"bar/NOTICE_BAR": nil,
+		}),
+		android.FixtureAddTextFile("foo/Android.bp", `
+			cc_binary {
+				name: "foo",
+				notice: "NOTICE_FOO",
+				shared_libs: ["libbar"],
+			}
+		`),
+		android.FixtureAddTextFile("bar/Android.bp", `
+			cc_library {
+				name: "libbar",
+				notice: "NOTICE_BAR",
+			}
+		`),
+	).RunTestWithBp(t, `
+		android_filesystem {
+			name: "myfilesystem",
+			deps: ["foo"],
+			base_dir: "system",
+			include_notices: true,
+		}
+	`)
+
+	module := result.ModuleForTests("myfilesystem", "android_common")
+
+	var manifest struct {
+		Files []struct {
+			Name  string
+			Texts []string
+		}
+	}
+	content := android.ContentFromFileRuleForTests(t, module.Output("notice_index/notice_manifest.json"))
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	texts := make(map[string][]string)
+	for _, f := range manifest.Files {
+		texts[f.Name] = f.Texts
+	}
+	android.AssertDeepEquals(t, "foo notices", []string{"foo/NOTICE_FOO"}, texts["/system/bin/foo"])
+	android.AssertDeepEquals(t, "libbar notices", []string{"bar/NOTICE_BAR"}, texts["/system/lib64/libbar.so"])
+
+	rootZip := module.Output("root.zip")
+	android.AssertStringDoesContain(t, "root zip should include the notices",
+		rootZip.RuleParams.Command, "root/system/etc/NOTICE.xml.gz")
+	android.AssertStringListContains(t, "notices should be an input of the root zip",
+		rootZip.Implicits.Strings(), "out/soong/.intermediates/myfilesystem/android_common/notice_index/NOTICE.xml.gz")
+}
@@ -22,9 +22,11 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"android/soong/finder"
@@ -42,6 +44,7 @@ var (
 	verbose       bool
 	dbPath        string
 	numIterations int
+	daemonSocket  string
 )
 
 func init() {
@@ -49,6 +52,8 @@ func init() {
 		"filepath of profile file to write (optional)")
 	flag.BoolVar(&verbose, "v", false, "log additional information")
 	flag.StringVar(&dbPath, "db", "", "filepath of cache db")
+	flag.StringVar(&daemonSocket, "daemon", "",
+		"run a finder daemon listening on this unix socket instead of searching")
 
 	flag.StringVar(&excludeDirs, "exclude-dirs", "",
 		"comma-separated list of directory names to exclude from search")
@@ -64,10 +69,24 @@ func init() {
 
 var usage = func() {
 	fmt.Printf("usage: finder -name <fileName> --db <dbPath> <searchDirectory> [<searchDirectory>...]\n")
+	fmt.Printf("   or: finder --daemon <socketPath>\n")
+	for _, name := range subcommandNames() {
+		fmt.Printf("   or: finder %s\n", subcommands[name].usage)
+	}
 	flag.PrintDefaults()
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if _, ok := subcommands[os.Args[1]]; ok {
+			if err := runSubcommand(os.Args[1], os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	err := run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err.Error())
@@ -105,6 +124,10 @@ func run() error {
 
 	logger.Printf("Finder starting at %v\n", startTime)
 
+	if daemonSocket != "" {
+		return runDaemon(logger)
+	}
+
 	rootPaths := flag.Args()
 	if len(rootPaths) < 1 {
 		usage()
@@ -154,3 +177,23 @@ func runFind(params finder.CacheParams, logger *log.Logger) (paths []string, err
 	defer service.Shutdown()
 	return service.FindAll(), nil
 }
+
+// runDaemon answers queries from soong_ui until it is interrupted
+func runDaemon(logger *log.Logger) error {
+	daemon, err := finder.NewDaemon(daemonSocket, logger)
+	if err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		daemon.Close()
+	}()
+
+	logger.Printf("Finder daemon listening on %v\n", daemonSocket)
+	err = daemon.Serve()
+	os.Remove(daemonSocket)
+	return err
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"android/soong/finder"
+	"android/soong/finder/fs"
+)
+
+// The query subcommands read a cache db written by a Finder (for example
+// $OUT_DIR/.module_paths/files.db) without updating it.
+
+type subcommand struct {
+	usage       string
+	description string
+	run         func(jsonOutput bool, db string, args []string) error
+	needsDb     bool
+	hasRoot     bool
+	numArgs     int
+}
+
+var subcommands = map[string]subcommand{
+	"find": {
+		usage:       "find --db <dbPath> [--root <dir>] [--json] <pattern>",
+		description: "list the cached files whose names match the glob <pattern>",
+		run:         runFindQuery,
+		needsDb:     true,
+		hasRoot:     true,
+		numArgs:     1,
+	},
+	"dirs": {
+		usage:       "dirs --db <dbPath> [--root <dir>] [--json] <pattern>",
+		description: "list the cached directories that hold a file whose name matches the glob <pattern>",
+		run:         runDirsQuery,
+		needsDb:     true,
+		hasRoot:     true,
+		numArgs:     1,
+	},
+	"diff": {
+		usage:       "diff [--json] <oldDbPath> <newDbPath>",
+		description: "list the directories that differ between two snapshots of a cache db",
+		run:         runDiffQuery,
+		numArgs:     2,
+	},
+	"changed": {
+		usage:       "changed --db <dbPath> [--json]",
+		description: "list the cached directories whose stats changed since the db was written",
+		run:         runChangedQuery,
+		needsDb:     true,
+		numArgs:     0,
+	},
+}
+
+var queryRoot string
+
+func subcommandNames() []string {
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runSubcommand runs the query subcommand <name> with the remaining command line arguments
+func runSubcommand(name string, args []string) error {
+	cmd := subcommands[name]
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: finder %s\n", cmd.usage)
+		fmt.Fprintf(flags.Output(), "%s\n", cmd.description)
+		flags.PrintDefaults()
+	}
+	jsonOutput := flags.Bool("json", false, "print the results as json")
+	var db string
+	if cmd.needsDb {
+		flags.StringVar(&db, "db", "", "filepath of cache db")
+	}
+	if cmd.hasRoot {
+		flags.StringVar(&queryRoot, "root", "/",
+			"directory to search, relative to the working directory of the db if not absolute")
+	}
+	flags.Parse(args)
+
+	if cmd.needsDb && db == "" {
+		flags.Usage()
+		return fmt.Errorf("Param 'db' must be nonempty")
+	}
+	if flags.NArg() != cmd.numArgs {
+		flags.Usage()
+		return fmt.Errorf("Expected %v arguments, got %v", cmd.numArgs, flags.NArg())
+	}
+	return cmd.run(*jsonOutput, db, flags.Args())
+}
+
+func readDb(dbPath string) (*finder.Db, error) {
+	return finder.ReadDb(fs.OsFs, dbPath)
+}
+
+// printResults prints <results> as json, or calls printPlain to print them as text
+func printResults(w io.Writer, jsonOutput bool, results interface{}, printPlain func()) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	printPlain()
+	return nil
+}
+
+func printLines(lines []string) {
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+func runFindQuery(jsonOutput bool, dbPath string, args []string) error {
+	db, err := readDb(dbPath)
+	if err != nil {
+		return err
+	}
+	files, err := db.FindGlob(queryRoot, args[0])
+	if err != nil {
+		return err
+	}
+	return printResults(os.Stdout, jsonOutput, files, func() { printLines(files) })
+}
+
+func runDirsQuery(jsonOutput bool, dbPath string, args []string) error {
+	db, err := readDb(dbPath)
+	if err != nil {
+		return err
+	}
+	dirs, err := db.DirsContaining(queryRoot, args[0])
+	if err != nil {
+		return err
+	}
+	return printResults(os.Stdout, jsonOutput, dirs, func() { printLines(dirs) })
+}
+
+func printChanges(jsonOutput bool, changes []finder.DbDirChange) error {
+	if changes == nil {
+		changes = []finder.DbDirChange{}
+	}
+	return printResults(os.Stdout, jsonOutput, changes, func() {
+		for _, change := range changes {
+			fmt.Println(change.String())
+		}
+	})
+}
+
+func runDiffQuery(jsonOutput bool, dbPath string, args []string) error {
+	oldDb, err := readDb(args[0])
+	if err != nil {
+		return err
+	}
+	newDb, err := readDb(args[1])
+	if err != nil {
+		return err
+	}
+	if oldDb.Params.WorkingDirectory != newDb.Params.WorkingDirectory {
+		fmt.Fprintf(os.Stderr, "warning: the dbs have different working directories %q and %q\n",
+			oldDb.Params.WorkingDirectory, newDb.Params.WorkingDirectory)
+	}
+	return printChanges(jsonOutput, finder.DiffDbs(oldDb, newDb))
+}
+
+func runChangedQuery(jsonOutput bool, dbPath string, args []string) error {
+	db, err := readDb(dbPath)
+	if err != nil {
+		return err
+	}
+	if view := fs.OsFs.ViewId(); view != db.FilesystemView {
+		fmt.Fprintf(os.Stderr, "warning: the db was written by %q, not %q\n", db.FilesystemView, view)
+	}
+	return printChanges(jsonOutput, db.ChangedDirs(fs.OsFs))
+}
@@ -0,0 +1,580 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"android/soong/finder/fs"
+)
+
+// This file provides a finder daemon, which keeps the node tree of a Finder in memory between
+// builds and answers queries over a Unix socket.
+// Instead of statting every directory in the db when a build starts, the daemon watches every
+// directory in the tree (using inotify on Linux) and only lists the directories that were reported
+// as changed before answering the next query. When the tree changed, the db is dumped again
+// so that Finders that don't use the daemon start from an up-to-date cache.
+
+// The protocol is a stream of JSON encoded daemonRequests and daemonResponses. The first request
+// on a connection must be daemonOpOpen, which selects the cache to use. The daemon creates a new
+// Finder the first time it sees a CacheParams, so the first build using the daemon is as slow
+// as a build without it.
+
+const (
+	daemonOpOpen           = "open"
+	daemonOpFindNamed      = "find-named"
+	daemonOpFindFirstNamed = "find-first-named"
+	daemonOpEntries        = "entries"
+	daemonOpWaitForDbDump  = "wait-for-db-dump"
+)
+
+// the maximum amount of time the daemon may take to create a new Finder
+const daemonOpenTimeout = 10 * time.Minute
+
+// the maximum amount of time the daemon may take to answer any other request
+const daemonRequestTimeout = time.Minute
+
+type daemonRequest struct {
+	Op string
+
+	// Config and DbPath are only set for daemonOpOpen
+	Config cacheConfig `json:",omitempty"`
+	DbPath string      `json:",omitempty"`
+
+	// Root is the absolute path of the directory to search
+	Root string `json:",omitempty"`
+	// Name is the file name to search for
+	Name string `json:",omitempty"`
+}
+
+type daemonResponse struct {
+	Error string `json:",omitempty"`
+
+	// Files are the absolute paths of the matching files
+	Files []string `json:",omitempty"`
+	// Dirs are the entries of every directory under the requested root
+	Dirs []DirEntries `json:",omitempty"`
+}
+
+// a dirWatcher reports which directories had entries added, removed or renamed
+type dirWatcher interface {
+	// Add starts watching the directory at <path>
+	Add(path string) error
+	// Remove stops watching the directory at <path>
+	Remove(path string)
+	// Changes returns the directories that changed since the last call to Changes without
+	// blocking. If some changes were lost, <overflow> is true and every directory must be
+	// considered changed. <removed> are the directories that are no longer watched because they
+	// were removed, they must be added again if they were recreated.
+	Changes() (dirs []string, removed []string, overflow bool, err error)
+	Close() error
+}
+
+// a Daemon serves queries from Finders created by NewWithDaemon
+type Daemon struct {
+	listener   net.Listener
+	filesystem fs.FileSystem
+	logger     Logger
+	newWatcher func() (dirWatcher, error)
+	numThreads int
+
+	mutex   sync.Mutex
+	finders map[string]*watchedFinder
+	conns   map[net.Conn]bool
+
+	closed int32
+}
+
+// a watchedFinder is a Finder whose directories are being watched for changes
+type watchedFinder struct {
+	finder  *Finder
+	watcher dirWatcher
+	watched map[string]bool
+
+	// mutex ensures that only one request refreshes the Finder at a time
+	mutex sync.Mutex
+}
+
+// NewDaemon creates a Daemon listening on the Unix socket at <socketPath>. Call Serve to start
+// answering queries.
+func NewDaemon(socketPath string, logger Logger) (*Daemon, error) {
+	return newDaemonImpl(socketPath, fs.OsFs, logger, newInotifyWatcher, defaultNumThreads)
+}
+
+func newDaemonImpl(socketPath string, filesystem fs.FileSystem, logger Logger,
+	newWatcher func() (dirWatcher, error), numThreads int) (*Daemon, error) {
+
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a finder daemon is already listening on %v", socketPath)
+	}
+	// remove the socket of a daemon that didn't shut down cleanly
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Daemon{
+		listener:   listener,
+		filesystem: filesystem,
+		logger:     logger,
+		newWatcher: newWatcher,
+		numThreads: numThreads,
+		finders:    make(map[string]*watchedFinder),
+		conns:      make(map[net.Conn]bool),
+	}, nil
+}
+
+// Serve answers queries until Close is called
+func (d *Daemon) Serve() error {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&d.closed) != 0 {
+				return nil
+			}
+			return err
+		}
+		go d.serveConn(conn)
+	}
+}
+
+// Close stops listening for queries and stops watching the filesystem
+func (d *Daemon) Close() {
+	atomic.StoreInt32(&d.closed, 1)
+	d.listener.Close()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for conn := range d.conns {
+		conn.Close()
+	}
+	for _, w := range d.finders {
+		w.mutex.Lock()
+		w.finder.WaitForDbDump()
+		w.watcher.Close()
+		w.mutex.Unlock()
+	}
+	d.finders = nil
+}
+
+func (d *Daemon) verbosef(format string, args ...interface{}) {
+	d.logger.Output(2, fmt.Sprintf(format, args...))
+}
+
+func (d *Daemon) serveConn(conn net.Conn) {
+	d.mutex.Lock()
+	if d.finders == nil {
+		d.mutex.Unlock()
+		conn.Close()
+		return
+	}
+	d.conns[conn] = true
+	d.mutex.Unlock()
+
+	defer func() {
+		d.mutex.Lock()
+		delete(d.conns, conn)
+		d.mutex.Unlock()
+		conn.Close()
+	}()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	var w *watchedFinder
+	for {
+		var request daemonRequest
+		if err := decoder.Decode(&request); err != nil {
+			// the client closed the connection
+			return
+		}
+
+		var response daemonResponse
+		var err error
+		if request.Op == daemonOpOpen {
+			w, err = d.finderFor(request.Config, request.DbPath)
+		} else if w == nil {
+			err = fmt.Errorf("expected %q request, got %q", daemonOpOpen, request.Op)
+		} else {
+			response, err = w.answer(request)
+		}
+		if err != nil {
+			response = daemonResponse{Error: err.Error()}
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			d.verbosef("Failed to send response: %v\n", err)
+			return
+		}
+	}
+}
+
+// finderFor returns the watchedFinder for the given config, creating it if necessary
+func (d *Daemon) finderFor(config cacheConfig, dbPath string) (*watchedFinder, error) {
+	if config.FilesystemView != d.filesystem.ViewId() {
+		return nil, fmt.Errorf("finder daemon has filesystem view %q, not %q",
+			d.filesystem.ViewId(), config.FilesystemView)
+	}
+	configBytes, err := config.Dump()
+	if err != nil {
+		return nil, err
+	}
+	key := string(configBytes) + "\n" + dbPath
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.finders == nil {
+		return nil, fmt.Errorf("finder daemon is shutting down")
+	}
+	if w, ok := d.finders[key]; ok {
+		return w, nil
+	}
+
+	d.verbosef("Creating finder for %v\n", string(configBytes))
+	f, err := newImpl(config.CacheParams, d.filesystem, d.logger, dbPath, d.numThreads)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := d.newWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &watchedFinder{
+		finder:  f,
+		watcher: watcher,
+		watched: make(map[string]bool),
+	}
+	f.lock()
+	_, err = w.updateWatches()
+	if err == nil {
+		// catch any changes made while the tree was being scanned, before it was watched
+		f.shutdownWaitgroup.Wait()
+		f.restatAll()
+		_, err = w.updateWatches()
+	}
+	f.unlock()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	d.finders[key] = w
+	return w, nil
+}
+
+// answer refreshes the Finder and then answers the request
+func (w *watchedFinder) answer(request daemonRequest) (daemonResponse, error) {
+	var response daemonResponse
+	if err := w.refresh(); err != nil {
+		return response, err
+	}
+
+	f := w.finder
+	switch request.Op {
+	case daemonOpFindNamed:
+		response.Files = f.FindNamedAt(request.Root, request.Name)
+	case daemonOpFindFirstNamed:
+		response.Files = f.FindFirstNamedAt(request.Root, request.Name)
+	case daemonOpEntries:
+		response.Dirs = f.dirEntriesAt(request.Root)
+	case daemonOpWaitForDbDump:
+		f.WaitForDbDump()
+	default:
+		return response, fmt.Errorf("unknown request %q", request.Op)
+	}
+	return response, nil
+}
+
+// refresh updates the Finder with the changes reported by the watcher, and dumps the db again
+// if anything changed
+func (w *watchedFinder) refresh() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	f := w.finder
+	dirs, removed, overflow, err := w.watcher.Changes()
+	if err != nil {
+		return err
+	}
+	for _, path := range removed {
+		delete(w.watched, path)
+	}
+	if len(dirs) == 0 && !overflow {
+		return nil
+	}
+
+	// the previous dump may still be reading the node tree
+	f.WaitForDbDump()
+
+	f.lock()
+	defer f.unlock()
+
+	startTime := time.Now()
+	f.clearModified()
+	if overflow {
+		f.verbosef("Some filesystem events were lost, statting every directory\n")
+		f.restatAll()
+	} else {
+		f.verbosef("Refreshing %v changed directories\n", len(dirs))
+		f.refreshDirs(dirs)
+	}
+	// Directories that were created since the last refresh are watched now, but they may
+	// have changed before the watch was added, so list them once more.
+	for {
+		added, err := w.updateWatches()
+		if err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			break
+		}
+		f.refreshDirs(added)
+	}
+	if err := f.getErr(); err != nil {
+		f.verbosef("%v\n", err)
+	}
+	f.fsErrs = nil
+	f.verbosef("Refreshed finder in %v\n", time.Since(startTime))
+
+	f.goDumpDb()
+	return nil
+}
+
+// updateWatches makes the watched directories match the directories in the cache, and returns
+// the directories that weren't watched before. The caller must hold the Finder's lock.
+func (w *watchedFinder) updateWatches() (added []string, err error) {
+	current := make(map[string]bool)
+	for _, path := range w.finder.dirPaths() {
+		current[path] = true
+	}
+	// Remove the stale watches first, the kernel moves the watch of a renamed directory to its
+	// new path, and removing the old path afterwards would remove the watch of the new one.
+	for path := range w.watched {
+		if !current[path] {
+			w.watcher.Remove(path)
+			delete(w.watched, path)
+		}
+	}
+	for path := range current {
+		if !w.watched[path] {
+			if err := w.watcher.Add(path); err != nil {
+				return nil, err
+			}
+			w.watched[path] = true
+			added = append(added, path)
+		}
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+// dirEntriesAt returns the entries of every directory at or below <rootPath>
+func (f *Finder) dirEntriesAt(rootPath string) []DirEntries {
+	f.lock()
+	defer f.unlock()
+
+	node := f.nodes.GetNode(filepath.Clean(rootPath), false)
+	if node == nil {
+		return nil
+	}
+	var results []DirEntries
+	node.walk(func(n *pathMap) {
+		dirNames := make([]string, 0, len(n.children))
+		for name := range n.children {
+			dirNames = append(dirNames, name)
+		}
+		sort.Strings(dirNames)
+		results = append(results, DirEntries{
+			Path:      n.path,
+			DirNames:  dirNames,
+			FileNames: n.FileNames,
+		})
+	})
+	return results
+}
+
+// NewWithDaemon is like New, but answers queries using the finder daemon listening on
+// <socketPath>. It returns an error if the daemon can't be reached or can't create a cache for
+// <cacheParams>, in which case the caller can use New instead.
+// If the daemon fails later, the Finder falls back to loading the cache from <dbPath>.
+func NewWithDaemon(socketPath string, cacheParams CacheParams, filesystem fs.FileSystem,
+	logger Logger, dbPath string) (*Finder, error) {
+
+	absDbPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Finder{
+		numDbLoadingThreads: defaultNumThreads,
+		numSearchingThreads: defaultNumThreads,
+		cacheMetadata: cacheMetadata{
+			Version: versionString,
+			Config: cacheConfig{
+				CacheParams:    cacheParams,
+				FilesystemView: filesystem.ViewId(),
+			},
+		},
+		logger:     logger,
+		filesystem: filesystem,
+
+		nodes:  *newPathMap("/"),
+		DbPath: dbPath,
+
+		shutdownWaitgroup: sync.WaitGroup{},
+	}
+
+	client, err := dialDaemon(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.call(daemonRequest{
+		Op:     daemonOpOpen,
+		Config: f.cacheMetadata.Config,
+		DbPath: absDbPath,
+	}, daemonOpenTimeout)
+	if err != nil {
+		client.close()
+		return nil, err
+	}
+	f.daemon = client
+	return f, nil
+}
+
+// findWithDaemon asks the daemon for the files named <fileName> under <rootPath>. It returns
+// false if the Finder isn't using a daemon.
+func (f *Finder) findWithDaemon(op string, rootPath string, fileName string) ([]string, bool) {
+	f.lock()
+	defer f.unlock()
+	if f.daemon == nil {
+		return nil, false
+	}
+
+	isRel := !filepath.IsAbs(rootPath)
+	if isRel {
+		rootPath = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, rootPath)
+	}
+	response, err := f.daemon.call(daemonRequest{
+		Op:   op,
+		Root: filepath.Clean(rootPath),
+		Name: fileName,
+	}, daemonRequestTimeout)
+	if err != nil {
+		f.stopUsingDaemon(err)
+		return nil, false
+	}
+
+	results := response.Files
+	if results == nil {
+		results = []string{}
+	}
+	f.formatResults(results, isRel)
+	return results, true
+}
+
+// stopUsingDaemon loads the cache from the filesystem after the daemon failed.
+// The caller must hold the lock.
+func (f *Finder) stopUsingDaemon(err error) {
+	f.verbosef("Finder daemon failed, loading cache from %v: %v\n", f.DbPath, err)
+	f.daemon.close()
+	f.daemon = nil
+	f.loadFromFilesystem()
+	if err := f.getErr(); err != nil {
+		f.verbosef("%v\n", err)
+	}
+}
+
+// a daemonClient sends requests to a Daemon
+type daemonClient struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+func dialDaemon(socketPath string) (*daemonClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &daemonClient{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		decoder: json.NewDecoder(conn),
+	}, nil
+}
+
+func (c *daemonClient) call(request daemonRequest, timeout time.Duration) (daemonResponse, error) {
+	var response daemonResponse
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if err := c.encoder.Encode(request); err != nil {
+		return response, err
+	}
+	if err := c.decoder.Decode(&response); err != nil {
+		return response, err
+	}
+	if response.Error != "" {
+		return response, fmt.Errorf("finder daemon: %s", response.Error)
+	}
+	return response, nil
+}
+
+// entries returns a node tree holding every directory under <rootPath>
+func (c *daemonClient) entries(rootPath string) (*pathMap, error) {
+	response, err := c.call(daemonRequest{Op: daemonOpEntries, Root: rootPath}, daemonRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Dirs) == 0 {
+		return nil, nil
+	}
+
+	root := newPathMap(rootPath)
+	for _, dir := range response.Dirs {
+		rel := strings.TrimPrefix(strings.TrimPrefix(dir.Path, rootPath), "/")
+		node := root.GetNode(rel, true)
+		node.FileNames = dir.FileNames
+		for _, name := range dir.DirNames {
+			if _, ok := node.children[name]; !ok {
+				node.newChild(name)
+			}
+		}
+	}
+	root.UpdateNumDescendentsRecursive()
+	return root, nil
+}
+
+func (c *daemonClient) waitForDbDump() error {
+	_, err := c.call(daemonRequest{Op: daemonOpWaitForDbDump}, daemonRequestTimeout)
+	return err
+}
+
+func (c *daemonClient) close() {
+	c.conn.Close()
+}
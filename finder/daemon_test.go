@@ -0,0 +1,315 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+// a fakeWatcher is a dirWatcher whose changes are reported by the test
+type fakeWatcher struct {
+	mutex   sync.Mutex
+	watched map[string]bool
+	// moved maps the new paths of renamed directories to their old paths, the watch of the old
+	// path is shared with the new path until it is removed, like the kernel does
+	moved    map[string]string
+	changed  []string
+	removed  []string
+	overflow bool
+}
+
+func (w *fakeWatcher) Add(path string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.watched[path] = true
+	return nil
+}
+
+func (w *fakeWatcher) Remove(path string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delete(w.watched, path)
+	for newPath, oldPath := range w.moved {
+		if oldPath == path {
+			// removing the old path removes the shared watch
+			delete(w.watched, newPath)
+			delete(w.moved, newPath)
+		}
+	}
+}
+
+func (w *fakeWatcher) Changes() ([]string, []string, bool, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	changed, removed, overflow := w.changed, w.removed, w.overflow
+	w.changed, w.removed, w.overflow = nil, nil, false
+	return changed, removed, overflow, nil
+}
+
+func (w *fakeWatcher) Close() error {
+	return nil
+}
+
+// change reports changes to the watched directories among <paths>
+func (w *fakeWatcher) change(paths ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, path := range paths {
+		if w.watched[path] {
+			w.changed = append(w.changed, path)
+		}
+	}
+}
+
+// remove drops the watches of the directories at <paths>, like the kernel does when a watched
+// directory is removed
+func (w *fakeWatcher) remove(paths ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, path := range paths {
+		if w.watched[path] {
+			delete(w.watched, path)
+			w.changed = append(w.changed, path)
+			w.removed = append(w.removed, path)
+		}
+	}
+}
+
+// rename reports that the directory at <oldPath> was renamed to <newPath>
+func (w *fakeWatcher) rename(oldPath, newPath string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.moved[newPath] = oldPath
+}
+
+func (w *fakeWatcher) watchedPaths() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var paths []string
+	for path := range w.watched {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// startDaemon starts a Daemon using a fakeWatcher, and returns a Finder that uses it
+func startDaemon(t *testing.T, filesystem *fs.MockFs, cacheParams CacheParams) (*Daemon, *fakeWatcher, *Finder) {
+	watcher := &fakeWatcher{watched: make(map[string]bool), moved: make(map[string]string)}
+	logger := log.New(ioutil.Discard, "", 0)
+	socketPath := filepath.Join(t.TempDir(), "finder.sock")
+
+	daemon, err := newDaemonImpl(socketPath, filesystem, logger,
+		func() (dirWatcher, error) { return watcher, nil }, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go daemon.Serve()
+
+	filesystem.MkDirs("/finder")
+	f, err := NewWithDaemon(socketPath, cacheParams, filesystem, logger, "/finder/finder-db")
+	if err != nil {
+		daemon.Close()
+		t.Fatal(err)
+	}
+	return daemon, watcher, f
+}
+
+func TestDaemon(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/b/findme.txt", filesystem)
+	fs.Create(t, "/tmp/c/ignoreme.txt", filesystem)
+
+	daemon, watcher, finder := startDaemon(t, filesystem, CacheParams{
+		WorkingDirectory: "/tmp",
+		RootDirs:         []string{"/tmp"},
+		IncludeFiles:     []string{"findme.txt"},
+	})
+	defer daemon.Close()
+	defer finder.Shutdown()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/b/findme.txt", "/tmp/a/findme.txt", "/tmp/findme.txt"})
+	fs.AssertSameResponse(t, finder.FindFirstNamedAt("a", "findme.txt"),
+		[]string{"a/findme.txt"})
+	fs.AssertSameResponse(t, watcher.watchedPaths(),
+		[]string{"/tmp", "/tmp/a", "/tmp/a/b", "/tmp/c"})
+
+	// add a file in a new directory and remove a directory
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/c/d/findme.txt", filesystem)
+	fs.RemoveAll(t, "/tmp/a/b", filesystem)
+	filesystem.Clock.Tick()
+	filesystem.ClearMetrics()
+	watcher.change("/tmp/c", "/tmp/a")
+
+	foundPaths := finder.FindMatching(".", func(entries DirEntries) (dirs []string, files []string) {
+		return entries.DirNames, entries.FileNames
+	})
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"a/findme.txt", "c/d/findme.txt", "findme.txt"})
+	// the new directory is listed again after it is watched
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls,
+		[]string{"/tmp/a", "/tmp/c", "/tmp/c/d", "/tmp/c/d"})
+	fs.AssertSameResponse(t, watcher.watchedPaths(),
+		[]string{"/tmp", "/tmp/a", "/tmp/c", "/tmp/c/d"})
+
+	// the db is dumped again after a change
+	finder.WaitForDbDump()
+	f2 := finderWithSameParams(t, finder)
+	defer f2.Shutdown()
+	fs.AssertSameResponse(t, f2.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt", "/tmp/c/d/findme.txt", "/tmp/findme.txt"})
+}
+
+func TestDaemonRecreatedDir(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+
+	daemon, watcher, finder := startDaemon(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	})
+	defer daemon.Close()
+	defer finder.Shutdown()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt"})
+
+	// delete and recreate a directory before the next query
+	filesystem.Clock.Tick()
+	fs.RemoveAll(t, "/tmp/a", filesystem)
+	watcher.remove("/tmp/a")
+	watcher.change("/tmp")
+	fs.Create(t, "/tmp/a/other/findme.txt", filesystem)
+	filesystem.Clock.Tick()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/other/findme.txt"})
+	// the recreated directory is watched again
+	fs.AssertSameResponse(t, watcher.watchedPaths(),
+		[]string{"/tmp", "/tmp/a", "/tmp/a/other"})
+
+	// modify the recreated directory
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	filesystem.Clock.Tick()
+	watcher.change("/tmp/a")
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt", "/tmp/a/other/findme.txt"})
+}
+
+func TestDaemonRenamedDir(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+
+	daemon, watcher, finder := startDaemon(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	})
+	defer daemon.Close()
+	defer finder.Shutdown()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt"})
+
+	// rename a watched directory
+	filesystem.Clock.Tick()
+	fs.Move(t, "/tmp/a", "/tmp/b", filesystem)
+	watcher.rename("/tmp/a", "/tmp/b")
+	watcher.change("/tmp")
+	filesystem.Clock.Tick()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/b/findme.txt"})
+	fs.AssertSameResponse(t, watcher.watchedPaths(),
+		[]string{"/tmp", "/tmp/b"})
+
+	// modify the renamed directory
+	fs.Create(t, "/tmp/b/c/findme.txt", filesystem)
+	filesystem.Clock.Tick()
+	watcher.change("/tmp/b")
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/b/c/findme.txt", "/tmp/b/findme.txt"})
+}
+
+func TestDaemonOverflow(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+
+	daemon, watcher, finder := startDaemon(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	})
+	defer daemon.Close()
+	defer finder.Shutdown()
+
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/a/b/findme.txt", filesystem)
+	filesystem.Clock.Tick()
+	watcher.mutex.Lock()
+	watcher.overflow = true
+	watcher.mutex.Unlock()
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/b/findme.txt", "/tmp/a/findme.txt"})
+}
+
+func TestDaemonFallback(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+
+	cacheParams := CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	}
+	daemon, _, finder := startDaemon(t, filesystem, cacheParams)
+	defer finder.Shutdown()
+	finder.WaitForDbDump()
+
+	// the Finder loads the cache itself once the daemon is gone
+	daemon.Close()
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt"})
+
+	_, err := NewWithDaemon(filepath.Join(t.TempDir(), "missing.sock"), cacheParams, filesystem,
+		log.New(ioutil.Discard, "", 0), "/finder/finder-db")
+	if err == nil {
+		t.Error("expected an error for a missing daemon")
+	}
+
+	// the daemon rejects clients with a different view of the filesystem
+	daemon, _, finder = startDaemon(t, filesystem, cacheParams)
+	defer daemon.Close()
+	defer finder.Shutdown()
+	other := newFs()
+	other.SetViewId("other")
+	_, err = NewWithDaemon(daemon.listener.Addr().String(), cacheParams, other,
+		log.New(ioutil.Discard, "", 0), "/finder/finder-db")
+	if err == nil || !strings.Contains(err.Error(), "filesystem view") {
+		t.Errorf("expected a filesystem view error, got %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/finder/fs"
+)
+
+// This file provides read-only access to the cache database written by a Finder, so that the
+// database can be inspected without creating a Finder (which would update it).
+
+// a Db is the contents of a cache database
+type Db struct {
+	Version string
+	Params  CacheParams
+	// FilesystemView is the user and host that the database was written by
+	FilesystemView string
+	// Dirs are the directories in the database, sorted by Path
+	Dirs []DbDir
+
+	dirsByPath map[string]*DbDir
+}
+
+// a DbDir is a directory recorded in a Db
+type DbDir struct {
+	Path    string
+	ModTime int64
+	Inode   uint64
+	Device  uint64
+	// FileNames are the files in the directory that matched the CacheParams
+	FileNames []string
+	// DirNames are the subdirectories of the directory that are in the Db
+	DirNames []string
+}
+
+// ReadDb reads the cache database at <dbPath>
+func ReadDb(filesystem fs.FileSystem, dbPath string) (*Db, error) {
+	reader, err := filesystem.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	db, err := parseDb(bufio.NewReader(reader))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", dbPath, err)
+	}
+	return db, nil
+}
+
+func parseDb(reader *bufio.Reader) (*Db, error) {
+	readHeaderLine := func(what string) ([]byte, error) {
+		line, err := readLine(reader)
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, fmt.Errorf("failed to read %v: %v", what, err)
+		}
+		return bytes.TrimSuffix(line, []byte{lineSeparator}), nil
+	}
+
+	version, err := readHeaderLine("version")
+	if err != nil {
+		return nil, err
+	}
+	if string(version) != versionString {
+		return nil, fmt.Errorf("unsupported version %q, expected %q", version, versionString)
+	}
+
+	configBytes, err := readHeaderLine("params")
+	if err != nil {
+		return nil, err
+	}
+	var config cacheConfig
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse params: %v", err)
+	}
+
+	db := &Db{
+		Version:        string(version),
+		Params:         config.CacheParams,
+		FilesystemView: config.FilesystemView,
+		dirsByPath:     make(map[string]*DbDir),
+	}
+	for block := 1; ; block++ {
+		data, err := readLine(reader)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(data) > 0 {
+			infos, parseErr := parseCacheEntry(data)
+			if parseErr != nil {
+				return nil, fmt.Errorf("failed to parse block %v: %v", block, parseErr)
+			}
+			for _, info := range infos {
+				db.Dirs = append(db.Dirs, DbDir{
+					Path:      info.Path,
+					ModTime:   info.ModTime,
+					Inode:     info.Inode,
+					Device:    info.Device,
+					FileNames: info.FileNames,
+				})
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	sort.Slice(db.Dirs, func(i, j int) bool { return db.Dirs[i].Path < db.Dirs[j].Path })
+	for i := range db.Dirs {
+		db.dirsByPath[db.Dirs[i].Path] = &db.Dirs[i]
+	}
+	// Dirs are sorted, so the subdirectories of each directory are added in order
+	for i := range db.Dirs {
+		dir := &db.Dirs[i]
+		if dir.Path == "/" {
+			continue
+		}
+		if parent, ok := db.dirsByPath[filepath.Dir(dir.Path)]; ok {
+			parent.DirNames = append(parent.DirNames, filepath.Base(dir.Path))
+		}
+	}
+	return db, nil
+}
+
+// Dir returns the directory at the absolute <path>, or nil if it isn't in the Db
+func (db *Db) Dir(path string) *DbDir {
+	return db.dirsByPath[filepath.Clean(path)]
+}
+
+// FindMatching is like Finder.FindMatching, but searches the Db
+func (db *Db) FindMatching(rootPath string, filter WalkFunc) []string {
+	isRel := !filepath.IsAbs(rootPath)
+	if isRel {
+		rootPath = filepath.Join(db.Params.WorkingDirectory, rootPath)
+	}
+
+	rootPath = filepath.Clean(rootPath)
+	queue := []string{rootPath}
+	if db.Dir(rootPath) == nil {
+		// rootPath is above the root dirs of the Db, start at the topmost dirs below it
+		queue = nil
+		for _, dir := range db.Dirs {
+			if (rootPath == "/" || strings.HasPrefix(dir.Path, rootPath+"/")) &&
+				db.Dir(filepath.Dir(dir.Path)) == nil {
+				queue = append(queue, dir.Path)
+			}
+		}
+	}
+
+	results := []string{}
+	for len(queue) > 0 {
+		dir := db.Dir(queue[0])
+		queue = queue[1:]
+		if dir == nil {
+			continue
+		}
+		dirNames, fileNames := filter(DirEntries{
+			Path:      dir.Path,
+			DirNames:  append([]string(nil), dir.DirNames...),
+			FileNames: append([]string(nil), dir.FileNames...),
+		})
+		for _, name := range fileNames {
+			results = append(results, joinCleanPaths(dir.Path, name))
+		}
+		for _, name := range dirNames {
+			queue = append(queue, joinCleanPaths(dir.Path, name))
+		}
+	}
+
+	if isRel {
+		for i := range results {
+			results[i] = strings.Replace(results[i], db.Params.WorkingDirectory+"/", "", 1)
+		}
+	}
+	sort.Strings(results)
+	return results
+}
+
+// FindGlob returns the files under <rootPath> whose names match <pattern>, using the syntax of
+// filepath.Match
+func (db *Db) FindGlob(rootPath string, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return db.FindMatching(rootPath, func(entries DirEntries) (dirs []string, files []string) {
+		for _, name := range entries.FileNames {
+			if match, _ := filepath.Match(pattern, name); match {
+				files = append(files, name)
+			}
+		}
+		return entries.DirNames, files
+	}), nil
+}
+
+// DirsContaining returns the directories under <rootPath> that contain a file whose name
+// matches <pattern>, using the syntax of filepath.Match
+func (db *Db) DirsContaining(rootPath string, pattern string) ([]string, error) {
+	files, err := db.FindGlob(rootPath, pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	dirs := []string{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// a DbDirChange describes how a directory differs between two snapshots of a cache database, or
+// between a cache database and the filesystem
+type DbDirChange struct {
+	Path string
+	// Added and Removed are set if the directory is only in the new or in the old snapshot
+	Added   bool `json:",omitempty"`
+	Removed bool `json:",omitempty"`
+	// StatsChanged is set if the modification time, inode or device of the directory changed
+	StatsChanged bool `json:",omitempty"`
+	// AddedFiles and RemovedFiles are the matching files that were added to or removed from
+	// the directory
+	AddedFiles   []string `json:",omitempty"`
+	RemovedFiles []string `json:",omitempty"`
+}
+
+func (c DbDirChange) String() string {
+	var changes []string
+	switch {
+	case c.Added:
+		changes = append(changes, "added")
+	case c.Removed:
+		changes = append(changes, "removed")
+	case c.StatsChanged:
+		changes = append(changes, "stats changed")
+	}
+	for _, file := range c.AddedFiles {
+		changes = append(changes, "+"+file)
+	}
+	for _, file := range c.RemovedFiles {
+		changes = append(changes, "-"+file)
+	}
+	return c.Path + ": " + strings.Join(changes, " ")
+}
+
+// DiffDbs returns the directories that differ between the <old> and <new> snapshots of a cache
+// database, sorted by path
+func DiffDbs(old, new *Db) []DbDirChange {
+	var changes []DbDirChange
+	for _, oldDir := range old.Dirs {
+		newDir := new.Dir(oldDir.Path)
+		if newDir == nil {
+			changes = append(changes, DbDirChange{
+				Path:         oldDir.Path,
+				Removed:      true,
+				RemovedFiles: oldDir.FileNames,
+			})
+			continue
+		}
+		change := DbDirChange{
+			Path: oldDir.Path,
+			StatsChanged: oldDir.ModTime != newDir.ModTime || oldDir.Inode != newDir.Inode ||
+				oldDir.Device != newDir.Device,
+			AddedFiles:   subtract(newDir.FileNames, oldDir.FileNames),
+			RemovedFiles: subtract(oldDir.FileNames, newDir.FileNames),
+		}
+		if change.StatsChanged || len(change.AddedFiles) > 0 || len(change.RemovedFiles) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for _, newDir := range new.Dirs {
+		if old.Dir(newDir.Path) == nil {
+			changes = append(changes, DbDirChange{
+				Path:       newDir.Path,
+				Added:      true,
+				AddedFiles: newDir.FileNames,
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// ChangedDirs returns the directories in the Db whose stats on <filesystem> differ from the
+// stats recorded in the Db, which are the directories that the next Finder will list again.
+// Directories that no longer exist are reported as removed.
+func (db *Db) ChangedDirs(filesystem fs.FileSystem) []DbDirChange {
+	var changes []DbDirChange
+	for _, dir := range db.Dirs {
+		stats, err := statDir(filesystem, dir.Path)
+		if err != nil {
+			changes = append(changes, DbDirChange{Path: dir.Path, Removed: true})
+		} else if stats.ModTime != dir.ModTime || stats.Inode != dir.Inode || stats.Device != dir.Device {
+			changes = append(changes, DbDirChange{Path: dir.Path, StatsChanged: true})
+		}
+	}
+	return changes
+}
+
+// subtract returns the elements of <a> that are not in <b>
+func subtract(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !inB[s] {
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
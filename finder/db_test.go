@@ -0,0 +1,135 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"reflect"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+func TestReadDb(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/b/Android.mk", filesystem)
+	fs.Create(t, "/tmp/a/b/c/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/b/ignore.txt", filesystem)
+	fs.Create(t, "/tmp/d/Android.mk", filesystem)
+
+	finder := newFinder(t, filesystem, CacheParams{
+		WorkingDirectory: "/tmp",
+		RootDirs:         []string{"/tmp"},
+		IncludeFiles:     []string{"Android.bp", "Android.mk"},
+	})
+	finder.Shutdown()
+
+	db, err := ReadDb(filesystem, finder.DbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := db.Params.IncludeFiles, []string{"Android.bp", "Android.mk"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want IncludeFiles %q, got %q", w, g)
+	}
+	if dir := db.Dir("/tmp/a/b"); dir == nil {
+		t.Errorf("expected /tmp/a/b in db")
+	} else {
+		fs.AssertSameResponse(t, dir.FileNames, []string{"Android.mk"})
+		fs.AssertSameResponse(t, dir.DirNames, []string{"c"})
+	}
+
+	files, err := db.FindGlob("/tmp", "Android.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, files, []string{"/tmp/Android.bp", "/tmp/a/Android.bp",
+		"/tmp/a/b/Android.mk", "/tmp/a/b/c/Android.bp", "/tmp/d/Android.mk"})
+
+	files, err = db.FindGlob("a", "*.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, files, []string{"a/Android.bp", "a/b/c/Android.bp"})
+
+	dirs, err := db.DirsContaining("/", "Android.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, dirs, []string{"/tmp/a/b", "/tmp/d"})
+
+	if _, err := db.FindGlob("/", "["); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+func TestDiffDbs(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/Android.bp", filesystem)
+	fs.Create(t, "/tmp/b/Android.bp", filesystem)
+	fs.Create(t, "/tmp/c/Android.bp", filesystem)
+
+	finder := newFinder(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"Android.bp", "Android.mk"},
+	})
+	finder.Shutdown()
+	oldDb, err := ReadDb(filesystem, finder.DbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/a/Android.mk", filesystem)
+	fs.RemoveAll(t, "/tmp/b", filesystem)
+	fs.Create(t, "/tmp/d/Android.bp", filesystem)
+	filesystem.Clock.Tick()
+
+	// the stats of the changed directories differ from the db before it is updated
+	changed := oldDb.ChangedDirs(filesystem)
+	want := []DbDirChange{
+		{Path: "/tmp", StatsChanged: true},
+		{Path: "/tmp/a", StatsChanged: true},
+		{Path: "/tmp/b", Removed: true},
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("want changed dirs\n%v\ngot\n%v", want, changed)
+	}
+
+	finder = finderWithSameParams(t, finder)
+	finder.Shutdown()
+	newDb, err := ReadDb(filesystem, finder.DbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := DiffDbs(oldDb, newDb)
+	want = []DbDirChange{
+		{Path: "/tmp", StatsChanged: true},
+		{Path: "/tmp/a", StatsChanged: true, AddedFiles: []string{"Android.mk"}},
+		{Path: "/tmp/b", Removed: true, RemovedFiles: []string{"Android.bp"}},
+		{Path: "/tmp/d", Added: true, AddedFiles: []string{"Android.bp"}},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("want diff\n%v\ngot\n%v", want, diff)
+	}
+	if g, w := diff[1].String(), "/tmp/a: stats changed +Android.mk"; g != w {
+		t.Errorf("want %q, got %q", w, g)
+	}
+
+	if changed := newDb.ChangedDirs(filesystem); len(changed) != 0 {
+		t.Errorf("expected no changed dirs after the db was updated, got %v", changed)
+	}
+}
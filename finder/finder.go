@@ -160,6 +160,9 @@ type Finder struct {
 	// non-temporary state
 	modifiedFlag int32
 	nodes        pathMap
+
+	// daemon is set if queries are answered by a finder daemon rather than by nodes
+	daemon *daemonClient
 }
 
 var defaultNumThreads = runtime.NumCPU() * 2
@@ -241,6 +244,9 @@ func (f *Finder) FindNamed(fileName string) []string {
 // The reason a caller might use FindNamedAt instead of FindNamed is if they want
 // to limit their search to a subset of the cache
 func (f *Finder) FindNamedAt(rootPath string, fileName string) []string {
+	if results, ok := f.findWithDaemon(daemonOpFindNamed, rootPath, fileName); ok {
+		return results
+	}
 	filter := func(entries DirEntries) (dirNames []string, fileNames []string) {
 		matches := []string{}
 		for _, foundName := range entries.FileNames {
@@ -263,6 +269,9 @@ func (f *Finder) FindFirstNamed(fileName string) []string {
 // FindFirstNamedAt searches for every file named <fileName>
 // Whenever it finds a match, it stops search subdirectories
 func (f *Finder) FindFirstNamedAt(rootPath string, fileName string) []string {
+	if results, ok := f.findWithDaemon(daemonOpFindFirstNamed, rootPath, fileName); ok {
+		return results
+	}
 	filter := func(entries DirEntries) (dirNames []string, fileNames []string) {
 		matches := []string{}
 		for _, foundName := range entries.FileNames {
@@ -301,7 +310,17 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 	f.lock()
 	defer f.unlock()
 
-	node := f.nodes.GetNode(rootPath, false)
+	var node *pathMap
+	if f.daemon != nil {
+		var err error
+		node, err = f.daemon.entries(rootPath)
+		if err != nil {
+			f.stopUsingDaemon(err)
+		}
+	}
+	if f.daemon == nil {
+		node = f.nodes.GetNode(rootPath, false)
+	}
 	if node == nil {
 		f.verbosef("No data for path %v ; apparently not included in cache params: %v\n",
 			rootPath, f.cacheMetadata.Config.CacheParams)
@@ -314,12 +333,7 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 	results := f.findInCacheMultithreaded(node, filter, f.numSearchingThreads)
 
 	// format and return results
-	if isRel {
-		for i := 0; i < len(results); i++ {
-			results[i] = strings.Replace(results[i], workingDir+"/", "", 1)
-		}
-	}
-	sort.Strings(results)
+	f.formatResults(results, isRel)
 	f.verbosef("Found %v files under %v in %v using cache\n",
 		len(results), rootPath, time.Since(scanStart))
 	return results
@@ -329,15 +343,42 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 // Currently, that only entails waiting for the database dump to complete.
 func (f *Finder) Shutdown() {
 	f.WaitForDbDump()
+
+	f.lock()
+	defer f.unlock()
+	if f.daemon != nil {
+		f.daemon.close()
+		f.daemon = nil
+	}
 }
 
 // WaitForDbDump returns once the database has been written to f.DbPath.
 func (f *Finder) WaitForDbDump() {
+	f.lock()
+	if f.daemon != nil {
+		if err := f.daemon.waitForDbDump(); err != nil {
+			f.stopUsingDaemon(err)
+		}
+	}
+	f.unlock()
+
 	f.shutdownWaitgroup.Wait()
 }
 
 // End of public api
 
+// formatResults sorts the results of a query, and makes them relative to the working directory
+// if the query was relative
+func (f *Finder) formatResults(results []string, isRel bool) {
+	if isRel {
+		workingDir := f.cacheMetadata.Config.WorkingDirectory
+		for i := 0; i < len(results); i++ {
+			results[i] = strings.Replace(results[i], workingDir+"/", "", 1)
+		}
+	}
+	sort.Strings(results)
+}
+
 func (f *Finder) goDumpDb() {
 	if f.wasModified() {
 		f.shutdownWaitgroup.Add(1)
@@ -559,6 +600,14 @@ func (m *pathMap) MergeIn(other *pathMap) {
 	m.UpdateNumDescendents()
 }
 
+// walk calls visit for this node and every node below it
+func (m *pathMap) walk(visit func(*pathMap)) {
+	visit(m)
+	for _, child := range m.children {
+		child.walk(visit)
+	}
+}
+
 func (m *pathMap) DumpAll() []dirFullInfo {
 	results := []dirFullInfo{}
 	m.dumpInto("", &results)
@@ -697,7 +746,7 @@ func (f *Finder) serializeCacheEntry(dirInfos []dirFullInfo) ([]byte, error) {
 	return bytes, err
 }
 
-func (f *Finder) parseCacheEntry(bytes []byte) ([]dirFullInfo, error) {
+func parseCacheEntry(bytes []byte) ([]dirFullInfo, error) {
 	var cacheEntry CacheEntry
 	err := json.Unmarshal(bytes, &cacheEntry)
 	if err != nil {
@@ -743,13 +792,13 @@ func (f *Finder) parseCacheEntry(bytes []byte) ([]dirFullInfo, error) {
 //   other outputs.
 const lineSeparator = byte('\n')
 
-func (f *Finder) readLine(reader *bufio.Reader) ([]byte, error) {
+func readLine(reader *bufio.Reader) ([]byte, error) {
 	return reader.ReadBytes(lineSeparator)
 }
 
 // validateCacheHeader reads the cache header from cacheReader and tells whether the cache is compatible with this Finder
 func (f *Finder) validateCacheHeader(cacheReader *bufio.Reader) bool {
-	cacheVersionBytes, err := f.readLine(cacheReader)
+	cacheVersionBytes, err := readLine(cacheReader)
 	if err != nil {
 		f.verbosef("Failed to read database header; database is invalid\n")
 		return false
@@ -764,7 +813,7 @@ func (f *Finder) validateCacheHeader(cacheReader *bufio.Reader) bool {
 		return false
 	}
 
-	cacheParamBytes, err := f.readLine(cacheReader)
+	cacheParamBytes, err := readLine(cacheReader)
 	if err != nil {
 		f.verbosef("Failed to read database search params; database is invalid\n")
 		return false
@@ -793,7 +842,7 @@ func (f *Finder) loadBytes(id int, data []byte) (m *pathMap, dirsToWalk []string
 
 	helperStartTime := time.Now()
 
-	cachedNodes, err := f.parseCacheEntry(data)
+	cachedNodes, err := parseCacheEntry(data)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Failed to parse block %v: %v\n", id, err.Error())
 	}
@@ -871,7 +920,7 @@ func (f *Finder) startFromExternalCache() (err error) {
 			// to unmarshal it in parallel. In order to find valid places to
 			// break the input, we scan for the line separators that we inserted
 			// (for this purpose) when we dumped the database.
-			data, err := f.readLine(bufferedReader)
+			data, err := readLine(bufferedReader)
 			var response dataBlock
 			done := false
 			if err != nil && err != io.EOF {
@@ -1040,6 +1089,85 @@ func (f *Finder) startWithoutExternalCache() {
 	f.verbosef("Scanned filesystem (not using cache) in %v\n", time.Now().Sub(startTime))
 }
 
+// refreshDirs updates the cache for directories whose entries are known to have changed, and
+// scans any new subdirectories. Paths that are not in the cache are ignored.
+// The caller must hold the lock.
+func (f *Finder) refreshDirs(paths []string) {
+	// Look up every node before starting any threads, listDirSync may modify the tree.
+	nodes := make([]*pathMap, 0, len(paths))
+	for _, path := range paths {
+		node := f.nodes.GetNode(filepath.Clean(path), false)
+		if node != nil {
+			// forget the stats so that statDirAsync lists the directory again even if
+			// its modification time didn't visibly change
+			node.statResponse = statResponse{}
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+
+	f.threadPool = newThreadPool(f.numDbLoadingThreads)
+	for _, node := range nodes {
+		f.statDirAsync(node)
+	}
+	f.threadPool.Wait()
+	f.threadPool = nil
+
+	f.nodes.UpdateNumDescendentsRecursive()
+}
+
+// restatAll stats every directory in the cache under the root dirs, like when loading the db,
+// and lists the ones that changed. The caller must hold the lock.
+func (f *Finder) restatAll() {
+	var nodes []*pathMap
+	f.walkRootDirs(func(node *pathMap) {
+		nodes = append(nodes, node)
+	})
+
+	f.threadPool = newThreadPool(f.numDbLoadingThreads)
+	for _, node := range nodes {
+		f.statDirAsync(node)
+	}
+	f.threadPool.Wait()
+	f.threadPool = nil
+
+	f.nodes.UpdateNumDescendentsRecursive()
+}
+
+// dirPaths returns the path of every existing directory in the cache under the root dirs
+// The caller must hold the lock.
+func (f *Finder) dirPaths() []string {
+	var paths []string
+	f.walkRootDirs(func(node *pathMap) {
+		if node.ModTime != 0 {
+			paths = append(paths, node.path)
+		}
+	})
+	return paths
+}
+
+// walkRootDirs calls visit once for every node at or below the root dirs
+func (f *Finder) walkRootDirs(visit func(*pathMap)) {
+	seen := make(map[*pathMap]bool)
+	for _, root := range f.cacheMetadata.Config.RootDirs {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, root)
+		}
+		node := f.nodes.GetNode(filepath.Clean(root), false)
+		if node == nil {
+			continue
+		}
+		node.walk(func(node *pathMap) {
+			if !seen[node] {
+				seen[node] = true
+				visit(node)
+			}
+		})
+	}
+}
+
 // isInfoUpToDate tells whether <new> can confirm that results computed at <old> are still valid
 func (f *Finder) isInfoUpToDate(old statResponse, new statResponse) (equal bool) {
 	if old.Inode != new.Inode {
@@ -1064,6 +1192,10 @@ func (f *Finder) setModified() {
 	atomic.StoreInt32(&f.modifiedFlag, newVal)
 }
 
+func (f *Finder) clearModified() {
+	atomic.StoreInt32(&f.modifiedFlag, 0)
+}
+
 // sortedDirEntries exports directory entries to facilitate dumping them to the external cache
 func (f *Finder) sortedDirEntries() []dirFullInfo {
 	startTime := time.Now()
@@ -1274,29 +1406,38 @@ func (f *Finder) statDirAsync(dir *pathMap) {
 }
 
 func (f *Finder) statDirSync(path string) statResponse {
-
-	fileInfo, err := f.filesystem.Lstat(path)
-
-	var stats statResponse
+	stats, err := statDir(f.filesystem, path)
 	if err != nil {
 		// possibly record this error
 		f.onFsError(path, err)
 		// in case of a failure to stat the directory, treat the directory as missing (modTime = 0)
-		return stats
+		return statResponse{}
+	}
+	return stats
+}
+
+// statDir returns the stats of the directory at <path>, or the error returned by Lstat
+func statDir(filesystem fs.FileSystem, path string) (statResponse, error) {
+
+	fileInfo, err := filesystem.Lstat(path)
+
+	var stats statResponse
+	if err != nil {
+		return stats, err
 	}
 	modTime := fileInfo.ModTime()
 	stats = statResponse{}
-	inode, err := f.filesystem.InodeNumber(fileInfo)
+	inode, err := filesystem.InodeNumber(fileInfo)
 	if err != nil {
 		panic(fmt.Sprintf("Could not get inode number of %v: %v\n", path, err.Error()))
 	}
 	stats.Inode = inode
-	device, err := f.filesystem.DeviceNumber(fileInfo)
+	device, err := filesystem.DeviceNumber(fileInfo)
 	if err != nil {
 		panic(fmt.Sprintf("Could not get device number of %v: %v\n", path, err.Error()))
 	}
 	stats.Device = device
-	permissionsChangeTime, err := f.filesystem.PermTime(fileInfo)
+	permissionsChangeTime, err := filesystem.PermTime(fileInfo)
 
 	if err != nil {
 		panic(fmt.Sprintf("Could not get permissions modification time (CTime) of %v: %v\n", path, err.Error()))
@@ -1310,7 +1451,7 @@ func (f *Finder) statDirSync(path string) statResponse {
 	}
 	stats.ModTime = modTime.UnixNano()
 
-	return stats
+	return stats, nil
 }
 
 func (f *Finder) shouldIncludeFile(fileName string) bool {
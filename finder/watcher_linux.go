@@ -0,0 +1,126 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+// the events that can change the result of listing or statting a directory
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF |
+	syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+// an inotifyWatcher is a dirWatcher using a non-blocking inotify file descriptor, so that the
+// pending events can be read just before answering a query
+type inotifyWatcher struct {
+	fd    int
+	paths map[int32]string
+	wds   map[string]int32
+	buf   []byte
+}
+
+func newInotifyWatcher() (dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	return &inotifyWatcher{
+		fd:    fd,
+		paths: make(map[int32]string),
+		wds:   make(map[string]int32),
+		buf:   make([]byte, 64*1024),
+	}, nil
+}
+
+func (w *inotifyWatcher) Add(path string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+	if err == syscall.ENOENT || err == syscall.ENOTDIR || err == syscall.EACCES {
+		// the directory was removed or isn't readable, the next listing of its parent
+		// will take care of it
+		return nil
+	} else if err == syscall.ENOSPC {
+		return fmt.Errorf("could not watch %v: too many watches, consider increasing "+
+			"/proc/sys/fs/inotify/max_user_watches", path)
+	} else if err != nil {
+		return fmt.Errorf("could not watch %v: %v", path, err)
+	}
+	if oldPath, ok := w.paths[int32(wd)]; ok && oldPath != path {
+		// the directory was renamed, the kernel returned the watch of its old path, which must
+		// not be removed with the old path
+		delete(w.wds, oldPath)
+	}
+	w.paths[int32(wd)] = path
+	w.wds[path] = int32(wd)
+	return nil
+}
+
+func (w *inotifyWatcher) Remove(path string) {
+	if wd, ok := w.wds[path]; ok {
+		syscall.InotifyRmWatch(w.fd, uint32(wd))
+		delete(w.wds, path)
+		delete(w.paths, wd)
+	}
+}
+
+func (w *inotifyWatcher) Changes() (dirs []string, removed []string, overflow bool, err error) {
+	changed := make(map[string]bool)
+	for {
+		n, err := syscall.Read(w.fd, w.buf)
+		if err == syscall.EAGAIN {
+			break
+		} else if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return nil, nil, false, os.NewSyscallError("read", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&w.buf[offset]))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				overflow = true
+				continue
+			}
+			path, ok := w.paths[event.Wd]
+			if !ok {
+				continue
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				// the kernel removed the watch because the directory was removed
+				delete(w.paths, event.Wd)
+				delete(w.wds, path)
+				removed = append(removed, path)
+			}
+			changed[path] = true
+		}
+	}
+
+	for path := range changed {
+		dirs = append(dirs, path)
+	}
+	sort.Strings(dirs)
+	sort.Strings(removed)
+	return dirs, removed, overflow, nil
+}
+
+func (w *inotifyWatcher) Close() error {
+	return syscall.Close(w.fd)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInotifyWatcherRenamedDir(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "a")
+	newPath := filepath.Join(dir, "b")
+	if err := os.Mkdir(oldPath, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := newInotifyWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(oldPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := watcher.Changes(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the kernel returns the same watch for the new path, removing the old path afterwards
+	// must not remove it
+	if err := watcher.Add(newPath); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Remove(oldPath)
+
+	if err := ioutil.WriteFile(filepath.Join(newPath, "file"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	dirs, removed, overflow, err := watcher.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dirs, []string{newPath}) || removed != nil || overflow {
+		t.Errorf("expected changes in %q, got %q, removed %q, overflow %v", newPath, dirs, removed, overflow)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"android/soong/android"
+	"android/soong/bazel"
 	"android/soong/dexpreopt"
 
 	"github.com/google/blueprint"
@@ -40,6 +41,8 @@ type AndroidLibraryDependency interface {
 
 func init() {
 	RegisterAARBuildComponents(android.InitRegistrationContext)
+
+	android.RegisterBp2BuildMutator("android_library", AndroidLibraryBp2Build)
 }
 
 func RegisterAARBuildComponents(ctx android.RegistrationContext) {
@@ -498,6 +501,10 @@ var _ AndroidLibraryDependency = (*AndroidLibrary)(nil)
 
 func (a *AndroidLibrary) DepsMutator(ctx android.BottomUpMutatorContext) {
 	a.Module.deps(ctx)
+	// The framework resources are provided by the sdk in Bazel.
+	if ctx.BazelConversionMode() {
+		return
+	}
 	sdkDep := decodeSdkDep(ctx, android.SdkContext(a))
 	if sdkDep.hasFrameworkLibs() {
 		a.aapt.deps(ctx, sdkDep)
@@ -567,9 +574,84 @@ func AndroidLibraryFactory() android.Module {
 
 	android.InitApexModule(module)
 	InitJavaModule(module, android.DeviceSupported)
+	android.InitBazelModule(module)
 	return module
 }
 
+type bazelAndroidLibraryAttributes struct {
+	Srcs           bazel.LabelListAttribute
+	Deps           bazel.LabelListAttribute
+	Exports        bazel.LabelListAttribute
+	Manifest       bazel.LabelAttribute
+	Resource_files bazel.LabelListAttribute
+	Javacopts      bazel.StringListAttribute
+	Sdk_version    string
+}
+
+type bazelAndroidLibrary struct {
+	android.BazelTargetModuleBase
+	bazelAndroidLibraryAttributes
+}
+
+func BazelAndroidLibraryFactory() android.Module {
+	module := &bazelAndroidLibrary{}
+	module.AddProperties(&module.bazelAndroidLibraryAttributes)
+	android.InitBazelTargetModule(module)
+	return module
+}
+
+func (m *bazelAndroidLibrary) Name() string {
+	return m.BaseModuleName()
+}
+
+func (m *bazelAndroidLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {}
+
+func AndroidLibraryBp2Build(ctx android.TopDownMutatorContext) {
+	m, ok := ctx.Module().(*AndroidLibrary)
+	if !ok || !m.ConvertWithBp2build(ctx) {
+		return
+	}
+
+	if ctx.ModuleType() != "android_library" {
+		return
+	}
+
+	if !bp2BuildSupportedJavaSrcs(ctx, &m.Module) {
+		return
+	}
+
+	javaAttrs := bp2BuildParseJavaProps(ctx, &m.Module)
+
+	manifest := proptools.StringDefault(m.aaptProperties.Manifest, "AndroidManifest.xml")
+
+	// Resource_dirs defaults to ["res"], the glob is empty if it doesn't exist.
+	resourceDirs := m.aaptProperties.Resource_dirs
+	if resourceDirs == nil {
+		resourceDirs = []string{"res"}
+	}
+	var resourceGlobs []string
+	for _, dir := range resourceDirs {
+		resourceGlobs = append(resourceGlobs, filepath.Join(dir, "**/*"))
+	}
+
+	attrs := &bazelAndroidLibraryAttributes{
+		Srcs:           javaAttrs.srcs,
+		Deps:           javaAttrs.deps,
+		Exports:        javaAttrs.exports,
+		Manifest:       bazel.LabelAttribute{Value: android.BazelLabelForModuleSrcSingle(ctx, manifest)},
+		Resource_files: bazel.MakeLabelListAttribute(android.BazelLabelForModuleSrc(ctx, resourceGlobs)),
+		Javacopts:      javaAttrs.javacopts,
+		Sdk_version:    String(m.deviceProperties.Sdk_version),
+	}
+
+	props := bazel.BazelTargetModuleProperties{
+		Rule_class:        "android_library",
+		Bzl_load_location: "//build/bazel/rules:android_library.bzl",
+	}
+
+	ctx.CreateBazelTargetModule(BazelAndroidLibraryFactory, m.Name(), props, attrs)
+}
+
 //
 // AAR (android library) prebuilts
 //
@@ -0,0 +1,209 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"path/filepath"
+
+	"android/soong/android"
+	"android/soong/bazel"
+)
+
+// bp2build functions and helpers for converting java modules to Bazel.
+
+func init() {
+	android.DepsBp2BuildMutators(RegisterDepsBp2Build)
+}
+
+func RegisterDepsBp2Build(ctx android.RegisterMutatorsContext) {
+	ctx.BottomUp("java_bp2build_deps", depsBp2BuildMutator)
+}
+
+// javaModuleForBp2Build returns the Module of the java module types that have bp2build
+// converters, or nil for any other module.
+func javaModuleForBp2Build(module android.Module) *Module {
+	switch m := module.(type) {
+	case *Library:
+		return &m.Module
+	case *Binary:
+		return &m.Module
+	case *AndroidLibrary:
+		return &m.Module
+	}
+	return nil
+}
+
+// depsBp2BuildMutator adds dependencies on the libraries in the arch and target specific
+// properties of java modules, which the deps mutator only adds for the matching variant, so that
+// they can be resolved to Bazel labels by the bp2build mutators.
+func depsBp2BuildMutator(ctx android.BottomUpMutatorContext) {
+	module := javaModuleForBp2Build(ctx.Module())
+	if module == nil || !module.ConvertWithBp2build(ctx) {
+		return
+	}
+
+	var allDeps []string
+	for _, p := range module.GetTargetProperties(&CommonProperties{}) {
+		if props, ok := p.(*CommonProperties); ok {
+			allDeps = append(allDeps, props.Libs...)
+			allDeps = append(allDeps, props.Static_libs...)
+		}
+	}
+	for _, p := range module.GetArchProperties(ctx, &CommonProperties{}) {
+		if props, ok := p.(*CommonProperties); ok {
+			allDeps = append(allDeps, props.Libs...)
+			allDeps = append(allDeps, props.Static_libs...)
+		}
+	}
+
+	ctx.AddDependency(ctx.Module(), nil, android.SortedUniqueStrings(allDeps)...)
+}
+
+// bp2BuildSupportedJavaSrcs returns whether the srcs of a java module can be compiled by the Bazel
+// java rules, which don't generate java sources from aidl, logtags and proto files like Soong does.
+// The first unsupported src is recorded as the reason the module wasn't converted.
+func bp2BuildSupportedJavaSrcs(ctx android.TopDownMutatorContext, module *Module) bool {
+	srcs := android.CopyOf(module.properties.Srcs)
+	for _, p := range module.GetArchProperties(ctx, &CommonProperties{}) {
+		if props, ok := p.(*CommonProperties); ok {
+			srcs = append(srcs, props.Srcs...)
+		}
+	}
+	for _, p := range module.GetTargetProperties(&CommonProperties{}) {
+		if props, ok := p.(*CommonProperties); ok {
+			srcs = append(srcs, props.Srcs...)
+		}
+	}
+	for _, src := range srcs {
+		switch filepath.Ext(src) {
+		case ".aidl", ".logtags", ".proto":
+			module.MarkBp2buildUnconvertible(android.UnconvertedReasonUnsupportedProperty,
+				"srcs: generated java sources from "+src)
+			return false
+		}
+	}
+	return true
+}
+
+type javaAttributes struct {
+	srcs                bazel.LabelListAttribute
+	deps                bazel.LabelListAttribute
+	exports             bazel.LabelListAttribute
+	javacopts           bazel.StringListAttribute
+	resources           bazel.LabelListAttribute
+	resourceStripPrefix string
+}
+
+// javaDeps returns the modules that a java module is compiled against.
+func javaDeps(props *CommonProperties) []string {
+	deps := append([]string(nil), props.Libs...)
+	deps = append(deps, props.Static_libs...)
+	return android.SortedUniqueStrings(deps)
+}
+
+// javaExports returns the modules that are exported by a java module. Static libraries are
+// exported, as their classes are packaged into the jar of the module.
+func javaExports(props *CommonProperties) []string {
+	return android.SortedUniqueStrings(android.CopyOf(props.Static_libs))
+}
+
+// bp2BuildParseJavaProps returns the attributes that are common to the Bazel targets of java
+// modules, with the arch and target specific values of srcs, libs, static_libs and javacflags as
+// configurable values. Arch and target specific srcs are handled like those of cc modules.
+func bp2BuildParseJavaProps(ctx android.TopDownMutatorContext, module *Module) javaAttributes {
+	props := &module.properties
+
+	// baseSrcs and baseExcludeSrcs contain the srcs and exclude_srcs that are used for every
+	// configuration.
+	var baseSrcs, baseExcludeSrcs []string
+
+	// Parse srcs from an arch or OS's props value, taking the base srcs and exclude srcs into
+	// account.
+	parseSrcs := func(configProps *CommonProperties) bazel.LabelList {
+		allSrcs := append(android.CopyOf(baseSrcs), configProps.Srcs...)
+		allExcludeSrcs := append(android.CopyOf(baseExcludeSrcs), configProps.Exclude_srcs...)
+		return android.BazelLabelForModuleSrcExcludes(ctx, allSrcs, allExcludeSrcs)
+	}
+
+	var srcs bazel.LabelListAttribute
+	srcs.Value = parseSrcs(props)
+	baseSrcs = props.Srcs
+	baseExcludeSrcs = props.Exclude_srcs
+	baseSrcsLabelList := srcs.Value
+
+	archProperties := module.GetArchProperties(ctx, &CommonProperties{})
+	for arch, p := range archProperties {
+		if archProps, ok := p.(*CommonProperties); ok {
+			if len(archProps.Srcs) > 0 || len(archProps.Exclude_srcs) > 0 {
+				srcsList := parseSrcs(archProps)
+				srcs.SetValueForArch(arch.Name, srcsList)
+				// The base srcs value should not contain any arch-specific excludes.
+				srcs.Value = bazel.SubtractBazelLabelList(srcs.Value, bazel.LabelList{Includes: srcsList.Excludes})
+			}
+		}
+	}
+	// Remove the base srcs from the arch values now that the base value is final, and select the
+	// base srcs that were excluded for some archs in the default condition.
+	for arch := range archProperties {
+		srcs.SetValueForArch(arch.Name, bazel.SubtractBazelLabelList(srcs.GetValueForArch(arch.Name), srcs.Value))
+	}
+	srcs.SetValueForArch(bazel.CONDITIONS_DEFAULT, bazel.SubtractBazelLabelList(baseSrcsLabelList, srcs.Value))
+
+	attrs := javaAttributes{
+		srcs:      srcs,
+		deps:      bazel.MakeLabelListAttribute(android.BazelLabelForModuleDeps(ctx, javaDeps(props))),
+		exports:   bazel.MakeLabelListAttribute(android.BazelLabelForModuleDeps(ctx, javaExports(props))),
+		javacopts: bazel.MakeStringListAttribute(props.Javacflags),
+	}
+
+	for arch, p := range archProperties {
+		if archProps, ok := p.(*CommonProperties); ok {
+			attrs.deps.SetValueForArch(arch.Name, android.BazelLabelForModuleDeps(ctx, javaDeps(archProps)))
+			attrs.exports.SetValueForArch(arch.Name, android.BazelLabelForModuleDeps(ctx, javaExports(archProps)))
+			attrs.javacopts.SetValueForArch(arch.Name, archProps.Javacflags)
+		}
+	}
+
+	for os, p := range module.GetTargetProperties(&CommonProperties{}) {
+		if osProps, ok := p.(*CommonProperties); ok {
+			attrs.srcs.SetValueForOS(os.Name, bazel.SubtractBazelLabelList(parseSrcs(osProps), baseSrcsLabelList))
+			attrs.deps.SetValueForOS(os.Name, android.BazelLabelForModuleDeps(ctx, javaDeps(osProps)))
+			attrs.exports.SetValueForOS(os.Name, android.BazelLabelForModuleDeps(ctx, javaExports(osProps)))
+			attrs.javacopts.SetValueForOS(os.Name, osProps.Javacflags)
+		}
+	}
+
+	// Resources keep their paths relative to the module directory, or to the resource directory if
+	// there is only one. Bazel's default prefix is used when both are mixed, and for java_resources
+	// at the top level where the module directory is already the root.
+	resources := android.BazelLabelForModuleSrcExcludes(ctx, props.Java_resources, props.Exclude_java_resources)
+	var resourceGlobs, excludeResourceGlobs []string
+	for _, dir := range props.Java_resource_dirs {
+		resourceGlobs = append(resourceGlobs, filepath.Join(dir, "**/*"))
+	}
+	for _, dir := range props.Exclude_java_resource_dirs {
+		excludeResourceGlobs = append(excludeResourceGlobs, filepath.Join(dir, "**/*"))
+	}
+	resources.Append(android.BazelLabelForModuleSrcExcludes(ctx, resourceGlobs, excludeResourceGlobs))
+	attrs.resources = bazel.MakeLabelListAttribute(resources)
+
+	if len(props.Java_resource_dirs) == 0 && len(props.Java_resources) > 0 && ctx.ModuleDir() != "." {
+		attrs.resourceStripPrefix = ctx.ModuleDir()
+	} else if len(props.Java_resource_dirs) == 1 && len(props.Java_resources) == 0 {
+		attrs.resourceStripPrefix = filepath.Join(ctx.ModuleDir(), props.Java_resource_dirs[0])
+	}
+
+	return attrs
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/bazel"
 	"android/soong/cc"
 	"android/soong/dexpreopt"
 	"android/soong/java/config"
@@ -37,6 +38,9 @@ func init() {
 	registerJavaBuildComponents(android.InitRegistrationContext)
 
 	RegisterJavaSdkMemberTypes()
+
+	android.RegisterBp2BuildMutator("java_library", JavaLibraryBp2Build)
+	android.RegisterBp2BuildMutator("java_binary_host", JavaBinaryHostBp2Build)
 }
 
 func registerJavaBuildComponents(ctx android.RegistrationContext) {
@@ -231,6 +235,14 @@ type dependencyTag struct {
 	name string
 }
 
+// StaticLinkDep returns true for static_libs, whose classes are included in the jars of the
+// modules that depend on them.
+func (d dependencyTag) StaticLinkDep() bool {
+	return d == staticLibTag
+}
+
+var _ android.StaticLinkDependencyTag = dependencyTag{}
+
 // installDependencyTag is a dependency tag that is annotated to cause the installed files of the
 // dependency to be installed when the parent module is installed.
 type installDependencyTag struct {
@@ -494,6 +506,11 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 
 	j.checkSdkVersions(ctx)
+	if j.MixedBuildsEnabled(ctx) && j.bazelOutputsSufficient(ctx) &&
+		j.generateBazelBuildActions(ctx, j.GetBazelLabel(ctx, j)) {
+		return
+	}
+
 	j.dexpreopter.installPath = android.PathForModuleInstall(ctx, "framework", j.Stem()+".jar")
 	j.dexpreopter.isSDKLibrary = j.deviceProperties.IsSDKLibrary
 	if j.dexProperties.Uncompress_dex == nil {
@@ -518,6 +535,64 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
+// bazelOutputsSufficient returns true if the variant only needs the jars built by Bazel, i.e. it is
+// neither dexed nor installed. The other variants are still compiled by Soong.
+func (j *Library) bazelOutputsSufficient(ctx android.ModuleContext) bool {
+	if ctx.Host() {
+		// Host libraries are always installed.
+		return false
+	}
+	apexInfo := ctx.Provider(android.ApexInfoProvider).(android.ApexInfo)
+	if !apexInfo.IsForPlatform() {
+		// The apex variants are dexed to be packaged into the apex.
+		return false
+	}
+	return !Bool(j.properties.Installable) && !Bool(j.dexProperties.Compile_dex)
+}
+
+// generateBazelBuildActions uses the jars built by the Bazel target that replaces the library
+// instead of compiling it. Returns false if the results of the cquery are not available yet.
+func (j *Library) generateBazelBuildActions(ctx android.ModuleContext, label string) bool {
+	bazelCtx := ctx.Config().BazelContext
+	javaInfo, ok, err := bazelCtx.GetJavaInfo(label, ctx.Arch().ArchType)
+	if err != nil {
+		ctx.ModuleErrorf("Error getting Bazel JavaInfo: %s", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	if len(javaInfo.RuntimeJars) != 1 {
+		ctx.ModuleErrorf("expected exactly one runtime jar for %q, but got %q", label, javaInfo.RuntimeJars)
+		return false
+	}
+	if len(javaInfo.HeaderJars) > 1 {
+		ctx.ModuleErrorf("expected at most one header jar for %q, but got %q", label, javaInfo.HeaderJars)
+		return false
+	}
+
+	// TODO: dexing and installing the Bazel outputs is not supported yet, the jars can only be used
+	// by other modules at build time. See bazelOutputsSufficient.
+	implementationJar := android.PathForBazelOut(ctx, javaInfo.RuntimeJars[0])
+	j.implementationJarFile = implementationJar
+	j.implementationAndResourcesJar = implementationJar
+	j.outputFile = implementationJar
+	if len(javaInfo.HeaderJars) == 1 {
+		j.headerJarFile = android.PathForBazelOut(ctx, javaInfo.HeaderJars[0])
+	} else {
+		j.headerJarFile = implementationJar
+	}
+
+	ctx.SetProvider(JavaInfoProvider, JavaInfo{
+		HeaderJars:                     android.PathsIfNonNil(j.headerJarFile),
+		ImplementationAndResourcesJars: android.PathsIfNonNil(j.implementationAndResourcesJar),
+		ImplementationJars:             android.PathsIfNonNil(j.implementationJarFile),
+	})
+
+	return true
+}
+
 func (j *Library) DepsMutator(ctx android.BottomUpMutatorContext) {
 	j.deps(ctx)
 }
@@ -658,6 +733,7 @@ func LibraryFactory() android.Module {
 	android.InitApexModule(module)
 	android.InitSdkAwareModule(module)
 	InitJavaModule(module, android.HostAndDeviceSupported)
+	android.InitBazelModule(module)
 	return module
 }
 
@@ -680,9 +756,73 @@ func LibraryHostFactory() android.Module {
 	android.InitApexModule(module)
 	android.InitSdkAwareModule(module)
 	InitJavaModule(module, android.HostSupported)
+	android.InitBazelModule(module)
 	return module
 }
 
+type bazelJavaLibraryAttributes struct {
+	Srcs                  bazel.LabelListAttribute
+	Deps                  bazel.LabelListAttribute
+	Exports               bazel.LabelListAttribute
+	Resources             bazel.LabelListAttribute
+	Resource_strip_prefix string
+	Javacopts             bazel.StringListAttribute
+	Sdk_version           string
+}
+
+type bazelJavaLibrary struct {
+	android.BazelTargetModuleBase
+	bazelJavaLibraryAttributes
+}
+
+func BazelJavaLibraryFactory() android.Module {
+	module := &bazelJavaLibrary{}
+	module.AddProperties(&module.bazelJavaLibraryAttributes)
+	android.InitBazelTargetModule(module)
+	return module
+}
+
+func (m *bazelJavaLibrary) Name() string {
+	return m.BaseModuleName()
+}
+
+func (m *bazelJavaLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {}
+
+func JavaLibraryBp2Build(ctx android.TopDownMutatorContext) {
+	m, ok := ctx.Module().(*Library)
+	if !ok || !m.ConvertWithBp2build(ctx) {
+		return
+	}
+
+	// a Library can be something other than a java_library
+	if ctx.ModuleType() != "java_library" {
+		return
+	}
+
+	if !bp2BuildSupportedJavaSrcs(ctx, &m.Module) {
+		return
+	}
+
+	javaAttrs := bp2BuildParseJavaProps(ctx, &m.Module)
+
+	attrs := &bazelJavaLibraryAttributes{
+		Srcs:                  javaAttrs.srcs,
+		Deps:                  javaAttrs.deps,
+		Exports:               javaAttrs.exports,
+		Resources:             javaAttrs.resources,
+		Resource_strip_prefix: javaAttrs.resourceStripPrefix,
+		Javacopts:             javaAttrs.javacopts,
+		Sdk_version:           String(m.deviceProperties.Sdk_version),
+	}
+
+	props := bazel.BazelTargetModuleProperties{
+		Rule_class:        "java_library",
+		Bzl_load_location: "//build/bazel/rules:java_library.bzl",
+	}
+
+	ctx.CreateBazelTargetModule(BazelJavaLibraryFactory, m.Name(), props, attrs)
+}
+
 //
 // Java Tests
 //
@@ -808,7 +948,8 @@ func (j *Test) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		defaultUnitTest := !inList("tradefed", j.properties.Libs) && !inList("cts", j.testProperties.Test_suites)
 		j.testProperties.Test_options.Unit_test = proptools.BoolPtr(defaultUnitTest)
 	}
-	j.testConfig = tradefed.AutoGenJavaTestConfig(ctx, j.testProperties.Test_config, j.testProperties.Test_config_template,
+	var runner string
+	j.testConfig, runner = tradefed.AutoGenJavaTestConfig(ctx, j.testProperties.Test_config, j.testProperties.Test_config_template,
 		j.testProperties.Test_suites, j.testProperties.Auto_gen_config, j.testProperties.Test_options.Unit_test)
 
 	j.data = android.PathsForModuleSrc(ctx, j.testProperties.Data)
@@ -843,6 +984,15 @@ func (j *Test) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	})
 
 	j.Library.GenerateAndroidBuildActions(ctx)
+
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig:       j.testConfig,
+		ExtraTestConfigs: j.extraTestConfigs,
+		TestSuites:       j.testProperties.Test_suites,
+		Data:             j.data,
+		Runner:           runner,
+		UnitTest:         Bool(j.testProperties.Test_options.Unit_test),
+	})
 }
 
 func (j *TestHelperLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
@@ -850,10 +1000,15 @@ func (j *TestHelperLibrary) GenerateAndroidBuildActions(ctx android.ModuleContex
 }
 
 func (j *JavaTestImport) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	j.testConfig = tradefed.AutoGenJavaTestConfig(ctx, j.prebuiltTestProperties.Test_config, nil,
+	j.testConfig, _ = tradefed.AutoGenJavaTestConfig(ctx, j.prebuiltTestProperties.Test_config, nil,
 		j.prebuiltTestProperties.Test_suites, nil, nil)
 
 	j.Import.GenerateAndroidBuildActions(ctx)
+
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig: j.testConfig,
+		TestSuites: j.prebuiltTestProperties.Test_suites,
+	})
 }
 
 type testSdkMemberType struct {
@@ -1100,6 +1255,7 @@ func BinaryFactory() android.Module {
 
 	android.InitAndroidArchModule(module, android.HostAndDeviceSupported, android.MultilibCommonFirst)
 	android.InitDefaultableModule(module)
+	android.InitBazelModule(module)
 	return module
 }
 
@@ -1117,9 +1273,71 @@ func BinaryHostFactory() android.Module {
 
 	android.InitAndroidArchModule(module, android.HostSupported, android.MultilibCommonFirst)
 	android.InitDefaultableModule(module)
+	android.InitBazelModule(module)
 	return module
 }
 
+type bazelJavaBinaryAttributes struct {
+	Srcs                  bazel.LabelListAttribute
+	Deps                  bazel.LabelListAttribute
+	Resources             bazel.LabelListAttribute
+	Resource_strip_prefix string
+	Javacopts             bazel.StringListAttribute
+	Main_class            string
+}
+
+type bazelJavaBinary struct {
+	android.BazelTargetModuleBase
+	bazelJavaBinaryAttributes
+}
+
+func BazelJavaBinaryFactory() android.Module {
+	module := &bazelJavaBinary{}
+	module.AddProperties(&module.bazelJavaBinaryAttributes)
+	android.InitBazelTargetModule(module)
+	return module
+}
+
+func (m *bazelJavaBinary) Name() string {
+	return m.BaseModuleName()
+}
+
+func (m *bazelJavaBinary) GenerateAndroidBuildActions(ctx android.ModuleContext) {}
+
+func JavaBinaryHostBp2Build(ctx android.TopDownMutatorContext) {
+	m, ok := ctx.Module().(*Binary)
+	if !ok || !m.ConvertWithBp2build(ctx) {
+		return
+	}
+
+	// a Binary can be something other than a java_binary_host
+	if ctx.ModuleType() != "java_binary_host" {
+		return
+	}
+
+	if !bp2BuildSupportedJavaSrcs(ctx, &m.Module) {
+		return
+	}
+
+	javaAttrs := bp2BuildParseJavaProps(ctx, &m.Module)
+
+	attrs := &bazelJavaBinaryAttributes{
+		Srcs:                  javaAttrs.srcs,
+		Deps:                  javaAttrs.deps,
+		Resources:             javaAttrs.resources,
+		Resource_strip_prefix: javaAttrs.resourceStripPrefix,
+		Javacopts:             javaAttrs.javacopts,
+		Main_class:            String(m.binaryProperties.Main_class),
+	}
+
+	props := bazel.BazelTargetModuleProperties{
+		// Use the native java_binary rule.
+		Rule_class: "java_binary",
+	}
+
+	ctx.CreateBazelTargetModule(BazelJavaBinaryFactory, m.Name(), props, attrs)
+}
+
 //
 // Java prebuilts
 //
@@ -27,10 +27,12 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/bazel/cquery"
 	"android/soong/cc"
 	"android/soong/dexpreopt"
 	"android/soong/genrule"
 	"android/soong/python"
+	"android/soong/tradefed"
 )
 
 // Legacy preparer used for running tests within the java package.
@@ -1392,3 +1394,99 @@ func TestDefaultInstallable(t *testing.T) {
 	assertDeepEquals(t, "Default installable value should be true.", proptools.BoolPtr(true),
 		module.properties.Installable)
 }
+
+func TestJavaTestRunner(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddTextFile("template.xml", ""),
+	).RunTestWithBp(t, `
+		java_test_host {
+			name: "unit_test",
+			srcs: ["a.java"],
+			test_options: { unit_test: true },
+		}
+
+		java_test_host {
+			name: "host_test",
+			srcs: ["a.java"],
+			test_options: { unit_test: false },
+		}
+
+		java_test_host {
+			name: "template_test",
+			srcs: ["a.java"],
+			test_config_template: "template.xml",
+		}
+	`)
+
+	buildOS := android.BuildOs.String()
+	for _, tt := range []struct {
+		name, runner string
+	}{
+		{"unit_test", tradefed.IsolatedHostTestRunner},
+		{"host_test", tradefed.HostTestRunner},
+		// The runner of a test config generated from the template of the module is not known.
+		{"template_test", ""},
+	} {
+		module := result.ModuleForTests(tt.name, buildOS+"_common").Module()
+		info := result.ModuleProvider(module, android.TestModuleInfoProvider).(android.TestModuleInfo)
+		android.AssertStringEquals(t, tt.name+" runner", tt.runner, info.Runner)
+	}
+}
+
+func TestJavaLibraryMixedBuild(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureModifyConfig(func(config android.Config) {
+			config.BazelContext = android.MockBazelContext{
+				OutputBaseDir: "outputbase",
+				LabelToJavaInfo: map[string]cquery.JavaInfo{
+					"//foo/bar:bar": {
+						RuntimeJars: []string{"foo/bar/libbar.jar"},
+						HeaderJars:  []string{"foo/bar/libbar-hjar.jar"},
+					},
+				},
+			}
+		}),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			bazel_module: { label: "//foo/bar:bar" },
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["b.java"],
+			libs: ["foo"],
+		}
+
+		java_library {
+			name: "qux",
+			srcs: ["c.java"],
+			installable: true,
+			bazel_module: { label: "//foo/bar:bar" },
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	if javac := foo.MaybeRule("javac"); javac.Rule != nil {
+		t.Errorf("expected foo to not be compiled by Soong")
+	}
+
+	javaInfo := result.ModuleProvider(foo.Module(), JavaInfoProvider).(JavaInfo)
+	android.AssertDeepEquals(t, "foo implementation jars",
+		[]string{"outputbase/execroot/__main__/foo/bar/libbar.jar"}, javaInfo.ImplementationJars.Strings())
+	android.AssertDeepEquals(t, "foo header jars",
+		[]string{"outputbase/execroot/__main__/foo/bar/libbar-hjar.jar"}, javaInfo.HeaderJars.Strings())
+
+	javac := result.ModuleForTests("baz", "android_common").Rule("javac")
+	android.AssertStringDoesContain(t, "baz classpath", javac.Args["classpath"],
+		"outputbase/execroot/__main__/foo/bar/libbar-hjar.jar")
+
+	// The Bazel outputs are not dexed or installed yet, so installable libraries are still built by
+	// Soong.
+	qux := result.ModuleForTests("qux", "android_common")
+	qux.Rule("javac")
+	qux.Rule("d8")
+}
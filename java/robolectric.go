@@ -115,7 +115,8 @@ func (r *robolectricTest) DepsMutator(ctx android.BottomUpMutatorContext) {
 }
 
 func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	r.testConfig = tradefed.AutoGenRobolectricTestConfig(ctx, r.testProperties.Test_config,
+	var runner string
+	r.testConfig, runner = tradefed.AutoGenRobolectricTestConfig(ctx, r.testProperties.Test_config,
 		r.testProperties.Test_config_template, r.testProperties.Test_suites,
 		r.testProperties.Auto_gen_config)
 	r.data = android.PathsForModuleSrc(ctx, r.testProperties.Data)
@@ -207,6 +208,14 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	}
 
 	ctx.InstallFile(installPath, ctx.ModuleName()+".jar", r.combinedJar, installDeps...)
+
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig: r.testConfig,
+		TestSuites: r.testProperties.Test_suites,
+		Data:       r.data,
+		Runner:     runner,
+		UnitTest:   Bool(r.testProperties.Test_options.Unit_test),
+	})
 }
 
 func generateRoboTestConfig(ctx android.ModuleContext, outputFile android.WritablePath,
@@ -71,7 +71,8 @@ func (test *testDecorator) bootstrapperProps() []interface{} {
 }
 
 func (test *testDecorator) install(ctx android.ModuleContext, file android.Path) {
-	test.testConfig = tradefed.AutoGenPythonBinaryHostTestConfig(ctx, test.testProperties.Test_config,
+	var runner string
+	test.testConfig, runner = tradefed.AutoGenPythonBinaryHostTestConfig(ctx, test.testProperties.Test_config,
 		test.testProperties.Test_config_template, test.binaryDecorator.binaryProperties.Test_suites,
 		test.binaryDecorator.binaryProperties.Auto_gen_config)
 
@@ -94,6 +95,18 @@ func (test *testDecorator) install(ctx android.ModuleContext, file android.Path)
 			test.data = append(test.data, android.DataPath{SrcPath: javaDataSrcPath})
 		}
 	}
+
+	var data android.Paths
+	for _, d := range test.data {
+		data = append(data, d.SrcPath)
+	}
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig: test.testConfig,
+		TestSuites: test.binaryDecorator.binaryProperties.Test_suites,
+		Data:       data,
+		Runner:     runner,
+		UnitTest:   Bool(test.testProperties.Test_options.Unit_test),
+	})
 }
 
 func NewTest(hod android.HostOrDeviceSupported) *Module {
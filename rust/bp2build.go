@@ -0,0 +1,317 @@
+// Copyright 2021 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+	"android/soong/bazel"
+	"android/soong/rust/config"
+)
+
+// bp2build functions and helpers for converting rust modules to rules_rust targets.
+
+const rulesRustBzlLoadLocation = "@rules_rust//rust:defs.bzl"
+
+func init() {
+	android.DepsBp2BuildMutators(RegisterDepsBp2Build)
+
+	android.RegisterBp2BuildMutator("rust_library", RustLibraryBp2Build)
+	android.RegisterBp2BuildMutator("rust_library_host", RustLibraryHostBp2Build)
+	android.RegisterBp2BuildMutator("rust_binary", RustBinaryBp2Build)
+	android.RegisterBp2BuildMutator("rust_proc_macro", RustProcMacroBp2Build)
+}
+
+func RegisterDepsBp2Build(ctx android.RegisterMutatorsContext) {
+	ctx.BottomUp("rust_bp2build_deps", depsBp2BuildMutator)
+}
+
+var (
+	crateRootsKey  = android.NewOnceKey("rustBp2BuildCrateRoots")
+	crateRootsLock sync.Mutex
+)
+
+// crateRoots returns the crate roots of the rust modules in each directory, relative to the
+// directory.  They are collected by depsBp2BuildMutator.
+func crateRoots(config android.Config) map[string][]string {
+	return config.Once(crateRootsKey, func() interface{} {
+		return make(map[string][]string)
+	}).(map[string][]string)
+}
+
+func addCrateRoots(config android.Config, dir string, roots []string) {
+	crateRootsLock.Lock()
+	defer crateRootsLock.Unlock()
+	dirs := crateRoots(config)
+	dirs[dir] = android.FirstUniqueStrings(append(dirs[dir], roots...))
+}
+
+// baseCompilerProps returns the BaseCompilerProperties of a rust module.
+func baseCompilerProps(module *Module) *BaseCompilerProperties {
+	if module.compiler == nil {
+		return nil
+	}
+	for _, p := range module.compiler.compilerProps() {
+		if props, ok := p.(*BaseCompilerProperties); ok {
+			return props
+		}
+	}
+	return nil
+}
+
+// rustDeps returns the crates and C libraries that a rust module links against.
+func rustDeps(props *BaseCompilerProperties) []string {
+	var deps []string
+	deps = append(deps, props.Rustlibs...)
+	deps = append(deps, props.Rlibs...)
+	deps = append(deps, props.Dylibs...)
+	deps = append(deps, props.Static_libs...)
+	deps = append(deps, props.Whole_static_libs...)
+	deps = append(deps, props.Shared_libs...)
+	return android.SortedUniqueStrings(deps)
+}
+
+// rustProcMacroDeps returns the proc_macro crates that a rust module uses.
+func rustProcMacroDeps(props *BaseCompilerProperties) []string {
+	return android.SortedUniqueStrings(android.CopyOf(props.Proc_macros))
+}
+
+// depsBp2BuildMutator adds dependencies on the crates and libraries in the base, arch and target
+// specific properties of rust modules. The rust deps mutator doesn't run in Bazel conversion mode,
+// as the variations that it depends on are not created.  It also records the crate roots of all
+// rust modules, converted or not, so that the srcs of a converted crate can leave out the files
+// of the other crates in the same directory.
+func depsBp2BuildMutator(ctx android.BottomUpMutatorContext) {
+	module, ok := ctx.Module().(*Module)
+	if !ok {
+		return
+	}
+
+	var allDeps, roots []string
+	addProps := func(props *BaseCompilerProperties) {
+		allDeps = append(allDeps, rustDeps(props)...)
+		allDeps = append(allDeps, rustProcMacroDeps(props)...)
+		if root := rustCrateRoot(props.Srcs); root != "" {
+			roots = append(roots, filepath.Clean(root))
+		}
+	}
+
+	if props := baseCompilerProps(module); props != nil {
+		addProps(props)
+	}
+	for _, p := range module.GetArchProperties(ctx, &BaseCompilerProperties{}) {
+		if props, ok := p.(*BaseCompilerProperties); ok {
+			addProps(props)
+		}
+	}
+	for _, p := range module.GetTargetProperties(&BaseCompilerProperties{}) {
+		if props, ok := p.(*BaseCompilerProperties); ok {
+			addProps(props)
+		}
+	}
+
+	addCrateRoots(ctx.Config(), ctx.ModuleDir(), roots)
+
+	if !module.ConvertWithBp2build(ctx) {
+		return
+	}
+	ctx.AddDependency(module, nil, android.SortedUniqueStrings(allDeps)...)
+}
+
+type bazelRustAttributes struct {
+	Crate_name      string
+	Crate_root      bazel.LabelAttribute
+	Srcs            bazel.LabelListAttribute
+	Edition         string
+	Deps            bazel.LabelListAttribute
+	Proc_macro_deps bazel.LabelListAttribute
+	Crate_features  bazel.StringListAttribute
+	Rustc_flags     bazel.StringListAttribute
+}
+
+type bazelRust struct {
+	android.BazelTargetModuleBase
+	bazelRustAttributes
+}
+
+func BazelRustFactory() android.Module {
+	module := &bazelRust{}
+	module.AddProperties(&module.bazelRustAttributes)
+	android.InitBazelTargetModule(module)
+	return module
+}
+
+func (m *bazelRust) Name() string {
+	return m.BaseModuleName()
+}
+
+func (m *bazelRust) GenerateAndroidBuildActions(ctx android.ModuleContext) {}
+
+func RustLibraryBp2Build(ctx android.TopDownMutatorContext) {
+	rustBp2Build(ctx, "rust_library", "rust_library")
+}
+
+func RustLibraryHostBp2Build(ctx android.TopDownMutatorContext) {
+	rustBp2Build(ctx, "rust_library_host", "rust_library")
+}
+
+func RustBinaryBp2Build(ctx android.TopDownMutatorContext) {
+	rustBp2Build(ctx, "rust_binary", "rust_binary")
+}
+
+func RustProcMacroBp2Build(ctx android.TopDownMutatorContext) {
+	rustBp2Build(ctx, "rust_proc_macro", "rust_proc_macro")
+}
+
+// rustBp2Build converts a module of the given module type to a target of the given rules_rust
+// rule class.
+func rustBp2Build(ctx android.TopDownMutatorContext, moduleType, ruleClass string) {
+	m, ok := ctx.Module().(*Module)
+	if !ok || !m.ConvertWithBp2build(ctx) {
+		return
+	}
+
+	// all rust module types share the Module type
+	if ctx.ModuleType() != moduleType {
+		return
+	}
+
+	props := baseCompilerProps(m)
+	if props == nil {
+		return
+	}
+
+	attrs := bp2BuildParseRustProps(ctx, m, props)
+
+	bazelProps := bazel.BazelTargetModuleProperties{
+		Rule_class:        ruleClass,
+		Bzl_load_location: rulesRustBzlLoadLocation,
+	}
+
+	ctx.CreateBazelTargetModule(BazelRustFactory, m.Name(), bazelProps, attrs)
+}
+
+// rustCrateRoot returns the crate root in srcs, which is its only file that isn't a source
+// provider reference.
+func rustCrateRoot(srcs []string) string {
+	for _, src := range srcs {
+		if android.SrcIsModule(src) == "" {
+			return src
+		}
+	}
+	return ""
+}
+
+// rustSrcs returns the labels for the srcs attribute of a crate. Soong only lists the crate root,
+// and rustc finds the other files of the crate through its mod declarations, so the .rs files
+// under the directory of the crate root are included.  The roots of the other crates in the
+// directory of the module and the directories under their roots are left out, as are the
+// directories that are other packages.
+func rustSrcs(ctx android.TopDownMutatorContext, srcs []string) bazel.LabelList {
+	otherRoots := crateRoots(ctx.Config())[ctx.ModuleDir()]
+
+	var modules, globs, excludes []string
+	for _, src := range srcs {
+		if android.SrcIsModule(src) != "" {
+			modules = append(modules, src)
+			continue
+		}
+
+		root := filepath.Clean(src)
+		dir := filepath.Dir(root)
+		globs = append(globs, filepath.Join(dir, "**/*.rs"))
+		for _, other := range otherRoots {
+			otherDir := filepath.Dir(other)
+			if other == root {
+				continue
+			} else if otherDir == dir {
+				excludes = append(excludes, other)
+			} else if dir == "." || strings.HasPrefix(otherDir, dir+"/") {
+				excludes = append(excludes, filepath.Join(otherDir, "**/*.rs"))
+			}
+		}
+	}
+
+	labels := android.BazelLabelForModuleSrc(ctx, modules)
+	for _, label := range android.BazelLabelForModuleSrcExcludes(ctx, globs, excludes).Includes {
+		// Files in subdirectories with their own Android.bp have absolute labels in other
+		// packages.
+		if !strings.HasPrefix(label.Label, "//") {
+			labels.Includes = append(labels.Includes, label)
+		}
+	}
+	return labels
+}
+
+// rustcFlags returns the rustc flags for the flags and cfgs properties.
+func rustcFlags(props *BaseCompilerProperties) []string {
+	flags := android.CopyOf(props.Flags)
+	for _, cfg := range props.Cfgs {
+		flags = append(flags, "--cfg="+cfg)
+	}
+	return flags
+}
+
+func bp2BuildParseRustProps(ctx android.TopDownMutatorContext, module *Module, props *BaseCompilerProperties) *bazelRustAttributes {
+	attrs := &bazelRustAttributes{
+		Crate_name:      props.Crate_name,
+		Srcs:            bazel.MakeLabelListAttribute(rustSrcs(ctx, props.Srcs)),
+		Edition:         proptools.StringDefault(props.Edition, config.DefaultEdition),
+		Deps:            bazel.MakeLabelListAttribute(android.BazelLabelForModuleDeps(ctx, rustDeps(props))),
+		Proc_macro_deps: bazel.MakeLabelListAttribute(android.BazelLabelForModuleDeps(ctx, rustProcMacroDeps(props))),
+		Crate_features:  bazel.MakeStringListAttribute(props.Features),
+		Rustc_flags:     bazel.MakeStringListAttribute(rustcFlags(props)),
+	}
+
+	if root := rustCrateRoot(props.Srcs); root != "" {
+		attrs.Crate_root.Value = android.BazelLabelForModuleSrcSingle(ctx, root)
+	}
+
+	for arch, p := range module.GetArchProperties(ctx, &BaseCompilerProperties{}) {
+		if archProps, ok := p.(*BaseCompilerProperties); ok {
+			// Arch specific srcs are only supported for a crate root that isn't set in the base
+			// properties, as a crate has a single root.
+			if root := rustCrateRoot(archProps.Srcs); root != "" && attrs.Crate_root.Value.Label == "" {
+				attrs.Crate_root.SetValueForArch(arch.Name, android.BazelLabelForModuleSrcSingle(ctx, root))
+			}
+			archSrcs := rustSrcs(ctx, archProps.Srcs)
+			attrs.Srcs.SetValueForArch(arch.Name, bazel.SubtractBazelLabelList(archSrcs, attrs.Srcs.Value))
+			attrs.Deps.SetValueForArch(arch.Name, android.BazelLabelForModuleDeps(ctx, rustDeps(archProps)))
+			attrs.Proc_macro_deps.SetValueForArch(arch.Name,
+				android.BazelLabelForModuleDeps(ctx, rustProcMacroDeps(archProps)))
+			attrs.Crate_features.SetValueForArch(arch.Name, archProps.Features)
+			attrs.Rustc_flags.SetValueForArch(arch.Name, rustcFlags(archProps))
+		}
+	}
+
+	for os, p := range module.GetTargetProperties(&BaseCompilerProperties{}) {
+		if osProps, ok := p.(*BaseCompilerProperties); ok {
+			osSrcs := rustSrcs(ctx, osProps.Srcs)
+			attrs.Srcs.SetValueForOS(os.Name, bazel.SubtractBazelLabelList(osSrcs, attrs.Srcs.Value))
+			attrs.Deps.SetValueForOS(os.Name, android.BazelLabelForModuleDeps(ctx, rustDeps(osProps)))
+			attrs.Proc_macro_deps.SetValueForOS(os.Name,
+				android.BazelLabelForModuleDeps(ctx, rustProcMacroDeps(osProps)))
+			attrs.Crate_features.SetValueForOS(os.Name, osProps.Features)
+			attrs.Rustc_flags.SetValueForOS(os.Name, rustcFlags(osProps))
+		}
+	}
+
+	return attrs
+}
@@ -104,6 +104,7 @@ type Module struct {
 	android.ModuleBase
 	android.DefaultableModuleBase
 	android.ApexModuleBase
+	android.BazelModuleBase
 
 	VendorProperties cc.VendorProperties
 
@@ -654,6 +655,7 @@ func (mod *Module) Init() android.Module {
 	android.InitApexModule(mod)
 
 	android.InitDefaultableModule(mod)
+	android.InitBazelModule(mod)
 	return mod
 }
 
@@ -873,6 +875,14 @@ func (d dependencyTag) InstallDepNeeded() bool {
 
 var _ android.InstallNeededDependencyTag = dependencyTag{}
 
+// StaticLinkDep returns true for rlibs, whose contents are linked into the crates that depend on
+// them.
+func (d dependencyTag) StaticLinkDep() bool {
+	return d == rlibDepTag
+}
+
+var _ android.StaticLinkDependencyTag = dependencyTag{}
+
 var (
 	customBindgenDepTag = dependencyTag{name: "customBindgenTag"}
 	rlibDepTag          = dependencyTag{name: "rlibTag", library: true}
@@ -1151,6 +1161,12 @@ func linkPathFromFilePath(filepath android.Path) string {
 }
 
 func (mod *Module) DepsMutator(actx android.BottomUpMutatorContext) {
+	// In Bazel conversion mode the variations that the dependencies are added on don't exist, the
+	// dependencies needed by bp2build are added by the rust_bp2build_deps mutator instead.
+	if actx.BazelConversionMode() {
+		return
+	}
+
 	ctx := &depsContext{
 		BottomUpMutatorContext: actx,
 	}
@@ -109,7 +109,8 @@ func (test *testDecorator) compilerProps() []interface{} {
 }
 
 func (test *testDecorator) install(ctx ModuleContext) {
-	test.testConfig = tradefed.AutoGenRustTestConfig(ctx,
+	var runner string
+	test.testConfig, runner = tradefed.AutoGenRustTestConfig(ctx,
 		test.Properties.Test_config,
 		test.Properties.Test_config_template,
 		test.Properties.Test_suites,
@@ -133,6 +134,18 @@ func (test *testDecorator) install(ctx ModuleContext) {
 		test.Properties.Test_options.Unit_test = proptools.BoolPtr(true)
 	}
 	test.binaryDecorator.install(ctx)
+
+	var data android.Paths
+	for _, d := range test.data {
+		data = append(data, d.SrcPath)
+	}
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig: test.testConfig,
+		TestSuites: test.Properties.Test_suites,
+		Data:       data,
+		Runner:     runner,
+		UnitTest:   Bool(test.Properties.Test_options.Unit_test),
+	})
 }
 
 func (test *testDecorator) compilerFlags(ctx ModuleContext, flags Flags) Flags {
@@ -364,7 +364,8 @@ func (s *ShTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		}
 		configs = append(configs, tradefed.Object{"target_preparer", "com.android.tradefed.targetprep.PushFilePreparer", options})
 	}
-	s.testConfig = tradefed.AutoGenShellTestConfig(ctx, s.testProperties.Test_config,
+	var runner string
+	s.testConfig, runner = tradefed.AutoGenShellTestConfig(ctx, s.testProperties.Test_config,
 		s.testProperties.Test_config_template, s.testProperties.Test_suites, configs, s.testProperties.Auto_gen_config, s.outputFilePath.Base())
 
 	s.dataModules = make(map[string]android.Path)
@@ -403,6 +404,18 @@ func (s *ShTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			ctx.PropertyErrorf(property, "%q of type %q is not supported", dep.Name(), ctx.OtherModuleType(dep))
 		}
 	})
+
+	data := append(android.Paths(nil), s.data...)
+	for _, relPath := range android.SortedStringKeys(s.dataModules) {
+		data = append(data, s.dataModules[relPath])
+	}
+	ctx.SetProvider(android.TestModuleInfoProvider, android.TestModuleInfo{
+		TestConfig:  s.testConfig,
+		TestSuites:  s.testProperties.Test_suites,
+		Data:        data,
+		Runner:      runner,
+		RequireRoot: Bool(s.testProperties.Require_root),
+	})
 }
 
 func (s *ShTest) InstallInData() bool {
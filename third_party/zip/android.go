@@ -85,6 +85,12 @@ func (w *Writer) CopyFrom(orig *File, newName string) error {
 	return err
 }
 
+// StripExtras returns the extra fields of a file header without the zip64 and extended timestamp
+// extras, see stripExtras.
+func StripExtras(extra []byte) []byte {
+	return stripExtras(extra)
+}
+
 // The zip64 extras change between the Central Directory and Local File Header, while we use
 // the same structure for both. The Local File Haeder is taken care of by us writing a data
 // descriptor with the zip64 values. The Central Directory Entry is written by Close(), where
@@ -143,6 +149,9 @@ func (w *Writer) CreateCompressedHeader(fh *FileHeader) (io.WriteCloser, error)
 
 	fh.CreatorVersion = fh.CreatorVersion&0xff00 | zipVersion20 // preserve compatibility byte
 	fh.ReaderVersion = zipVersion20
+	if fh.Method == Zstd {
+		fh.ReaderVersion = zipVersion63
+	}
 
 	fw := &compressedFileWriter{
 		fileWriter{
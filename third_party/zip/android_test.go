@@ -141,6 +141,75 @@ func TestCopyFromZip64(t *testing.T) {
 	}
 }
 
+func TestCopyFromZstd(t *testing.T) {
+	// Not a real Zstandard frame, the contents of the entry are never decompressed.
+	compressed := []byte{0x28, 0xb5, 0x2f, 0xfd, 1, 2, 3}
+
+	fromZipBytes := &bytes.Buffer{}
+	fromZip := NewWriter(fromZipBytes)
+	fh := &FileHeader{
+		Name:               "intermediate",
+		Method:             Zstd,
+		UncompressedSize64: 100,
+		CRC32:              0x12345678,
+	}
+	w, err := fromZip.CreateCompressedHeader(fh)
+	if err != nil {
+		t.Fatalf("CreateCompressedHeader: %v", err)
+	}
+	if _, err := w.Write(compressed); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := fromZip.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	fromZipReader, err := NewReader(bytes.NewReader(fromZipBytes.Bytes()), int64(fromZipBytes.Len()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if _, err := fromZipReader.File[0].Open(); err != ErrAlgorithm {
+		t.Errorf("Expected %v opening a Zstandard entry, got %v", ErrAlgorithm, err)
+	}
+
+	toZipBytes := &bytes.Buffer{}
+	toZip := NewWriter(toZipBytes)
+	if err := toZip.CopyFrom(fromZipReader.File[0], "copied"); err != nil {
+		t.Fatalf("CopyFrom: %v", err)
+	}
+	if err := toZip.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	toZipReader, err := NewReader(bytes.NewReader(toZipBytes.Bytes()), int64(toZipBytes.Len()))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	f := toZipReader.File[0]
+	if f.Method != Zstd || f.ReaderVersion != zipVersion63 {
+		t.Errorf("Expected method %d and reader version %d, got %d and %d",
+			Zstd, zipVersion63, f.Method, f.ReaderVersion)
+	}
+	if f.CRC32 != fh.CRC32 || f.UncompressedSize64 != fh.UncompressedSize64 {
+		t.Errorf("Expected CRC32 %08x and size %d, got %08x and %d",
+			fh.CRC32, fh.UncompressedSize64, f.CRC32, f.UncompressedSize64)
+	}
+	dataOffset, err := f.DataOffset()
+	if err != nil {
+		t.Fatalf("DataOffset: %v", err)
+	}
+	raw := make([]byte, f.CompressedSize64)
+	if _, err := f.zipr.ReadAt(raw, dataOffset); err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if !bytes.Equal(raw, compressed) {
+		t.Errorf("Expected raw contents %v, got %v", compressed, raw)
+	}
+}
+
 // Test for b/187485108: zip64 output can't be read by p7zip 16.02.
 func TestZip64P7ZipRecords(t *testing.T) {
 	if testing.Short() {
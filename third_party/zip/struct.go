@@ -29,6 +29,10 @@ import (
 const (
 	Store   uint16 = 0
 	Deflate uint16 = 8
+
+	// Zstd is the Zstandard method. There is no built-in compressor or decompressor for it, entries
+	// using it are meant for intermediate zips that are copied raw with Writer.CopyFrom.
+	Zstd uint16 = 93
 )
 
 const (
@@ -56,6 +60,7 @@ const (
 	// version numbers
 	zipVersion20 = 20 // 2.0
 	zipVersion45 = 45 // 4.5 (reads and writes zip64 archives)
+	zipVersion63 = 63 // 6.3 (Zstandard compression)
 
 	// limits for non zip64 files
 	uint16max = (1 << 16) - 1
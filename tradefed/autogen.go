@@ -138,7 +138,7 @@ func autogenTemplateWithNameAndOutputFile(ctx android.ModuleContext, name string
 }
 
 func AutoGenNativeTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool, testInstallBase string) android.Path {
+	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool, testInstallBase string) (android.Path, string) {
 
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
@@ -148,17 +148,19 @@ func AutoGenNativeTestConfig(ctx android.ModuleContext, testConfigProp *string,
 		} else {
 			if ctx.Device() {
 				autogenTemplate(ctx, autogenPath, "${NativeTestConfigTemplate}", config, testInstallBase)
+				return autogenPath, GTestRunner
 			} else {
 				autogenTemplate(ctx, autogenPath, "${NativeHostTestConfigTemplate}", config, testInstallBase)
+				return autogenPath, HostGTestRunner
 			}
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenShellTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool, outputFileName string) android.Path {
+	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool, outputFileName string) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
 		templatePath := getTestConfigTemplate(ctx, testConfigTemplateProp)
@@ -166,14 +168,15 @@ func AutoGenShellTestConfig(ctx android.ModuleContext, testConfigProp *string,
 			autogenTemplateWithNameAndOutputFile(ctx, ctx.ModuleName(), autogenPath, templatePath.String(), config, outputFileName, "")
 		} else {
 			autogenTemplateWithNameAndOutputFile(ctx, ctx.ModuleName(), autogenPath, "${ShellTestConfigTemplate}", config, outputFileName, "")
+			return autogenPath, ExecutableTargetTestRunner
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenNativeBenchmarkTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, testSuites []string, configs []Config, autoGenConfig *bool) android.Path {
+	testConfigTemplateProp *string, testSuites []string, configs []Config, autoGenConfig *bool) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
 		templatePath := getTestConfigTemplate(ctx, testConfigTemplateProp)
@@ -181,14 +184,15 @@ func AutoGenNativeBenchmarkTestConfig(ctx android.ModuleContext, testConfigProp
 			autogenTemplate(ctx, autogenPath, templatePath.String(), configs, "")
 		} else {
 			autogenTemplate(ctx, autogenPath, "${NativeBenchmarkTestConfigTemplate}", configs, "")
+			return autogenPath, GoogleBenchmarkTestRunner
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenJavaTestConfig(ctx android.ModuleContext, testConfigProp *string, testConfigTemplateProp *string,
-	testSuites []string, autoGenConfig *bool, unitTest *bool) android.Path {
+	testSuites []string, autoGenConfig *bool, unitTest *bool) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
 		templatePath := getTestConfigTemplate(ctx, testConfigTemplateProp)
@@ -196,22 +200,26 @@ func AutoGenJavaTestConfig(ctx android.ModuleContext, testConfigProp *string, te
 			autogenTemplate(ctx, autogenPath, templatePath.String(), nil, "")
 		} else {
 			if ctx.Device() {
+				// The runner of the device template is not known, it may run the test with any runner
+				// that supports jars.
 				autogenTemplate(ctx, autogenPath, "${JavaTestConfigTemplate}", nil, "")
 			} else {
 				if Bool(unitTest) {
 					autogenTemplate(ctx, autogenPath, "${JavaHostUnitTestConfigTemplate}", nil, "")
+					return autogenPath, IsolatedHostTestRunner
 				} else {
 					autogenTemplate(ctx, autogenPath, "${JavaHostTestConfigTemplate}", nil, "")
+					return autogenPath, HostTestRunner
 				}
 			}
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenPythonBinaryHostTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, testSuites []string, autoGenConfig *bool) android.Path {
+	testConfigTemplateProp *string, testSuites []string, autoGenConfig *bool) (android.Path, string) {
 
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
@@ -220,14 +228,15 @@ func AutoGenPythonBinaryHostTestConfig(ctx android.ModuleContext, testConfigProp
 			autogenTemplate(ctx, autogenPath, templatePath.String(), nil, "")
 		} else {
 			autogenTemplate(ctx, autogenPath, "${PythonBinaryHostTestConfigTemplate}", nil, "")
+			return autogenPath, PythonBinaryHostTestRunner
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenRustTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool) android.Path {
+	testConfigTemplateProp *string, testSuites []string, config []Config, autoGenConfig *bool) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
 		templatePath := getTestConfigTemplate(ctx, testConfigTemplateProp)
@@ -236,13 +245,15 @@ func AutoGenRustTestConfig(ctx android.ModuleContext, testConfigProp *string,
 		} else {
 			if ctx.Device() {
 				autogenTemplate(ctx, autogenPath, "${RustDeviceTestConfigTemplate}", config, "")
+				return autogenPath, RustBinaryTestRunner
 			} else {
 				autogenTemplate(ctx, autogenPath, "${RustHostTestConfigTemplate}", config, "")
+				return autogenPath, RustBinaryHostTestRunner
 			}
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 func AutoGenRustBenchmarkConfig(ctx android.ModuleContext, testConfigProp *string,
@@ -265,7 +276,7 @@ func AutoGenRustBenchmarkConfig(ctx android.ModuleContext, testConfigProp *strin
 }
 
 func AutoGenRobolectricTestConfig(ctx android.ModuleContext, testConfigProp *string, testConfigTemplateProp *string,
-	testSuites []string, autoGenConfig *bool) android.Path {
+	testSuites []string, autoGenConfig *bool) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	if autogenPath != nil {
 		templatePath := getTestConfigTemplate(ctx, testConfigTemplateProp)
@@ -273,10 +284,11 @@ func AutoGenRobolectricTestConfig(ctx android.ModuleContext, testConfigProp *str
 			autogenTemplate(ctx, autogenPath, templatePath.String(), nil, "")
 		} else {
 			autogenTemplate(ctx, autogenPath, "${RobolectricTestConfigTemplate}", nil, "")
+			return autogenPath, IsolatedHostTestRunner
 		}
-		return autogenPath
+		return autogenPath, ""
 	}
-	return path
+	return path, ""
 }
 
 var autogenInstrumentationTest = pctx.StaticRule("autogenInstrumentationTest", blueprint.RuleParams{
@@ -289,14 +301,16 @@ var autogenInstrumentationTest = pctx.StaticRule("autogenInstrumentationTest", b
 }, "name", "template", "extraConfigs")
 
 func AutoGenInstrumentationTestConfig(ctx android.ModuleContext, testConfigProp *string,
-	testConfigTemplateProp *string, manifest android.Path, testSuites []string, autoGenConfig *bool, configs []Config) android.Path {
+	testConfigTemplateProp *string, manifest android.Path, testSuites []string, autoGenConfig *bool, configs []Config) (android.Path, string) {
 	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, testConfigTemplateProp)
 	var configStrings []string
 	if autogenPath != nil {
 		template := "${InstrumentationTestConfigTemplate}"
+		runner := AndroidJUnitTestRunner
 		moduleTemplate := getTestConfigTemplate(ctx, testConfigTemplateProp)
 		if moduleTemplate.Valid() {
 			template = moduleTemplate.String()
+			runner = ""
 		}
 		for _, config := range configs {
 			configStrings = append(configStrings, config.Config())
@@ -315,9 +329,9 @@ func AutoGenInstrumentationTestConfig(ctx android.ModuleContext, testConfigProp
 				"extraConfigs": extraConfigs,
 			},
 		})
-		return autogenPath
+		return autogenPath, runner
 	}
-	return path
+	return path, ""
 }
 
 var Bool = proptools.Bool
@@ -22,6 +22,21 @@ var (
 	pctx = android.NewPackageContext("android/soong/tradefed")
 )
 
+// The runner classes of the default test config templates, which the AutoGen functions return along
+// with the test configs they generate from them.
+const (
+	AndroidJUnitTestRunner     = "com.android.tradefed.testtype.AndroidJUnitTest"
+	ExecutableTargetTestRunner = "com.android.tradefed.testtype.binary.ExecutableTargetTest"
+	GoogleBenchmarkTestRunner  = "com.android.tradefed.testtype.GoogleBenchmarkTest"
+	GTestRunner                = "com.android.tradefed.testtype.GTest"
+	HostGTestRunner            = "com.android.tradefed.testtype.HostGTest"
+	HostTestRunner             = "com.android.tradefed.testtype.HostTest"
+	IsolatedHostTestRunner     = "com.android.tradefed.testtype.IsolatedHostTest"
+	PythonBinaryHostTestRunner = "com.android.tradefed.testtype.python.PythonBinaryHostTest"
+	RustBinaryHostTestRunner   = "com.android.tradefed.testtype.rust.RustBinaryHostTest"
+	RustBinaryTestRunner       = "com.android.tradefed.testtype.rust.RustBinaryTest"
+)
+
 func init() {
 	pctx.SourcePathVariable("AutoGenTestConfigScript", "build/make/tools/auto_gen_test_config.py")
 	pctx.SourcePathVariable("InstrumentationTestConfigTemplate", "build/make/core/instrumentation_test_config_template.xml")
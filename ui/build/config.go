@@ -769,6 +769,17 @@ func (c *configImpl) FileListDir() string {
 	return filepath.Join(c.OutDir(), ".module_paths")
 }
 
+// FinderDaemonSocket returns the unix socket of the finder daemon that is used
+// to find source files if it is running, which can be set with
+// SOONG_FINDER_DAEMON_SOCKET and defaults to $OUT_DIR/.finder_daemon.sock.
+// Start the daemon with `finder --daemon <socket>`.
+func (c *configImpl) FinderDaemonSocket() string {
+	if socket, ok := c.environ.Get("SOONG_FINDER_DAEMON_SOCKET"); ok && socket != "" {
+		return socket
+	}
+	return filepath.Join(c.OutDir(), ".finder_daemon.sock")
+}
+
 func (c *configImpl) KatiSuffix() string {
 	if c.katiSuffix != "" {
 		return c.katiSuffix
@@ -1196,6 +1207,62 @@ func (c *configImpl) LogsDir() string {
 	return c.OutDir()
 }
 
+// StatusEventLog returns the file or FIFO that build status events are
+// streamed to as JSON lines, or "" if SOONG_UI_EVENT_LOG is not set. Relative
+// paths are relative to the logs directory.
+func (c *configImpl) StatusEventLog() string {
+	if p, ok := c.environ.Get("SOONG_UI_EVENT_LOG"); ok && p != "" {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(c.LogsDir(), p)
+	}
+	return ""
+}
+
+// SboxCacheDir returns the absolute path of the directory of the local action
+// cache used by sbox, or "" if SBOX_CACHE_DIR is not set.
+func (c *configImpl) SboxCacheDir() string {
+	if dir, ok := c.environ.Get("SBOX_CACHE_DIR"); ok && dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+	return ""
+}
+
+// SboxCacheMaxSize returns the maximum size in bytes of the local action cache
+// used by sbox, or "" to use the default.
+func (c *configImpl) SboxCacheMaxSize() string {
+	size, _ := c.environ.Get("SBOX_CACHE_MAX_SIZE")
+	return size
+}
+
+// SboxCacheStatsFile returns the file that sbox appends local action cache
+// hits and misses to during the ninja run.
+func (c *configImpl) SboxCacheStatsFile() string {
+	return filepath.Join(c.SoongOutDir(), "sbox_cache_stats.log")
+}
+
+// SboxStrict returns true if SBOX_STRICT is set, which makes sbox run
+// commands with sandboxed inputs with the source tree hidden.
+func (c *configImpl) SboxStrict() bool {
+	return c.environ.IsEnvTrue("SBOX_STRICT")
+}
+
+// SboxStrictAllowlist returns the absolute path of the allowlist for sbox
+// strict mode, or "" if SBOX_STRICT_ALLOWLIST is not set.
+func (c *configImpl) SboxStrictAllowlist() string {
+	if file, ok := c.environ.Get("SBOX_STRICT_ALLOWLIST"); ok && file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			return abs
+		}
+		return file
+	}
+	return ""
+}
+
 // BazelMetricsDir returns the <logs dir>/bazel_metrics directory
 // where the bazel profiles are located.
 func (c *configImpl) BazelMetricsDir() string {
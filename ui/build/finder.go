@@ -90,8 +90,22 @@ func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
 		IncludeSuffixes: []string{".bzl"},
 	}
 	dumpDir := config.FileListDir()
-	f, err = finder.New(cacheParams, filesystem, logger.New(ioutil.Discard),
-		filepath.Join(dumpDir, "files.db"))
+	dbPath := filepath.Join(dumpDir, "files.db")
+
+	// Use the finder daemon if it is running, it doesn't need to stat every
+	// directory in the tree.
+	socket := config.FinderDaemonSocket()
+	if _, err := os.Stat(socket); err == nil {
+		f, err = finder.NewWithDaemon(socket, cacheParams, filesystem,
+			logger.New(ioutil.Discard), dbPath)
+		if err == nil {
+			ctx.Verbosef("Using finder daemon at %s", socket)
+			return f
+		}
+		ctx.Verbosef("Not using finder daemon at %s: %v", socket, err)
+	}
+
+	f, err = finder.New(cacheParams, filesystem, logger.New(ioutil.Discard), dbPath)
 	if err != nil {
 		ctx.Fatalf("Could not create module-finder: %v", err)
 	}
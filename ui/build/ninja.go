@@ -173,6 +173,27 @@ func runNinjaForBuild(ctx Context, config Config) {
 	cmd.Environment.Set("DIST_DIR", config.DistDir())
 	cmd.Environment.Set("SHELL", "/bin/bash")
 
+	// Enable the local action cache in sbox. The cache configuration does not
+	// affect the outputs of the actions.
+	if cacheDir := config.SboxCacheDir(); cacheDir != "" {
+		cmd.Environment.Set("SBOX_CACHE_DIR", cacheDir)
+		if maxSize := config.SboxCacheMaxSize(); maxSize != "" {
+			cmd.Environment.Set("SBOX_CACHE_MAX_SIZE", maxSize)
+		}
+		cmd.Environment.Set("SBOX_CACHE_STATS", config.SboxCacheStatsFile())
+		os.Remove(config.SboxCacheStatsFile())
+		defer reportSboxCacheStats(ctx, config)
+	}
+
+	// Run sbox commands with the source tree hidden to detect undeclared
+	// inputs.
+	if config.SboxStrict() {
+		cmd.Environment.Set("SBOX_STRICT", "true")
+		if allowlist := config.SboxStrictAllowlist(); allowlist != "" {
+			cmd.Environment.Set("SBOX_STRICT_ALLOWLIST", allowlist)
+		}
+	}
+
 	// Print the environment variables that Ninja is operating in.
 	ctx.Verboseln("Ninja environment: ")
 	envVars := cmd.Environment.Environ()
@@ -217,6 +217,13 @@ func (c *Cmd) wrapSandbox() {
 		sandboxArgs = append(sandboxArgs, "-B", ccacheDir)
 	}
 
+	if sboxCacheDir := c.config.SboxCacheDir(); sboxCacheDir != "" {
+		if err := os.MkdirAll(sboxCacheDir, 0777); err != nil {
+			c.ctx.Fatalf("Failed to create sbox cache dir %q: %v", sboxCacheDir, err)
+		}
+		sandboxArgs = append(sandboxArgs, "-B", sboxCacheDir)
+	}
+
 	// Stop nsjail from parsing arguments
 	sandboxArgs = append(sandboxArgs, "--")
 
@@ -0,0 +1,100 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sboxCacheStats are the totals of the lines that sbox appends to the stats
+// file of the local action cache, which are "hit <bytes restored>", "miss" or
+// "error <message>".
+type sboxCacheStats struct {
+	hits          int
+	misses        int
+	bytesRestored int64
+	errors        []string
+}
+
+func parseSboxCacheStats(r io.Reader) (sboxCacheStats, error) {
+	var stats sboxCacheStats
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.SplitN(line, " ", 2)
+		switch fields[0] {
+		case "hit":
+			stats.hits++
+			if len(fields) == 2 {
+				if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+					stats.bytesRestored += n
+				}
+			}
+		case "miss":
+			stats.misses++
+		case "error":
+			if len(fields) == 2 {
+				stats.errors = append(stats.errors, fields[1])
+			} else {
+				stats.errors = append(stats.errors, "unknown error")
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
+
+func (s sboxCacheStats) String() string {
+	lookups := s.hits + s.misses
+	hitRate := 0.0
+	if lookups > 0 {
+		hitRate = float64(s.hits) * 100 / float64(lookups)
+	}
+	ret := fmt.Sprintf("%d hits, %d misses (%.1f%% hit rate), %.1f MiB restored",
+		s.hits, s.misses, hitRate, float64(s.bytesRestored)/(1024*1024))
+	if len(s.errors) > 0 {
+		ret += fmt.Sprintf(", %d errors", len(s.errors))
+	}
+	return ret
+}
+
+// reportSboxCacheStats logs the hits and misses of the local action cache
+// used by sbox during the ninja run.
+func reportSboxCacheStats(ctx Context, config Config) {
+	f, err := os.Open(config.SboxCacheStatsFile())
+	if os.IsNotExist(err) {
+		// No sbox actions were run.
+		return
+	} else if err != nil {
+		ctx.Verbosef("Failed to read sbox cache stats: %v", err)
+		return
+	}
+	defer f.Close()
+
+	stats, err := parseSboxCacheStats(f)
+	if err != nil {
+		ctx.Verbosef("Failed to read sbox cache stats: %v", err)
+		return
+	}
+
+	ctx.Println("sbox action cache: " + stats.String())
+	for _, e := range stats.errors {
+		ctx.Verbosef("sbox action cache error: %s", e)
+	}
+}
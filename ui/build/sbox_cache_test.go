@@ -0,0 +1,52 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSboxCacheStats(t *testing.T) {
+	input := strings.Join([]string{
+		"miss",
+		"hit 1048576",
+		"hit 524288",
+		"error failed to create \"foo\": permission denied",
+		"miss",
+		"hit",
+		"",
+	}, "\n")
+
+	stats, err := parseSboxCacheStats(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := sboxCacheStats{
+		hits:          3,
+		misses:        2,
+		bytesRestored: 1572864,
+		errors:        []string{"failed to create \"foo\": permission denied"},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("want %#v, got %#v", want, stats)
+	}
+
+	if g, w := stats.String(), "3 hits, 2 misses (60.0% hit rate), 1.5 MiB restored, 1 errors"; g != w {
+		t.Errorf("want %q, got %q", w, g)
+	}
+}
@@ -232,16 +232,21 @@ func runSoong(ctx Context, config Config) {
 		ctx.Fatalf("failed to write environment file %s: %s", envFile, err)
 	}
 
+	usedEnvFiles := []string{filepath.Join(config.SoongOutDir(), usedEnvFile)}
+	if integratedBp2Build {
+		usedEnvFiles = append(usedEnvFiles, filepath.Join(config.SoongOutDir(), usedEnvFile+".bp2build"))
+	}
+
+	// Record the state of build.ninja and the environment before it may be regenerated, so that
+	// the reasons for regenerating it can be explained afterwards.
+	regenExplainer := newSoongRegenExplainer(config.SoongOutDir(), usedEnvFiles, soongBuildEnv)
+
 	func() {
 		ctx.BeginTrace(metrics.RunSoong, "environment check")
 		defer ctx.EndTrace()
 
-		soongBuildEnvFile := filepath.Join(config.SoongOutDir(), usedEnvFile)
-		checkEnvironmentFile(soongBuildEnv, soongBuildEnvFile)
-
-		if integratedBp2Build {
-			bp2buildEnvFile := filepath.Join(config.SoongOutDir(), usedEnvFile+".bp2build")
-			checkEnvironmentFile(soongBuildEnv, bp2buildEnvFile)
+		for _, envFile := range usedEnvFiles {
+			checkEnvironmentFile(soongBuildEnv, envFile)
 		}
 	}()
 
@@ -294,6 +299,13 @@ func runSoong(ctx Context, config Config) {
 	// This build generates <builddir>/build.ninja, which is used later by build/soong/ui/build/build.go#Build().
 	ninja("bootstrap", ".bootstrap/build.ninja")
 
+	func() {
+		ctx.BeginTrace(metrics.RunSoong, "explain regeneration")
+		defer ctx.EndTrace()
+
+		explainSoongRegen(ctx, regenExplainer)
+	}()
+
 	var soongBuildMetrics *soong_metrics_proto.SoongBuildMetrics
 	if shouldCollectBuildSoongMetrics(config) {
 		soongBuildMetrics := loadSoongBuildMetrics(ctx, config)
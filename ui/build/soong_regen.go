@@ -0,0 +1,382 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"android/soong/makedeps"
+	"android/soong/shared"
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// This file explains why soong_build was re-run by comparing the inputs of build.ninja
+// (as listed in its depfile) against the state that was saved when it was last generated.
+
+const (
+	soongRegenStateFile = ".soong_regen_state.json"
+
+	// The number of reasons printed to the terminal
+	soongRegenPrintedReasons = 10
+	// The number of reasons recorded in the metrics
+	soongRegenRecordedReasons = 100
+	// Glob results larger than this are hashed instead of saved in the state file
+	maxSavedGlobSize = 256 * 1024
+)
+
+// soongRegenState records the inputs of build.ninja when it was last generated
+type soongRegenState struct {
+	Inputs map[string]soongRegenInput
+}
+
+type soongRegenInput struct {
+	ModTime int64
+	Size    int64
+	Hash    string   `json:",omitempty"`
+	Lines   []string `json:",omitempty"`
+}
+
+type soongRegenExplainer struct {
+	soongOutDir string
+	envFiles    []string
+
+	ninjaExisted bool
+	ninjaModTime time.Time
+	envReasons   []*soong_metrics_proto.SoongRegenReason
+}
+
+// newSoongRegenExplainer records the state of build.ninja before soong_build may be re-run. It
+// must be called before the stale environment files are removed by checkEnvironmentFile.
+func newSoongRegenExplainer(soongOutDir string, envFiles []string, env *Environment) *soongRegenExplainer {
+	e := &soongRegenExplainer{
+		soongOutDir: soongOutDir,
+		envFiles:    envFiles,
+	}
+
+	if info, err := os.Stat(e.ninjaFile()); err == nil {
+		e.ninjaExisted = true
+		e.ninjaModTime = info.ModTime()
+	}
+
+	seen := make(map[string]bool)
+	for _, envFile := range envFiles {
+		usedEnv, err := shared.EnvFromFile(envFile)
+		if err != nil {
+			if e.ninjaExisted {
+				e.envReasons = append(e.envReasons, regenReason(soong_metrics_proto.SoongRegenReason_ENVIRONMENT,
+					filepath.Base(envFile), "could not be read"))
+			}
+			continue
+		}
+		var keys []string
+		for key := range usedEnv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cur, _ := env.Get(key)
+			if old := usedEnv[key]; old != cur && !seen[key] {
+				seen[key] = true
+				e.envReasons = append(e.envReasons, regenReason(soong_metrics_proto.SoongRegenReason_ENVIRONMENT,
+					key, fmt.Sprintf("%q -> %q", old, cur)))
+			}
+		}
+	}
+	return e
+}
+
+func (e *soongRegenExplainer) ninjaFile() string {
+	return filepath.Join(e.soongOutDir, "build.ninja")
+}
+
+func (e *soongRegenExplainer) stateFile() string {
+	return filepath.Join(e.soongOutDir, soongRegenStateFile)
+}
+
+// explain returns the reasons that build.ninja was regenerated, or nil if it wasn't. The state
+// of its inputs is saved for the next run whenever it was regenerated.
+func (e *soongRegenExplainer) explain() ([]*soong_metrics_proto.SoongRegenReason, error) {
+	info, err := os.Stat(e.ninjaFile())
+	if err != nil {
+		return nil, err
+	}
+	regenerated := !e.ninjaExisted || !info.ModTime().Equal(e.ninjaModTime)
+	if !regenerated {
+		if _, err := os.Stat(e.stateFile()); err == nil {
+			return nil, nil
+		}
+	}
+
+	oldState := e.readState()
+	inputs, err := e.inputs()
+	if err != nil {
+		return nil, err
+	}
+	newState := soongRegenState{Inputs: make(map[string]soongRegenInput)}
+	for _, input := range inputs {
+		cur, exists, err := readRegenInput(input, oldState.Inputs[input])
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			newState.Inputs[input] = cur
+		}
+	}
+	if err := e.writeState(newState); err != nil || !regenerated {
+		return nil, err
+	}
+
+	var reasons []*soong_metrics_proto.SoongRegenReason
+	if !e.ninjaExisted {
+		reasons = append(reasons, regenReason(soong_metrics_proto.SoongRegenReason_MISSING_OUTPUT,
+			"build.ninja", "did not exist"))
+	}
+	reasons = append(reasons, e.envReasons...)
+	if e.ninjaExisted {
+		for _, input := range inputs {
+			kind := regenInputKind(input)
+			old, hadOld := oldState.Inputs[input]
+			if cur, exists := newState.Inputs[input]; !exists {
+				reasons = append(reasons, regenReason(kind, input, "removed"))
+			} else if time.Unix(0, cur.ModTime).After(e.ninjaModTime) {
+				reasons = append(reasons, regenReason(kind, input, describeInputChange(old, hadOld, cur)))
+			}
+		}
+		reasons = append(reasons, e.bootstrapReasons()...)
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, regenReason(soong_metrics_proto.SoongRegenReason_UNKNOWN,
+			"build.ninja", "no changed inputs were found, the soong_build command line may have changed"))
+	}
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasons[i].GetKind() < reasons[j].GetKind()
+	})
+	return reasons, nil
+}
+
+// inputs returns the inputs of build.ninja from its depfile, excluding the environment files,
+// whose changes are explained by the environment variables
+func (e *soongRegenExplainer) inputs() ([]string, error) {
+	depFile := e.ninjaFile() + ".d"
+	f, err := os.Open(depFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	deps, err := makedeps.Parse(depFile, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []string
+	for _, input := range deps.Inputs {
+		if !inList(input, e.envFiles) {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
+
+// bootstrapReasons returns the Go packages of soong_build that were rebuilt since build.ninja was
+// last generated, which makes the bootstrap build.ninja re-run soong_build.
+func (e *soongRegenExplainer) bootstrapReasons() []*soong_metrics_proto.SoongRegenReason {
+	bootstrapDir := filepath.Join(e.soongOutDir, ".bootstrap")
+	info, err := os.Stat(filepath.Join(bootstrapDir, "bin", "soong_build"))
+	if err != nil || !info.ModTime().After(e.ninjaModTime) {
+		return nil
+	}
+
+	var reasons []*soong_metrics_proto.SoongRegenReason
+	entries, _ := ioutil.ReadDir(bootstrapDir)
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "bin" {
+			continue
+		}
+		rebuilt := false
+		filepath.Walk(filepath.Join(bootstrapDir, entry.Name()), func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.HasSuffix(path, ".a") && info.ModTime().After(e.ninjaModTime) {
+				rebuilt = true
+				return io.EOF
+			}
+			return nil
+		})
+		if rebuilt {
+			reasons = append(reasons, regenReason(soong_metrics_proto.SoongRegenReason_BOOTSTRAP,
+				entry.Name(), "Go package was rebuilt"))
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, regenReason(soong_metrics_proto.SoongRegenReason_BOOTSTRAP,
+			"soong_build", "was rebuilt"))
+	}
+	return reasons
+}
+
+func (e *soongRegenExplainer) readState() soongRegenState {
+	var state soongRegenState
+	if data, err := ioutil.ReadFile(e.stateFile()); err == nil {
+		// A corrupt state file only makes the explanations less detailed
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func (e *soongRegenExplainer) writeState(state soongRegenState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(e.stateFile(), data, 0644)
+}
+
+// readRegenInput returns the current state of an input, reusing the hash from <old> if the
+// input looks unchanged
+func readRegenInput(path string, old soongRegenInput) (soongRegenInput, bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return soongRegenInput{}, false, nil
+	} else if err != nil {
+		return soongRegenInput{}, false, err
+	}
+
+	cur := soongRegenInput{
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+	if cur.ModTime == old.ModTime && cur.Size == old.Size {
+		cur.Hash, cur.Lines = old.Hash, old.Lines
+		return cur, true, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return soongRegenInput{}, false, err
+	}
+	sum := sha256.Sum256(data)
+	cur.Hash = hex.EncodeToString(sum[:])
+	if regenInputKind(path) == soong_metrics_proto.SoongRegenReason_GLOB && len(data) <= maxSavedGlobSize {
+		cur.Lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	return cur, true, nil
+}
+
+func regenInputKind(path string) soong_metrics_proto.SoongRegenReason_Kind {
+	switch {
+	case strings.Contains(path, "/.glob/") || strings.Contains(path, "/globs/"):
+		return soong_metrics_proto.SoongRegenReason_GLOB
+	case filepath.Ext(path) == ".bp":
+		return soong_metrics_proto.SoongRegenReason_BLUEPRINT_FILE
+	default:
+		return soong_metrics_proto.SoongRegenReason_OTHER_INPUT
+	}
+}
+
+// describeInputChange describes how an input that is newer than build.ninja changed
+func describeInputChange(old soongRegenInput, hadOld bool, cur soongRegenInput) string {
+	switch {
+	case !hadOld:
+		return "new input"
+	case old.Hash == cur.Hash:
+		return "touched, contents unchanged"
+	case old.Lines != nil && cur.Lines != nil:
+		return describeLineChanges(old.Lines, cur.Lines)
+	default:
+		return "modified"
+	}
+}
+
+func describeLineChanges(oldLines, newLines []string) string {
+	oldSet := make(map[string]bool, len(oldLines))
+	for _, line := range oldLines {
+		oldSet[line] = true
+	}
+	newSet := make(map[string]bool, len(newLines))
+	for _, line := range newLines {
+		newSet[line] = true
+	}
+
+	var changes []string
+	for _, line := range newLines {
+		if !oldSet[line] {
+			changes = append(changes, "+"+line)
+		}
+	}
+	for _, line := range oldLines {
+		if !newSet[line] {
+			changes = append(changes, "-"+line)
+		}
+	}
+	if len(changes) == 0 {
+		return "reordered"
+	}
+	const maxChanges = 3
+	if len(changes) > maxChanges {
+		return fmt.Sprintf("%s and %d more", strings.Join(changes[:maxChanges], " "), len(changes)-maxChanges)
+	}
+	return strings.Join(changes, " ")
+}
+
+func regenReason(kind soong_metrics_proto.SoongRegenReason_Kind, name, detail string) *soong_metrics_proto.SoongRegenReason {
+	return &soong_metrics_proto.SoongRegenReason{
+		Kind:   kind.Enum(),
+		Name:   proto.String(name),
+		Detail: proto.String(detail),
+	}
+}
+
+// explainSoongRegen prints the top reasons that soong_build was re-run, if it was, and records
+// them in the metrics.
+func explainSoongRegen(ctx Context, explainer *soongRegenExplainer) {
+	reasons, err := explainer.explain()
+	if err != nil {
+		ctx.Verbosef("Failed to explain why soong_build was re-run: %s", err)
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	ctx.Println("soong_build was re-run because:")
+	for i, reason := range reasons {
+		if i == soongRegenPrintedReasons {
+			ctx.Printf("  ... and %d more\n", len(reasons)-i)
+			break
+		}
+		ctx.Printf("  %s: %s: %s\n", strings.ToLower(reason.GetKind().String()), reason.GetName(), reason.GetDetail())
+	}
+
+	if ctx.Metrics != nil {
+		regen := &soong_metrics_proto.SoongRegenInfo{
+			NumReasons: proto.Uint32(uint32(len(reasons))),
+		}
+		if len(reasons) > soongRegenRecordedReasons {
+			reasons = reasons[:soongRegenRecordedReasons]
+		}
+		regen.Reasons = reasons
+		ctx.Metrics.SetSoongRegenInfo(regen)
+	}
+}
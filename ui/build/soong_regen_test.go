@@ -0,0 +1,128 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/shared"
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+func TestSoongRegenExplainer(t *testing.T) {
+	outDir := t.TempDir()
+	bpFile := filepath.Join(outDir, "Android.bp")
+	otherBpFile := filepath.Join(outDir, "a", "Android.bp")
+	globFile := filepath.Join(outDir, ".glob", "a", "*.java")
+	envFile := filepath.Join(outDir, usedEnvFile)
+	ninjaFile := filepath.Join(outDir, "build.ninja")
+
+	base := time.Now().Add(-time.Hour)
+	writeFile := func(path, contents string, modTime time.Duration) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		touch := base.Add(modTime)
+		if err := os.Chtimes(path, touch, touch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeEnv := func(env map[string]string) {
+		t.Helper()
+		data, err := shared.EnvFileContents(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(envFile, string(data), 0)
+	}
+	explain := func(env map[string]string, regenerate func()) []string {
+		t.Helper()
+		var environ Environment
+		for k, v := range env {
+			environ.Set(k, v)
+		}
+		explainer := newSoongRegenExplainer(outDir, []string{envFile}, &environ)
+		regenerate()
+		reasons, err := explainer.explain()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ret []string
+		for _, reason := range reasons {
+			ret = append(ret, fmt.Sprintf("%s %s: %s", reason.GetKind(),
+				strings.TrimPrefix(reason.GetName(), outDir+"/"), reason.GetDetail()))
+		}
+		return ret
+	}
+
+	writeFile(bpFile, "soong_namespace {}", 0)
+	writeFile(otherBpFile, "java_library {}", 0)
+	writeFile(globFile, "a/A.java\na/B.java\n", 0)
+	writeFile(ninjaFile+".d", fmt.Sprintf("%s: %s %s %s %s\n", ninjaFile, bpFile, otherBpFile, globFile, envFile), 0)
+	writeEnv(map[string]string{"FOO": "1"})
+	writeFile(ninjaFile, "", time.Minute)
+
+	// build.ninja was not regenerated, but the state is saved for the next run
+	if reasons := explain(map[string]string{"FOO": "1"}, func() {}); reasons != nil {
+		t.Errorf("expected no reasons, got %q", reasons)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, soongRegenStateFile)); err != nil {
+		t.Errorf("expected the state to be saved: %s", err)
+	}
+
+	reasons := explain(map[string]string{"FOO": "2"}, func() {
+		writeFile(bpFile, "soong_namespace {}", 2*time.Minute)
+		writeFile(otherBpFile, "java_library {name: \"a\"}", 2*time.Minute)
+		writeFile(globFile, "a/A.java\na/C.java\n", 2*time.Minute)
+		writeEnv(map[string]string{"FOO": "2"})
+		writeFile(ninjaFile, "", 3*time.Minute)
+	})
+	want := []string{
+		`ENVIRONMENT FOO: "1" -> "2"`,
+		`GLOB .glob/a/*.java: +a/C.java -a/B.java`,
+		`BLUEPRINT_FILE Android.bp: touched, contents unchanged`,
+		`BLUEPRINT_FILE a/Android.bp: modified`,
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("want reasons:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(reasons, "\n"))
+	}
+
+	// soong_build was re-run for a reason that isn't an input
+	reasons = explain(map[string]string{"FOO": "2"}, func() {
+		writeFile(ninjaFile, "", 4*time.Minute)
+	})
+	if len(reasons) != 1 || !strings.HasPrefix(reasons[0], soong_metrics_proto.SoongRegenReason_UNKNOWN.String()) {
+		t.Errorf("expected an unknown reason, got %q", reasons)
+	}
+
+	os.Remove(ninjaFile)
+	reasons = explain(map[string]string{"FOO": "2"}, func() {
+		writeFile(ninjaFile, "", 5*time.Minute)
+	})
+	if g, w := reasons, []string{"MISSING_OUTPUT build.ninja: did not exist"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want reasons %q, got %q", w, g)
+	}
+}
@@ -210,6 +210,11 @@ func (m *Metrics) SetSoongBuildMetrics(metrics *soong_metrics_proto.SoongBuildMe
 	m.metrics.SoongBuildMetrics = metrics
 }
 
+// SetSoongRegenInfo sets the reasons that soong_build was re-run.
+func (m *Metrics) SetSoongRegenInfo(regen *soong_metrics_proto.SoongRegenInfo) {
+	m.metrics.SoongRegen = regen
+}
+
 // A CriticalUserJourneysMetrics is a struct that contains critical user journey
 // metrics. These critical user journeys are defined under cuj/cuj.go file.
 type CriticalUserJourneysMetrics struct {
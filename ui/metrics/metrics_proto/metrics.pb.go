@@ -155,6 +155,61 @@ func (ModuleTypeInfo_BuildSystem) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_6039342a2ba47b72, []int{5, 0}
 }
 
+type SoongRegenReason_Kind int32
+
+const (
+	SoongRegenReason_UNKNOWN        SoongRegenReason_Kind = 0
+	SoongRegenReason_MISSING_OUTPUT SoongRegenReason_Kind = 1
+	SoongRegenReason_ENVIRONMENT    SoongRegenReason_Kind = 2
+	SoongRegenReason_BOOTSTRAP      SoongRegenReason_Kind = 3
+	SoongRegenReason_GLOB           SoongRegenReason_Kind = 4
+	SoongRegenReason_BLUEPRINT_FILE SoongRegenReason_Kind = 5
+	SoongRegenReason_OTHER_INPUT    SoongRegenReason_Kind = 6
+)
+
+var SoongRegenReason_Kind_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "MISSING_OUTPUT",
+	2: "ENVIRONMENT",
+	3: "BOOTSTRAP",
+	4: "GLOB",
+	5: "BLUEPRINT_FILE",
+	6: "OTHER_INPUT",
+}
+
+var SoongRegenReason_Kind_value = map[string]int32{
+	"UNKNOWN":        0,
+	"MISSING_OUTPUT": 1,
+	"ENVIRONMENT":    2,
+	"BOOTSTRAP":      3,
+	"GLOB":           4,
+	"BLUEPRINT_FILE": 5,
+	"OTHER_INPUT":    6,
+}
+
+func (x SoongRegenReason_Kind) Enum() *SoongRegenReason_Kind {
+	p := new(SoongRegenReason_Kind)
+	*p = x
+	return p
+}
+
+func (x SoongRegenReason_Kind) String() string {
+	return proto.EnumName(SoongRegenReason_Kind_name, int32(x))
+}
+
+func (x *SoongRegenReason_Kind) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(SoongRegenReason_Kind_value, data, "SoongRegenReason_Kind")
+	if err != nil {
+		return err
+	}
+	*x = SoongRegenReason_Kind(value)
+	return nil
+}
+
+func (SoongRegenReason_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{10, 0}
+}
+
 type MetricsBase struct {
 	// Timestamp generated when the build starts.
 	BuildDateTimestamp *int64 `protobuf:"varint,1,opt,name=build_date_timestamp,json=buildDateTimestamp" json:"build_date_timestamp,omitempty"`
@@ -207,10 +262,12 @@ type MetricsBase struct {
 	// The build command that the user entered to the build system.
 	BuildCommand *string `protobuf:"bytes,26,opt,name=build_command,json=buildCommand" json:"build_command,omitempty"`
 	// The metrics for calling Bazel.
-	BazelRuns            []*PerfInfo `protobuf:"bytes,27,rep,name=bazel_runs,json=bazelRuns" json:"bazel_runs,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	BazelRuns []*PerfInfo `protobuf:"bytes,27,rep,name=bazel_runs,json=bazelRuns" json:"bazel_runs,omitempty"`
+	// The reasons that soong_build was re-run, if it was.
+	SoongRegen           *SoongRegenInfo `protobuf:"bytes,28,opt,name=soong_regen,json=soongRegen" json:"soong_regen,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *MetricsBase) Reset()         { *m = MetricsBase{} }
@@ -432,6 +489,13 @@ func (m *MetricsBase) GetBazelRuns() []*PerfInfo {
 	return nil
 }
 
+func (m *MetricsBase) GetSoongRegen() *SoongRegenInfo {
+	if m != nil {
+		return m.SoongRegen
+	}
+	return nil
+}
+
 type BuildConfig struct {
 	UseGoma              *bool    `protobuf:"varint,1,opt,name=use_goma,json=useGoma" json:"use_goma,omitempty"`
 	UseRbe               *bool    `protobuf:"varint,2,opt,name=use_rbe,json=useRbe" json:"use_rbe,omitempty"`
@@ -970,10 +1034,121 @@ func (m *SoongBuildMetrics) GetMaxHeapSize() uint64 {
 	return 0
 }
 
+type SoongRegenInfo struct {
+	// The most important reasons that soong_build was re-run.
+	Reasons []*SoongRegenReason `protobuf:"bytes,1,rep,name=reasons" json:"reasons,omitempty"`
+	// The total number of reasons found, which may be more than were recorded.
+	NumReasons           *uint32  `protobuf:"varint,2,opt,name=num_reasons,json=numReasons" json:"num_reasons,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SoongRegenInfo) Reset()         { *m = SoongRegenInfo{} }
+func (m *SoongRegenInfo) String() string { return proto.CompactTextString(m) }
+func (*SoongRegenInfo) ProtoMessage()    {}
+func (*SoongRegenInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{9}
+}
+
+func (m *SoongRegenInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SoongRegenInfo.Unmarshal(m, b)
+}
+func (m *SoongRegenInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SoongRegenInfo.Marshal(b, m, deterministic)
+}
+func (m *SoongRegenInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SoongRegenInfo.Merge(m, src)
+}
+func (m *SoongRegenInfo) XXX_Size() int {
+	return xxx_messageInfo_SoongRegenInfo.Size(m)
+}
+func (m *SoongRegenInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_SoongRegenInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SoongRegenInfo proto.InternalMessageInfo
+
+func (m *SoongRegenInfo) GetReasons() []*SoongRegenReason {
+	if m != nil {
+		return m.Reasons
+	}
+	return nil
+}
+
+func (m *SoongRegenInfo) GetNumReasons() uint32 {
+	if m != nil && m.NumReasons != nil {
+		return *m.NumReasons
+	}
+	return 0
+}
+
+type SoongRegenReason struct {
+	// The kind of change that caused soong_build to be re-run.
+	Kind *SoongRegenReason_Kind `protobuf:"varint,1,opt,name=kind,enum=soong_build_metrics.SoongRegenReason_Kind,def=0" json:"kind,omitempty"`
+	// The name of the changed input, eg. the path of a file or the name of an
+	// environment variable.
+	Name *string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	// A human readable description of the change.
+	Detail               *string  `protobuf:"bytes,3,opt,name=detail" json:"detail,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SoongRegenReason) Reset()         { *m = SoongRegenReason{} }
+func (m *SoongRegenReason) String() string { return proto.CompactTextString(m) }
+func (*SoongRegenReason) ProtoMessage()    {}
+func (*SoongRegenReason) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{10}
+}
+
+func (m *SoongRegenReason) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SoongRegenReason.Unmarshal(m, b)
+}
+func (m *SoongRegenReason) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SoongRegenReason.Marshal(b, m, deterministic)
+}
+func (m *SoongRegenReason) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SoongRegenReason.Merge(m, src)
+}
+func (m *SoongRegenReason) XXX_Size() int {
+	return xxx_messageInfo_SoongRegenReason.Size(m)
+}
+func (m *SoongRegenReason) XXX_DiscardUnknown() {
+	xxx_messageInfo_SoongRegenReason.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SoongRegenReason proto.InternalMessageInfo
+
+const Default_SoongRegenReason_Kind SoongRegenReason_Kind = SoongRegenReason_UNKNOWN
+
+func (m *SoongRegenReason) GetKind() SoongRegenReason_Kind {
+	if m != nil && m.Kind != nil {
+		return *m.Kind
+	}
+	return Default_SoongRegenReason_Kind
+}
+
+func (m *SoongRegenReason) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *SoongRegenReason) GetDetail() string {
+	if m != nil && m.Detail != nil {
+		return *m.Detail
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_BuildVariant", MetricsBase_BuildVariant_name, MetricsBase_BuildVariant_value)
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_Arch", MetricsBase_Arch_name, MetricsBase_Arch_value)
 	proto.RegisterEnum("soong_build_metrics.ModuleTypeInfo_BuildSystem", ModuleTypeInfo_BuildSystem_name, ModuleTypeInfo_BuildSystem_value)
+	proto.RegisterEnum("soong_build_metrics.SoongRegenReason_Kind", SoongRegenReason_Kind_name, SoongRegenReason_Kind_value)
 	proto.RegisterType((*MetricsBase)(nil), "soong_build_metrics.MetricsBase")
 	proto.RegisterType((*BuildConfig)(nil), "soong_build_metrics.BuildConfig")
 	proto.RegisterType((*SystemResourceInfo)(nil), "soong_build_metrics.SystemResourceInfo")
@@ -983,6 +1158,8 @@ func init() {
 	proto.RegisterType((*CriticalUserJourneyMetrics)(nil), "soong_build_metrics.CriticalUserJourneyMetrics")
 	proto.RegisterType((*CriticalUserJourneysMetrics)(nil), "soong_build_metrics.CriticalUserJourneysMetrics")
 	proto.RegisterType((*SoongBuildMetrics)(nil), "soong_build_metrics.SoongBuildMetrics")
+	proto.RegisterType((*SoongRegenInfo)(nil), "soong_build_metrics.SoongRegenInfo")
+	proto.RegisterType((*SoongRegenReason)(nil), "soong_build_metrics.SoongRegenReason")
 }
 
 func init() {
@@ -990,92 +1167,104 @@ func init() {
 }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 1380 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xef, 0x52, 0x1b, 0x37,
-	0x10, 0x8f, 0xc1, 0x60, 0x7b, 0xfd, 0x07, 0x23, 0xa0, 0x5c, 0x48, 0xd2, 0x52, 0xb7, 0x49, 0x99,
-	0x4e, 0x43, 0x32, 0x34, 0xc3, 0x64, 0x98, 0x4c, 0xa7, 0xe0, 0xd0, 0x34, 0x65, 0xc0, 0x8c, 0x08,
-	0x69, 0xda, 0x7e, 0x50, 0xe5, 0xb3, 0x0c, 0x97, 0xdc, 0x9d, 0x6e, 0x24, 0x1d, 0xc5, 0x79, 0xb3,
-	0x7e, 0xee, 0x4b, 0xf4, 0x05, 0xfa, 0x04, 0x7d, 0x81, 0x8e, 0x56, 0x77, 0xe6, 0x20, 0x6e, 0xc2,
-	0xe4, 0xdb, 0xe9, 0xb7, 0xbf, 0xdf, 0x6a, 0xb5, 0xd2, 0xee, 0xda, 0xd0, 0x8c, 0x84, 0x51, 0x81,
-	0xaf, 0xd7, 0x13, 0x25, 0x8d, 0x24, 0x0b, 0x5a, 0xca, 0xf8, 0x84, 0xf5, 0xd3, 0x20, 0x1c, 0xb0,
-	0xcc, 0xd4, 0xf9, 0xbb, 0x01, 0xf5, 0x7d, 0xf7, 0xbd, 0xc3, 0xb5, 0x20, 0x0f, 0x61, 0xd1, 0x11,
-	0x06, 0xdc, 0x08, 0x66, 0x82, 0x48, 0x68, 0xc3, 0xa3, 0xc4, 0x2b, 0xad, 0x96, 0xd6, 0xa6, 0x29,
-	0x41, 0xdb, 0x53, 0x6e, 0xc4, 0x8b, 0xdc, 0x42, 0x6e, 0x42, 0xd5, 0x29, 0x82, 0x81, 0x37, 0xb5,
-	0x5a, 0x5a, 0xab, 0xd1, 0x0a, 0xae, 0x9f, 0x0f, 0xc8, 0x16, 0xdc, 0x4c, 0x42, 0x6e, 0x86, 0x52,
-	0x45, 0xec, 0x4c, 0x28, 0x1d, 0xc8, 0x98, 0xf9, 0x72, 0x20, 0x62, 0x1e, 0x09, 0x6f, 0x1a, 0xb9,
-	0xcb, 0x39, 0xe1, 0xa5, 0xb3, 0x77, 0x33, 0x33, 0xb9, 0x0b, 0x2d, 0xc3, 0xd5, 0x89, 0x30, 0x2c,
-	0x51, 0x72, 0x90, 0xfa, 0xc6, 0x2b, 0xa3, 0xa0, 0xe9, 0xd0, 0x43, 0x07, 0x92, 0x01, 0x2c, 0x66,
-	0x34, 0x17, 0xc4, 0x19, 0x57, 0x01, 0x8f, 0x8d, 0x37, 0xb3, 0x5a, 0x5a, 0x6b, 0x6d, 0xdc, 0x5f,
-	0x9f, 0x70, 0xe6, 0xf5, 0xc2, 0x79, 0xd7, 0x77, 0xac, 0xe5, 0xa5, 0x13, 0x6d, 0x4d, 0xef, 0x1e,
-	0x3c, 0xa3, 0xc4, 0xf9, 0x2b, 0x1a, 0x48, 0x0f, 0xea, 0xd9, 0x2e, 0x5c, 0xf9, 0xa7, 0xde, 0x2c,
-	0x3a, 0xbf, 0xfb, 0x41, 0xe7, 0xdb, 0xca, 0x3f, 0xdd, 0xaa, 0x1c, 0x1f, 0xec, 0x1d, 0xf4, 0x7e,
-	0x3e, 0xa0, 0xe0, 0x5c, 0x58, 0x90, 0xac, 0xc3, 0x42, 0xc1, 0xe1, 0x38, 0xea, 0x0a, 0x1e, 0x71,
-	0xfe, 0x82, 0x98, 0x07, 0xf0, 0x0d, 0x64, 0x61, 0x31, 0x3f, 0x49, 0xc7, 0xf4, 0x2a, 0xd2, 0xdb,
-	0xce, 0xd2, 0x4d, 0xd2, 0x9c, 0xbd, 0x07, 0xb5, 0x53, 0xa9, 0xb3, 0x60, 0x6b, 0x1f, 0x15, 0x6c,
-	0xd5, 0x3a, 0xc0, 0x50, 0x29, 0x34, 0xd1, 0xd9, 0x46, 0x3c, 0x70, 0x0e, 0xe1, 0xa3, 0x1c, 0xd6,
-	0xad, 0x93, 0x8d, 0x78, 0x80, 0x3e, 0x97, 0xa1, 0x82, 0x3e, 0xa5, 0xf6, 0xea, 0x78, 0x86, 0x59,
-	0xbb, 0xec, 0x69, 0xd2, 0xc9, 0x36, 0x93, 0x9a, 0x89, 0x73, 0xa3, 0xb8, 0xd7, 0x40, 0x73, 0xdd,
-	0x99, 0x77, 0x2d, 0x34, 0xe6, 0xf8, 0x4a, 0x6a, 0x6d, 0x5d, 0x34, 0x2f, 0x38, 0x5d, 0x8b, 0xf5,
-	0x34, 0xb9, 0x07, 0x73, 0x05, 0x0e, 0x86, 0xdd, 0x72, 0xcf, 0x67, 0xcc, 0xc2, 0x40, 0xee, 0xc3,
-	0x42, 0x81, 0x37, 0x3e, 0xe2, 0x9c, 0x4b, 0xec, 0x98, 0x5b, 0x88, 0x5b, 0xa6, 0x86, 0x0d, 0x02,
-	0xe5, 0xb5, 0x5d, 0xdc, 0x32, 0x35, 0x4f, 0x03, 0x45, 0xbe, 0x83, 0xba, 0x16, 0x26, 0x4d, 0x98,
-	0x91, 0x32, 0xd4, 0xde, 0xfc, 0xea, 0xf4, 0x5a, 0x7d, 0xe3, 0xce, 0xc4, 0x14, 0x1d, 0x0a, 0x35,
-	0x7c, 0x1e, 0x0f, 0x25, 0x05, 0x54, 0xbc, 0xb0, 0x02, 0xb2, 0x05, 0xb5, 0x37, 0xdc, 0x04, 0x4c,
-	0xa5, 0xb1, 0xf6, 0xc8, 0x75, 0xd4, 0x55, 0xcb, 0xa7, 0x69, 0xac, 0xc9, 0x13, 0x00, 0xc7, 0x44,
-	0xf1, 0xc2, 0x75, 0xc4, 0x35, 0xb4, 0xe6, 0xea, 0x38, 0x88, 0x5f, 0x73, 0xa7, 0x5e, 0xbc, 0x96,
-	0x1a, 0x05, 0xa8, 0xfe, 0x16, 0x66, 0x8c, 0x34, 0x3c, 0xf4, 0x96, 0x56, 0x4b, 0x1f, 0x16, 0x3a,
-	0x2e, 0x79, 0x09, 0x93, 0x5a, 0x91, 0xf7, 0x09, 0xba, 0xb8, 0x37, 0xd1, 0xc5, 0x91, 0xc5, 0xb0,
-	0x24, 0xb3, 0x17, 0x46, 0xe7, 0xf5, 0x55, 0x88, 0x74, 0xa1, 0xe1, 0x54, 0xbe, 0x8c, 0x87, 0xc1,
-	0x89, 0xb7, 0x8c, 0x0e, 0x57, 0x27, 0x3a, 0x44, 0x61, 0x17, 0x79, 0xb4, 0xde, 0xbf, 0x58, 0x90,
-	0x15, 0xc0, 0xa7, 0x8f, 0x2d, 0xca, 0xc3, 0x3b, 0x1e, 0xaf, 0xc9, 0x2f, 0xb0, 0xa8, 0x47, 0xda,
-	0x88, 0x88, 0x29, 0xa1, 0x65, 0xaa, 0x7c, 0xc1, 0x82, 0x78, 0x28, 0xbd, 0x9b, 0xb8, 0xd1, 0x57,
-	0x93, 0x23, 0x47, 0x01, 0xcd, 0xf8, 0x98, 0x06, 0xa2, 0xdf, 0xc1, 0xc8, 0x17, 0xd0, 0xcc, 0x63,
-	0x8f, 0x22, 0x1e, 0x0f, 0xbc, 0x15, 0xdc, 0xbb, 0x91, 0x85, 0x86, 0x98, 0xbd, 0xab, 0x3e, 0x7f,
-	0x2b, 0x42, 0x77, 0x57, 0xb7, 0xae, 0x75, 0x57, 0x28, 0xb0, 0x77, 0xd5, 0x79, 0x08, 0x8d, 0x4b,
-	0x4d, 0xad, 0x0a, 0xe5, 0xe3, 0xa3, 0x5d, 0xda, 0xbe, 0x41, 0x9a, 0x50, 0xb3, 0x5f, 0x4f, 0x77,
-	0x77, 0x8e, 0x9f, 0xb5, 0x4b, 0xa4, 0x02, 0xb6, 0x11, 0xb6, 0xa7, 0x3a, 0x4f, 0xa0, 0x8c, 0xcf,
-	0xbe, 0x0e, 0x79, 0x19, 0xb7, 0x6f, 0x58, 0xeb, 0x36, 0xdd, 0x6f, 0x97, 0x48, 0x0d, 0x66, 0xb6,
-	0xe9, 0xfe, 0xe6, 0xa3, 0xf6, 0x94, 0xc5, 0x5e, 0x3d, 0xde, 0x6c, 0x4f, 0x13, 0x80, 0xd9, 0x57,
-	0x8f, 0x37, 0xd9, 0xe6, 0xa3, 0x76, 0xb9, 0x73, 0x02, 0xf5, 0x42, 0x96, 0xed, 0x9c, 0x48, 0xb5,
-	0x60, 0x27, 0x32, 0xe2, 0x38, 0x4d, 0xaa, 0xb4, 0x92, 0x6a, 0xf1, 0x4c, 0x46, 0xdc, 0x96, 0x95,
-	0x35, 0xa9, 0xbe, 0xc0, 0x09, 0x52, 0xa5, 0xb3, 0xa9, 0x16, 0xb4, 0x2f, 0xc8, 0x97, 0xd0, 0x1a,
-	0x4a, 0x9b, 0xe6, 0xb1, 0x72, 0x1a, 0xed, 0x0d, 0x44, 0x8f, 0x9d, 0xbc, 0x23, 0x81, 0xbc, 0x9b,
-	0x65, 0xb2, 0x01, 0x4b, 0xf8, 0xdc, 0x58, 0x72, 0x3a, 0xd2, 0x81, 0xcf, 0x43, 0x16, 0x89, 0x48,
-	0xaa, 0x11, 0x6e, 0x5e, 0xa6, 0x0b, 0x68, 0x3c, 0xcc, 0x6c, 0xfb, 0x68, 0xb2, 0x43, 0x87, 0x9f,
-	0xf1, 0x20, 0xe4, 0xfd, 0x50, 0xd8, 0x4e, 0xab, 0x31, 0x9e, 0x19, 0xda, 0x1c, 0xa3, 0xdd, 0x24,
-	0xd5, 0x9d, 0x7f, 0x4b, 0x50, 0xcd, 0x33, 0x4c, 0x08, 0x94, 0x07, 0x42, 0xfb, 0xe8, 0xb6, 0x46,
-	0xf1, 0xdb, 0x62, 0xf8, 0x80, 0xdc, 0x3c, 0xc4, 0x6f, 0x72, 0x07, 0x40, 0x1b, 0xae, 0x0c, 0x0e,
-	0x55, 0x3c, 0x47, 0x99, 0xd6, 0x10, 0xb1, 0xb3, 0x94, 0xdc, 0x82, 0x9a, 0x12, 0x3c, 0x74, 0xd6,
-	0x32, 0x5a, 0xab, 0x16, 0x40, 0xe3, 0xe7, 0x00, 0x2e, 0x78, 0x9b, 0x08, 0x9c, 0x6d, 0xe5, 0x9d,
-	0x29, 0xaf, 0x44, 0x6b, 0x0e, 0x3d, 0xd6, 0x82, 0xfc, 0x0e, 0xcb, 0x89, 0x92, 0xbe, 0xd0, 0x5a,
-	0xe8, 0x2b, 0xcf, 0x73, 0x16, 0x1f, 0xca, 0xda, 0xe4, 0x87, 0xe2, 0x34, 0x97, 0xde, 0xe7, 0xd2,
-	0xd8, 0x51, 0x11, 0xee, 0xfc, 0x39, 0x0d, 0x0b, 0x13, 0xe8, 0xe3, 0xc3, 0x96, 0x0a, 0x87, 0x5d,
-	0x83, 0x76, 0xaa, 0x85, 0xc2, 0xd3, 0xb0, 0x28, 0xb0, 0xed, 0x15, 0x93, 0x51, 0xa6, 0x2d, 0x8b,
-	0xdb, 0x43, 0xed, 0x23, 0x6a, 0x27, 0x5b, 0x56, 0x53, 0x45, 0xae, 0x4b, 0x4f, 0xdb, 0x59, 0x0a,
-	0xec, 0xdb, 0x00, 0x11, 0x3f, 0x67, 0x4a, 0x6b, 0xf6, 0xa6, 0x9f, 0xa7, 0x29, 0xe2, 0xe7, 0x54,
-	0xeb, 0xbd, 0x3e, 0xf9, 0x1a, 0xe6, 0xa3, 0x20, 0x96, 0x8a, 0x25, 0xfc, 0x44, 0xb0, 0x21, 0x4f,
-	0x43, 0xa3, 0x5d, 0xb6, 0xe8, 0x1c, 0x1a, 0x0e, 0xf9, 0x89, 0xf8, 0x01, 0x61, 0xe4, 0xf2, 0xd7,
-	0x57, 0xb8, 0xb3, 0x19, 0xd7, 0x1a, 0x0a, 0xdc, 0x4f, 0xa1, 0x1e, 0x48, 0x16, 0xc4, 0x49, 0x6a,
-	0xec, 0xb6, 0x15, 0x77, 0x77, 0x81, 0x7c, 0x6e, 0x91, 0xbd, 0x3e, 0x59, 0x85, 0x46, 0x20, 0x99,
-	0x4c, 0x4d, 0x46, 0xa8, 0x22, 0x01, 0x02, 0xd9, 0x43, 0x68, 0xaf, 0x4f, 0x9e, 0xc0, 0xca, 0x99,
-	0x0c, 0xd3, 0xd8, 0x70, 0x35, 0xb2, 0xed, 0xc9, 0x88, 0x73, 0xc3, 0xf4, 0x1f, 0x81, 0xf1, 0x4f,
-	0x85, 0xc6, 0x11, 0x5d, 0xa6, 0xde, 0x98, 0xd1, 0x75, 0x84, 0xa3, 0xcc, 0x4e, 0xbe, 0x87, 0xdb,
-	0x41, 0xfc, 0x1e, 0x3d, 0xa0, 0x7e, 0xa5, 0xc0, 0xb9, 0xe2, 0xa1, 0xf3, 0x4f, 0x09, 0x5a, 0xfb,
-	0x72, 0x90, 0x86, 0xe2, 0xc5, 0x28, 0x71, 0xd7, 0xf6, 0x5b, 0xde, 0x2d, 0x5d, 0x92, 0xf1, 0xfa,
-	0x5a, 0x1b, 0x0f, 0x26, 0x8f, 0xf5, 0x4b, 0x52, 0xd7, 0x3c, 0x5d, 0xc9, 0x15, 0x06, 0x7c, 0xff,
-	0x02, 0x25, 0x9f, 0x41, 0x3d, 0x42, 0x0d, 0x33, 0xa3, 0x24, 0xaf, 0x03, 0x88, 0xc6, 0x6e, 0x6c,
-	0x65, 0xc7, 0x69, 0xc4, 0xe4, 0x90, 0x39, 0xd0, 0x5d, 0x79, 0x93, 0x36, 0xe2, 0x34, 0xea, 0x0d,
-	0xdd, 0x7e, 0xba, 0xf3, 0x20, 0x6b, 0x21, 0x99, 0xd7, 0x4b, 0x7d, 0xa8, 0x06, 0x33, 0x47, 0xbd,
-	0xde, 0x81, 0x6d, 0x58, 0x55, 0x28, 0xef, 0x6f, 0xef, 0xed, 0xb6, 0xa7, 0x3a, 0x21, 0xac, 0x74,
-	0x55, 0x60, 0x6c, 0x49, 0x1f, 0x6b, 0xa1, 0x7e, 0x92, 0xa9, 0x8a, 0xc5, 0x28, 0x1f, 0x10, 0x93,
-	0x5e, 0xea, 0x16, 0x54, 0xf2, 0x01, 0x34, 0xf5, 0x9e, 0x79, 0x51, 0xf8, 0x61, 0x43, 0x73, 0x41,
-	0xa7, 0x0f, 0xb7, 0x26, 0xec, 0xa6, 0x2f, 0xe6, 0x51, 0xd9, 0x4f, 0x5f, 0x6b, 0xaf, 0x84, 0xf5,
-	0x37, 0x39, 0xb3, 0xff, 0x1f, 0x2d, 0x45, 0x71, 0xe7, 0xaf, 0x12, 0xcc, 0xbf, 0x33, 0xfd, 0x88,
-	0x07, 0x95, 0x3c, 0x6f, 0x25, 0xcc, 0x5b, 0xbe, 0xb4, 0xf3, 0x2b, 0xfb, 0x79, 0xe8, 0x0e, 0xd4,
-	0xa4, 0xe3, 0xb5, 0x7d, 0xf3, 0xae, 0x25, 0xf2, 0x30, 0x94, 0x3e, 0xf3, 0x65, 0x1a, 0x9b, 0xac,
-	0xd4, 0xe6, 0xd0, 0xb0, 0x6d, 0xf1, 0xae, 0x85, 0x6d, 0x05, 0x17, 0xb9, 0x3a, 0x78, 0x9b, 0xb7,
-	0xa5, 0xd6, 0x05, 0xf5, 0x28, 0x78, 0x2b, 0xec, 0xef, 0x31, 0x5b, 0x93, 0xa7, 0x82, 0x27, 0x8e,
-	0xe6, 0x2a, 0xae, 0x1e, 0xf1, 0xf3, 0x1f, 0x05, 0x4f, 0x2c, 0x67, 0x67, 0xe9, 0xd7, 0x6c, 0xe4,
-	0x67, 0xe7, 0x66, 0xf8, 0x97, 0xe4, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xcb, 0xfb, 0x8e, 0xf5,
-	0xa2, 0x0c, 0x00, 0x00,
+	// 1572 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xe1, 0x72, 0xdb, 0xc6,
+	0x11, 0x0e, 0x25, 0x4a, 0x24, 0x97, 0x22, 0x05, 0x9d, 0xec, 0x18, 0x96, 0x9d, 0x56, 0x45, 0xea,
+	0x54, 0x93, 0x69, 0x94, 0x8c, 0x9a, 0xf1, 0x64, 0x34, 0x9e, 0xb6, 0x12, 0xcd, 0x28, 0xac, 0x4c,
+	0x52, 0x73, 0x22, 0xdd, 0xb4, 0xfd, 0x71, 0x3d, 0x02, 0x47, 0x0a, 0x36, 0x80, 0xc3, 0xe0, 0x0e,
+	0xaa, 0xe4, 0x87, 0xe9, 0x7b, 0xf4, 0x77, 0x9f, 0xa5, 0x4f, 0xd0, 0x07, 0x68, 0xe7, 0xf6, 0x00,
+	0x8a, 0x92, 0xe9, 0x58, 0x93, 0x7f, 0xbc, 0xdd, 0xef, 0xfb, 0x6e, 0x6f, 0x6f, 0x6f, 0x17, 0x84,
+	0x56, 0x2c, 0x74, 0x16, 0xfa, 0x6a, 0x3f, 0xcd, 0xa4, 0x96, 0x64, 0x5b, 0x49, 0x99, 0xcc, 0xd8,
+	0x24, 0x0f, 0xa3, 0x80, 0x15, 0x2e, 0xef, 0x9f, 0x2d, 0x68, 0xf6, 0xed, 0xef, 0x63, 0xae, 0x04,
+	0xf9, 0x06, 0x1e, 0x58, 0x40, 0xc0, 0xb5, 0x60, 0x3a, 0x8c, 0x85, 0xd2, 0x3c, 0x4e, 0xdd, 0xca,
+	0x6e, 0x65, 0x6f, 0x95, 0x12, 0xf4, 0xbd, 0xe4, 0x5a, 0x8c, 0x4a, 0x0f, 0x79, 0x0c, 0x75, 0xcb,
+	0x08, 0x03, 0x77, 0x65, 0xb7, 0xb2, 0xd7, 0xa0, 0x35, 0x5c, 0xf7, 0x02, 0x72, 0x08, 0x8f, 0xd3,
+	0x88, 0xeb, 0xa9, 0xcc, 0x62, 0x76, 0x29, 0x32, 0x15, 0xca, 0x84, 0xf9, 0x32, 0x10, 0x09, 0x8f,
+	0x85, 0xbb, 0x8a, 0xd8, 0x47, 0x25, 0xe0, 0xb5, 0xf5, 0x77, 0x0a, 0x37, 0x79, 0x06, 0x6d, 0xcd,
+	0xb3, 0x99, 0xd0, 0x2c, 0xcd, 0x64, 0x90, 0xfb, 0xda, 0xad, 0x22, 0xa1, 0x65, 0xad, 0x67, 0xd6,
+	0x48, 0x02, 0x78, 0x50, 0xc0, 0x6c, 0x10, 0x97, 0x3c, 0x0b, 0x79, 0xa2, 0xdd, 0xb5, 0xdd, 0xca,
+	0x5e, 0xfb, 0xe0, 0xab, 0xfd, 0x25, 0x67, 0xde, 0x5f, 0x38, 0xef, 0xfe, 0xb1, 0xf1, 0xbc, 0xb6,
+	0xa4, 0xc3, 0xd5, 0xee, 0xe0, 0x84, 0x12, 0xab, 0xb7, 0xe8, 0x20, 0x43, 0x68, 0x16, 0xbb, 0xf0,
+	0xcc, 0xbf, 0x70, 0xd7, 0x51, 0xfc, 0xd9, 0x47, 0xc5, 0x8f, 0x32, 0xff, 0xe2, 0xb0, 0x36, 0x1e,
+	0x9c, 0x0e, 0x86, 0x7f, 0x1e, 0x50, 0xb0, 0x12, 0xc6, 0x48, 0xf6, 0x61, 0x7b, 0x41, 0x70, 0x1e,
+	0x75, 0x0d, 0x8f, 0xb8, 0x75, 0x03, 0x2c, 0x03, 0xf8, 0x2d, 0x14, 0x61, 0x31, 0x3f, 0xcd, 0xe7,
+	0xf0, 0x3a, 0xc2, 0x1d, 0xeb, 0xe9, 0xa4, 0x79, 0x89, 0x3e, 0x85, 0xc6, 0x85, 0x54, 0x45, 0xb0,
+	0x8d, 0x9f, 0x15, 0x6c, 0xdd, 0x08, 0x60, 0xa8, 0x14, 0x5a, 0x28, 0x76, 0x90, 0x04, 0x56, 0x10,
+	0x7e, 0x96, 0x60, 0xd3, 0x88, 0x1c, 0x24, 0x01, 0x6a, 0x3e, 0x82, 0x1a, 0x6a, 0x4a, 0xe5, 0x36,
+	0xf1, 0x0c, 0xeb, 0x66, 0x39, 0x54, 0xc4, 0x2b, 0x36, 0x93, 0x8a, 0x89, 0x2b, 0x9d, 0x71, 0x77,
+	0x03, 0xdd, 0x4d, 0xeb, 0xee, 0x1a, 0xd3, 0x1c, 0xe3, 0x67, 0x52, 0x29, 0x23, 0xd1, 0xba, 0xc1,
+	0x74, 0x8c, 0x6d, 0xa8, 0xc8, 0x17, 0xb0, 0xb9, 0x80, 0xc1, 0xb0, 0xdb, 0xb6, 0x7c, 0xe6, 0x28,
+	0x0c, 0xe4, 0x2b, 0xd8, 0x5e, 0xc0, 0xcd, 0x8f, 0xb8, 0x69, 0x13, 0x3b, 0xc7, 0x2e, 0xc4, 0x2d,
+	0x73, 0xcd, 0x82, 0x30, 0x73, 0x1d, 0x1b, 0xb7, 0xcc, 0xf5, 0xcb, 0x30, 0x23, 0xbf, 0x87, 0xa6,
+	0x12, 0x3a, 0x4f, 0x99, 0x96, 0x32, 0x52, 0xee, 0xd6, 0xee, 0xea, 0x5e, 0xf3, 0xe0, 0xb3, 0xa5,
+	0x29, 0x3a, 0x13, 0xd9, 0xb4, 0x97, 0x4c, 0x25, 0x05, 0x64, 0x8c, 0x0c, 0x81, 0x1c, 0x42, 0xe3,
+	0x2d, 0xd7, 0x21, 0xcb, 0xf2, 0x44, 0xb9, 0xe4, 0x3e, 0xec, 0xba, 0xc1, 0xd3, 0x3c, 0x51, 0xe4,
+	0x05, 0x80, 0x45, 0x22, 0x79, 0xfb, 0x3e, 0xe4, 0x06, 0x7a, 0x4b, 0x76, 0x12, 0x26, 0x6f, 0xb8,
+	0x65, 0x3f, 0xb8, 0x17, 0x1b, 0x09, 0xc8, 0xfe, 0x1d, 0xac, 0x69, 0xa9, 0x79, 0xe4, 0x3e, 0xdc,
+	0xad, 0x7c, 0x9c, 0x68, 0xb1, 0xe4, 0x35, 0x2c, 0x6b, 0x45, 0xee, 0xa7, 0x28, 0xf1, 0xc5, 0x52,
+	0x89, 0x73, 0x63, 0xc3, 0x27, 0x59, 0x54, 0x18, 0xdd, 0x52, 0x77, 0x4d, 0xa4, 0x03, 0x1b, 0x96,
+	0xe5, 0xcb, 0x64, 0x1a, 0xce, 0xdc, 0x47, 0x28, 0xb8, 0xbb, 0x54, 0x10, 0x89, 0x1d, 0xc4, 0xd1,
+	0xe6, 0xe4, 0x66, 0x41, 0x76, 0x00, 0x4b, 0x1f, 0x5b, 0x94, 0x8b, 0x77, 0x3c, 0x5f, 0x93, 0xbf,
+	0xc0, 0x03, 0x75, 0xad, 0xb4, 0x88, 0x59, 0x26, 0x94, 0xcc, 0x33, 0x5f, 0xb0, 0x30, 0x99, 0x4a,
+	0xf7, 0x31, 0x6e, 0xf4, 0x9b, 0xe5, 0x91, 0x23, 0x81, 0x16, 0x78, 0x4c, 0x03, 0x51, 0xef, 0xd9,
+	0xc8, 0xe7, 0xd0, 0x2a, 0x63, 0x8f, 0x63, 0x9e, 0x04, 0xee, 0x0e, 0xee, 0xbd, 0x51, 0x84, 0x86,
+	0x36, 0x73, 0x57, 0x13, 0xfe, 0x4e, 0x44, 0xf6, 0xae, 0x9e, 0xdc, 0xeb, 0xae, 0x90, 0x80, 0x77,
+	0xf5, 0x12, 0x9a, 0x45, 0x9d, 0x88, 0x99, 0x48, 0xdc, 0xa7, 0x18, 0xf4, 0xe7, 0x1f, 0x4e, 0x37,
+	0x35, 0xb0, 0xa2, 0x52, 0xe7, 0x6b, 0xef, 0x1b, 0xd8, 0xb8, 0xd5, 0x1a, 0xeb, 0x50, 0x1d, 0x9f,
+	0x77, 0xa9, 0xf3, 0x09, 0x69, 0x41, 0xc3, 0xfc, 0x7a, 0xd9, 0x3d, 0x1e, 0x9f, 0x38, 0x15, 0x52,
+	0x03, 0xd3, 0x4e, 0x9d, 0x15, 0xef, 0x05, 0x54, 0xf1, 0xf1, 0x34, 0xa1, 0x6c, 0x06, 0xce, 0x27,
+	0xc6, 0x7b, 0x44, 0xfb, 0x4e, 0x85, 0x34, 0x60, 0xed, 0x88, 0xf6, 0x9f, 0x7f, 0xeb, 0xac, 0x18,
+	0xdb, 0x8f, 0xdf, 0x3d, 0x77, 0x56, 0x09, 0xc0, 0xfa, 0x8f, 0xdf, 0x3d, 0x67, 0xcf, 0xbf, 0x75,
+	0xaa, 0xde, 0x0c, 0x9a, 0x0b, 0x77, 0x65, 0xa6, 0x4d, 0xae, 0x04, 0x9b, 0xc9, 0x98, 0xe3, 0x4c,
+	0xaa, 0xd3, 0x5a, 0xae, 0xc4, 0x89, 0x8c, 0xb9, 0x79, 0x9c, 0xc6, 0x95, 0x4d, 0x04, 0xce, 0xa1,
+	0x3a, 0x5d, 0xcf, 0x95, 0xa0, 0x13, 0x41, 0x7e, 0x0d, 0xed, 0xa9, 0x34, 0x97, 0x35, 0x67, 0xae,
+	0xa2, 0x7f, 0x03, 0xad, 0x63, 0x4b, 0xf7, 0x24, 0x90, 0xf7, 0xef, 0x8a, 0x1c, 0xc0, 0x43, 0x2c,
+	0x5a, 0x96, 0x5e, 0x5c, 0xab, 0xd0, 0xe7, 0x11, 0x8b, 0x45, 0x2c, 0xb3, 0x6b, 0xdc, 0xbc, 0x4a,
+	0xb7, 0xd1, 0x79, 0x56, 0xf8, 0xfa, 0xe8, 0x32, 0xa3, 0x8b, 0x5f, 0xf2, 0x30, 0xe2, 0x93, 0x48,
+	0x98, 0x7e, 0xad, 0x30, 0x9e, 0x35, 0xda, 0x9a, 0x5b, 0x3b, 0x69, 0xae, 0xbc, 0xff, 0x56, 0xa0,
+	0x5e, 0xde, 0x13, 0x21, 0x50, 0x0d, 0x84, 0xf2, 0x51, 0xb6, 0x41, 0xf1, 0xb7, 0xb1, 0x61, 0x19,
+	0xda, 0xa9, 0x8a, 0xbf, 0xc9, 0x67, 0x00, 0x4a, 0xf3, 0x4c, 0xe3, 0x68, 0xc6, 0x73, 0x54, 0x69,
+	0x03, 0x2d, 0x66, 0x22, 0x93, 0x27, 0xd0, 0xc8, 0x04, 0x8f, 0xac, 0xb7, 0x8a, 0xde, 0xba, 0x31,
+	0xa0, 0xf3, 0x57, 0x00, 0x36, 0x78, 0x93, 0x08, 0x9c, 0x90, 0xd5, 0xe3, 0x15, 0xb7, 0x42, 0x1b,
+	0xd6, 0x3a, 0x56, 0x82, 0xfc, 0x1d, 0x1e, 0xa5, 0x99, 0xf4, 0x85, 0x52, 0x42, 0xdd, 0x29, 0xf2,
+	0x75, 0x2c, 0xb7, 0xbd, 0xe5, 0xe5, 0x66, 0x39, 0xb7, 0xaa, 0xfc, 0xe1, 0x5c, 0x68, 0xd1, 0xec,
+	0xfd, 0x6b, 0x15, 0xb6, 0x97, 0xc0, 0xe7, 0x87, 0xad, 0x2c, 0x1c, 0x76, 0x0f, 0x9c, 0x5c, 0x89,
+	0x0c, 0x4f, 0xc3, 0xe2, 0xd0, 0x34, 0x69, 0x4c, 0x46, 0x95, 0xb6, 0x8d, 0xdd, 0x1c, 0xaa, 0x8f,
+	0x56, 0x33, 0x1f, 0x8b, 0x97, 0xb9, 0x88, 0xb5, 0xe9, 0x71, 0xac, 0x67, 0x01, 0xfd, 0x14, 0x20,
+	0xe6, 0x57, 0x2c, 0x53, 0x8a, 0xbd, 0x9d, 0x94, 0x69, 0x8a, 0xf9, 0x15, 0x55, 0xea, 0x74, 0x42,
+	0xbe, 0x84, 0xad, 0x38, 0x4c, 0x64, 0xc6, 0x52, 0x3e, 0x13, 0x6c, 0xca, 0xf3, 0x48, 0x2b, 0x9b,
+	0x2d, 0xba, 0x89, 0x8e, 0x33, 0x3e, 0x13, 0xdf, 0xa3, 0x19, 0xb1, 0xfc, 0xcd, 0x1d, 0xec, 0x7a,
+	0x81, 0xe5, 0x6f, 0x6e, 0x61, 0x7f, 0x01, 0xcd, 0x50, 0xb2, 0x30, 0x49, 0x73, 0x6d, 0xb6, 0xad,
+	0xd9, 0xbb, 0x0b, 0x65, 0xcf, 0x58, 0x4e, 0x27, 0x64, 0x17, 0x36, 0x42, 0xc9, 0x64, 0xae, 0x0b,
+	0x40, 0x1d, 0x01, 0x10, 0xca, 0x21, 0x9a, 0x4e, 0x27, 0xe4, 0x05, 0xec, 0x5c, 0xca, 0x28, 0x4f,
+	0x34, 0xcf, 0xae, 0x4d, 0x93, 0xd3, 0xe2, 0x4a, 0x33, 0xf5, 0x8f, 0x50, 0xfb, 0x17, 0x42, 0xe1,
+	0xa0, 0xaf, 0x52, 0x77, 0x8e, 0xe8, 0x58, 0xc0, 0x79, 0xe1, 0x27, 0x7f, 0x84, 0xa7, 0x61, 0xf2,
+	0x13, 0x7c, 0x40, 0xfe, 0x4e, 0x98, 0x7c, 0x48, 0xc1, 0xfb, 0x4f, 0x05, 0xda, 0x7d, 0x19, 0xe4,
+	0x91, 0x18, 0x5d, 0xa7, 0xf6, 0xda, 0xfe, 0x56, 0xf6, 0x5c, 0x9b, 0x64, 0xbc, 0xbe, 0xf6, 0xc1,
+	0xd7, 0xcb, 0x3f, 0x0e, 0x6e, 0x51, 0x6d, 0x0b, 0xb6, 0x4f, 0x6e, 0xe1, 0x33, 0x61, 0x72, 0x63,
+	0x25, 0xbf, 0x84, 0x66, 0x8c, 0x1c, 0xa6, 0xaf, 0xd3, 0xf2, 0x1d, 0x40, 0x3c, 0x97, 0x31, 0x2f,
+	0x3b, 0xc9, 0x63, 0x26, 0xa7, 0xcc, 0x1a, 0xed, 0x95, 0xb7, 0xe8, 0x46, 0x92, 0xc7, 0xc3, 0xa9,
+	0xdd, 0x4f, 0x79, 0x5f, 0x17, 0x2d, 0xa4, 0x50, 0xbd, 0xd5, 0x87, 0x1a, 0xb0, 0x76, 0x3e, 0x1c,
+	0x0e, 0x4c, 0xc3, 0xaa, 0x43, 0xb5, 0x7f, 0x74, 0xda, 0x75, 0x56, 0xbc, 0x08, 0x76, 0x3a, 0x59,
+	0xa8, 0xcd, 0x93, 0x1e, 0x2b, 0x91, 0xfd, 0x49, 0xe6, 0x59, 0x22, 0xae, 0xcb, 0x31, 0xb3, 0xac,
+	0x52, 0x0f, 0xa1, 0x56, 0x8e, 0xb1, 0x95, 0x9f, 0x98, 0x3a, 0x0b, 0x9f, 0x47, 0xb4, 0x24, 0x78,
+	0x13, 0x78, 0xb2, 0x64, 0x37, 0x75, 0x33, 0xd5, 0xaa, 0x7e, 0xfe, 0x46, 0xb9, 0x15, 0x7c, 0x7f,
+	0xcb, 0x33, 0xfb, 0xe1, 0x68, 0x29, 0x92, 0xbd, 0x7f, 0x57, 0x60, 0xeb, 0xbd, 0x19, 0x4a, 0x5c,
+	0xa8, 0x95, 0x79, 0xab, 0x60, 0xde, 0xca, 0xa5, 0x99, 0x82, 0xc5, 0x47, 0xa6, 0x3d, 0x50, 0x8b,
+	0xce, 0xd7, 0xa6, 0xe6, 0x6d, 0x4b, 0xe4, 0x51, 0x24, 0x7d, 0xe6, 0xcb, 0x3c, 0xd1, 0xc5, 0x53,
+	0xdb, 0x44, 0xc7, 0x91, 0xb1, 0x77, 0x8c, 0xd9, 0xbc, 0xe0, 0x45, 0xac, 0x0a, 0xdf, 0x95, 0x6d,
+	0xa9, 0x7d, 0x03, 0x3d, 0x0f, 0xdf, 0x09, 0xf3, 0x55, 0x67, 0xde, 0xe4, 0x85, 0xe0, 0xa9, 0x85,
+	0xd9, 0x17, 0xd7, 0x8c, 0xf9, 0xd5, 0x0f, 0x82, 0xa7, 0x06, 0xe3, 0x65, 0xd0, 0xbe, 0x3d, 0x99,
+	0xc8, 0x1f, 0xa0, 0x96, 0x09, 0xae, 0x64, 0x52, 0xe6, 0xe7, 0xd9, 0x47, 0xe6, 0x19, 0x45, 0x34,
+	0x2d, 0x59, 0xa6, 0xc4, 0x4c, 0x05, 0x95, 0x22, 0xf6, 0xac, 0x90, 0xe4, 0xb1, 0x05, 0x2a, 0xef,
+	0x7f, 0x15, 0x70, 0xee, 0xd2, 0xc9, 0x09, 0x54, 0xdf, 0x86, 0x49, 0x50, 0x54, 0xfb, 0x97, 0xf7,
+	0xda, 0x73, 0xff, 0x34, 0x4c, 0x82, 0x9b, 0x42, 0x47, 0x81, 0xa5, 0x2d, 0xfe, 0x53, 0x58, 0x0f,
+	0x84, 0xe6, 0x61, 0x54, 0xfc, 0x45, 0x2a, 0x56, 0xde, 0x25, 0x54, 0x8d, 0xc4, 0xed, 0xfa, 0x25,
+	0xd0, 0xee, 0xf7, 0xce, 0xcf, 0x7b, 0x83, 0x13, 0x36, 0x1c, 0x8f, 0xce, 0xc6, 0x23, 0xa7, 0x42,
+	0x36, 0xa1, 0xd9, 0x1d, 0xbc, 0xee, 0xd1, 0xe1, 0xa0, 0xdf, 0x1d, 0x8c, 0x9c, 0x15, 0x33, 0x99,
+	0x8f, 0x87, 0xc3, 0xd1, 0xf9, 0x88, 0x1e, 0x9d, 0x39, 0xab, 0xa6, 0xd0, 0x4f, 0x5e, 0x0d, 0x8f,
+	0x9d, 0xaa, 0x61, 0x1f, 0xbf, 0x1a, 0x77, 0xcf, 0x68, 0x6f, 0x30, 0x62, 0xdf, 0xf7, 0x5e, 0x75,
+	0x9d, 0x35, 0xc3, 0x1e, 0x8e, 0x7e, 0xe8, 0x52, 0xd6, 0x1b, 0x18, 0xb9, 0xf5, 0xe3, 0x87, 0x7f,
+	0x2d, 0x3e, 0xd7, 0x8a, 0x93, 0x31, 0xfc, 0x3b, 0xf9, 0xff, 0x01, 0x00, 0x21, 0xe4, 0xd5, 0x5a,
+	0x5e, 0x0e, 0x00, 0x00,
 }
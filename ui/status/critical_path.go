@@ -20,12 +20,18 @@ import (
 	"android/soong/ui/logger"
 )
 
-func NewCriticalPath(log logger.Logger) StatusOutput {
+// NewCriticalPath returns a StatusOutput that computes the critical path of
+// the build. The critical path is logged to the verbose log when the output
+// is flushed, and if jsonReport or textReport are not empty a full
+// CriticalPathReport is written to them.
+func NewCriticalPath(log logger.Logger, jsonReport, textReport string) StatusOutput {
 	return &criticalPath{
-		log:     log,
-		running: make(map[*Action]time.Time),
-		nodes:   make(map[string]*node),
-		clock:   osClock{},
+		log:        log,
+		running:    make(map[*Action]time.Time),
+		nodes:      make(map[string]*node),
+		clock:      osClock{},
+		jsonReport: jsonReport,
+		textReport: textReport,
 	}
 }
 
@@ -37,7 +43,18 @@ type criticalPath struct {
 
 	start, end time.Time
 
+	// The number of running actions after every StartAction and
+	// FinishAction, used to report the parallelism over time.
+	parallelism []parallelismChange
+
 	clock clock
+
+	jsonReport, textReport string
+}
+
+type parallelismChange struct {
+	time    time.Time
+	running int
 }
 
 type clock interface {
@@ -55,6 +72,11 @@ type node struct {
 	cumulativeDuration time.Duration
 	duration           time.Duration
 	input              *node
+
+	start, end time.Time
+
+	// All of the nodes producing inputs of this node, including input.
+	inputs []*node
 }
 
 func (cp *criticalPath) StartAction(action *Action, counts Counts) {
@@ -63,6 +85,7 @@ func (cp *criticalPath) StartAction(action *Action, counts Counts) {
 		cp.start = start
 	}
 	cp.running[action] = start
+	cp.parallelism = append(cp.parallelism, parallelismChange{start, len(cp.running)})
 }
 
 func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
@@ -71,11 +94,17 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 
 		// Determine the input to this edge with the longest cumulative duration
 		var criticalPathInput *node
+		var inputs []*node
+		seenInputs := make(map[*node]bool)
 		for _, input := range result.Action.Inputs {
 			if x := cp.nodes[input]; x != nil {
 				if criticalPathInput == nil || x.cumulativeDuration > criticalPathInput.cumulativeDuration {
 					criticalPathInput = x
 				}
+				if !seenInputs[x] {
+					seenInputs[x] = true
+					inputs = append(inputs, x)
+				}
 			}
 		}
 
@@ -92,6 +121,9 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 			cumulativeDuration: cumulativeDuration,
 			duration:           duration,
 			input:              criticalPathInput,
+			start:              start,
+			end:                end,
+			inputs:             inputs,
 		}
 
 		for _, output := range result.Action.Outputs {
@@ -99,6 +131,7 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 		}
 
 		cp.end = end
+		cp.parallelism = append(cp.parallelism, parallelismChange{end, len(cp.running)})
 	}
 }
 
@@ -126,6 +159,20 @@ func (cp *criticalPath) Flush() {
 				seconds/60, seconds%60, criticalPath[i].action.Description)
 		}
 	}
+
+	if cp.jsonReport != "" || cp.textReport != "" {
+		report := cp.report()
+		if cp.jsonReport != "" {
+			if err := WriteCriticalPathReportJson(report, cp.jsonReport); err != nil {
+				cp.log.Printf("Failed to write critical path report %s: %v\n", cp.jsonReport, err)
+			}
+		}
+		if cp.textReport != "" {
+			if err := WriteCriticalPathReportText(report, cp.textReport); err != nil {
+				cp.log.Printf("Failed to write critical path report %s: %v\n", cp.textReport, err)
+			}
+		}
+	}
 }
 
 func (cp *criticalPath) Message(level MsgLevel, msg string) {}
@@ -0,0 +1,378 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// The maximum number of off-path actions listed in
+	// CriticalPathReport.NearCriticalPath.
+	nearCriticalPathCount = 20
+
+	// The number of samples in CriticalPathReport.Parallelism.
+	parallelismSampleCount = 100
+)
+
+// CriticalPathReport describes what limited the wall clock time of a build.
+// All times are in milliseconds relative to the start of the first action.
+type CriticalPathReport struct {
+	// Start is the time the first action started.
+	Start time.Time `json:"start"`
+
+	// ElapsedMs is the time between the start of the first action and the
+	// end of the last action.
+	ElapsedMs int64 `json:"elapsed_ms"`
+
+	// CriticalPathMs is the minimum time the build would have taken given
+	// perfect parallelism.
+	CriticalPathMs int64 `json:"critical_path_ms"`
+
+	// TotalActionMs is the sum of the durations of all actions.
+	TotalActionMs int64 `json:"total_action_ms"`
+
+	// Actions is the number of finished actions.
+	Actions int `json:"actions"`
+
+	// CriticalPath lists the actions on the critical path, from the first
+	// one to run to the last one.
+	CriticalPath []CriticalPathAction `json:"critical_path"`
+
+	// NearCriticalPath lists the actions that were not on the critical path
+	// with the least slack, starting with the closest one.
+	NearCriticalPath []CriticalPathAction `json:"near_critical_path"`
+
+	// Parallelism samples the number of running actions over the build.
+	Parallelism []ParallelismSample `json:"parallelism"`
+}
+
+// CriticalPathAction is a single action in a CriticalPathReport.
+type CriticalPathAction struct {
+	Description string   `json:"description"`
+	Outputs     []string `json:"outputs,omitempty"`
+
+	StartMs    int64 `json:"start_ms"`
+	EndMs      int64 `json:"end_ms"`
+	DurationMs int64 `json:"duration_ms"`
+
+	// SlackMs is how much longer the action could have taken before it
+	// would have made the critical path longer. It is always 0 for actions
+	// on the critical path.
+	SlackMs int64 `json:"slack_ms"`
+}
+
+// ParallelismSample is the number of running actions during a period of the
+// build.
+type ParallelismSample struct {
+	StartMs int64   `json:"start_ms"`
+	EndMs   int64   `json:"end_ms"`
+	Average float64 `json:"average"`
+	Max     int     `json:"max"`
+}
+
+// AverageParallelism returns the average number of running actions over the
+// whole build.
+func (r *CriticalPathReport) AverageParallelism() float64 {
+	if r.ElapsedMs == 0 {
+		return 0
+	}
+	return float64(r.TotalActionMs) / float64(r.ElapsedMs)
+}
+
+func (cp *criticalPath) report() *CriticalPathReport {
+	report := &CriticalPathReport{
+		Start:     cp.start,
+		ElapsedMs: durationMs(cp.end.Sub(cp.start)),
+	}
+
+	// The nodes map has an entry for every output, find the unique nodes.
+	var nodes []*node
+	seen := make(map[*node]bool)
+	for _, n := range cp.nodes {
+		if !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].start.Equal(nodes[j].start) {
+			return nodes[i].start.Before(nodes[j].start)
+		}
+		if !nodes[i].end.Equal(nodes[j].end) {
+			return nodes[i].end.Before(nodes[j].end)
+		}
+		return nodes[i].action.Description < nodes[j].action.Description
+	})
+
+	report.Actions = len(nodes)
+	for _, n := range nodes {
+		report.TotalActionMs += durationMs(n.duration)
+	}
+
+	criticalPath := cp.criticalPath()
+	if len(criticalPath) == 0 {
+		return report
+	}
+	criticalTime := criticalPath[0].cumulativeDuration
+	report.CriticalPathMs = durationMs(criticalTime)
+
+	onCriticalPath := make(map[*node]bool)
+	for i := len(criticalPath) - 1; i >= 0; i-- {
+		onCriticalPath[criticalPath[i]] = true
+		report.CriticalPath = append(report.CriticalPath, cp.reportAction(criticalPath[i], 0))
+	}
+
+	// The longest path through a node is the longest path to the end of the
+	// node plus the longest path from the end of the node through any of
+	// the nodes that depend on it.
+	dependents := make(map[*node][]*node)
+	for _, n := range nodes {
+		for _, input := range n.inputs {
+			dependents[input] = append(dependents[input], n)
+		}
+	}
+	tails := make(map[*node]time.Duration)
+	var tail func(n *node) time.Duration
+	tail = func(n *node) time.Duration {
+		if t, ok := tails[n]; ok {
+			return t
+		}
+		var t time.Duration
+		for _, d := range dependents[n] {
+			if x := d.duration + tail(d); x > t {
+				t = x
+			}
+		}
+		tails[n] = t
+		return t
+	}
+
+	type slackNode struct {
+		node  *node
+		slack time.Duration
+	}
+	var offPath []slackNode
+	for _, n := range nodes {
+		if !onCriticalPath[n] {
+			offPath = append(offPath, slackNode{n, criticalTime - n.cumulativeDuration - tail(n)})
+		}
+	}
+	sort.SliceStable(offPath, func(i, j int) bool { return offPath[i].slack < offPath[j].slack })
+	if len(offPath) > nearCriticalPathCount {
+		offPath = offPath[:nearCriticalPathCount]
+	}
+	for _, x := range offPath {
+		report.NearCriticalPath = append(report.NearCriticalPath, cp.reportAction(x.node, x.slack))
+	}
+
+	report.Parallelism = cp.parallelismSamples()
+
+	return report
+}
+
+func (cp *criticalPath) reportAction(n *node, slack time.Duration) CriticalPathAction {
+	return CriticalPathAction{
+		Description: n.action.Description,
+		Outputs:     n.action.Outputs,
+		StartMs:     durationMs(n.start.Sub(cp.start)),
+		EndMs:       durationMs(n.end.Sub(cp.start)),
+		DurationMs:  durationMs(n.duration),
+		SlackMs:     durationMs(slack),
+	}
+}
+
+// parallelismSamples splits the build into parallelismSampleCount periods of
+// equal length and computes the average and maximum number of running actions
+// in each of them.
+func (cp *criticalPath) parallelismSamples() []ParallelismSample {
+	elapsed := cp.end.Sub(cp.start)
+	if elapsed <= 0 {
+		return nil
+	}
+
+	count := parallelismSampleCount
+	width := elapsed / time.Duration(count)
+	if width == 0 {
+		count, width = 1, elapsed
+	}
+	bucketStart := func(i int) time.Duration { return time.Duration(i) * width }
+	bucketEnd := func(i int) time.Duration {
+		if i == count-1 {
+			return elapsed
+		}
+		return time.Duration(i+1) * width
+	}
+
+	busy := make([]time.Duration, count)
+	samples := make([]ParallelismSample, count)
+	for i, c := range cp.parallelism {
+		segStart := c.time.Sub(cp.start)
+		segEnd := elapsed
+		if i+1 < len(cp.parallelism) {
+			segEnd = cp.parallelism[i+1].time.Sub(cp.start)
+		}
+		if c.running == 0 || segEnd <= segStart {
+			continue
+		}
+
+		b := int(segStart / width)
+		if b >= count {
+			b = count - 1
+		}
+		for ; b < count && bucketStart(b) < segEnd; b++ {
+			start, end := segStart, segEnd
+			if s := bucketStart(b); s > start {
+				start = s
+			}
+			if e := bucketEnd(b); e < end {
+				end = e
+			}
+			if end <= start {
+				continue
+			}
+			busy[b] += (end - start) * time.Duration(c.running)
+			if c.running > samples[b].Max {
+				samples[b].Max = c.running
+			}
+		}
+	}
+
+	for i := range samples {
+		samples[i].StartMs = durationMs(bucketStart(i))
+		samples[i].EndMs = durationMs(bucketEnd(i))
+		samples[i].Average = float64(busy[i]) / float64(bucketEnd(i)-bucketStart(i))
+	}
+
+	return samples
+}
+
+// ReadCriticalPathReport reads a CriticalPathReport that was written by
+// WriteCriticalPathReportJson.
+func ReadCriticalPathReport(filename string) (*CriticalPathReport, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CriticalPathReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return report, nil
+}
+
+// WriteCriticalPathReportJson writes report to filename as JSON.
+func WriteCriticalPathReportJson(report *CriticalPathReport, filename string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0666)
+}
+
+// WriteCriticalPathReportText writes report to filename in a human readable
+// format.
+func WriteCriticalPathReportText(report *CriticalPathReport, filename string) error {
+	buf := &bytes.Buffer{}
+	if err := report.WriteText(buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0666)
+}
+
+// WriteText writes the report to w in a human readable format.
+func (r *CriticalPathReport) WriteText(w io.Writer) error {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "Elapsed time:        %s\n", formatMs(r.ElapsedMs))
+	fmt.Fprintf(buf, "Critical path:       %s", formatMs(r.CriticalPathMs))
+	if r.ElapsedMs > 0 {
+		fmt.Fprintf(buf, " (%d%% of elapsed time)", r.CriticalPathMs*100/r.ElapsedMs)
+	}
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "Total action time:   %s\n", formatMs(r.TotalActionMs))
+	fmt.Fprintf(buf, "Average parallelism: %.1f\n", r.AverageParallelism())
+	fmt.Fprintf(buf, "Actions:             %d\n", r.Actions)
+
+	if len(r.CriticalPath) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(buf, "Critical path:")
+		fmt.Fprintf(buf, "  %10s %10s %10s  %s\n", "start", "end", "duration", "action")
+		for _, a := range r.CriticalPath {
+			fmt.Fprintf(buf, "  %10s %10s %10s  %s\n",
+				formatMs(a.StartMs), formatMs(a.EndMs), formatMs(a.DurationMs), a.Description)
+		}
+	}
+
+	if len(r.NearCriticalPath) > 0 {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(buf, "Actions closest to the critical path:")
+		fmt.Fprintf(buf, "  %10s %10s %10s %10s  %s\n", "slack", "start", "end", "duration", "action")
+		for _, a := range r.NearCriticalPath {
+			fmt.Fprintf(buf, "  %10s %10s %10s %10s  %s\n",
+				formatMs(a.SlackMs), formatMs(a.StartMs), formatMs(a.EndMs), formatMs(a.DurationMs), a.Description)
+		}
+	}
+
+	if len(r.Parallelism) > 0 {
+		const barWidth = 40
+		maxAverage := 0.0
+		for _, s := range r.Parallelism {
+			if s.Average > maxAverage {
+				maxAverage = s.Average
+			}
+		}
+
+		fmt.Fprintln(buf)
+		fmt.Fprintln(buf, "Parallelism over time:")
+		fmt.Fprintf(buf, "  %10s %10s %8s %5s\n", "start", "end", "average", "max")
+		for _, s := range r.Parallelism {
+			bar := 0
+			if maxAverage > 0 {
+				bar = int(s.Average / maxAverage * barWidth)
+			}
+			fmt.Fprintf(buf, "  %10s %10s %8.1f %5d  %s\n",
+				formatMs(s.StartMs), formatMs(s.EndMs), s.Average, s.Max, strings.Repeat("#", bar))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func durationMs(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// formatMs formats a number of milliseconds as [h:]mm:ss.sss.
+func formatMs(ms int64) string {
+	sign := ""
+	if ms < 0 {
+		sign, ms = "-", -ms
+	}
+	seconds := ms / 1000
+	if seconds >= 3600 {
+		return fmt.Sprintf("%s%d:%02d:%02d.%03d", sign, seconds/3600, seconds/60%60, seconds%60, ms%1000)
+	}
+	return fmt.Sprintf("%s%d:%02d.%03d", sign, seconds/60, seconds%60, ms%1000)
+}
@@ -137,7 +137,7 @@ func TestCriticalPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cp := &testCriticalPath{
-				criticalPath: NewCriticalPath(nil).(*criticalPath),
+				criticalPath: NewCriticalPath(nil, "", "").(*criticalPath),
 				actions:      make(map[int]*Action),
 			}
 
@@ -164,3 +164,77 @@ func TestCriticalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestCriticalPathReport(t *testing.T) {
+	//  a
+	//  |\
+	//  b c e
+	//  |/
+	//  d
+	cp := &testCriticalPath{
+		criticalPath: NewCriticalPath(nil, "", "").(*criticalPath),
+		actions:      make(map[int]*Action),
+	}
+	ms := time.Millisecond
+	cp.start(0, 0, []string{"a"}, nil)
+	cp.start(4, 0, []string{"e"}, nil)
+	cp.finish(0, 1000*ms)
+	cp.start(1, 1000*ms, []string{"b"}, []string{"a"})
+	cp.start(2, 1000*ms, []string{"c"}, []string{"a"})
+	cp.finish(1, 2000*ms)
+	cp.finish(4, 2500*ms)
+	cp.finish(2, 3000*ms)
+	cp.start(3, 3000*ms, []string{"d"}, []string{"b", "c"})
+	cp.finish(3, 4000*ms)
+
+	report := cp.report()
+
+	if report.ElapsedMs != 4000 {
+		t.Errorf("ElapsedMs = %d, want 4000", report.ElapsedMs)
+	}
+	if report.CriticalPathMs != 4000 {
+		t.Errorf("CriticalPathMs = %d, want 4000", report.CriticalPathMs)
+	}
+	if report.TotalActionMs != 7500 {
+		t.Errorf("TotalActionMs = %d, want 7500", report.TotalActionMs)
+	}
+	if report.Actions != 5 {
+		t.Errorf("Actions = %d, want 5", report.Actions)
+	}
+
+	wantCriticalPath := []CriticalPathAction{
+		{Description: "a", Outputs: []string{"a"}, StartMs: 0, EndMs: 1000, DurationMs: 1000},
+		{Description: "c", Outputs: []string{"c"}, StartMs: 1000, EndMs: 3000, DurationMs: 2000},
+		{Description: "d", Outputs: []string{"d"}, StartMs: 3000, EndMs: 4000, DurationMs: 1000},
+	}
+	if !reflect.DeepEqual(report.CriticalPath, wantCriticalPath) {
+		t.Errorf("CriticalPath = %#v, want %#v", report.CriticalPath, wantCriticalPath)
+	}
+
+	wantNearCriticalPath := []CriticalPathAction{
+		{Description: "b", Outputs: []string{"b"}, StartMs: 1000, EndMs: 2000, DurationMs: 1000, SlackMs: 1000},
+		{Description: "e", Outputs: []string{"e"}, StartMs: 0, EndMs: 2500, DurationMs: 2500, SlackMs: 1500},
+	}
+	if !reflect.DeepEqual(report.NearCriticalPath, wantNearCriticalPath) {
+		t.Errorf("NearCriticalPath = %#v, want %#v", report.NearCriticalPath, wantNearCriticalPath)
+	}
+
+	if len(report.Parallelism) != parallelismSampleCount {
+		t.Fatalf("len(Parallelism) = %d, want %d", len(report.Parallelism), parallelismSampleCount)
+	}
+	for _, tt := range []struct {
+		sample int
+		want   ParallelismSample
+	}{
+		{0, ParallelismSample{StartMs: 0, EndMs: 40, Average: 2, Max: 2}},
+		{30, ParallelismSample{StartMs: 1200, EndMs: 1240, Average: 3, Max: 3}},
+		{99, ParallelismSample{StartMs: 3960, EndMs: 4000, Average: 1, Max: 1}},
+	} {
+		if got := report.Parallelism[tt.sample]; got != tt.want {
+			t.Errorf("Parallelism[%d] = %#v, want %#v", tt.sample, got, tt.want)
+		}
+	}
+	if got := report.AverageParallelism(); got != 1.875 {
+		t.Errorf("AverageParallelism() = %v, want 1.875", got)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"android/soong/ui/logger"
+)
+
+// jsonEvent is a single line of the JSON event log. Exactly one of Action,
+// Result, Message or Counts is set, depending on Type.
+type jsonEvent struct {
+	// Type is one of "start", "finish", "message" or "counts".
+	Type string `json:"type"`
+
+	// Time is the time of the event in nanoseconds since the Unix epoch.
+	Time int64 `json:"time"`
+
+	Action  *jsonAction       `json:"action,omitempty"`
+	Result  *jsonActionResult `json:"result,omitempty"`
+	Message *jsonMessage      `json:"message,omitempty"`
+	Counts  *jsonCounts       `json:"counts,omitempty"`
+}
+
+type jsonAction struct {
+	// Id is unique for each action in a log, and matches the "start" event
+	// with the "finish" event of the same action.
+	Id          int      `json:"id"`
+	Description string   `json:"description,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+	Inputs      []string `json:"inputs,omitempty"`
+}
+
+type jsonActionResult struct {
+	jsonAction
+
+	Output string          `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Stats  jsonActionStats `json:"stats"`
+}
+
+type jsonActionStats struct {
+	UserTimeMs                 uint32 `json:"user_time_ms"`
+	SystemTimeMs               uint32 `json:"system_time_ms"`
+	MaxRssKB                   uint64 `json:"max_rss_kb"`
+	MinorPageFaults            uint64 `json:"minor_page_faults"`
+	MajorPageFaults            uint64 `json:"major_page_faults"`
+	IOInputKB                  uint64 `json:"io_input_kb"`
+	IOOutputKB                 uint64 `json:"io_output_kb"`
+	VoluntaryContextSwitches   uint64 `json:"voluntary_context_switches"`
+	InvoluntaryContextSwitches uint64 `json:"involuntary_context_switches"`
+}
+
+type jsonMessage struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+type jsonCounts struct {
+	TotalActions    int `json:"total_actions"`
+	RunningActions  int `json:"running_actions"`
+	StartedActions  int `json:"started_actions"`
+	FinishedActions int `json:"finished_actions"`
+}
+
+type jsonEventLog struct {
+	log logger.Logger
+
+	w   io.WriteCloser
+	buf *bufio.Writer
+	enc *json.Encoder
+
+	clock clock
+
+	ids    map[*Action]int
+	nextId int
+
+	counts    Counts
+	hasCounts bool
+
+	// Set once a write fails (usually because the reader of a FIFO went
+	// away), after which all events are dropped.
+	broken bool
+}
+
+// NewJsonEventLog returns a StatusOutput that writes every action start,
+// action finish, message and counts change as a single line of JSON to
+// filename. filename may be a regular file, which will be truncated, or a
+// FIFO that an external tool is reading from. A FIFO without a reader is
+// skipped instead of blocking the build.
+func NewJsonEventLog(log logger.Logger, filename string) StatusOutput {
+	w, err := openEventLogFile(filename)
+	if err != nil {
+		log.Println("Failed to create JSON event log:", err)
+		return nil
+	}
+
+	return newJsonEventLog(log, w, osClock{})
+}
+
+func newJsonEventLog(log logger.Logger, w io.WriteCloser, clock clock) *jsonEventLog {
+	buf := bufio.NewWriter(w)
+	return &jsonEventLog{
+		log:   log,
+		w:     w,
+		buf:   buf,
+		enc:   json.NewEncoder(buf),
+		clock: clock,
+		ids:   make(map[*Action]int),
+	}
+}
+
+func openEventLogFile(filename string) (*os.File, error) {
+	if fi, err := os.Stat(filename); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+		// Opening a FIFO for writing blocks until there is a reader, so
+		// open it non-blocking and fail with ENXIO if nobody is listening.
+		f, err := os.OpenFile(filename, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if errors.Is(err, syscall.ENXIO) {
+			return nil, errors.New(filename + " is a FIFO without a reader")
+		}
+		return f, err
+	}
+
+	return os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (j *jsonEventLog) StartAction(action *Action, counts Counts) {
+	id := j.nextId
+	j.nextId++
+	j.ids[action] = id
+
+	j.write(&jsonEvent{
+		Type:   "start",
+		Action: toJsonAction(id, action),
+	})
+	j.updateCounts(counts)
+}
+
+func (j *jsonEventLog) FinishAction(result ActionResult, counts Counts) {
+	id, ok := j.ids[result.Action]
+	if ok {
+		delete(j.ids, result.Action)
+	} else {
+		id = j.nextId
+		j.nextId++
+	}
+
+	event := &jsonActionResult{
+		Output: result.Output,
+		Stats: jsonActionStats{
+			UserTimeMs:                 result.Stats.UserTime,
+			SystemTimeMs:               result.Stats.SystemTime,
+			MaxRssKB:                   result.Stats.MaxRssKB,
+			MinorPageFaults:            result.Stats.MinorPageFaults,
+			MajorPageFaults:            result.Stats.MajorPageFaults,
+			IOInputKB:                  result.Stats.IOInputKB,
+			IOOutputKB:                 result.Stats.IOOutputKB,
+			VoluntaryContextSwitches:   result.Stats.VoluntaryContextSwitches,
+			InvoluntaryContextSwitches: result.Stats.InvoluntaryContextSwitches,
+		},
+	}
+	if result.Action != nil {
+		event.jsonAction = *toJsonAction(id, result.Action)
+	} else {
+		event.jsonAction.Id = id
+	}
+	if result.Error != nil {
+		event.Error = result.Error.Error()
+	}
+
+	j.write(&jsonEvent{
+		Type:   "finish",
+		Result: event,
+	})
+	j.updateCounts(counts)
+}
+
+func (j *jsonEventLog) Message(level MsgLevel, message string) {
+	j.write(&jsonEvent{
+		Type: "message",
+		Message: &jsonMessage{
+			Level: level.String(),
+			Text:  message,
+		},
+	})
+}
+
+func (j *jsonEventLog) Flush() {
+	if !j.broken {
+		j.buf.Flush()
+	}
+	j.w.Close()
+}
+
+func (j *jsonEventLog) Write(p []byte) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+// updateCounts writes a "counts" event if counts differs from the last one
+// that was written.
+func (j *jsonEventLog) updateCounts(counts Counts) {
+	if j.hasCounts && j.counts == counts {
+		return
+	}
+	j.counts = counts
+	j.hasCounts = true
+
+	j.write(&jsonEvent{
+		Type: "counts",
+		Counts: &jsonCounts{
+			TotalActions:    counts.TotalActions,
+			RunningActions:  counts.RunningActions,
+			StartedActions:  counts.StartedActions,
+			FinishedActions: counts.FinishedActions,
+		},
+	})
+}
+
+// write encodes a single event and flushes it so that readers following the
+// log see it immediately.
+func (j *jsonEventLog) write(event *jsonEvent) {
+	if j.broken {
+		return
+	}
+
+	event.Time = j.clock.Now().UnixNano()
+
+	err := j.enc.Encode(event)
+	if err == nil {
+		err = j.buf.Flush()
+	}
+	if err != nil {
+		j.log.Println("Failed to write JSON event log, disabling it:", err)
+		j.broken = true
+	}
+}
+
+func toJsonAction(id int, action *Action) *jsonAction {
+	return &jsonAction{
+		Id:          id,
+		Description: action.Description,
+		Command:     action.Command,
+		Outputs:     action.Outputs,
+		Inputs:      action.Inputs,
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestJsonEventLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := newJsonEventLog(logger.New(ioutil.Discard), nopCloser{buf}, testClock(time.Unix(0, 1000)))
+
+	status := &Status{}
+	status.AddOutput(log)
+	s := status.StartTool()
+	s.SetTotalActions(2)
+
+	a := &Action{Description: "a", Command: "touch a", Outputs: []string{"a"}}
+	b := &Action{Description: "b", Outputs: []string{"b"}, Inputs: []string{"a"}}
+
+	s.StartAction(a)
+	s.Print("hello")
+	s.FinishAction(ActionResult{
+		Action: a,
+		Stats: ActionResultStats{
+			UserTime:                   1,
+			SystemTime:                 2,
+			MaxRssKB:                   3,
+			MinorPageFaults:            4,
+			MajorPageFaults:            5,
+			IOInputKB:                  6,
+			IOOutputKB:                 7,
+			VoluntaryContextSwitches:   8,
+			InvoluntaryContextSwitches: 9,
+		},
+	})
+	s.StartAction(b)
+	s.FinishAction(ActionResult{Action: b, Output: "oops", Error: errors.New("failed")})
+	s.Finish()
+	status.Finish()
+
+	want := []string{
+		`{"type":"start","time":1000,"action":{"id":0,"description":"a","command":"touch a","outputs":["a"]}}`,
+		`{"type":"counts","time":1000,"counts":{"total_actions":2,"running_actions":1,"started_actions":1,"finished_actions":0}}`,
+		`{"type":"message","time":1000,"message":{"level":"print","text":"hello"}}`,
+		`{"type":"finish","time":1000,"result":{"id":0,"description":"a","command":"touch a","outputs":["a"],"stats":{"user_time_ms":1,"system_time_ms":2,"max_rss_kb":3,"minor_page_faults":4,"major_page_faults":5,"io_input_kb":6,"io_output_kb":7,"voluntary_context_switches":8,"involuntary_context_switches":9}}}`,
+		`{"type":"counts","time":1000,"counts":{"total_actions":2,"running_actions":0,"started_actions":1,"finished_actions":1}}`,
+		`{"type":"start","time":1000,"action":{"id":1,"description":"b","outputs":["b"],"inputs":["a"]}}`,
+		`{"type":"counts","time":1000,"counts":{"total_actions":2,"running_actions":1,"started_actions":2,"finished_actions":1}}`,
+		`{"type":"finish","time":1000,"result":{"id":1,"description":"b","outputs":["b"],"inputs":["a"],"output":"oops","error":"failed","stats":{"user_time_ms":0,"system_time_ms":0,"max_rss_kb":0,"minor_page_faults":0,"major_page_faults":0,"io_input_kb":0,"io_output_kb":0,"voluntary_context_switches":0,"involuntary_context_switches":0}}}`,
+		`{"type":"counts","time":1000,"counts":{"total_actions":2,"running_actions":0,"started_actions":2,"finished_actions":2}}`,
+	}
+
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d:\n%s", len(want), len(got), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d:\nwant: %s\n got: %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestJsonEventLogFifoWithoutReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "json_event_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fifo := filepath.Join(dir, "events")
+	if err := syscall.Mkfifo(fifo, 0666); err != nil {
+		t.Skip("unable to create FIFO:", err)
+	}
+
+	if log := NewJsonEventLog(logger.New(ioutil.Discard), fifo); log != nil {
+		t.Errorf("expected no output for a FIFO without a reader, got %#v", log)
+	}
+}
@@ -183,6 +183,21 @@ func (l MsgLevel) Prefix() string {
 	}
 }
 
+func (l MsgLevel) String() string {
+	switch l {
+	case VerboseLvl:
+		return "verbose"
+	case StatusLvl:
+		return "status"
+	case PrintLvl:
+		return "print"
+	case ErrorLvl:
+		return "error"
+	default:
+		panic("Unknown message level")
+	}
+}
+
 // StatusOutput is the interface used to get status information as a Status
 // output.
 //
@@ -51,6 +51,21 @@ func (u *uniqueSet) Set(s string) error {
 	return nil
 }
 
+type compressionRules []zip.CompressionRule
+
+func (c *compressionRules) String() string {
+	return `""`
+}
+
+func (c *compressionRules) Set(s string) error {
+	rule, err := zip.ParseCompressionRule(s)
+	if err != nil {
+		return err
+	}
+	*c = append(*c, rule)
+	return nil
+}
+
 type file struct{}
 
 func (file) String() string { return `""` }
@@ -60,6 +75,15 @@ func (file) Set(s string) error {
 	return nil
 }
 
+type explicitPathInZip struct{}
+
+func (explicitPathInZip) String() string { return `""` }
+
+func (explicitPathInZip) Set(s string) error {
+	fileArgsBuilder.ExplicitPathInZip(s)
+	return nil
+}
+
 type listFiles struct{}
 
 func (listFiles) String() string { return `""` }
@@ -119,6 +143,7 @@ func (rootPrefix) Set(s string) error {
 var (
 	fileArgsBuilder  = zip.NewFileArgsBuilder()
 	nonDeflatedFiles = make(uniqueSet)
+	compression      compressionRules
 )
 
 func main() {
@@ -159,6 +184,7 @@ func main() {
 	ignoreMissingFiles := flags.Bool("ignore_missing_files", false, "continue if a requested file does not exist")
 	symlinks := flags.Bool("symlinks", true, "store symbolic links in zip instead of following them")
 	srcJar := flags.Bool("srcjar", false, "move .java files to locations that match their package statement")
+	storeCompressed := flags.Bool("store_compressed", false, "store files with extensions of already compressed formats without compression")
 
 	parallelJobs := flags.Int("parallel", runtime.NumCPU(), "number of parallel threads to use")
 	cpuProfile := flags.String("cpuprofile", "", "write cpu profile to file")
@@ -169,7 +195,10 @@ func main() {
 	flags.Var(&rspFiles{}, "r", "file containing list of files to zip with Ninja rsp file escaping")
 	flags.Var(&dir{}, "D", "directory to include in zip")
 	flags.Var(&file{}, "f", "file to include in zip")
+	flags.Var(&explicitPathInZip{}, "e", "path in the zip of the file in the following -f argument, instead of its path relative to -C")
 	flags.Var(&nonDeflatedFiles, "s", "file path to be stored within the zip without compression")
+	flags.Var(&compression, "compression", "compression of the files matching a glob, as <glob>=store or <glob>=deflate[:<level>], "+
+		"the first matching rule applies")
 	flags.Var(&relativeRoot{}, "C", "path to use as relative root of files in following -f, -l, or -D arguments")
 	flags.Var(&junkPaths{}, "j", "junk paths, zip files without directory names")
 
@@ -211,6 +240,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *storeCompressed {
+		compression = append(compression, zip.StoreAlreadyCompressedRules()...)
+	}
+
 	err := zip.Zip(zip.ZipArgs{
 		FileArgs:                 fileArgsBuilder.FileArgs(),
 		OutputFilePath:           *out,
@@ -221,6 +254,7 @@ func main() {
 		ManifestSourcePath:       *manifest,
 		NumParallelJobs:          *parallelJobs,
 		NonDeflatedFiles:         nonDeflatedFiles,
+		CompressionRules:         compression,
 		WriteIfChanged:           *writeIfChanged,
 		StoreSymlinks:            *symlinks,
 		IgnoreMissingFiles:       *ignoreMissingFiles,
@@ -0,0 +1,123 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint/pathtools"
+
+	"android/soong/third_party/zip"
+)
+
+// CompressionRule selects the compression of the entries whose path in the zip matches Pattern.
+type CompressionRule struct {
+	// Pattern is a glob that may contain **. A pattern without a / is matched against the base
+	// name of the entry, so that "*.png" matches png files in any directory.
+	Pattern string
+
+	// Method is zip.Store or zip.Deflate.
+	Method uint16
+
+	// Level is the compression level, or -1 for the default level of the method.
+	Level int
+}
+
+// AlreadyCompressedExtensions are the extensions of file formats that are already compressed, and
+// don't shrink further when compressed again in the zip.
+var AlreadyCompressedExtensions = []string{
+	".apex", ".apk", ".br", ".bz2", ".flac", ".gif", ".gz", ".jar", ".jpeg", ".jpg", ".lz4",
+	".mp3", ".mp4", ".ogg", ".png", ".webm", ".webp", ".xz", ".zip", ".zst",
+}
+
+// StoreAlreadyCompressedRules returns rules that store the files in AlreadyCompressedExtensions
+// without compression.
+func StoreAlreadyCompressedRules() []CompressionRule {
+	var rules []CompressionRule
+	for _, ext := range AlreadyCompressedExtensions {
+		rules = append(rules, CompressionRule{Pattern: "*" + ext, Method: zip.Store, Level: -1})
+	}
+	return rules
+}
+
+// ParseCompressionRule parses a rule of the form <pattern>=<method>[:<level>], where the method is
+// store or deflate, e.g. "*.png=store" or "res/**/*=deflate:9".
+func ParseCompressionRule(s string) (CompressionRule, error) {
+	pattern, policy := s, ""
+	if i := strings.LastIndex(s, "="); i >= 0 {
+		pattern, policy = s[:i], s[i+1:]
+	}
+	if pattern == "" || policy == "" {
+		return CompressionRule{}, fmt.Errorf("compression rule %q must be of the form <pattern>=<method>[:<level>]", s)
+	}
+
+	rule := CompressionRule{Pattern: pattern, Level: -1}
+	method, level := policy, ""
+	if i := strings.Index(policy, ":"); i >= 0 {
+		method, level = policy[:i], policy[i+1:]
+	}
+
+	maxLevel := 0
+	switch method {
+	case "store":
+		rule.Method = zip.Store
+	case "deflate":
+		rule.Method = zip.Deflate
+		maxLevel = 9
+	case "zstd":
+		// TODO: write zstd entries once a zstd encoder is available to soong_zip under external/,
+		// third_party/zip, merge_zips and zip2zip already pass them through.
+		return CompressionRule{}, fmt.Errorf("compression rule %q uses zstd, which soong_zip can't write yet", s)
+	default:
+		return CompressionRule{}, fmt.Errorf("compression rule %q has unknown method %q, expected store or deflate", s, method)
+	}
+
+	if level != "" {
+		l, err := strconv.Atoi(level)
+		if err != nil || l < 1 || l > maxLevel {
+			return CompressionRule{}, fmt.Errorf("compression rule %q has invalid level %q for %s", s, level, method)
+		}
+		rule.Level = l
+	}
+
+	return rule, nil
+}
+
+// matches returns whether the rule applies to the entry at the given path in the zip.
+func (r CompressionRule) matches(dest string) (bool, error) {
+	if !strings.Contains(r.Pattern, "/") {
+		dest = path.Base(dest)
+	}
+	return pathtools.Match(r.Pattern, dest)
+}
+
+// compressionFor returns the method and level of the first rule that matches the entry at the given
+// path in the zip, or Deflate at the default level if none match.
+func compressionFor(rules []CompressionRule, dest string, defaultLevel int) (uint16, int, error) {
+	for _, rule := range rules {
+		if match, err := rule.matches(dest); err != nil {
+			return 0, 0, fmt.Errorf("%s: %s", err.Error(), rule.Pattern)
+		} else if match {
+			if rule.Method == zip.Deflate && rule.Level == -1 {
+				return rule.Method, defaultLevel, nil
+			}
+			return rule.Method, rule.Level, nil
+		}
+	}
+	return zip.Deflate, defaultLevel, nil
+}
@@ -65,10 +65,12 @@ type byteReaderCloser struct {
 type pathMapping struct {
 	dest, src string
 	zipMethod uint16
+	compLevel int
 }
 
 type FileArg struct {
 	PathPrefixInZip, SourcePrefixToStrip string
+	ExplicitPathInZip                    string
 	SourceFiles                          []string
 	JunkPaths                            bool
 	GlobDir                              string
@@ -105,6 +107,13 @@ func (b *FileArgsBuilder) PathPrefixInZip(rootPrefix string) *FileArgsBuilder {
 	return b
 }
 
+// ExplicitPathInZip sets the path in the zip of the file added by the next call to File, relative
+// to the path prefix in the zip.
+func (b *FileArgsBuilder) ExplicitPathInZip(s string) *FileArgsBuilder {
+	b.state.ExplicitPathInZip = s
+	return b
+}
+
 func (b *FileArgsBuilder) File(name string) *FileArgsBuilder {
 	if b.err != nil {
 		return b
@@ -113,13 +122,27 @@ func (b *FileArgsBuilder) File(name string) *FileArgsBuilder {
 	arg := b.state
 	arg.SourceFiles = []string{name}
 	b.fileArgs = append(b.fileArgs, arg)
+	b.state.ExplicitPathInZip = ""
 	return b
 }
 
+// checkNoExplicitPathInZip reports an error if an explicit path in the zip was set for arguments
+// other than a single file.
+func (b *FileArgsBuilder) checkNoExplicitPathInZip(name string) {
+	if b.state.ExplicitPathInZip != "" {
+		b.err = fmt.Errorf("explicit path in zip %q must be followed by a file, got %q",
+			b.state.ExplicitPathInZip, name)
+	}
+}
+
 func (b *FileArgsBuilder) Dir(name string) *FileArgsBuilder {
 	if b.err != nil {
 		return b
 	}
+	b.checkNoExplicitPathInZip(name)
+	if b.err != nil {
+		return b
+	}
 
 	arg := b.state
 	arg.GlobDir = name
@@ -132,6 +155,10 @@ func (b *FileArgsBuilder) List(name string) *FileArgsBuilder {
 	if b.err != nil {
 		return b
 	}
+	b.checkNoExplicitPathInZip(name)
+	if b.err != nil {
+		return b
+	}
 
 	f, err := b.fs.Open(name)
 	if err != nil {
@@ -157,6 +184,10 @@ func (b *FileArgsBuilder) RspFile(name string) *FileArgsBuilder {
 	if b.err != nil {
 		return b
 	}
+	b.checkNoExplicitPathInZip(name)
+	if b.err != nil {
+		return b
+	}
 
 	f, err := b.fs.Open(name)
 	if err != nil {
@@ -213,8 +244,9 @@ type ZipWriter struct {
 	cpuRateLimiter    *CPURateLimiter
 	memoryRateLimiter *MemoryRateLimiter
 
-	compressorPool sync.Pool
-	compLevel      int
+	compressorPools     map[int]*sync.Pool
+	compressorPoolsLock sync.Mutex
+	compLevel           int
 
 	followSymlinks     pathtools.ShouldFollowSymlinks
 	ignoreMissingFiles bool
@@ -226,6 +258,9 @@ type ZipWriter struct {
 type zipEntry struct {
 	fh *zip.FileHeader
 
+	// Compression level for the Deflate method
+	compLevel int
+
 	// List of delayed io.Reader
 	futureReaders chan chan io.Reader
 
@@ -244,6 +279,7 @@ type ZipArgs struct {
 	ManifestSourcePath       string
 	NumParallelJobs          int
 	NonDeflatedFiles         map[string]bool
+	CompressionRules         []CompressionRule
 	WriteIfChanged           bool
 	StoreSymlinks            bool
 	IgnoreMissingFiles       bool
@@ -265,6 +301,7 @@ func zipTo(args ZipArgs, w io.Writer) error {
 		createdDirs:        make(map[string]string),
 		createdFiles:       make(map[string]string),
 		directories:        args.AddDirectoryEntriesToZip,
+		compressorPools:    make(map[int]*sync.Pool),
 		compLevel:          args.CompressionLevel,
 		followSymlinks:     followSymlinks,
 		ignoreMissingFiles: args.IgnoreMissingFiles,
@@ -343,7 +380,8 @@ func zipTo(args ZipArgs, w io.Writer) error {
 			srcs = append(srcs, result.Matches...)
 		}
 		for _, src := range srcs {
-			err := fillPathPairs(fa, src, &pathMappings, args.NonDeflatedFiles, noCompression)
+			err := fillPathPairs(fa, src, &pathMappings, args.NonDeflatedFiles, args.CompressionRules,
+				args.CompressionLevel, noCompression)
 			if err != nil {
 				return err
 			}
@@ -396,11 +434,14 @@ func Zip(args ZipArgs) error {
 }
 
 func fillPathPairs(fa FileArg, src string, pathMappings *[]pathMapping,
-	nonDeflatedFiles map[string]bool, noCompression bool) error {
+	nonDeflatedFiles map[string]bool, compressionRules []CompressionRule, compLevel int,
+	noCompression bool) error {
 
 	var dest string
 
-	if fa.JunkPaths {
+	if fa.ExplicitPathInZip != "" {
+		dest = fa.ExplicitPathInZip
+	} else if fa.JunkPaths {
 		dest = filepath.Base(src)
 	} else {
 		var err error
@@ -420,9 +461,15 @@ func fillPathPairs(fa FileArg, src string, pathMappings *[]pathMapping,
 	zipMethod := zip.Deflate
 	if _, found := nonDeflatedFiles[dest]; found || noCompression {
 		zipMethod = zip.Store
+	} else {
+		var err error
+		zipMethod, compLevel, err = compressionFor(compressionRules, dest, compLevel)
+		if err != nil {
+			return err
+		}
 	}
 	*pathMappings = append(*pathMappings,
-		pathMapping{dest: dest, src: src, zipMethod: zipMethod})
+		pathMapping{dest: dest, src: src, zipMethod: zipMethod, compLevel: compLevel})
 
 	return nil
 }
@@ -465,7 +512,7 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 
 	if emulateJar {
 		// manifest may be empty, in which case addManifest will fill in a default
-		pathMappings = append(pathMappings, pathMapping{jar.ManifestFile, manifest, zip.Deflate})
+		pathMappings = append(pathMappings, pathMapping{jar.ManifestFile, manifest, zip.Deflate, z.compLevel})
 
 		jarSort(pathMappings)
 	}
@@ -478,7 +525,7 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 			if emulateJar && ele.dest == jar.ManifestFile {
 				err = z.addManifest(ele.dest, ele.src, ele.zipMethod)
 			} else {
-				err = z.addFile(ele.dest, ele.src, ele.zipMethod, emulateJar, srcJar)
+				err = z.addFile(ele.dest, ele.src, ele.zipMethod, ele.compLevel, emulateJar, srcJar)
 			}
 			if err != nil {
 				z.errors <- err
@@ -577,7 +624,7 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 }
 
 // imports (possibly with compression) <src> into the zip at sub-path <dest>
-func (z *ZipWriter) addFile(dest, src string, method uint16, emulateJar, srcJar bool) error {
+func (z *ZipWriter) addFile(dest, src string, method uint16, compLevel int, emulateJar, srcJar bool) error {
 	var fileSize int64
 	var executable bool
 
@@ -667,7 +714,7 @@ func (z *ZipWriter) addFile(dest, src string, method uint16, emulateJar, srcJar
 			return err
 		}
 
-		return z.writeFileContents(header, r)
+		return z.writeFileContents(header, compLevel, r)
 	} else {
 		return fmt.Errorf("%s is not a file, directory, or symlink", src)
 	}
@@ -706,10 +753,11 @@ func (z *ZipWriter) addManifest(dest string, src string, _ uint16) error {
 
 	reader := &byteReaderCloser{bytes.NewReader(buf), ioutil.NopCloser(nil)}
 
-	return z.writeFileContents(fh, reader)
+	return z.writeFileContents(fh, z.compLevel, reader)
 }
 
-func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.ReaderAtSeekerCloser) (err error) {
+func (z *ZipWriter) writeFileContents(header *zip.FileHeader, compLevel int,
+	r pathtools.ReaderAtSeekerCloser) (err error) {
 
 	header.SetModTime(z.time)
 
@@ -719,7 +767,8 @@ func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.Reader
 	// Pre-fill a zipEntry, it will be sent in the compressChan once
 	// we're sure about the Method and CRC.
 	ze := &zipEntry{
-		fh: header,
+		fh:        header,
+		compLevel: compLevel,
 	}
 
 	ze.allocatedSize = int64(header.UncompressedSize64)
@@ -765,7 +814,7 @@ func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.Reader
 			}
 
 			wg.Add(1)
-			go z.compressPartialFile(sr, dict, last, resultChan, wg)
+			go z.compressPartialFile(sr, dict, compLevel, last, resultChan, wg)
 		}
 
 		close(ze.futureReaders)
@@ -801,10 +850,11 @@ func (z *ZipWriter) crcFile(r io.Reader, ze *zipEntry, resultChan chan *zipEntry
 	close(resultChan)
 }
 
-func (z *ZipWriter) compressPartialFile(r io.Reader, dict []byte, last bool, resultChan chan io.Reader, wg *sync.WaitGroup) {
+func (z *ZipWriter) compressPartialFile(r io.Reader, dict []byte, compLevel int, last bool,
+	resultChan chan io.Reader, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	result, err := z.compressBlock(r, dict, last)
+	result, err := z.compressBlock(r, dict, compLevel, last)
 	if err != nil {
 		z.errors <- err
 		return
@@ -815,22 +865,36 @@ func (z *ZipWriter) compressPartialFile(r io.Reader, dict []byte, last bool, res
 	resultChan <- result
 }
 
-func (z *ZipWriter) compressBlock(r io.Reader, dict []byte, last bool) (*bytes.Buffer, error) {
+// compressorPool returns the pool of flate.Writers for the given compression level.
+func (z *ZipWriter) compressorPool(compLevel int) *sync.Pool {
+	z.compressorPoolsLock.Lock()
+	defer z.compressorPoolsLock.Unlock()
+
+	pool, ok := z.compressorPools[compLevel]
+	if !ok {
+		pool = &sync.Pool{}
+		z.compressorPools[compLevel] = pool
+	}
+	return pool
+}
+
+func (z *ZipWriter) compressBlock(r io.Reader, dict []byte, compLevel int, last bool) (*bytes.Buffer, error) {
 	buf := new(bytes.Buffer)
 	var fw *flate.Writer
 	var err error
 	if len(dict) > 0 {
 		// There's no way to Reset a Writer with a new dictionary, so
 		// don't use the Pool
-		fw, err = flate.NewWriterDict(buf, z.compLevel, dict)
+		fw, err = flate.NewWriterDict(buf, compLevel, dict)
 	} else {
+		pool := z.compressorPool(compLevel)
 		var ok bool
-		if fw, ok = z.compressorPool.Get().(*flate.Writer); ok {
+		if fw, ok = pool.Get().(*flate.Writer); ok {
 			fw.Reset(buf)
 		} else {
-			fw, err = flate.NewWriter(buf, z.compLevel)
+			fw, err = flate.NewWriter(buf, compLevel)
 		}
-		defer z.compressorPool.Put(fw)
+		defer pool.Put(fw)
 	}
 	if err != nil {
 		return nil, err
@@ -886,7 +950,7 @@ func (z *ZipWriter) compressWholeFile(ze *zipEntry, r io.ReadSeeker, compressCha
 	close(ze.futureReaders)
 
 	if ze.fh.Method == zip.Deflate {
-		compressed, err := z.compressBlock(r, nil, true)
+		compressed, err := z.compressBlock(r, nil, ze.compLevel, true)
 		if err != nil {
 			z.errors <- err
 			return
@@ -109,6 +109,7 @@ func TestZip(t *testing.T) {
 		compressionLevel   int
 		emulateJar         bool
 		nonDeflatedFiles   map[string]bool
+		compressionRules   []CompressionRule
 		dirEntries         bool
 		manifest           string
 		storeSymlinks      bool
@@ -373,6 +374,35 @@ func TestZip(t *testing.T) {
 				fh("b", fileB, zip.Deflate),
 			},
 		},
+		{
+			name: "explicit path in zip",
+			args: fileArgsBuilder().
+				PathPrefixInZip("foo").
+				SourcePrefixToStrip("a").
+				ExplicitPathInZip("bar/b").
+				File("c").
+				File("a/a/b"),
+			compressionLevel: 9,
+
+			files: []zip.FileHeader{
+				fh("foo/bar/b", fileC, zip.Deflate),
+				fh("foo/a/b", fileB, zip.Deflate),
+			},
+		},
+		{
+			name: "explicit path in zip with junk paths",
+			args: fileArgsBuilder().
+				JunkPaths(true).
+				ExplicitPathInZip("bar/b").
+				File("a/a/a").
+				File("a/a/b"),
+			compressionLevel: 9,
+
+			files: []zip.FileHeader{
+				fh("bar/b", fileA, zip.Deflate),
+				fh("b", fileB, zip.Deflate),
+			},
+		},
 		{
 			name: "non deflated files",
 			args: fileArgsBuilder().
@@ -386,6 +416,40 @@ func TestZip(t *testing.T) {
 				fh("a/a/b", fileB, zip.Deflate),
 			},
 		},
+		{
+			name: "compression rules",
+			args: fileArgsBuilder().
+				File("a/a/a").
+				File("a/a/b").
+				File("c"),
+			compressionLevel: 9,
+			compressionRules: []CompressionRule{
+				{Pattern: "a/**/b", Method: zip.Store, Level: -1},
+				{Pattern: "c", Method: zip.Deflate, Level: 1},
+			},
+
+			files: []zip.FileHeader{
+				fh("a/a/a", fileA, zip.Deflate),
+				fh("a/a/b", fileB, zip.Store),
+				fh("c", fileC, zip.Deflate),
+			},
+		},
+		{
+			name: "compression rules with stored files",
+			args: fileArgsBuilder().
+				File("a/a/a").
+				File("c"),
+			compressionLevel: 9,
+			nonDeflatedFiles: map[string]bool{"c": true},
+			compressionRules: []CompressionRule{
+				{Pattern: "*", Method: zip.Deflate, Level: -1},
+			},
+
+			files: []zip.FileHeader{
+				fh("a/a/a", fileA, zip.Deflate),
+				fh("c", fileC, zip.Store),
+			},
+		},
 		{
 			name: "ignore missing files",
 			args: fileArgsBuilder().
@@ -441,6 +505,7 @@ func TestZip(t *testing.T) {
 			args.EmulateJar = test.emulateJar
 			args.AddDirectoryEntriesToZip = test.dirEntries
 			args.NonDeflatedFiles = test.nonDeflatedFiles
+			args.CompressionRules = test.compressionRules
 			args.ManifestSourcePath = test.manifest
 			args.StoreSymlinks = test.storeSymlinks
 			args.IgnoreMissingFiles = test.ignoreMissingFiles
@@ -535,6 +600,102 @@ func TestZip(t *testing.T) {
 	}
 }
 
+func TestExplicitPathInZipErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args *FileArgsBuilder
+		err  string
+	}{
+		{
+			name: "dir",
+			args: fileArgsBuilder().ExplicitPathInZip("bar").Dir("a"),
+			err:  `explicit path in zip "bar" must be followed by a file, got "a"`,
+		},
+		{
+			name: "list",
+			args: fileArgsBuilder().ExplicitPathInZip("bar").List("l"),
+			err:  `explicit path in zip "bar" must be followed by a file, got "l"`,
+		},
+		{
+			name: "rsp file",
+			args: fileArgsBuilder().ExplicitPathInZip("bar").RspFile("rsp"),
+			err:  `explicit path in zip "bar" must be followed by a file, got "rsp"`,
+		},
+		{
+			name: "only applies to the next file",
+			args: fileArgsBuilder().ExplicitPathInZip("bar").File("a/a/a").Dir("a"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.args.Error()
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			} else if err == nil || err.Error() != test.err {
+				t.Fatalf("want error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestParseCompressionRule(t *testing.T) {
+	testCases := []struct {
+		rule string
+		want CompressionRule
+		err  string
+	}{
+		{
+			rule: "*.png=store",
+			want: CompressionRule{Pattern: "*.png", Method: zip.Store, Level: -1},
+		},
+		{
+			rule: "res/**/*=deflate:9",
+			want: CompressionRule{Pattern: "res/**/*", Method: zip.Deflate, Level: 9},
+		},
+		{
+			rule: "*.class=zstd",
+			err:  `compression rule "*.class=zstd" uses zstd, which soong_zip can't write yet`,
+		},
+		{
+			rule: "*.png",
+			err:  `compression rule "*.png" must be of the form <pattern>=<method>[:<level>]`,
+		},
+		{
+			rule: "*.png=brotli",
+			err:  `compression rule "*.png=brotli" has unknown method "brotli", expected store or deflate`,
+		},
+		{
+			rule: "*.png=deflate:10",
+			err:  `compression rule "*.png=deflate:10" has invalid level "10" for deflate`,
+		},
+		{
+			rule: "*.png=store:1",
+			err:  `compression rule "*.png=store:1" has invalid level "1" for store`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.rule, func(t *testing.T) {
+			got, err := ParseCompressionRule(test.rule)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("want error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("want %#v, got %#v", test.want, got)
+			}
+		})
+	}
+}
+
 func TestSrcJar(t *testing.T) {
 	mockFs := pathtools.MockFs(map[string][]byte{
 		"wrong_package.java":       []byte("package foo;"),